@@ -0,0 +1,506 @@
+// Package client implements a typed client of the Kronos REST api, whose types are defined by package model.
+//
+//	c, err := client.New(client.Options{Server: "http://localhost:9175", APIKey: key, Namespace: "team-a"})
+//	if err != nil {
+//		return err
+//	}
+//
+//	sched, err := c.GetSchedule(ctx, id)
+//	if errors.Is(err, client.ErrNotFound) {
+//		...
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ostafen/kronos/model"
+)
+
+const (
+	apiKeyHeader         = "X-API-Key"
+	namespaceHeader      = "X-Kronos-Namespace"
+	idempotencyKeyHeader = "Idempotency-Key"
+
+	defaultTimeout = time.Minute
+)
+
+type Options struct {
+	// Server is the base url of the server, such as http://localhost:9175.
+	Server string
+	// APIKey is sent through the X-API-Key header.
+	APIKey string
+	// Token is sent as a bearer token, and is ignored when an api key is set.
+	Token string
+	// Namespace selects the namespace requests operate on.
+	Namespace  string
+	HTTPClient *http.Client
+	// Retry is the retry policy of idempotent requests, DefaultRetryPolicy if nil.
+	Retry *RetryPolicy
+}
+
+// Client is a client of the Kronos api, safe for concurrent use. Methods return an *Error
+// when the server answers with a non successful status code.
+type Client struct {
+	baseURL    *url.URL
+	apiKey     string
+	token      string
+	namespace  string
+	httpClient *http.Client
+	retry      RetryPolicy
+}
+
+func New(opts Options) (*Client, error) {
+	if opts.Server == "" {
+		return nil, fmt.Errorf("server url is required")
+	}
+
+	baseURL, err := url.Parse(strings.TrimSuffix(opts.Server, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid server url: %w", err)
+	}
+
+	if baseURL.Scheme != "http" && baseURL.Scheme != "https" {
+		return nil, fmt.Errorf("invalid server url %s: the scheme must be http or https", opts.Server)
+	}
+
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+
+	retry := DefaultRetryPolicy
+	if opts.Retry != nil {
+		retry = *opts.Retry
+	}
+
+	if retry.MaxAttempts < 1 {
+		return nil, fmt.Errorf("invalid retry policy: at least one attempt is required")
+	}
+
+	return &Client{
+		baseURL:    baseURL,
+		apiKey:     opts.APIKey,
+		token:      opts.Token,
+		namespace:  opts.Namespace,
+		httpClient: httpClient,
+		retry:      retry,
+	}, nil
+}
+
+// WithNamespace returns a copy of the client operating on the given namespace.
+func (c *Client) WithNamespace(namespace string) *Client {
+	clone := *c
+	clone.namespace = namespace
+	return &clone
+}
+
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   any
+	// once disables retries, for requests which are not idempotent or whose failures are meaningful to the caller
+	once bool
+}
+
+func (c *Client) newRequest(ctx context.Context, req *request, body []byte, idempotencyKey string) (*http.Request, error) {
+	u := *c.baseURL
+	u.Path += req.path
+	u.RawQuery = req.query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	switch {
+	case c.apiKey != "":
+		httpReq.Header.Set(apiKeyHeader, c.apiKey)
+	case c.token != "":
+		httpReq.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	if c.namespace != "" {
+		httpReq.Header.Set(namespaceHeader, c.namespace)
+	}
+
+	if idempotencyKey != "" {
+		httpReq.Header.Set(idempotencyKeyHeader, idempotencyKey)
+	}
+	return httpReq, nil
+}
+
+// send sends a request, retrying it according to the retry policy, and returns the body of the successful response.
+func (c *Client) send(ctx context.Context, req *request) ([]byte, error) {
+	var body []byte
+	if req.body != nil {
+		data, err := json.Marshal(req.body)
+		if err != nil {
+			return nil, err
+		}
+		body = data
+	}
+
+	attempts := c.retry.MaxAttempts
+	if req.once {
+		attempts = 1
+	}
+
+	// the same key is sent by every attempt, so that the server processes the request at most once
+	var key string
+	if req.method == http.MethodPost && !req.once {
+		k, err := idempotencyKey(ctx)
+		if err != nil {
+			return nil, err
+		}
+		key = k
+	}
+
+	for attempt := 1; ; attempt++ {
+		httpReq, err := c.newRequest(ctx, req, body, key)
+		if err != nil {
+			return nil, err
+		}
+
+		data, resp, err := c.roundTrip(httpReq)
+		if err == nil {
+			return data, nil
+		}
+
+		if attempt >= attempts || !shouldRetry(ctx, resp, err) {
+			return nil, err
+		}
+
+		if err := sleep(ctx, c.retry.backoff(attempt, resp)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// roundTrip sends a single request, and returns an *Error along with the response if its status code is not successful.
+func (c *Client) roundTrip(req *http.Request) ([]byte, *http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, resp, &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+	return data, resp, nil
+}
+
+// do sends a request and decodes its json response into out, unless nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	data, err := c.send(ctx, &request{method: method, path: path, query: query, body: body})
+	if err != nil {
+		return err
+	}
+	return decode(data, out)
+}
+
+func decode(data []byte, out any) error {
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+// call sends a request and returns its decoded json response.
+func call[T any](ctx context.Context, c *Client, method, path string, query url.Values, body any) (T, error) {
+	var res T
+	if err := c.do(ctx, method, path, query, body, &res); err != nil {
+		var zero T
+		return zero, err
+	}
+	return res, nil
+}
+
+func schedulePath(id int64, suffix string) string {
+	return "/api/v1/schedules/" + strconv.FormatInt(id, 10) + suffix
+}
+
+func windowQuery(window string) url.Values {
+	if window == "" {
+		return nil
+	}
+	return url.Values{"window": {window}}
+}
+
+func (c *Client) ListSchedules(ctx context.Context) ([]*model.CronSchedule, error) {
+	return call[[]*model.CronSchedule](ctx, c, "GET", "/api/v1/schedules", nil, nil)
+}
+
+func (c *Client) GetSchedule(ctx context.Context, id int64) (*model.CronSchedule, error) {
+	return call[*model.CronSchedule](ctx, c, "GET", schedulePath(id, ""), nil, nil)
+}
+
+func (c *Client) RegisterSchedule(ctx context.Context, input *model.ScheduleRegisterInput) (*model.CronSchedule, error) {
+	return call[*model.CronSchedule](ctx, c, "POST", "/api/v1/schedules", nil, input)
+}
+
+func (c *Client) DeleteSchedule(ctx context.Context, id int64) error {
+	return c.do(ctx, "DELETE", schedulePath(id, ""), nil, nil, nil)
+}
+
+func (c *Client) PauseSchedule(ctx context.Context, id int64) (*model.CronSchedule, error) {
+	return call[*model.CronSchedule](ctx, c, "POST", schedulePath(id, "/pause"), nil, nil)
+}
+
+func (c *Client) ResumeSchedule(ctx context.Context, id int64) (*model.CronSchedule, error) {
+	return call[*model.CronSchedule](ctx, c, "POST", schedulePath(id, "/resume"), nil, nil)
+}
+
+func (c *Client) TriggerSchedule(ctx context.Context, id int64) (*model.CronSchedule, error) {
+	return call[*model.CronSchedule](ctx, c, "POST", schedulePath(id, "/trigger"), nil, nil)
+}
+
+// GetScheduleStats returns the statistics of a schedule over the given window (e.g. "1h", "7d"),
+// or over the default one if empty.
+func (c *Client) GetScheduleStats(ctx context.Context, id int64, window string) (*model.CronStats, error) {
+	return call[*model.CronStats](ctx, c, "GET", schedulePath(id, "/stats"), windowQuery(window), nil)
+}
+
+func (c *Client) GetScheduleHistory(ctx context.Context, id int64) ([]*model.CronStatus, error) {
+	return call[[]*model.CronStatus](ctx, c, "GET", "/api/v1/history/"+strconv.FormatInt(id, 10), nil, nil)
+}
+
+func (c *Client) GetHistory(ctx context.Context) ([]*model.CronStatus, error) {
+	return call[[]*model.CronStatus](ctx, c, "GET", "/api/v1/history", nil, nil)
+}
+
+func (c *Client) GetStats(ctx context.Context, window string) (*model.CronStats, error) {
+	return call[*model.CronStats](ctx, c, "GET", "/api/v1/stats", windowQuery(window), nil)
+}
+
+// Apply reconciles the schedules of the namespace with the desired ones. When the apply fails after changing
+// some schedules, the result reporting the applied changes is returned along with the error.
+func (c *Client) Apply(ctx context.Context, req *model.ApplyRequest) (*model.ApplyResult, error) {
+	res, err := call[*model.ApplyResult](ctx, c, "POST", "/api/v1/apply", nil, req)
+	if partial := new(model.ApplyResult); partialResult(err, partial) {
+		return partial, err
+	}
+	return res, err
+}
+
+// ImportCrontab applies the schedules converted from the entries of a crontab, and reports the lines not imported.
+// As with Apply, a partially applied import is returned along with the error.
+func (c *Client) ImportCrontab(ctx context.Context, req *model.CrontabImportRequest) (*model.CrontabImportResult, error) {
+	res, err := call[*model.CrontabImportResult](ctx, c, "POST", "/api/v1/crontab/import", nil, req)
+	if partial := new(model.CrontabImportResult); partialResult(err, partial) {
+		return partial, err
+	}
+	return res, err
+}
+
+// partialResult decodes into out the result of a failed apply, which the server reports in place of an error
+// message, and returns whether there is one.
+func partialResult(err error, out any) bool {
+	var e *Error
+	return errors.As(err, &e) && json.Unmarshal([]byte(e.Message), out) == nil
+}
+
+// ExportCrontab returns the schedules rendered as crontab lines.
+func (c *Client) ExportCrontab(ctx context.Context) (string, error) {
+	data, err := c.send(ctx, &request{method: "GET", path: "/api/v1/crontab/export"})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (c *Client) QueryAudit(ctx context.Context, query *model.AuditQuery) (*model.AuditPage, error) {
+	values := url.Values{}
+	setQuery(values, "namespace", query.Namespace)
+	setQuery(values, "actor", query.Actor)
+	setQuery(values, "action", string(query.Action))
+
+	if query.ScheduleID != 0 {
+		values.Set("scheduleId", strconv.FormatInt(query.ScheduleID, 10))
+	}
+
+	if query.Cursor != 0 {
+		values.Set("cursor", strconv.FormatInt(query.Cursor, 10))
+	}
+
+	if query.Limit != 0 {
+		values.Set("limit", strconv.Itoa(query.Limit))
+	}
+
+	if !query.From.IsZero() {
+		values.Set("from", query.From.Format(time.RFC3339))
+	}
+
+	if !query.To.IsZero() {
+		values.Set("to", query.To.Format(time.RFC3339))
+	}
+
+	return call[*model.AuditPage](ctx, c, "GET", "/api/v1/audit", values, nil)
+}
+
+func setQuery(values url.Values, name, value string) {
+	if value != "" {
+		values.Set(name, value)
+	}
+}
+
+func (c *Client) ListNamespaces(ctx context.Context) ([]*model.Namespace, error) {
+	return call[[]*model.Namespace](ctx, c, "GET", "/api/v1/namespaces", nil, nil)
+}
+
+func (c *Client) GetNamespace(ctx context.Context, name string) (*model.Namespace, error) {
+	return call[*model.Namespace](ctx, c, "GET", "/api/v1/namespaces/"+url.PathEscape(name), nil, nil)
+}
+
+func (c *Client) CreateNamespace(ctx context.Context, input *model.NamespaceInput) (*model.Namespace, error) {
+	return call[*model.Namespace](ctx, c, "POST", "/api/v1/namespaces", nil, input)
+}
+
+func (c *Client) UpdateNamespace(ctx context.Context, input *model.NamespaceInput) (*model.Namespace, error) {
+	return call[*model.Namespace](ctx, c, "PUT", "/api/v1/namespaces/"+url.PathEscape(input.Name), nil, input)
+}
+
+func (c *Client) DeleteNamespace(ctx context.Context, name string) error {
+	return c.do(ctx, "DELETE", "/api/v1/namespaces/"+url.PathEscape(name), nil, nil, nil)
+}
+
+func (c *Client) ListAPIKeys(ctx context.Context) ([]*model.APIKey, error) {
+	return call[[]*model.APIKey](ctx, c, "GET", "/api/v1/apikeys", nil, nil)
+}
+
+// CreateAPIKey creates an api key, whose plaintext value is only returned by this call. The request is never retried.
+func (c *Client) CreateAPIKey(ctx context.Context, input *model.APIKeyCreateInput) (*model.CreatedAPIKey, error) {
+	data, err := c.send(ctx, &request{method: "POST", path: "/api/v1/apikeys", body: input, once: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var key model.CreatedAPIKey
+	if err := decode(data, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (c *Client) DeleteAPIKey(ctx context.Context, id int64) error {
+	return c.do(ctx, "DELETE", "/api/v1/apikeys/"+strconv.FormatInt(id, 10), nil, nil, nil)
+}
+
+func (c *Client) ListRoleBindings(ctx context.Context) ([]*model.RoleBinding, error) {
+	return call[[]*model.RoleBinding](ctx, c, "GET", "/api/v1/rolebindings", nil, nil)
+}
+
+func (c *Client) CreateRoleBinding(ctx context.Context, input *model.RoleBindingInput) (*model.RoleBinding, error) {
+	return call[*model.RoleBinding](ctx, c, "POST", "/api/v1/rolebindings", nil, input)
+}
+
+func (c *Client) DeleteRoleBinding(ctx context.Context, id int64) error {
+	return c.do(ctx, "DELETE", "/api/v1/rolebindings/"+strconv.FormatInt(id, 10), nil, nil, nil)
+}
+
+func (c *Client) ListSecrets(ctx context.Context) ([]*model.Secret, error) {
+	return call[[]*model.Secret](ctx, c, "GET", "/api/v1/secrets", nil, nil)
+}
+
+func (c *Client) PutSecret(ctx context.Context, name, value string) (*model.Secret, error) {
+	// model.SecretInput is not used, since its value is redacted when encoded
+	body := map[string]string{"value": value}
+
+	return call[*model.Secret](ctx, c, "PUT", "/api/v1/secrets/"+url.PathEscape(name), nil, body)
+}
+
+func (c *Client) DeleteSecret(ctx context.Context, name string) error {
+	return c.do(ctx, "DELETE", "/api/v1/secrets/"+url.PathEscape(name), nil, nil, nil)
+}
+
+func (c *Client) ListCalendars(ctx context.Context) ([]*model.Calendar, error) {
+	return call[[]*model.Calendar](ctx, c, "GET", "/api/v1/calendars", nil, nil)
+}
+
+func (c *Client) GetCalendar(ctx context.Context, name string) (*model.Calendar, error) {
+	return call[*model.Calendar](ctx, c, "GET", "/api/v1/calendars/"+url.PathEscape(name), nil, nil)
+}
+
+// PutCalendar creates the calendar named after input.Name, or replaces an existing one.
+func (c *Client) PutCalendar(ctx context.Context, input *model.CalendarInput) (*model.Calendar, error) {
+	return call[*model.Calendar](ctx, c, "PUT", "/api/v1/calendars/"+url.PathEscape(input.Name), nil, input)
+}
+
+func (c *Client) DeleteCalendar(ctx context.Context, name string) error {
+	return c.do(ctx, "DELETE", "/api/v1/calendars/"+url.PathEscape(name), nil, nil, nil)
+}
+
+type HealthStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Liveness returns the status reported by the liveness probe. An unavailable server is not reported as an error.
+func (c *Client) Liveness(ctx context.Context) (*HealthStatus, error) {
+	return c.health(ctx, "/healthz")
+}
+
+// Readiness returns the status reported by the readiness probe. An unavailable server is not reported as an error.
+func (c *Client) Readiness(ctx context.Context) (*HealthStatus, error) {
+	return c.health(ctx, "/readyz")
+}
+
+func (c *Client) health(ctx context.Context, path string) (*HealthStatus, error) {
+	data, err := c.send(ctx, &request{method: "GET", path: path, once: true})
+
+	var status HealthStatus
+
+	// an unavailable server reports its status with a 503
+	var e *Error
+	if errors.As(err, &e) && e.StatusCode == http.StatusServiceUnavailable && json.Unmarshal([]byte(e.Message), &status) == nil {
+		return &status, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := decode(data, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Metrics returns the metrics of the server, in the Prometheus text format.
+func (c *Client) Metrics(ctx context.Context) (string, error) {
+	data, err := c.send(ctx, &request{method: "GET", path: "/metrics"})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
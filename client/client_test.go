@@ -0,0 +1,146 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ostafen/kronos/model"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedRequest struct {
+	method         string
+	idempotencyKey string
+}
+
+// newFakeServer returns a client of a server answering with the given status codes, in order,
+// and then with 200 and an empty json object.
+func newFakeServer(t *testing.T, statuses ...int) (*Client, func() []recordedRequest) {
+	var (
+		mtx      sync.Mutex
+		requests []recordedRequest
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		n := len(requests)
+		requests = append(requests, recordedRequest{method: r.Method, idempotencyKey: r.Header.Get(idempotencyKeyHeader)})
+
+		if n < len(statuses) {
+			http.Error(w, "failure", statuses[n])
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	t.Cleanup(server.Close)
+
+	c, err := New(Options{
+		Server: server.URL,
+		Retry:  &RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	return c, func() []recordedRequest {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return requests
+	}
+}
+
+func TestRetry(t *testing.T) {
+	t.Run("transient failures", func(t *testing.T) {
+		c, requests := newFakeServer(t, http.StatusServiceUnavailable, http.StatusBadGateway)
+
+		_, err := c.GetSchedule(context.Background(), 1)
+		require.NoError(t, err)
+		require.Len(t, requests(), 3)
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		c, requests := newFakeServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+
+		_, err := c.ListSchedules(context.Background())
+		require.ErrorIs(t, err, ErrUnavailable)
+		require.Len(t, requests(), 3)
+	})
+
+	t.Run("client errors", func(t *testing.T) {
+		c, requests := newFakeServer(t, http.StatusConflict)
+
+		_, err := c.RegisterSchedule(context.Background(), &model.ScheduleRegisterInput{})
+		require.ErrorIs(t, err, ErrConflict)
+		require.Len(t, requests(), 1)
+	})
+
+	t.Run("post requests reuse their idempotency key", func(t *testing.T) {
+		c, requests := newFakeServer(t, http.StatusTooManyRequests)
+
+		_, err := c.TriggerSchedule(context.Background(), 1)
+		require.NoError(t, err)
+
+		_, err = c.TriggerSchedule(WithIdempotencyKey(context.Background(), "my-key"), 1)
+		require.NoError(t, err)
+
+		reqs := requests()
+		require.Len(t, reqs, 3)
+		require.NotEmpty(t, reqs[0].idempotencyKey)
+		require.Equal(t, reqs[0].idempotencyKey, reqs[1].idempotencyKey)
+		require.Equal(t, "my-key", reqs[2].idempotencyKey)
+	})
+
+	t.Run("api key creation", func(t *testing.T) {
+		c, requests := newFakeServer(t, http.StatusServiceUnavailable)
+
+		_, err := c.CreateAPIKey(context.Background(), &model.APIKeyCreateInput{Name: "key"})
+		require.ErrorIs(t, err, ErrUnavailable)
+
+		reqs := requests()
+		require.Len(t, reqs, 1)
+		require.Empty(t, reqs[0].idempotencyKey)
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		c, requests := newFakeServer(t, http.StatusServiceUnavailable)
+		c.retry.MinBackoff = time.Hour
+		c.retry.MaxBackoff = time.Hour
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := c.ListNamespaces(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Len(t, requests(), 1)
+	})
+}
+
+func TestErrorIs(t *testing.T) {
+	var err error = &Error{StatusCode: http.StatusNotFound, Message: "schedule not found"}
+
+	require.ErrorIs(t, err, ErrNotFound)
+	require.NotErrorIs(t, err, ErrConflict)
+	require.NotErrorIs(t, ErrNotFound, err)
+
+	var e *Error
+	require.True(t, errors.As(err, &e))
+	require.Equal(t, "schedule not found", e.Message)
+}
+
+func TestBackoff(t *testing.T) {
+	p := &RetryPolicy{MaxAttempts: 10, MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	for retry, max := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		d := p.backoff(retry+1, nil)
+		require.GreaterOrEqual(t, d, max/2)
+		require.LessOrEqual(t, d, max)
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": {"3"}}}
+	require.Equal(t, time.Second, p.backoff(1, resp))
+}
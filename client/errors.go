@@ -0,0 +1,46 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Error is returned when the server answers with a non successful status code, and holds the message of the server.
+// It can be matched against the errors below through errors.Is, which only compares status codes.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+var (
+	// ErrBadRequest is returned for invalid inputs, including webhooks to denied destinations
+	// and references to unknown TLS profiles, credentials or secrets.
+	ErrBadRequest = &Error{StatusCode: http.StatusBadRequest}
+	// ErrUnauthorized is returned when the api key or token is missing, invalid or expired.
+	ErrUnauthorized = &Error{StatusCode: http.StatusUnauthorized}
+	// ErrForbidden is returned when the caller lacks the required scope or role, or a namespace quota is exceeded.
+	ErrForbidden = &Error{StatusCode: http.StatusForbidden}
+	ErrNotFound  = &Error{StatusCode: http.StatusNotFound}
+	// ErrConflict is returned when a title or namespace is already in use, when deleting a non empty namespace
+	// or a secret referenced by schedules, and when a request with the same idempotency key is in progress.
+	ErrConflict = &Error{StatusCode: http.StatusConflict}
+	// ErrIdempotencyKeyReused is returned when an idempotency key is reused for a different request.
+	ErrIdempotencyKeyReused = &Error{StatusCode: http.StatusUnprocessableEntity}
+	ErrRateLimited          = &Error{StatusCode: http.StatusTooManyRequests}
+	// ErrNotImplemented is returned by the secret endpoints when the server has no encryption key.
+	ErrNotImplemented = &Error{StatusCode: http.StatusNotImplemented}
+	ErrUnavailable    = &Error{StatusCode: http.StatusServiceUnavailable}
+)
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("request failed with status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("request failed with status %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Is reports whether target is an Error with the same status code and without a message, like the errors above.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Message == "" && t.StatusCode == e.StatusCode
+}
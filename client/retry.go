@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how requests are retried after a network error, or a 429, 502, 503 or 504 status.
+//
+// Only idempotent requests are retried: GET, PUT and DELETE requests, and POST requests, which the client sends
+// with an Idempotency-Key header so that the server replays the response to a request it already processed.
+// The creation of api keys is never retried, since the server doesn't replay responses holding a key.
+// Note that a DELETE retried after a lost response may fail with ErrNotFound, the resource being already deleted.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a request, including the first one: 1 disables retries.
+	MaxAttempts int
+	// MinBackoff is the wait before the first retry, which doubles at each retry up to MaxBackoff.
+	// A Retry-After header sent by the server takes precedence, but is capped by MaxBackoff too.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	MinBackoff:  200 * time.Millisecond,
+	MaxBackoff:  5 * time.Second,
+}
+
+// backoff returns the wait before the given retry, starting from 1.
+func (p *RetryPolicy) backoff(retry int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, p.MaxBackoff)
+		}
+	}
+
+	d := p.MinBackoff
+	for i := 1; i < retry && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, p.MaxBackoff)
+
+	// a random jitter prevents clients failing together from retrying together
+	if d > 1 {
+		d = d/2 + rand.N(d/2)
+	}
+	return d
+}
+
+// shouldRetry reports whether a request which failed with the given error is worth retrying.
+// resp is nil when no response was received.
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if resp == nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// sleep waits for d, unless ctx is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type idempotencyKeyCtxKey struct{}
+
+// WithIdempotencyKey makes the POST requests issued with the returned context carry the given idempotency key,
+// in place of a random one. This allows to safely repeat an operation across restarts of the caller.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtxKey{}, key)
+}
+
+func idempotencyKey(ctx context.Context) (string, error) {
+	if key, ok := ctx.Value(idempotencyKeyCtxKey{}).(string); ok && key != "" {
+		return key, nil
+	}
+
+	var buf [16]byte
+	if _, err := crand.Read(buf[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf[:]), nil
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"io"
+
+	"github.com/ostafen/kronos/internal/ctl"
+)
+
+// runApply implements the "kronos apply" command, which is the same as "kronosctl apply",
+// and returns the exit code of the process.
+func runApply(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	return ctl.Run("kronos", append([]string{"apply"}, args...), stdin, stdout, stderr)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ostafen/kronos/internal/auth"
+	"github.com/ostafen/kronos/internal/ctl"
+	"github.com/ostafen/kronos/model"
+	"github.com/stretchr/testify/require"
+)
+
+const scheduleYAML = `
+namespace: default
+schedules:
+  - title: nightly-report
+    url: %[1]s
+    cronExpr: "%[2]s"
+    isRecurring: true
+    endAt: %[3]s
+    metadata:
+      team: billing
+%[4]s`
+
+const cleanupYAML = `
+  - title: cleanup
+    url: %s
+    cronExpr: "0 3 * * *"
+    isRecurring: true
+`
+
+func TestApply(t *testing.T) {
+	env := newTestEnv(t)
+	t.Setenv("KRONOSCTL_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+
+	server := httptest.NewServer(env.router)
+	t.Cleanup(server.Close)
+
+	key := env.aUser("deployer", auth.RoleEditor, nil)
+	endAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+
+	file := filepath.Join(t.TempDir(), "schedules.yaml")
+	writeFile := func(cronExpr string, withCleanup bool) {
+		extra := ""
+		if withCleanup {
+			extra = fmt.Sprintf(cleanupYAML, env.webhookURL)
+		}
+		data := fmt.Sprintf(scheduleYAML, env.webhookURL, cronExpr, endAt, extra)
+		require.NoError(t, os.WriteFile(file, []byte(data), 0600))
+	}
+
+	apply := func(args ...string) (int, string) {
+		var stdout, stderr bytes.Buffer
+		args = append([]string{"-f", file, "--server", server.URL, "--api-key", key, "--detailed-exitcode"}, args...)
+		code := runApply(args, nil, &stdout, &stderr)
+		require.Empty(t, stderr.String())
+		return code, stdout.String()
+	}
+
+	titles := func() map[string]*model.CronSchedule {
+		res := make(map[string]*model.CronSchedule)
+		require.NoError(t, env.svc.IterSchedules(context.Background(), func(s *model.CronSchedule) error {
+			res[s.Title] = s
+			return nil
+		}))
+		return res
+	}
+
+	writeFile("0 2 * * *", true)
+
+	code, out := apply("--dry-run")
+	require.Equal(t, ctl.ExitDrift, code)
+	require.Contains(t, out, `+ create "nightly-report"`)
+	require.Contains(t, out, `+ create "cleanup"`)
+	require.Contains(t, out, "(dry run)")
+	require.Empty(t, titles())
+
+	code, _ = apply()
+	require.Equal(t, ctl.ExitDrift, code)
+	require.Len(t, titles(), 2)
+
+	code, out = apply()
+	require.Equal(t, 0, code)
+	require.Contains(t, out, "0 create, 0 update, 0 delete, 2 unchanged, 0 unmanaged")
+
+	writeFile("0 4 * * *", false)
+
+	code, out = apply()
+	require.Equal(t, ctl.ExitDrift, code)
+	require.Contains(t, out, `~ update "nightly-report"`)
+	require.Contains(t, out, `cronExpr: "0 2 * * *" -> "0 4 * * *"`)
+	require.Contains(t, out, `! unmanaged "cleanup"`)
+
+	before := titles()
+	require.Equal(t, "0 4 * * *", before["nightly-report"].CronExpr)
+	require.Contains(t, before, "cleanup")
+
+	code, out = apply("--prune")
+	require.Equal(t, ctl.ExitDrift, code)
+	require.Contains(t, out, `- delete "cleanup"`)
+
+	after := titles()
+	require.Len(t, after, 1)
+	require.Equal(t, before["nightly-report"].ID, after["nightly-report"].ID)
+	require.Equal(t, before["nightly-report"].CreatedAt.Unix(), after["nightly-report"].CreatedAt.Unix())
+
+	page, err := env.svc.QueryAudit(context.Background(), &model.AuditQuery{Action: model.AuditActionUpdate})
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 1)
+}
+
+func TestApplyRejectsInvalidFiles(t *testing.T) {
+	env := newTestEnv(t)
+	t.Setenv("KRONOSCTL_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+
+	server := httptest.NewServer(env.router)
+	t.Cleanup(server.Close)
+
+	key := env.aUser("deployer", auth.RoleEditor, nil)
+
+	for _, data := range []string{
+		"schedules:\n  - title: a\n    url: http://localhost\n    isRecurring: true\n    cronExpr: invalid\n",
+		"schedules:\n  - title: a\n    url: http://localhost\n    isRecurring: true\n    cronExpr: '* * * * *'\n  - title: a\n    url: http://localhost\n    isRecurring: true\n    cronExpr: '* * * * *'\n",
+		"schedules:\n  - title: a\n    unknownField: true\n",
+	} {
+		var stdout, stderr bytes.Buffer
+		code := runApply([]string{"-f", "-", "--server", server.URL, "--api-key", key}, bytes.NewBufferString(data), &stdout, &stderr)
+		require.Equal(t, 1, code)
+		require.NotEmpty(t, stderr.String())
+	}
+
+	require.NoError(t, env.svc.IterSchedules(context.Background(), func(s *model.CronSchedule) error {
+		return fmt.Errorf("unexpected schedule %q", s.Title)
+	}))
+}
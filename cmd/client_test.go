@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ostafen/kronos/client"
+	"github.com/ostafen/kronos/internal/auth"
+	"github.com/ostafen/kronos/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	env := newTestEnv(t)
+
+	server := httptest.NewServer(env.router)
+	t.Cleanup(server.Close)
+
+	newClient := func(key string) *client.Client {
+		c, err := client.New(client.Options{Server: server.URL, APIKey: key, Namespace: model.DefaultNamespace})
+		require.NoError(t, err)
+		return c
+	}
+
+	ctx := context.Background()
+	editor := newClient(env.aUser("editor", auth.RoleEditor, nil))
+	viewer := newClient(env.aUser("viewer", auth.RoleViewer, nil))
+
+	recurring := true
+	input := &model.ScheduleRegisterInput{
+		Title:       "nightly",
+		URL:         env.webhookURL,
+		IsRecurring: &recurring,
+		CronExpr:    "0 2 * * *",
+		Metadata:    map[string]string{"team": "billing"},
+	}
+
+	sched, err := editor.RegisterSchedule(ctx, input)
+	require.NoError(t, err)
+	require.Equal(t, "nightly", sched.Title)
+	require.Equal(t, model.DefaultNamespace, sched.Namespace)
+
+	got, err := viewer.GetSchedule(ctx, sched.ID)
+	require.NoError(t, err)
+	require.Equal(t, sched.ID, got.ID)
+	require.Equal(t, input.Metadata, got.Metadata)
+
+	schedules, err := viewer.ListSchedules(ctx)
+	require.NoError(t, err)
+	require.Len(t, schedules, 1)
+
+	paused, err := editor.PauseSchedule(ctx, sched.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.ScheduleStatusPaused, paused.Status)
+
+	resumed, err := editor.ResumeSchedule(ctx, sched.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.ScheduleStatusActive, resumed.Status)
+
+	res, err := editor.Apply(ctx, &model.ApplyRequest{Schedules: []*model.ScheduleRegisterInput{input}, DryRun: true})
+	require.NoError(t, err)
+	require.False(t, res.Drift)
+
+	page, err := viewer.QueryAudit(ctx, &model.AuditQuery{ScheduleID: sched.ID, Action: model.AuditActionPause})
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 1)
+
+	_, err = viewer.GetStats(ctx, "1h")
+	require.NoError(t, err)
+
+	status, err := viewer.Readiness(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "ok", status.Status)
+
+	// typed errors
+	_, err = editor.RegisterSchedule(ctx, input)
+	require.ErrorIs(t, err, client.ErrConflict)
+
+	_, err = viewer.PauseSchedule(ctx, sched.ID)
+	require.ErrorIs(t, err, client.ErrForbidden)
+
+	_, err = newClient("invalid").ListSchedules(ctx)
+	require.ErrorIs(t, err, client.ErrUnauthorized)
+
+	_, err = editor.RegisterSchedule(ctx, &model.ScheduleRegisterInput{Title: "invalid"})
+	require.ErrorIs(t, err, client.ErrBadRequest)
+
+	require.NoError(t, editor.DeleteSchedule(ctx, sched.ID))
+
+	_, err = viewer.GetSchedule(ctx, sched.ID)
+	require.ErrorIs(t, err, client.ErrNotFound)
+
+	var e *client.Error
+	require.ErrorAs(t, err, &e)
+	require.NotEmpty(t, e.Message)
+}
+
+func TestClientIdempotentRetries(t *testing.T) {
+	env := newTestEnv(t)
+
+	server := httptest.NewServer(env.router)
+	t.Cleanup(server.Close)
+
+	c, err := client.New(client.Options{Server: server.URL, APIKey: env.aUser("editor", auth.RoleEditor, nil)})
+	require.NoError(t, err)
+
+	recurring := true
+	input := &model.ScheduleRegisterInput{Title: "nightly", URL: env.webhookURL, IsRecurring: &recurring, CronExpr: "0 2 * * *"}
+
+	// a request repeated with the same idempotency key, as by a retry, is replayed rather than failing with a conflict
+	ctx := client.WithIdempotencyKey(context.Background(), "register-nightly")
+
+	first, err := c.RegisterSchedule(ctx, input)
+	require.NoError(t, err)
+
+	second, err := c.RegisterSchedule(ctx, input)
+	require.NoError(t, err)
+	require.Equal(t, first.ID, second.ID)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ostafen/kronos/internal/auth"
+	"github.com/ostafen/kronos/internal/ctl"
+	"github.com/ostafen/kronos/model"
+	"github.com/stretchr/testify/require"
+)
+
+const testCrontab = `# nightly backup
+0 2 * * * /usr/local/bin/backup
+*/5 * * * * /usr/local/bin/poll
+@reboot /usr/local/bin/warmup
+`
+
+func TestCrontabImportExport(t *testing.T) {
+	env := newTestEnv(t)
+	t.Setenv("KRONOSCTL_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+
+	server := httptest.NewServer(env.router)
+	t.Cleanup(server.Close)
+
+	key := env.aUser("editor", auth.RoleEditor, nil)
+
+	run := func(stdin string, args ...string) string {
+		var stdout, stderr bytes.Buffer
+		args = append(args, "--server", server.URL, "--api-key", key, "--namespace", model.DefaultNamespace)
+
+		code := ctl.Run("kronosctl", args, strings.NewReader(stdin), &stdout, &stderr)
+		require.Equal(t, 0, code, stderr.String())
+		return stdout.String()
+	}
+
+	importCrontab := func(crontab string, args ...string) string {
+		return run(crontab, append([]string{"crontab", "import", "-f", "-", "--url-template", env.webhookURL + "?entry={{.ID}}"}, args...)...)
+	}
+
+	out := importCrontab(testCrontab, "--dry-run")
+	require.Contains(t, out, "line 4 skipped: unsupported macro @reboot")
+	require.Contains(t, out, "2 create, 0 update, 0 delete, 0 unchanged, 0 unmanaged (dry run)")
+	require.NotContains(t, run("", "schedules", "list"), "backup")
+
+	out = importCrontab(testCrontab)
+	require.Contains(t, out, "2 create, 0 update, 0 delete, 0 unchanged, 0 unmanaged")
+
+	// importing the crontab again changes nothing, while changed entries update their schedule
+	out = importCrontab(testCrontab)
+	require.Contains(t, out, "0 create, 0 update, 0 delete, 2 unchanged, 0 unmanaged")
+
+	out = importCrontab(strings.Replace(testCrontab, "# nightly backup", "# nightly backup of the database", 1))
+	require.Contains(t, out, "0 create, 1 update, 0 delete, 1 unchanged, 0 unmanaged")
+
+	exported := run("", "crontab", "export")
+	require.Contains(t, exported, "# nightly backup of the database\n0 2 * * * /usr/local/bin/backup\n")
+	require.Contains(t, exported, "*/5 * * * * /usr/local/bin/poll\n")
+
+	// the exported crontab imports to the same schedules
+	out = importCrontab(exported)
+	require.Contains(t, out, "0 create, 0 update, 0 delete, 2 unchanged, 0 unmanaged")
+}
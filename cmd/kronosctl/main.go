@@ -0,0 +1,11 @@
+package main
+
+import (
+	"os"
+
+	"github.com/ostafen/kronos/internal/ctl"
+)
+
+func main() {
+	os.Exit(ctl.Run("kronosctl", os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
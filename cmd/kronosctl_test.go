@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ostafen/kronos/internal/auth"
+	"github.com/ostafen/kronos/internal/ctl"
+	"github.com/ostafen/kronos/model"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestKronosctl(t *testing.T) {
+	env := newTestEnv(t)
+	t.Setenv("KRONOSCTL_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+
+	server := httptest.NewServer(env.router)
+	t.Cleanup(server.Close)
+
+	key := env.aUser("operator", auth.RoleEditor, nil)
+
+	run := func(args ...string) string {
+		var stdout, stderr bytes.Buffer
+		code := ctl.Run("kronosctl", args, nil, &stdout, &stderr)
+		require.Equal(t, 0, code, stderr.String())
+		return stdout.String()
+	}
+
+	run("config", "set-context", "local", "--server", server.URL, "--api-key", key, "--namespace", model.DefaultNamespace)
+	run("config", "use-context", "local")
+	require.Equal(t, "local\n", run("config", "current-context"))
+
+	var created model.CronSchedule
+	out := run("schedules", "create", "--title", "nightly", "--url", env.webhookURL, "--cron", "0 2 * * *", "--metadata", "team=billing", "-o", "json")
+	require.NoError(t, json.Unmarshal([]byte(out), &created))
+	require.Equal(t, "nightly", created.Title)
+	require.Equal(t, "0 2 * * *", created.CronExpr)
+	require.Equal(t, map[string]string{"team": "billing"}, created.Metadata)
+
+	id := strconv.FormatInt(created.ID, 10)
+
+	out = run("schedules", "list")
+	require.Contains(t, out, "TITLE")
+	require.Contains(t, out, "nightly")
+
+	var updated map[string]any
+	out = run("schedules", "update", id, "--cron", "0 4 * * *", "-o", "yaml")
+	require.NoError(t, yaml.Unmarshal([]byte(out), &updated))
+	require.Equal(t, int(created.ID), updated["id"])
+	require.Equal(t, "0 4 * * *", updated["cronExpr"])
+	require.Equal(t, map[string]any{"team": "billing"}, updated["metadata"])
+
+	out = run("schedules", "pause", id)
+	require.Contains(t, out, string(model.ScheduleStatusPaused))
+
+	out = run("schedules", "resume", id)
+	require.Contains(t, out, string(model.ScheduleStatusActive))
+
+	run("schedules", "delete", id)
+	require.NotContains(t, run("schedules", "list"), "nightly")
+
+	out = run("health")
+	require.Contains(t, out, "readiness")
+
+	// explicit credentials take precedence over those of the context
+	var stdout, stderr bytes.Buffer
+	code := ctl.Run("kronosctl", []string{"schedules", "list", "--api-key", "invalid"}, nil, &stdout, &stderr)
+	require.Equal(t, 1, code)
+	require.True(t, strings.HasPrefix(stderr.String(), "Error:"))
+}
@@ -1,16 +1,29 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/ostafen/kronos/engine"
 	"github.com/ostafen/kronos/internal/api"
+	"github.com/ostafen/kronos/internal/auth"
 	"github.com/ostafen/kronos/internal/config"
+	"github.com/ostafen/kronos/internal/egress"
+	"github.com/ostafen/kronos/internal/oauth"
+	"github.com/ostafen/kronos/internal/secrets"
 	"github.com/ostafen/kronos/internal/service"
-	"github.com/ostafen/kronos/internal/store"
+	"github.com/ostafen/kronos/internal/tlsprofile"
+	"github.com/ostafen/kronos/internal/tracing"
+	"github.com/ostafen/kronos/model"
+	"github.com/ostafen/kronos/store"
 	statichttp "github.com/ostafen/kronos/webbuild"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -48,6 +61,10 @@ func printLogo() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "apply" {
+		os.Exit(runApply(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	printLogo()
 
 	conf, err := config.Read()
@@ -57,22 +74,146 @@ func main() {
 
 	setupLogging(conf.Logging)
 
+	shutdownTracing, err := tracing.Setup(context.Background(), conf.Tracing, getVersion())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
 	store, err := store.New(conf.Store.Path)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	svc := service.NewScheduleService(
-		store,
-		service.NewNotificationService(),
-	)
-	defer svc.Stop()
+	policy, err := egress.NewPolicy(conf.Egress)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	profiles, err := tlsprofile.NewRegistry(conf.Webhooks)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	credentials, err := oauth.NewRegistry(conf.Webhooks)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	keyring, err := secrets.NewKeyring(conf.Secrets)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	secretSvc := service.NewSecretService(store, keyring)
+
+	rotated, err := secretSvc.RotateSecrets(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if rotated > 0 {
+		log.WithField("count", rotated).Info("secrets re-encrypted with the current key")
+	}
+
+	eng, err := engine.New(engine.Options{
+		Store:        store,
+		DefaultQuota: model.Quota(conf.Tenancy.DefaultQuota),
+		Notifier:     service.NewNotificationService(policy, profiles, credentials),
+		Secrets:      secretSvc,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := eng.Start(); err != nil {
+		log.Fatal(err)
+	}
 
-	configureRouter(svc)
+	svc, nsSvc, calendarSvc := eng.Schedules(), eng.Namespaces(), eng.Calendars()
+
+	keySvc := service.NewAPIKeyService(store)
+	bindingSvc := service.NewRoleBindingService(store)
+	idemSvc := service.NewIdempotencyService(store, conf.Idempotency.Retention)
+
+	authn, err := auth.NewAuthenticator(conf.Auth, keySvc)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", conf.Port),
+		Handler: configureRouter(conf, svc, nsSvc, keySvc, bindingSvc, secretSvc, calendarSvc, idemSvc, authn),
+	}
+
+	if conf.TLS.CertFile != "" {
+		server.TLSConfig, err = tlsprofile.NewServerConfig(conf.TLS)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	servers := []*http.Server{server}
+	if separateMetrics(conf) {
+		servers = append(servers, &http.Server{
+			Addr:    fmt.Sprintf(":%d", conf.Metrics.Port),
+			Handler: newManagementRouter(svc),
+		})
+	}
 
-	http.ListenAndServe(fmt.Sprintf(":%d", conf.Port), nil)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// TODO: soft shutdown
+	for _, server := range servers {
+		go serve(server)
+	}
+
+	<-ctx.Done()
+
+	shutdown(servers, eng, store, conf.ShutdownTimeout)
+}
+
+// serve listens over TLS when the server has a TLS configuration, which also enables HTTP/2.
+func serve(server *http.Server) {
+	log.WithField("addr", server.Addr).
+		WithField("tls", server.TLSConfig != nil).
+		Info("listening")
+
+	var err error
+	if server.TLSConfig != nil {
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
+
+// shutdown stops accepting new requests and ticks, drains in-flight requests and deliveries
+// and finally closes the store.
+func shutdown(servers []*http.Server, eng *engine.Engine, store store.Store, timeout time.Duration) {
+	log.WithField("timeout", timeout).Info("shutting down")
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			log.WithError(err).
+				WithField("addr", server.Addr).
+				Error("unable to gracefully shutdown the http server")
+		}
+	}
+
+	if err := eng.Stop(ctx); err != nil {
+		log.WithError(err).Error("unable to drain in-flight deliveries")
+	}
+
+	if err := store.Close(); err != nil {
+		log.WithError(err).Error("unable to close the store")
+	}
 }
 
 func setupLogging(config config.Log) {
@@ -107,34 +248,128 @@ func getFormatter(format string) log.Formatter {
 	return &log.JSONFormatter{}
 }
 
-func configureRouter(svc service.ScheduleService) {
+func configureRouter(
+	conf *config.Config,
+	svc service.ScheduleService,
+	nsSvc service.NamespaceService,
+	keySvc service.APIKeyService,
+	bindingSvc service.RoleBindingService,
+	secretSvc service.SecretService,
+	calendarSvc service.CalendarService,
+	idemSvc service.IdempotencyService,
+	authn *auth.Authenticator,
+) http.Handler {
+	r := newRouter(conf, svc, nsSvc, keySvc, bindingSvc, secretSvc, calendarSvc, idemSvc, authn)
+
+	return withCors(r, cors.Options{
+		AllowedOrigins: conf.Cors.AllowedOrigins,
+		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
+		AllowedHeaders: []string{"Content-Type", "Authorization", "X-API-Key", auth.NamespaceHeader, api.IdempotencyKeyHeader},
+		ExposedHeaders: []string{api.IdempotentReplayedHeader},
+	})
+}
+
+func newRouter(
+	conf *config.Config,
+	svc service.ScheduleService,
+	nsSvc service.NamespaceService,
+	keySvc service.APIKeyService,
+	bindingSvc service.RoleBindingService,
+	secretSvc service.SecretService,
+	calendarSvc service.CalendarService,
+	idemSvc service.IdempotencyService,
+	authn *auth.Authenticator,
+) *mux.Router {
 	r := mux.NewRouter()
+	r.Use(tracing.Middleware)
+
 	fs := http.FileServer(http.FS(statichttp.Static))
 	r.PathPrefix("/web").Handler(http.StripPrefix("/", fs))
 
 	handler := api.NewScheduleApiHandler(svc)
+	healthHandler := api.NewHealthApiHandler(svc)
+	nsHandler := api.NewNamespaceApiHandler(nsSvc)
+	keyHandler := api.NewAPIKeyApiHandler(keySvc)
+	bindingHandler := api.NewRoleBindingApiHandler(bindingSvc)
+	secretHandler := api.NewSecretApiHandler(secretSvc)
+	calendarHandler := api.NewCalendarApiHandler(calendarSvc)
+	// responses to api key creation are not made idempotent, since they hold the plaintext of the key
+	idempotent := api.NewIdempotencyHandler(idemSvc).Wrap
 
-	r.Handle("/metrics", promhttp.Handler()).Methods("GET")
+	if !separateMetrics(conf) {
+		r.Handle("/metrics", promhttp.Handler()).Methods("GET")
+	}
 
-	// TODO: health endpoint
+	r.HandleFunc("/healthz", healthHandler.Liveness).Methods("GET")
+	r.HandleFunc("/readyz", healthHandler.Readiness).Methods("GET")
 
-	r.HandleFunc("/api/v1/schedules", handler.ListSchedules).Methods("GET")
-	r.HandleFunc("/api/v1/schedules/{id}", handler.GetSchedule).Methods("GET")
-	r.HandleFunc("/api/v1/schedules/{id}", handler.DeleteSchedule).Methods("DELETE")
+	v1 := r.PathPrefix("/api/v1").Subrouter()
+	v1.Use(authn.Middleware, auth.NewAuthorizer(conf.Auth.RBAC.Enabled, bindingSvc).Middleware)
 
-	r.HandleFunc("/api/v1/schedules", handler.RegisterSchedule).Methods("POST")
-	r.HandleFunc("/api/v1/schedules/{id}/pause", handler.PauseSchedule).Methods("POST")
-	r.HandleFunc("/api/v1/schedules/{id}/resume", handler.ResumeSchedule).Methods("POST")
-	r.HandleFunc("/api/v1/schedules/{id}/trigger", handler.TriggerSchedule).Methods("POST")
+	v1.HandleFunc("/schedules", auth.Require(auth.ScopeRead, handler.Authorize(auth.ActionView, handler.ListSchedules))).Methods("GET")
+	v1.HandleFunc("/schedules/{id}", auth.Require(auth.ScopeRead, handler.Authorize(auth.ActionView, handler.GetSchedule))).Methods("GET")
+	v1.HandleFunc("/schedules/{id}", auth.Require(auth.ScopeWrite, handler.Authorize(auth.ActionEdit, handler.DeleteSchedule))).Methods("DELETE")
 
-	r.HandleFunc("/api/v1/history", handler.GetHistory).Methods("GET")
-	r.HandleFunc("/api/v1/history/{id}", handler.GetCronHistory).Methods("GET")
+	v1.HandleFunc("/schedules", auth.Require(auth.ScopeWrite, handler.Authorize(auth.ActionEdit, idempotent(handler.RegisterSchedule)))).Methods("POST")
+	v1.HandleFunc("/schedules/{id}/pause", auth.Require(auth.ScopeWrite, handler.Authorize(auth.ActionOperate, idempotent(handler.PauseSchedule)))).Methods("POST")
+	v1.HandleFunc("/schedules/{id}/resume", auth.Require(auth.ScopeWrite, handler.Authorize(auth.ActionOperate, idempotent(handler.ResumeSchedule)))).Methods("POST")
+	v1.HandleFunc("/schedules/{id}/trigger", auth.Require(auth.ScopeTrigger, handler.Authorize(auth.ActionOperate, idempotent(handler.TriggerSchedule)))).Methods("POST")
+	v1.HandleFunc("/schedules/{id}/stats", auth.Require(auth.ScopeRead, handler.Authorize(auth.ActionView, handler.GetCronStats))).Methods("GET")
 
-	http.Handle("/", withCors(r, cors.Options{
-		AllowedOrigins: []string{"*"},
-		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
-		AllowedHeaders: []string{"Content-Type", "Authorization"},
-	}))
+	v1.HandleFunc("/apply", auth.Require(auth.ScopeWrite, handler.Authorize(auth.ActionEdit, idempotent(handler.ApplySchedules)))).Methods("POST")
+
+	v1.HandleFunc("/crontab/import", auth.Require(auth.ScopeWrite, handler.Authorize(auth.ActionEdit, idempotent(handler.ImportCrontab)))).Methods("POST")
+	v1.HandleFunc("/crontab/export", auth.Require(auth.ScopeRead, handler.Authorize(auth.ActionView, handler.ExportCrontab))).Methods("GET")
+
+	v1.HandleFunc("/history", auth.Require(auth.ScopeRead, handler.AuthorizeAll(auth.ActionView, handler.GetHistory))).Methods("GET")
+	v1.HandleFunc("/history/{id}", auth.Require(auth.ScopeRead, handler.Authorize(auth.ActionView, handler.GetCronHistory))).Methods("GET")
+
+	v1.HandleFunc("/stats", auth.Require(auth.ScopeRead, handler.AuthorizeAll(auth.ActionView, handler.GetStats))).Methods("GET")
+
+	v1.HandleFunc("/audit", auth.Require(auth.ScopeRead, handler.AuthorizeAll(auth.ActionView, handler.GetAuditLog))).Methods("GET")
+
+	v1.HandleFunc("/namespaces", auth.Require(auth.ScopeRead, nsHandler.ListNamespaces)).Methods("GET")
+	v1.HandleFunc("/namespaces", auth.Require(auth.ScopeAdmin, idempotent(nsHandler.CreateNamespace))).Methods("POST")
+	v1.HandleFunc("/namespaces/{name}", auth.Require(auth.ScopeRead, nsHandler.GetNamespace)).Methods("GET")
+	v1.HandleFunc("/namespaces/{name}", auth.Require(auth.ScopeAdmin, nsHandler.UpdateNamespace)).Methods("PUT")
+	v1.HandleFunc("/namespaces/{name}", auth.Require(auth.ScopeAdmin, nsHandler.DeleteNamespace)).Methods("DELETE")
+
+	v1.HandleFunc("/apikeys", auth.Require(auth.ScopeAdmin, keyHandler.ListAPIKeys)).Methods("GET")
+	v1.HandleFunc("/apikeys", auth.Require(auth.ScopeAdmin, keyHandler.CreateAPIKey)).Methods("POST")
+	v1.HandleFunc("/apikeys/{id}", auth.Require(auth.ScopeAdmin, keyHandler.DeleteAPIKey)).Methods("DELETE")
+
+	v1.HandleFunc("/rolebindings", auth.Require(auth.ScopeWrite, bindingHandler.ListRoleBindings)).Methods("GET")
+	v1.HandleFunc("/rolebindings", auth.Require(auth.ScopeWrite, idempotent(bindingHandler.CreateRoleBinding))).Methods("POST")
+	v1.HandleFunc("/rolebindings/{id}", auth.Require(auth.ScopeWrite, bindingHandler.DeleteRoleBinding)).Methods("DELETE")
+
+	v1.HandleFunc("/secrets", auth.Require(auth.ScopeRead, handler.AuthorizeAll(auth.ActionView, secretHandler.ListSecrets))).Methods("GET")
+	v1.HandleFunc("/secrets/{name}", auth.Require(auth.ScopeWrite, handler.AuthorizeAll(auth.ActionEdit, secretHandler.PutSecret))).Methods("PUT")
+	v1.HandleFunc("/secrets/{name}", auth.Require(auth.ScopeWrite, handler.AuthorizeAll(auth.ActionEdit, secretHandler.DeleteSecret))).Methods("DELETE")
+
+	v1.HandleFunc("/calendars", auth.Require(auth.ScopeRead, handler.AuthorizeAll(auth.ActionView, calendarHandler.ListCalendars))).Methods("GET")
+	v1.HandleFunc("/calendars/{name}", auth.Require(auth.ScopeRead, handler.AuthorizeAll(auth.ActionView, calendarHandler.GetCalendar))).Methods("GET")
+	v1.HandleFunc("/calendars/{name}", auth.Require(auth.ScopeWrite, handler.AuthorizeAll(auth.ActionEdit, calendarHandler.PutCalendar))).Methods("PUT")
+	v1.HandleFunc("/calendars/{name}", auth.Require(auth.ScopeWrite, handler.AuthorizeAll(auth.ActionEdit, calendarHandler.DeleteCalendar))).Methods("DELETE")
+
+	return r
+}
+
+// separateMetrics reports whether metrics are served by a dedicated listener, rather than by the api one.
+func separateMetrics(conf *config.Config) bool {
+	return conf.Metrics.Port != 0 && conf.Metrics.Port != conf.Port
+}
+
+// newManagementRouter serves metrics and health probes, which stay reachable
+// without credentials even when the api requires client certificates.
+func newManagementRouter(svc service.ScheduleService) *mux.Router {
+	r := mux.NewRouter()
+
+	healthHandler := api.NewHealthApiHandler(svc)
+
+	r.Handle("/metrics", promhttp.Handler()).Methods("GET")
+	r.HandleFunc("/healthz", healthHandler.Liveness).Methods("GET")
+	r.HandleFunc("/readyz", healthHandler.Readiness).Methods("GET")
+	return r
 }
 
 func withCors(handler http.Handler, opts cors.Options) http.Handler {
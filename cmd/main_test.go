@@ -0,0 +1,503 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ostafen/kronos/engine"
+	"github.com/ostafen/kronos/internal/api"
+	"github.com/ostafen/kronos/internal/auth"
+	"github.com/ostafen/kronos/internal/config"
+	"github.com/ostafen/kronos/internal/secrets"
+	"github.com/ostafen/kronos/internal/service"
+	"github.com/ostafen/kronos/model"
+	"github.com/ostafen/kronos/store"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnv struct {
+	t          *testing.T
+	router     *mux.Router
+	svc        service.ScheduleService
+	keySvc     service.APIKeyService
+	bindingSvc service.RoleBindingService
+	secretSvc  service.SecretService
+	webhookURL string
+	schedules  int
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	st, err := store.New(filepath.Join(t.TempDir(), "kronos.db"))
+	require.NoError(t, err)
+
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	conf := &config.Config{
+		Auth: config.Auth{
+			Enabled:  true,
+			AdminKey: "admin-key",
+			RBAC:     config.RBAC{Enabled: true},
+		},
+	}
+
+	keyring, err := secrets.NewKeyring(config.Secrets{Key: base64.StdEncoding.EncodeToString(make([]byte, 32))})
+	require.NoError(t, err)
+
+	secretSvc := service.NewSecretService(st, keyring)
+	eng, err := engine.New(engine.Options{Store: st, Secrets: secretSvc})
+	require.NoError(t, err)
+	require.NoError(t, eng.Start())
+
+	svc, nsSvc := eng.Schedules(), eng.Namespaces()
+	keySvc := service.NewAPIKeyService(st)
+	bindingSvc := service.NewRoleBindingService(st)
+
+	authn, err := auth.NewAuthenticator(conf.Auth, keySvc)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, eng.Stop(context.Background()))
+		webhook.Close()
+		st.Close()
+	})
+
+	return &testEnv{
+		t:          t,
+		router:     newRouter(conf, svc, nsSvc, keySvc, bindingSvc, secretSvc, eng.Calendars(), service.NewIdempotencyService(st, time.Hour), authn),
+		svc:        svc,
+		keySvc:     keySvc,
+		bindingSvc: bindingSvc,
+		secretSvc:  secretSvc,
+		webhookURL: webhook.URL,
+	}
+}
+
+// aUser creates an api key named after the subject, holding the write scope, whose subject is bound to the given role.
+func (env *testEnv) aUser(subject string, role auth.Role, selector map[string]string) string {
+	key, err := env.keySvc.CreateAPIKey(context.Background(), &model.APIKeyCreateInput{
+		Name:   subject,
+		Scopes: []string{string(auth.ScopeWrite)},
+	})
+	require.NoError(env.t, err)
+
+	if role != "" {
+		_, err = env.bindingSvc.CreateRoleBinding(context.Background(), &model.RoleBindingInput{
+			Subject:   auth.APIKeySubject(key.ID),
+			Role:      string(role),
+			Namespace: model.DefaultNamespace,
+			Selector:  selector,
+		})
+		require.NoError(env.t, err)
+	}
+	return key.Key
+}
+
+func (env *testEnv) aSchedule(labels map[string]string) int64 {
+	env.schedules++
+
+	recurring := true
+	sched, err := env.svc.RegisterSchedule(context.Background(), &model.ScheduleRegisterInput{
+		Title:       fmt.Sprintf("test-schedule-%d", env.schedules),
+		CronExpr:    "0 0 * * *",
+		URL:         env.webhookURL,
+		IsRecurring: &recurring,
+		StartAt:     time.Now(),
+		EndAt:       time.Now().Add(time.Hour),
+		Metadata:    labels,
+	})
+	require.NoError(env.t, err)
+	return sched.ID
+}
+
+func (env *testEnv) aRoleBinding() int64 {
+	binding, err := env.bindingSvc.CreateRoleBinding(context.Background(), &model.RoleBindingInput{
+		Subject: "someone",
+		Role:    string(auth.RoleViewer),
+	})
+	require.NoError(env.t, err)
+	return binding.ID
+}
+
+func (env *testEnv) do(key, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("X-API-Key", key)
+	req.Header.Set(auth.NamespaceHeader, model.DefaultNamespace)
+
+	rec := httptest.NewRecorder()
+	env.router.ServeHTTP(rec, req)
+	return rec
+}
+
+type routeCase struct {
+	method  string
+	path    string
+	body    string
+	allowed []auth.Role
+}
+
+var (
+	everyone  = []auth.Role{"", auth.RoleViewer, auth.RoleOperator, auth.RoleEditor, auth.RoleAdmin}
+	viewers   = []auth.Role{auth.RoleViewer, auth.RoleOperator, auth.RoleEditor, auth.RoleAdmin}
+	operators = []auth.Role{auth.RoleOperator, auth.RoleEditor, auth.RoleAdmin}
+	editors   = []auth.Role{auth.RoleEditor, auth.RoleAdmin}
+	admins    = []auth.Role{auth.RoleAdmin}
+	nobody    = []auth.Role{}
+)
+
+func scheduleBody(url string) string {
+	endAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+	return fmt.Sprintf(`{"title": "t", "url": %q, "cronExpr": "0 0 * * *", "isRecurring": true, "endAt": %q, "metadata": {"env": "prod"}}`, url, endAt)
+}
+
+func TestRoutesAuthorization(t *testing.T) {
+	env := newTestEnv(t)
+
+	cases := []routeCase{
+		{method: "GET", path: "/web", allowed: everyone},
+		{method: "GET", path: "/metrics", allowed: everyone},
+		{method: "GET", path: "/healthz", allowed: everyone},
+		{method: "GET", path: "/readyz", allowed: everyone},
+		{method: "GET", path: "/api/v1/schedules", allowed: viewers},
+		{method: "GET", path: "/api/v1/schedules/{id}", allowed: viewers},
+		{method: "DELETE", path: "/api/v1/schedules/{id}", allowed: editors},
+		{method: "POST", path: "/api/v1/schedules", body: scheduleBody(env.webhookURL), allowed: editors},
+		{method: "POST", path: "/api/v1/schedules/{id}/pause", allowed: operators},
+		{method: "POST", path: "/api/v1/schedules/{id}/resume", allowed: operators},
+		{method: "POST", path: "/api/v1/schedules/{id}/trigger", allowed: operators},
+		{method: "GET", path: "/api/v1/schedules/{id}/stats", allowed: viewers},
+		{method: "POST", path: "/api/v1/apply", body: `{"schedules": [], "dryRun": true}`, allowed: editors},
+		{method: "POST", path: "/api/v1/crontab/import", body: `{"crontab": "# empty", "urlTemplate": "http://localhost", "dryRun": true}`, allowed: editors},
+		{method: "GET", path: "/api/v1/crontab/export", allowed: viewers},
+		{method: "GET", path: "/api/v1/history", allowed: viewers},
+		{method: "GET", path: "/api/v1/history/{id}", allowed: viewers},
+		{method: "GET", path: "/api/v1/stats", allowed: viewers},
+		{method: "GET", path: "/api/v1/audit", allowed: viewers},
+		{method: "GET", path: "/api/v1/namespaces", allowed: everyone},
+		{method: "POST", path: "/api/v1/namespaces", body: `{"name": "other"}`, allowed: nobody},
+		{method: "GET", path: "/api/v1/namespaces/{name}", allowed: everyone},
+		{method: "PUT", path: "/api/v1/namespaces/{name}", body: `{}`, allowed: nobody},
+		{method: "DELETE", path: "/api/v1/namespaces/{name}", allowed: nobody},
+		{method: "GET", path: "/api/v1/apikeys", allowed: nobody},
+		{method: "POST", path: "/api/v1/apikeys", body: `{"name": "k", "scopes": ["read"]}`, allowed: nobody},
+		{method: "DELETE", path: "/api/v1/apikeys/{id}", allowed: nobody},
+		{method: "GET", path: "/api/v1/rolebindings", allowed: admins},
+		{method: "POST", path: "/api/v1/rolebindings", body: `{"subject": "s", "role": "viewer"}`, allowed: admins},
+		{method: "DELETE", path: "/api/v1/rolebindings/{id}", allowed: admins},
+		{method: "GET", path: "/api/v1/secrets", allowed: viewers},
+		{method: "PUT", path: "/api/v1/secrets/{name}", body: `{"value": "s3cret"}`, allowed: editors},
+		{method: "DELETE", path: "/api/v1/secrets/{name}", allowed: editors},
+		{method: "GET", path: "/api/v1/calendars", allowed: viewers},
+		{method: "GET", path: "/api/v1/calendars/{name}", allowed: viewers},
+		{method: "PUT", path: "/api/v1/calendars/{name}", body: `{"dates": ["2030-12-25"]}`, allowed: editors},
+		{method: "DELETE", path: "/api/v1/calendars/{name}", allowed: editors},
+	}
+
+	covered := make(map[string]bool)
+	for _, c := range cases {
+		covered[c.method+" "+c.path] = true
+	}
+
+	err := env.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil || route.GetHandler() == nil {
+			return nil
+		}
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			methods = []string{"GET"}
+		}
+
+		for _, m := range methods {
+			require.True(t, covered[m+" "+path], "route %s %s is not covered", m, path)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	keys := make(map[auth.Role]string)
+	for _, role := range everyone {
+		keys[role] = env.aUser("user-"+string(role), role, nil)
+	}
+
+	for _, c := range cases {
+		for _, role := range everyone {
+			t.Run(fmt.Sprintf("%s %s as %q", c.method, c.path, role), func(t *testing.T) {
+				path := c.path
+				path = strings.ReplaceAll(path, "{name}", model.DefaultNamespace)
+				switch {
+				case strings.HasPrefix(path, "/api/v1/rolebindings/"):
+					path = strings.ReplaceAll(path, "{id}", strconv.FormatInt(env.aRoleBinding(), 10))
+				case strings.HasPrefix(path, "/api/v1/apikeys/"):
+					path = strings.ReplaceAll(path, "{id}", "1")
+				default:
+					path = strings.ReplaceAll(path, "{id}", strconv.FormatInt(env.aSchedule(map[string]string{"env": "prod"}), 10))
+				}
+
+				rec := env.do(keys[role], c.method, path, c.body)
+
+				if contains(c.allowed, role) {
+					require.NotEqual(t, http.StatusForbidden, rec.Code, rec.Body.String())
+					require.NotEqual(t, http.StatusUnauthorized, rec.Code, rec.Body.String())
+				} else {
+					require.Equal(t, http.StatusForbidden, rec.Code, rec.Body.String())
+				}
+			})
+		}
+	}
+}
+
+func TestRoleBindingSelector(t *testing.T) {
+	env := newTestEnv(t)
+
+	key := env.aUser("staging-editor", auth.RoleEditor, map[string]string{"env": "staging"})
+
+	staging := env.aSchedule(map[string]string{"env": "staging"})
+	prod := env.aSchedule(map[string]string{"env": "prod"})
+
+	rec := env.do(key, "GET", "/api/v1/schedules", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), fmt.Sprintf(`"id":%d`, staging))
+	require.NotContains(t, rec.Body.String(), fmt.Sprintf(`"id":%d`, prod))
+
+	rec = env.do(key, "DELETE", fmt.Sprintf("/api/v1/schedules/%d", prod), "")
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = env.do(key, "DELETE", fmt.Sprintf("/api/v1/schedules/%d", staging), "")
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	// new schedules must match the selector too
+	rec = env.do(key, "POST", "/api/v1/schedules", scheduleBody(env.webhookURL))
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	// aggregated routes require a binding on the whole namespace
+	rec = env.do(key, "GET", "/api/v1/history", "")
+	require.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestRoleBindingsOfSameNamedKeys(t *testing.T) {
+	env := newTestEnv(t)
+
+	editor := env.aUser("ci", auth.RoleEditor, nil)
+	unbound := env.aUser("ci", "", nil)
+
+	rec := env.do(editor, "POST", "/api/v1/schedules", scheduleBody(env.webhookURL))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// keys are bound by id, so that a key named like another doesn't inherit its roles
+	rec = env.do(unbound, "GET", "/api/v1/schedules", "")
+	require.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func contains(roles []auth.Role, role auth.Role) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAuditLog(t *testing.T) {
+	env := newTestEnv(t)
+
+	key := env.aUser("night-operator", auth.RoleOperator, nil)
+	id := env.aSchedule(nil)
+
+	principal, err := env.keySvc.VerifyAPIKey(context.Background(), key)
+	require.NoError(t, err)
+	actor := principal.Subject
+
+	for _, action := range []string{"pause", "resume", "pause"} {
+		rec := env.do(key, "POST", fmt.Sprintf("/api/v1/schedules/%d/%s", id, action), "")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var page model.AuditPage
+
+	rec := env.do(key, "GET", "/api/v1/audit?actor="+actor+"&action=pause&limit=1", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+
+	require.Len(t, page.Entries, 1)
+	require.NotZero(t, page.NextCursor)
+
+	entry := page.Entries[0]
+	require.Equal(t, actor, entry.Actor)
+	require.Equal(t, auth.MethodAPIKey, entry.Method)
+	require.Equal(t, model.AuditActionPause, entry.Action)
+	require.Equal(t, id, entry.ScheduleID)
+	require.Equal(t, model.DefaultNamespace, entry.Namespace)
+	require.NotEmpty(t, entry.SourceIP)
+	require.Equal(t, model.ScheduleStatusActive, entry.Before.Status)
+	require.Equal(t, model.ScheduleStatusPaused, entry.After.Status)
+
+	rec = env.do(key, "GET", fmt.Sprintf("/api/v1/audit?actor=%s&action=pause&limit=1&cursor=%d", actor, page.NextCursor), "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	page = model.AuditPage{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Len(t, page.Entries, 1)
+	require.Less(t, page.Entries[0].ID, entry.ID)
+
+	// the registration, issued internally, is recorded as well
+	rec = env.do(key, "GET", fmt.Sprintf("/api/v1/audit?scheduleId=%d", id), "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	page = model.AuditPage{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Len(t, page.Entries, 4)
+	require.Zero(t, page.NextCursor)
+
+	registration := page.Entries[3]
+	require.Equal(t, model.AuditActionRegister, registration.Action)
+	require.Nil(t, registration.Before)
+	require.NotNil(t, registration.After)
+
+	rec = env.do(key, "GET", "/api/v1/audit?action=rename", "")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestSeparateMetricsListener(t *testing.T) {
+	env := newTestEnv(t)
+
+	conf := &config.Config{Port: 9175, Metrics: config.Metrics{Port: 9176}}
+	authn, err := auth.NewAuthenticator(conf.Auth, env.keySvc)
+	require.NoError(t, err)
+
+	router := newRouter(conf, env.svc, nil, env.keySvc, env.bindingSvc, env.secretSvc, nil, nil, authn)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	management := newManagementRouter(env.svc)
+	for _, path := range []string{"/metrics", "/healthz", "/readyz"} {
+		rec := httptest.NewRecorder()
+		management.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		require.NotEqual(t, http.StatusNotFound, rec.Code, path)
+	}
+}
+
+func TestSecretHeaders(t *testing.T) {
+	env := newTestEnv(t)
+
+	received := make(chan string, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("X-Webhook-Token")
+	}))
+	defer receiver.Close()
+
+	key := env.aUser("editor", auth.RoleEditor, nil)
+
+	rec := env.do(key, "PUT", "/api/v1/secrets/billing-token", `{"value": "s3cret"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NotContains(t, rec.Body.String(), "s3cret")
+
+	endAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+	body := fmt.Sprintf(`{"title": "t", "url": %q, "cronExpr": "0 0 * * *", "isRecurring": true, "endAt": %q, "secretHeaders": {"%s": "%s"}}`, receiver.URL, endAt, "X-Webhook-Token", "billing-token")
+
+	rec = env.do(key, "POST", "/api/v1/schedules", strings.Replace(body, "billing-token", "missing", 1))
+	require.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+
+	rec = env.do(key, "POST", "/api/v1/schedules", body)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var sched model.CronSchedule
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &sched))
+
+	for _, path := range []string{fmt.Sprintf("/api/v1/schedules/%d", sched.ID), "/api/v1/secrets"} {
+		rec = env.do(key, "GET", path, "")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		require.Contains(t, rec.Body.String(), "billing-token")
+		require.NotContains(t, rec.Body.String(), "s3cret")
+	}
+
+	rec = env.do(key, "POST", fmt.Sprintf("/api/v1/schedules/%d/trigger", sched.ID), "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	select {
+	case token := <-received:
+		require.Equal(t, "s3cret", token)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not delivered")
+	}
+
+	// secrets referenced by schedules cannot be deleted
+	rec = env.do(key, "DELETE", "/api/v1/secrets/billing-token", "")
+	require.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+
+	rec = env.do(key, "DELETE", fmt.Sprintf("/api/v1/schedules/%d", sched.ID), "")
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+	rec = env.do(key, "DELETE", "/api/v1/secrets/billing-token", "")
+	require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+}
+
+func TestIdempotentCreation(t *testing.T) {
+	env := newTestEnv(t)
+
+	key := env.aUser("deployer", auth.RoleEditor, nil)
+
+	post := func(idempotencyKey, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/schedules", bytes.NewBufferString(body))
+		req.Header.Set("X-API-Key", key)
+		if idempotencyKey != "" {
+			req.Header.Set(api.IdempotencyKeyHeader, idempotencyKey)
+		}
+
+		rec := httptest.NewRecorder()
+		env.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	body := scheduleBody(env.webhookURL)
+
+	first := post("deploy-1", body)
+	require.Equal(t, http.StatusOK, first.Code, first.Body.String())
+	require.Empty(t, first.Header().Get(api.IdempotentReplayedHeader))
+
+	// retries with the same key get the original response
+	retry := post("deploy-1", body)
+	require.Equal(t, http.StatusOK, retry.Code, retry.Body.String())
+	require.Equal(t, "true", retry.Header().Get(api.IdempotentReplayedHeader))
+	require.Equal(t, first.Body.String(), retry.Body.String())
+
+	// reusing a key for a different request is an error
+	rec := post("deploy-1", strings.Replace(body, `"title": "t"`, `"title": "other"`, 1))
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+
+	// without a key, duplicated titles are rejected
+	rec = post("", body)
+	require.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+
+	rec = post("deploy-2", body)
+	require.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+
+	// bodies over the limit are rejected rather than truncated, and don't consume the key
+	big := strings.Replace(body, `"title": "t"`, `"title": "big"`, 1)
+	rec = post("deploy-3", big+strings.Repeat(" ", 1<<20))
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code, rec.Body.String())
+
+	rec = post("deploy-3", big)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Empty(t, rec.Header().Get(api.IdempotentReplayedHeader))
+
+	n := 0
+	require.NoError(t, env.svc.IterSchedules(context.Background(), func(*model.CronSchedule) error {
+		n++
+		return nil
+	}))
+	require.Equal(t, 2, n)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	recorderOnce sync.Once
+	recorder     *tracetest.SpanRecorder
+)
+
+// spanRecorder installs a recorder of the spans started by the process, only once, since tracers obtained
+// before the first tracer provider is installed keep delegating to it.
+func spanRecorder() *tracetest.SpanRecorder {
+	recorderOnce.Do(func() {
+		recorder = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	})
+	return recorder
+}
+
+// childOf returns the ended span with the given name whose parent is the given span.
+func childOf(spans []sdktrace.ReadOnlySpan, parent trace.SpanContext, name string) sdktrace.ReadOnlySpan {
+	for _, span := range spans {
+		if span.Name() == name && span.Parent().SpanID() == parent.SpanID() {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestRequestTrace(t *testing.T) {
+	recorder := spanRecorder()
+
+	env := newTestEnv(t)
+	id := env.aSchedule(nil)
+
+	rec := env.do("admin-key", http.MethodGet, fmt.Sprintf("/api/v1/schedules/%d", id), "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	spans := recorder.Ended()
+
+	var server sdktrace.ReadOnlySpan
+	for _, span := range spans {
+		if span.Name() == "GET /api/v1/schedules/{id}" {
+			server = span
+		}
+	}
+	require.NotNil(t, server)
+	require.False(t, server.Parent().IsValid())
+
+	// api -> service -> store
+	service := childOf(spans, server.SpanContext(), "ScheduleService.GetSchedule")
+	require.NotNil(t, service)
+	require.NotNil(t, childOf(spans, service.SpanContext(), "schedules.get"))
+}
@@ -0,0 +1,184 @@
+// Package engine runs the Kronos scheduler in-process, for programs embedding Kronos rather than running it as a server.
+//
+//	eng, err := engine.New(engine.Options{StorePath: "kronos.db"})
+//	if err != nil {
+//		return err
+//	}
+//
+//	eng.RegisterAction("cleanup", func(ctx context.Context, sched *model.CronSchedule) error {
+//		return cleanup(ctx)
+//	})
+//
+//	if err := eng.Start(); err != nil {
+//		return err
+//	}
+//	defer eng.Stop(context.Background())
+//
+//	recurring := true
+//	_, err = eng.Schedules().RegisterSchedule(ctx, &model.ScheduleRegisterInput{
+//		Title:       "nightly-cleanup",
+//		Action:      "cleanup",
+//		IsRecurring: &recurring,
+//		CronExpr:    "0 3 * * *",
+//	})
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/ostafen/kronos/internal/auth"
+	"github.com/ostafen/kronos/internal/service"
+	"github.com/ostafen/kronos/model"
+	"github.com/ostafen/kronos/store"
+)
+
+type (
+	// ScheduleService manages schedules, their history and statistics.
+	ScheduleService = service.ScheduleService
+	// NamespaceService manages namespaces and their quotas.
+	NamespaceService = service.NamespaceService
+	// CalendarService manages the calendars whose dates and blackout windows schedules don't run in.
+	CalendarService = service.CalendarService
+
+	// Action is a Go function run by the schedules referring to it by name, in place of a webhook delivery.
+	// Its error, if any, is recorded to history as a 500 status code, and a successful run as a 200.
+	Action = service.Action
+
+	// Notifier delivers the webhooks of schedules which don't refer to an action.
+	Notifier = service.NotificationService
+	// Target identifies where, and how, a webhook is delivered.
+	Target = service.Target
+	// SecretResolver returns the values of the secrets referenced by the headers of schedules.
+	SecretResolver = service.SecretResolver
+)
+
+// ErrUnknownAction is returned when registering a schedule referring to an action which is not registered.
+var ErrUnknownAction = service.ErrUnknownAction
+
+// ErrEngineExists is returned by New while another engine of the process hasn't been stopped: metrics are
+// process-wide, so that a process runs a single engine at a time.
+var ErrEngineExists = errors.New("another engine of the process has not been stopped")
+
+// exists is set while an engine of the process hasn't been stopped.
+var exists atomic.Bool
+
+type Options struct {
+	// Store persists schedules and their history. If nil, a SQLite database is opened at StorePath,
+	// and closed by Stop; a store given here is left open.
+	Store     store.Store
+	StorePath string
+	// DefaultQuota applies to namespaces which don't define their own. Zero values mean no limit.
+	DefaultQuota model.Quota
+	// Notifier delivers webhooks. If nil, webhooks are delivered over HTTP to any destination.
+	Notifier Notifier
+	// Secrets resolves the secrets referenced by schedule headers. If nil, such schedules are rejected.
+	Secrets SecretResolver
+	// Actions are registered before the engine starts, as by RegisterAction.
+	Actions map[string]Action
+}
+
+// Engine schedules the deliveries of the schedules of a store. Its services are called
+// without any principal, hence are not subject to authorization.
+type Engine struct {
+	store      store.Store
+	ownsStore  bool
+	stopped    atomic.Bool
+	namespaces service.NamespaceService
+	schedules  service.ScheduleService
+	calendars  service.CalendarService
+}
+
+// New returns an engine, which doesn't process schedules until started.
+// Only one engine at a time may exist in a process, and a new one can be created once it is stopped.
+func New(opts Options) (*Engine, error) {
+	st, ownsStore := opts.Store, false
+	if st == nil && opts.StorePath == "" {
+		return nil, errors.New("either a store or the path of the store is required")
+	}
+
+	if !exists.CompareAndSwap(false, true) {
+		return nil, ErrEngineExists
+	}
+
+	if st == nil {
+		s, err := store.New(opts.StorePath)
+		if err != nil {
+			exists.Store(false)
+			return nil, fmt.Errorf("unable to open the store: %w", err)
+		}
+		st, ownsStore = s, true
+	}
+
+	notifier := opts.Notifier
+	if notifier == nil {
+		notifier = service.NewNotificationService(nil, nil, nil)
+	}
+
+	namespaces := service.NewNamespaceService(st, opts.DefaultQuota)
+
+	eng := &Engine{
+		store:      st,
+		ownsStore:  ownsStore,
+		namespaces: namespaces,
+		schedules:  service.NewScheduleService(st, notifier, namespaces, opts.Secrets),
+		calendars:  service.NewCalendarService(st),
+	}
+
+	for name, action := range opts.Actions {
+		eng.RegisterAction(name, action)
+	}
+	return eng, nil
+}
+
+// RegisterAction makes the given function available to schedules referring to it by name.
+// Actions are not persisted, so they must be registered again, before Start, whenever the program restarts.
+// Registering an action under an existing name replaces it.
+func (e *Engine) RegisterAction(name string, action Action) {
+	e.schedules.RegisterAction(name, action)
+}
+
+// Start schedules the active schedules of the store and starts processing their ticks.
+func (e *Engine) Start() error {
+	return e.schedules.Start()
+}
+
+// Stop stops processing ticks and waits for in-flight deliveries and actions to complete.
+// If ctx expires first, they are aborted, and their outcome is recorded to history anyway.
+// Finally, the store is closed, unless it was given through Options, and another engine can be created.
+func (e *Engine) Stop(ctx context.Context) error {
+	err := e.schedules.Stop(ctx)
+
+	if e.ownsStore {
+		err = errors.Join(err, e.store.Close())
+	}
+
+	if e.stopped.CompareAndSwap(false, true) {
+		exists.Store(false)
+	}
+	return err
+}
+
+func (e *Engine) Schedules() ScheduleService {
+	return e.schedules
+}
+
+func (e *Engine) Namespaces() NamespaceService {
+	return e.namespaces
+}
+
+func (e *Engine) Calendars() CalendarService {
+	return e.calendars
+}
+
+func (e *Engine) Store() store.Store {
+	return e.store
+}
+
+// WithNamespace returns a context making the calls to the services of the engine operate on the given namespace,
+// rather than on the default one.
+func WithNamespace(ctx context.Context, namespace string) context.Context {
+	return auth.WithNamespace(ctx, namespace)
+}
@@ -0,0 +1,211 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ostafen/kronos/model"
+	"github.com/ostafen/kronos/store"
+	"github.com/stretchr/testify/require"
+)
+
+func oneShot(title, action string, runAt time.Time) *model.ScheduleRegisterInput {
+	recurring := false
+	return &model.ScheduleRegisterInput{
+		Title:       title,
+		Action:      action,
+		IsRecurring: &recurring,
+		RunAt:       runAt,
+	}
+}
+
+func newEngine(t *testing.T, opts Options) *Engine {
+	eng, err := New(opts)
+	require.NoError(t, err)
+	require.NoError(t, eng.Start())
+
+	t.Cleanup(func() {
+		require.NoError(t, eng.Stop(context.Background()))
+	})
+	return eng
+}
+
+// statusCodes waits for the given number of history entries of a schedule, and returns their status codes.
+func statusCodes(t *testing.T, eng *Engine, id int64, n int) []int {
+	var history []*model.CronStatus
+	require.Eventually(t, func() bool {
+		h, err := eng.Schedules().GetCronHistory(context.Background(), id)
+		require.NoError(t, err)
+
+		history = h
+		return len(history) >= n
+	}, 5*time.Second, 10*time.Millisecond)
+
+	codes := make([]int, 0, len(history))
+	for _, status := range history {
+		codes = append(codes, status.StatusCode)
+	}
+	return codes
+}
+
+func TestActions(t *testing.T) {
+	runs := make(chan *model.CronSchedule, 1)
+
+	eng := newEngine(t, Options{
+		StorePath: filepath.Join(t.TempDir(), "kronos.db"),
+		Actions: map[string]Action{
+			"succeed": func(ctx context.Context, sched *model.CronSchedule) error {
+				runs <- sched
+				return nil
+			},
+		},
+	})
+
+	eng.RegisterAction("fail", func(ctx context.Context, sched *model.CronSchedule) error {
+		return errors.New("failure")
+	})
+
+	eng.RegisterAction("panic", func(ctx context.Context, sched *model.CronSchedule) error {
+		panic("boom")
+	})
+
+	ctx := context.Background()
+	runAt := time.Now().Add(200 * time.Millisecond)
+
+	succeeding, err := eng.Schedules().RegisterSchedule(ctx, oneShot("succeeding", "succeed", runAt))
+	require.NoError(t, err)
+	require.Equal(t, model.DefaultNamespace, succeeding.Namespace)
+
+	failing, err := eng.Schedules().RegisterSchedule(ctx, oneShot("failing", "fail", runAt))
+	require.NoError(t, err)
+
+	panicking, err := eng.Schedules().RegisterSchedule(ctx, oneShot("panicking", "panic", runAt))
+	require.NoError(t, err)
+
+	select {
+	case sched := <-runs:
+		require.Equal(t, succeeding.ID, sched.ID)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "action not run")
+	}
+
+	require.Equal(t, []int{http.StatusOK}, statusCodes(t, eng, succeeding.ID, 1))
+	require.Equal(t, []int{http.StatusInternalServerError}, statusCodes(t, eng, failing.ID, 1))
+	require.Equal(t, []int{http.StatusInternalServerError}, statusCodes(t, eng, panicking.ID, 1))
+}
+
+func TestInvalidActions(t *testing.T) {
+	eng := newEngine(t, Options{StorePath: filepath.Join(t.TempDir(), "kronos.db")})
+	ctx := context.Background()
+
+	_, err := eng.Schedules().RegisterSchedule(ctx, oneShot("unknown", "unknown", time.Now().Add(time.Hour)))
+	require.ErrorIs(t, err, ErrUnknownAction)
+
+	input := oneShot("both", "unknown", time.Now().Add(time.Hour))
+	input.URL = "http://localhost"
+
+	_, err = eng.Schedules().RegisterSchedule(ctx, input)
+	require.Error(t, err)
+}
+
+// countingStore counts the schedules saved through it, to check that the engine uses the store it is given.
+type countingStore struct {
+	store.Store
+	saved atomic.Int32
+}
+
+func (s *countingStore) CronScheduleRepository() store.CronScheduleRepository {
+	return &countingRepo{CronScheduleRepository: s.Store.CronScheduleRepository(), saved: &s.saved}
+}
+
+type countingRepo struct {
+	store.CronScheduleRepository
+	saved *atomic.Int32
+}
+
+func (r *countingRepo) Save(ctx context.Context, sched *model.CronSchedule) (int64, error) {
+	r.saved.Add(1)
+	return r.CronScheduleRepository.Save(ctx, sched)
+}
+
+func TestCustomStore(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "kronos.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { st.Close() })
+
+	counting := &countingStore{Store: st}
+
+	eng := newEngine(t, Options{Store: counting})
+	eng.RegisterAction("noop", func(ctx context.Context, sched *model.CronSchedule) error { return nil })
+
+	_, err = eng.Namespaces().CreateNamespace(context.Background(), &model.NamespaceInput{Name: "team-a"})
+	require.NoError(t, err)
+
+	ctx := WithNamespace(context.Background(), "team-a")
+
+	sched, err := eng.Schedules().RegisterSchedule(ctx, oneShot("noop", "noop", time.Now().Add(time.Hour)))
+	require.NoError(t, err)
+	require.Equal(t, "team-a", sched.Namespace)
+	require.Equal(t, int32(1), counting.saved.Load())
+
+	// stopping the engine leaves a store given through the options open
+	require.NoError(t, eng.Stop(context.Background()))
+	require.NoError(t, st.Ping(context.Background()))
+}
+
+func TestRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kronos.db")
+	ctx := context.Background()
+
+	eng, err := New(Options{StorePath: path})
+	require.NoError(t, err)
+	require.NoError(t, eng.Start())
+
+	eng.RegisterAction("run", func(ctx context.Context, sched *model.CronSchedule) error { return nil })
+
+	sched, err := eng.Schedules().RegisterSchedule(ctx, oneShot("after-restart", "run", time.Now().Add(500*time.Millisecond)))
+	require.NoError(t, err)
+	require.NoError(t, eng.Stop(ctx))
+
+	// actions are registered again by the restarted program, before the engine starts
+	runs := make(chan int64, 1)
+	eng = newEngine(t, Options{
+		StorePath: path,
+		Actions: map[string]Action{
+			"run": func(ctx context.Context, sched *model.CronSchedule) error {
+				runs <- sched.ID
+				return nil
+			},
+		},
+	})
+
+	select {
+	case id := <-runs:
+		require.Equal(t, sched.ID, id)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "action not run after restart")
+	}
+	require.Equal(t, []int{http.StatusOK}, statusCodes(t, eng, sched.ID, 1))
+}
+
+func TestSingleEngine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kronos.db")
+
+	eng, err := New(Options{StorePath: path})
+	require.NoError(t, err)
+
+	// metrics are process-wide, so that a second engine is refused until the first one is stopped
+	_, err = New(Options{StorePath: path})
+	require.ErrorIs(t, err, ErrEngineExists)
+
+	require.NoError(t, eng.Stop(context.Background()))
+
+	eng, err = New(Options{StorePath: path})
+	require.NoError(t, err)
+	require.NoError(t, eng.Stop(context.Background()))
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/ostafen/kronos/internal/service"
+	"github.com/ostafen/kronos/model"
+)
+
+type APIKeyApiHandler struct {
+	svc service.APIKeyService
+}
+
+func NewAPIKeyApiHandler(svc service.APIKeyService) *APIKeyApiHandler {
+	return &APIKeyApiHandler{
+		svc: svc,
+	}
+}
+
+func (api *APIKeyApiHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var input model.APIKeyCreateInput
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key, err := api.svc.CreateAPIKey(r.Context(), &input)
+	if err != nil {
+		code := errorStatus(err)
+		if code == http.StatusInternalServerError {
+			code = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	writeJSONStatus(w, http.StatusCreated, key)
+}
+
+func (api *APIKeyApiHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := api.svc.ListAPIKeys(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	writeJSON(w, keys)
+}
+
+func (api *APIKeyApiHandler) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = api.svc.DeleteAPIKey(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
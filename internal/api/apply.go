@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/ostafen/kronos/model"
+)
+
+func (api *ScheduleApiHandler) ApplySchedules(w http.ResponseWriter, r *http.Request) {
+	var req model.ApplyRequest
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res, err := api.svc.ApplySchedules(r.Context(), &req)
+	if err != nil {
+		code := errorStatus(err)
+		if code == http.StatusInternalServerError {
+			code = http.StatusBadRequest
+		}
+
+		// the changes made before the failure are reported, so that clients can reconcile them
+		if res != nil {
+			res.Error = err.Error()
+			writeJSONStatus(w, code, res)
+			return
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	writeJSON(w, res)
+}
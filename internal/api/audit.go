@@ -0,0 +1,88 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ostafen/kronos/model"
+)
+
+func (api *ScheduleApiHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	query, err := parseAuditQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := api.svc.QueryAudit(r.Context(), query)
+	if err != nil {
+		code := errorStatus(err)
+		if code == http.StatusInternalServerError {
+			code = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	writeJSON(w, page)
+}
+
+func parseAuditQuery(values url.Values) (*model.AuditQuery, error) {
+	query := &model.AuditQuery{
+		Namespace: values.Get("namespace"),
+		Actor:     values.Get("actor"),
+		Action:    model.AuditAction(values.Get("action")),
+	}
+
+	var err error
+	if query.ScheduleID, err = parseInt(values, "scheduleId"); err != nil {
+		return nil, err
+	}
+
+	if query.Cursor, err = parseInt(values, "cursor"); err != nil {
+		return nil, err
+	}
+
+	limit, err := parseInt(values, "limit")
+	if err != nil {
+		return nil, err
+	}
+	query.Limit = int(limit)
+
+	if query.From, err = parseTime(values, "from"); err != nil {
+		return nil, err
+	}
+
+	if query.To, err = parseTime(values, "to"); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
+func parseInt(values url.Values, name string) (int64, error) {
+	value := values.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %s", name, value)
+	}
+	return n, nil
+}
+
+func parseTime(values url.Values, name string) (time.Time, error) {
+	value := values.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %s: an RFC 3339 timestamp is expected", name, value)
+	}
+	return t, nil
+}
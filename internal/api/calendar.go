@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/ostafen/kronos/internal/service"
+	"github.com/ostafen/kronos/model"
+)
+
+type CalendarApiHandler struct {
+	svc service.CalendarService
+}
+
+func NewCalendarApiHandler(svc service.CalendarService) *CalendarApiHandler {
+	return &CalendarApiHandler{
+		svc: svc,
+	}
+}
+
+func (api *CalendarApiHandler) PutCalendar(w http.ResponseWriter, r *http.Request) {
+	var input model.CalendarInput
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	input.Name = mux.Vars(r)["name"]
+
+	calendar, err := api.svc.PutCalendar(r.Context(), &input)
+	if err != nil {
+		code := errorStatus(err)
+		if code == http.StatusInternalServerError {
+			code = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	writeJSON(w, calendar)
+}
+
+func (api *CalendarApiHandler) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	calendar, err := api.svc.GetCalendar(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	writeJSON(w, calendar)
+}
+
+func (api *CalendarApiHandler) ListCalendars(w http.ResponseWriter, r *http.Request) {
+	calendars, err := api.svc.ListCalendars(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	writeJSON(w, calendars)
+}
+
+func (api *CalendarApiHandler) DeleteCalendar(w http.ResponseWriter, r *http.Request) {
+	if err := api.svc.DeleteCalendar(r.Context(), mux.Vars(r)["name"]); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ostafen/kronos/internal/auth"
+	"github.com/ostafen/kronos/internal/crontab"
+	"github.com/ostafen/kronos/internal/egress"
+	"github.com/ostafen/kronos/internal/oauth"
+	"github.com/ostafen/kronos/internal/service"
+	"github.com/ostafen/kronos/internal/tlsprofile"
+	"github.com/ostafen/kronos/store"
+)
+
+// errorStatus maps the errors returned by services to the most appropriate status code.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, egress.ErrDenied),
+		errors.Is(err, tlsprofile.ErrUnknownProfile),
+		errors.Is(err, oauth.ErrUnknownCredentials),
+		errors.Is(err, oauth.ErrCredentialsNotAllowed),
+		errors.Is(err, service.ErrUnknownSecret),
+		errors.Is(err, service.ErrUnknownCalendar),
+		errors.Is(err, service.ErrUnknownAction),
+		errors.Is(err, crontab.ErrInvalidTemplate):
+		return http.StatusBadRequest
+	case errors.Is(err, store.ErrScheduleNotExist),
+		errors.Is(err, store.ErrNamespaceNotExist),
+		errors.Is(err, store.ErrAPIKeyNotExist),
+		errors.Is(err, store.ErrRoleBindingNotExist),
+		errors.Is(err, store.ErrSecretNotExist),
+		errors.Is(err, store.ErrCalendarNotExist):
+		return http.StatusNotFound
+	case errors.Is(err, store.ErrNamespaceExists),
+		errors.Is(err, store.ErrScheduleExists),
+		errors.Is(err, service.ErrNamespaceNotEmpty),
+		errors.Is(err, service.ErrSecretInUse),
+		errors.Is(err, service.ErrCalendarInUse),
+		errors.Is(err, service.ErrIdempotencyKeyInProgress):
+		return http.StatusConflict
+	case errors.Is(err, auth.ErrNamespaceDenied),
+		errors.Is(err, auth.ErrForbidden),
+		errors.Is(err, auth.ErrPermissionDenied),
+		errors.Is(err, service.ErrQuotaExceeded):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, service.ErrSecretsDisabled):
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ostafen/kronos/internal/service"
+)
+
+type HealthApiHandler struct {
+	svc service.ScheduleService
+}
+
+func NewHealthApiHandler(svc service.ScheduleService) *HealthApiHandler {
+	return &HealthApiHandler{
+		svc: svc,
+	}
+}
+
+type healthStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Liveness reports whether the scheduler loop is running.
+func (api *HealthApiHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, api.svc.Liveness())
+}
+
+// Readiness reports whether Kronos is able to serve requests, that is the store is reachable,
+// the scheduler loop is running and the service is not shutting down.
+func (api *HealthApiHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, api.svc.Readiness(r.Context()))
+}
+
+func writeHealth(w http.ResponseWriter, err error) {
+	status := healthStatus{Status: "ok"}
+	code := http.StatusOK
+	if err != nil {
+		status = healthStatus{Status: "unavailable", Error: err.Error()}
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(status)
+}
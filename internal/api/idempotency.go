@@ -0,0 +1,124 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/ostafen/kronos/internal/service"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks responses replayed from a previous request with the same key.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	maxIdempotentBodySize   = 1 << 20
+)
+
+type IdempotencyHandler struct {
+	svc service.IdempotencyService
+}
+
+func NewIdempotencyHandler(svc service.IdempotencyService) *IdempotencyHandler {
+	return &IdempotencyHandler{
+		svc: svc,
+	}
+}
+
+// captureWriter records the response written by a handler, while passing it through.
+type captureWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (w *captureWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *captureWriter) Write(data []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Wrap makes handler idempotent for requests carrying an Idempotency-Key header: the response to the first request
+// with a given key is stored, and replayed to the following ones within the retention window.
+// Responses with a 5xx status are not stored, so that failed requests can be retried with the same key.
+func (api *IdempotencyHandler) Wrap(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			handler(w, r)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			http.Error(w, "idempotency key is too long", http.StatusBadRequest)
+			return
+		}
+
+		// bodies are read one byte past the limit, so that longer ones are rejected rather than truncated
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBodySize+1))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if len(body) > maxIdempotentBodySize {
+			http.Error(w, "request body is too large for an idempotent request", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, err := api.svc.Begin(r.Context(), key, fingerprint(r, body))
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		if record != nil {
+			if record.ContentType != "" {
+				w.Header().Set("Content-Type", record.ContentType)
+			}
+			w.Header().Set(IdempotentReplayedHeader, "true")
+			w.WriteHeader(record.StatusCode)
+			w.Write(record.Body)
+			return
+		}
+
+		cw := &captureWriter{ResponseWriter: w}
+		handler(cw, r)
+
+		if cw.statusCode == 0 {
+			cw.statusCode = http.StatusOK
+		}
+
+		if cw.statusCode >= http.StatusInternalServerError {
+			err = api.svc.Abort(r.Context(), key)
+		} else {
+			err = api.svc.Complete(r.Context(), key, cw.statusCode, w.Header().Get("Content-Type"), cw.body.Bytes())
+		}
+
+		if err != nil {
+			log.WithError(err).Error("unable to store the response of an idempotent request")
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/ostafen/kronos/internal/service"
+	"github.com/ostafen/kronos/model"
+)
+
+type NamespaceApiHandler struct {
+	svc service.NamespaceService
+}
+
+func NewNamespaceApiHandler(svc service.NamespaceService) *NamespaceApiHandler {
+	return &NamespaceApiHandler{
+		svc: svc,
+	}
+}
+
+func decodeNamespaceInput(r *http.Request) (*model.NamespaceInput, error) {
+	var input model.NamespaceInput
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&input); err != nil {
+		return nil, err
+	}
+
+	v := validator.New()
+	if err := v.Struct(input); err != nil {
+		return nil, err
+	}
+	return &input, nil
+}
+
+func (api *NamespaceApiHandler) CreateNamespace(w http.ResponseWriter, r *http.Request) {
+	input, err := decodeNamespaceInput(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ns, err := api.svc.CreateNamespace(r.Context(), input)
+	if err != nil {
+		code := errorStatus(err)
+		if code == http.StatusInternalServerError {
+			code = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	writeJSONStatus(w, http.StatusCreated, ns)
+}
+
+func (api *NamespaceApiHandler) UpdateNamespace(w http.ResponseWriter, r *http.Request) {
+	input, err := decodeNamespaceInput(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	if input.Name != "" && input.Name != name {
+		http.Error(w, "namespace name cannot be changed", http.StatusBadRequest)
+		return
+	}
+	input.Name = name
+
+	ns, err := api.svc.UpdateNamespace(r.Context(), input)
+	if err != nil {
+		code := errorStatus(err)
+		if code == http.StatusInternalServerError {
+			code = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	writeJSON(w, ns)
+}
+
+func (api *NamespaceApiHandler) GetNamespace(w http.ResponseWriter, r *http.Request) {
+	ns, err := api.svc.GetNamespace(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	writeJSON(w, ns)
+}
+
+func (api *NamespaceApiHandler) ListNamespaces(w http.ResponseWriter, r *http.Request) {
+	namespaces, err := api.svc.ListNamespaces(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	writeJSON(w, namespaces)
+}
+
+func (api *NamespaceApiHandler) DeleteNamespace(w http.ResponseWriter, r *http.Request) {
+	err := api.svc.DeleteNamespace(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
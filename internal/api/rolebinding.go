@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/ostafen/kronos/internal/service"
+	"github.com/ostafen/kronos/model"
+)
+
+type RoleBindingApiHandler struct {
+	svc service.RoleBindingService
+}
+
+func NewRoleBindingApiHandler(svc service.RoleBindingService) *RoleBindingApiHandler {
+	return &RoleBindingApiHandler{
+		svc: svc,
+	}
+}
+
+func (api *RoleBindingApiHandler) CreateRoleBinding(w http.ResponseWriter, r *http.Request) {
+	var input model.RoleBindingInput
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	binding, err := api.svc.CreateRoleBinding(r.Context(), &input)
+	if err != nil {
+		code := errorStatus(err)
+		if code == http.StatusInternalServerError {
+			code = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	writeJSONStatus(w, http.StatusCreated, binding)
+}
+
+func (api *RoleBindingApiHandler) ListRoleBindings(w http.ResponseWriter, r *http.Request) {
+	bindings, err := api.svc.ListRoleBindings(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	writeJSON(w, bindings)
+}
+
+func (api *RoleBindingApiHandler) DeleteRoleBinding(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := api.svc.DeleteRoleBinding(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
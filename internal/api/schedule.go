@@ -2,13 +2,16 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
-	"github.com/ostafen/kronos/internal/model"
 	"github.com/ostafen/kronos/internal/service"
+	"github.com/ostafen/kronos/model"
 )
 
 type ScheduleApiHandler struct {
@@ -36,9 +39,9 @@ func (api *ScheduleApiHandler) RegisterSchedule(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	sched, err := api.svc.RegisterSchedule(&input)
+	sched, err := api.svc.RegisterSchedule(r.Context(), &input)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 	writeJSON(w, sched)
@@ -52,9 +55,9 @@ func (api *ScheduleApiHandler) PauseSchedule(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	sched, err := api.svc.PauseSchedule(id)
+	sched, err := api.svc.PauseSchedule(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -69,9 +72,9 @@ func (api *ScheduleApiHandler) ResumeSchedule(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	sched, err := api.svc.ResumeSchedule(id)
+	sched, err := api.svc.ResumeSchedule(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 	writeJSON(w, sched)
@@ -86,9 +89,9 @@ func (api *ScheduleApiHandler) TriggerSchedule(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	sched, err := api.svc.TriggerSchedule(id)
+	sched, err := api.svc.TriggerSchedule(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 	writeJSON(w, sched)
@@ -103,9 +106,9 @@ func (api *ScheduleApiHandler) GetSchedule(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	sched, err := api.svc.GetSchedule(id)
+	sched, err := api.svc.GetSchedule(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -125,9 +128,9 @@ func (api *ScheduleApiHandler) DeleteSchedule(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	err = api.svc.DeleteSchedule(id)
+	err = api.svc.DeleteSchedule(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -136,21 +139,21 @@ func (api *ScheduleApiHandler) DeleteSchedule(w http.ResponseWriter, r *http.Req
 
 func (api *ScheduleApiHandler) ListSchedules(w http.ResponseWriter, r *http.Request) {
 	schedules := make([]*model.CronSchedule, 0)
-	err := api.svc.IterSchedules(func(s *model.CronSchedule) error {
+	err := api.svc.IterSchedules(r.Context(), func(s *model.CronSchedule) error {
 		schedules = append(schedules, s)
 		return nil
 	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 	writeJSON(w, schedules)
 }
 
 func (api *ScheduleApiHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
-	statuses, err := api.svc.GetHistory()
+	statuses, err := api.svc.GetHistory(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 	writeJSON(w, statuses)
@@ -164,14 +167,80 @@ func (api *ScheduleApiHandler) GetCronHistory(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	statuses, err := api.svc.GetCronHistory(id)
+	statuses, err := api.svc.GetCronHistory(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 	writeJSON(w, statuses)
 }
 
+func (api *ScheduleApiHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	window, err := parseWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stats, err := api.svc.GetStats(r.Context(), window)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	writeJSON(w, stats)
+}
+
+func (api *ScheduleApiHandler) GetCronStats(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	window, err := parseWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stats, err := api.svc.GetCronStats(r.Context(), id, window)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	writeJSON(w, stats)
+}
+
+// parseWindow reads the "window" query parameter, which accepts any Go duration
+// as well as a number of days (e.g. "7d").
+func parseWindow(r *http.Request) (time.Duration, error) {
+	value := r.URL.Query().Get("window")
+	if value == "" {
+		return service.StatsWindowDefault, nil
+	}
+
+	var window time.Duration
+	if days, found := strings.CutSuffix(value, "d"); found {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid window %s", value)
+		}
+		window = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid window %s", value)
+		}
+		window = d
+	}
+
+	if window <= 0 {
+		return 0, fmt.Errorf(`"window" must be positive`)
+	}
+	return window, nil
+}
+
 func writeJSON(w http.ResponseWriter, body any) {
 	w.Header().Add("content-type", "application/json")
 
@@ -186,3 +255,15 @@ func writeJSON(w http.ResponseWriter, body any) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
+
+func writeJSONStatus(w http.ResponseWriter, status int, body any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
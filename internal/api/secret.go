@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/ostafen/kronos/internal/service"
+	"github.com/ostafen/kronos/model"
+)
+
+type SecretApiHandler struct {
+	svc service.SecretService
+}
+
+func NewSecretApiHandler(svc service.SecretService) *SecretApiHandler {
+	return &SecretApiHandler{
+		svc: svc,
+	}
+}
+
+func (api *SecretApiHandler) PutSecret(w http.ResponseWriter, r *http.Request) {
+	var input model.SecretInput
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	v := validator.New()
+	if err := v.Struct(input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	input.Name = mux.Vars(r)["name"]
+
+	secret, err := api.svc.PutSecret(r.Context(), &input)
+	if err != nil {
+		code := errorStatus(err)
+		if code == http.StatusInternalServerError {
+			code = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	writeJSON(w, secret)
+}
+
+func (api *SecretApiHandler) ListSecrets(w http.ResponseWriter, r *http.Request) {
+	secrets, err := api.svc.ListSecrets(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	writeJSON(w, secrets)
+}
+
+func (api *SecretApiHandler) DeleteSecret(w http.ResponseWriter, r *http.Request) {
+	if err := api.svc.DeleteSecret(r.Context(), mux.Vars(r)["name"]); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
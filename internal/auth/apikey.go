@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+const (
+	apiKeyPrefix = "kr_"
+
+	// APIKeyDisplayLen is the number of leading characters of a key which are retained, in clear, to identify it.
+	APIKeyDisplayLen = len(apiKeyPrefix) + 6
+)
+
+// GenerateAPIKey returns a new random api key. Only its hash should be persisted.
+func GenerateAPIKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// APIKeySubject returns the subject of the principal owning the api key with the given id.
+// Keys are identified by id rather than by name, which is not unique.
+func APIKeySubject(id int64) string {
+	return "apikey:" + strconv.FormatInt(id, 10)
+}
+
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyPrefix)
+}
+
+// HashAPIKey returns the hex encoded SHA-256 digest of the key.
+// Since keys are generated with 256 bits of entropy, a fast hash is enough to protect them at rest.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,306 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/ostafen/kronos/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+type Scope string
+
+const (
+	// ScopeRead grants access to schedules, history and statistics.
+	ScopeRead Scope = "read"
+	// ScopeTrigger grants the permission to manually trigger schedules.
+	ScopeTrigger Scope = "trigger"
+	// ScopeWrite grants the permission to create, modify and delete schedules. It implies ScopeRead and ScopeTrigger.
+	ScopeWrite Scope = "write"
+	// ScopeAdmin grants access to every operation, including the management of api keys.
+	ScopeAdmin Scope = "admin"
+)
+
+var scopes = map[Scope]bool{
+	ScopeRead:    true,
+	ScopeTrigger: true,
+	ScopeWrite:   true,
+	ScopeAdmin:   true,
+}
+
+func ParseScope(s string) (Scope, error) {
+	scope := Scope(strings.ToLower(s))
+	if !scopes[scope] {
+		return "", fmt.Errorf("unknown scope %s", s)
+	}
+	return scope, nil
+}
+
+const (
+	MethodAnonymous  = "anonymous"
+	MethodAPIKey     = "apikey"
+	MethodJWT        = "jwt"
+	MethodClientCert = "clientcert"
+)
+
+type Principal struct {
+	Subject string
+	Method  string
+	Scopes  []Scope
+	// Namespace the principal is bound to. Principals which are not bound to any namespace
+	// (such as the admin key) can operate on every namespace.
+	Namespace string
+}
+
+func (p *Principal) IsGlobal() bool {
+	return p.Namespace == ""
+}
+
+func (p *Principal) HasScope(scope Scope) bool {
+	for _, s := range p.Scopes {
+		switch {
+		case s == scope, s == ScopeAdmin:
+			return true
+		case s == ScopeWrite && (scope == ScopeRead || scope == ScopeTrigger):
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	ErrUnauthenticated = errors.New("missing or invalid credentials")
+	ErrForbidden       = errors.New("insufficient scope")
+	ErrNamespaceDenied = errors.New("access to namespace denied")
+)
+
+// NamespaceHeader selects the namespace a request operates on.
+// It is only meaningful for global principals, since the others are confined to their own namespace.
+const NamespaceHeader = "X-Kronos-Namespace"
+
+type principalKey struct{}
+
+type namespaceKey struct{}
+
+type sourceIPKey struct{}
+
+func WithSourceIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, sourceIPKey{}, ip)
+}
+
+// SourceIPFrom returns the address of the client which issued the request the context belongs to, if known.
+func SourceIPFrom(ctx context.Context) string {
+	ip, _ := ctx.Value(sourceIPKey{}).(string)
+	return ip
+}
+
+func sourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func WithNamespace(ctx context.Context, namespace string) context.Context {
+	return context.WithValue(ctx, namespaceKey{}, namespace)
+}
+
+// NamespaceFrom returns the namespace the request the context belongs to is scoped to.
+// An empty namespace means that the request can access all namespaces.
+func NamespaceFrom(ctx context.Context) string {
+	ns, _ := ctx.Value(namespaceKey{}).(string)
+	return ns
+}
+
+func resolveNamespace(p *Principal, requested string) (string, error) {
+	if p.IsGlobal() {
+		return requested, nil
+	}
+
+	if requested != "" && requested != p.Namespace {
+		return "", ErrNamespaceDenied
+	}
+	return p.Namespace, nil
+}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal which issued the request the context belongs to, if any.
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// KeyVerifier resolves an api key to the principal owning it.
+type KeyVerifier interface {
+	VerifyAPIKey(ctx context.Context, key string) (*Principal, error)
+}
+
+type Authenticator struct {
+	enabled  bool
+	adminKey string
+	keys     KeyVerifier
+	jwt      *JWTVerifier
+	// certScopes are granted to callers authenticated through a client certificate.
+	certScopes []Scope
+	// certNamespaces maps the lower case common names of client certificates to their namespace.
+	certNamespaces map[string]string
+}
+
+func NewAuthenticator(conf config.Auth, keys KeyVerifier) (*Authenticator, error) {
+	jwt, err := NewJWTVerifier(conf.JWT)
+	if err != nil {
+		return nil, err
+	}
+
+	certScopes := make([]Scope, 0, len(conf.ClientCert.Scopes))
+	for _, s := range conf.ClientCert.Scopes {
+		scope, err := ParseScope(s)
+		if err != nil {
+			return nil, err
+		}
+		certScopes = append(certScopes, scope)
+	}
+
+	certNamespaces := make(map[string]string, len(conf.ClientCert.Namespaces))
+	for cn, ns := range conf.ClientCert.Namespaces {
+		certNamespaces[strings.ToLower(cn)] = ns
+	}
+
+	if !conf.Enabled {
+		log.Warn("authentication is disabled, the api is accessible to anyone")
+	}
+
+	return &Authenticator{
+		enabled:        conf.Enabled,
+		adminKey:       conf.AdminKey,
+		keys:           keys,
+		jwt:            jwt,
+		certScopes:     certScopes,
+		certNamespaces: certNamespaces,
+	}, nil
+}
+
+var anonymous = &Principal{
+	Subject: MethodAnonymous,
+	Method:  MethodAnonymous,
+	Scopes:  []Scope{ScopeAdmin},
+}
+
+// Middleware authenticates each request, through either an api key, a JWT bearer token or a verified client certificate,
+// and attaches the resulting principal and namespace to the request context.
+// When authentication is disabled, requests are served on behalf of an anonymous administrator.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal := anonymous
+		if a.enabled {
+			p, err := a.authenticate(r)
+			if err != nil {
+				log.WithError(err).
+					WithField("remoteAddr", r.RemoteAddr).
+					Warn("authentication failed")
+
+				w.Header().Set("WWW-Authenticate", `Bearer realm="kronos"`)
+				http.Error(w, ErrUnauthenticated.Error(), http.StatusUnauthorized)
+				return
+			}
+			principal = p
+		}
+		namespace, err := resolveNamespace(principal, r.Header.Get(NamespaceHeader))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		ctx := WithNamespace(WithPrincipal(r.Context(), principal), namespace)
+		ctx = WithSourceIP(ctx, sourceIP(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (a *Authenticator) authenticate(r *http.Request) (*Principal, error) {
+	token := r.Header.Get("X-API-Key")
+	if token == "" {
+		bearer, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found {
+			return a.authenticateCert(r)
+		}
+		token = strings.TrimSpace(bearer)
+	}
+
+	if a.adminKey != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.adminKey)) == 1 {
+		return &Principal{Subject: "admin", Method: MethodAPIKey, Scopes: []Scope{ScopeAdmin}}, nil
+	}
+
+	if IsAPIKey(token) {
+		return a.keys.VerifyAPIKey(r.Context(), token)
+	}
+
+	if a.jwt == nil {
+		return nil, ErrUnauthenticated
+	}
+	return a.jwt.Verify(token)
+}
+
+// certGlobalNamespace is the namespace of the client certificates which can access every namespace.
+const certGlobalNamespace = "*"
+
+// authenticateCert authenticates requests carrying no other credentials through their client certificate,
+// which has already been verified during the TLS handshake. Its subject is prefixed, so that it can't collide
+// with the subject of other principals, and its namespace must be configured, so that no certificate issued
+// by the client CA is global by default.
+func (a *Authenticator) authenticateCert(r *http.Request) (*Principal, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, ErrUnauthenticated
+	}
+
+	cert := r.TLS.VerifiedChains[0][0]
+	if cert.Subject.CommonName == "" {
+		return nil, fmt.Errorf("%w: client certificate has no common name", ErrUnauthenticated)
+	}
+	namespace, ok := a.certNamespaces[strings.ToLower(cert.Subject.CommonName)]
+	if !ok {
+		return nil, fmt.Errorf("%w: no namespace is configured for client certificate %q", ErrUnauthenticated, cert.Subject.CommonName)
+	}
+
+	if namespace == certGlobalNamespace {
+		namespace = ""
+	}
+
+	return &Principal{
+		Subject:   "cert:" + cert.Subject.CommonName,
+		Method:    MethodClientCert,
+		Scopes:    a.certScopes,
+		Namespace: namespace,
+	}, nil
+}
+
+// Require only lets requests through if their principal has been granted the given scope.
+func Require(scope Scope, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := PrincipalFrom(r.Context())
+		if !ok {
+			http.Error(w, ErrUnauthenticated.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		if !p.HasScope(scope) {
+			http.Error(w, fmt.Sprintf("%s: %s required", ErrForbidden, scope), http.StatusForbidden)
+			return
+		}
+		handler(w, r)
+	}
+}
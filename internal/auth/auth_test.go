@@ -0,0 +1,287 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ostafen/kronos/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeSegment(t *testing.T, v any) string {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func signHS256(t *testing.T, secret string, claims map[string]any) string {
+	signed := encodeSegment(t, map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + encodeSegment(t, claims)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	signed := encodeSegment(t, map[string]string{"alg": "RS256", "kid": kid}) + "." + encodeSegment(t, claims)
+
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestJWTVerifierHS256(t *testing.T) {
+	v, err := NewJWTVerifier(config.JWT{Secret: "secret", Issuer: "kronos-tests", Audience: "kronos"})
+	require.NoError(t, err)
+
+	claims := map[string]any{
+		"sub":   "alice",
+		"iss":   "kronos-tests",
+		"aud":   []string{"kronos", "other"},
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"scope": "read trigger unknown",
+	}
+
+	p, err := v.Verify(signHS256(t, "secret", claims))
+	require.NoError(t, err)
+	require.Equal(t, "jwt:alice", p.Subject)
+	require.Equal(t, []Scope{ScopeRead, ScopeTrigger}, p.Scopes)
+
+	_, err = v.Verify(signHS256(t, "another-secret", claims))
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	claims["exp"] = time.Now().Add(-time.Minute).Unix()
+	_, err = v.Verify(signHS256(t, "secret", claims))
+	require.ErrorIs(t, err, ErrTokenExpired)
+
+	claims["exp"] = time.Now().Add(time.Minute).Unix()
+	claims["iss"] = "someone-else"
+	_, err = v.Verify(signHS256(t, "secret", claims))
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	claims["iss"] = "kronos-tests"
+	claims["aud"] = "other"
+	_, err = v.Verify(signHS256(t, "secret", claims))
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	claims["aud"] = "kronos"
+	for _, sub := range []any{"", " "} {
+		claims["sub"] = sub
+		_, err = v.Verify(signHS256(t, "secret", claims))
+		require.ErrorIs(t, err, ErrInvalidToken)
+	}
+	delete(claims, "sub")
+	_, err = v.Verify(signHS256(t, "secret", claims))
+	require.ErrorIs(t, err, ErrInvalidToken)
+	claims["sub"] = "alice"
+
+	// tokens which never expire are only accepted on request
+	claims["aud"] = "kronos"
+	delete(claims, "exp")
+	_, err = v.Verify(signHS256(t, "secret", claims))
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	v, err = NewJWTVerifier(config.JWT{Secret: "secret", Issuer: "kronos-tests", Audience: "kronos", AllowMissingExpiry: true})
+	require.NoError(t, err)
+	_, err = v.Verify(signHS256(t, "secret", claims))
+	require.NoError(t, err)
+}
+
+func TestJWTVerifierRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwks := map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "key-1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+	data, err := json.Marshal(jwks)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0600))
+
+	v, err := NewJWTVerifier(config.JWT{JWKSFile: path})
+	require.NoError(t, err)
+
+	claims := map[string]any{"sub": "bob", "scopes": []string{"admin"}, "exp": time.Now().Add(time.Minute).Unix()}
+
+	p, err := v.Verify(signRS256(t, key, "key-1", claims))
+	require.NoError(t, err)
+	require.True(t, p.HasScope(ScopeWrite))
+
+	_, err = v.Verify(signRS256(t, key, "key-2", claims))
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	// HS256 must not be accepted when no secret is configured
+	_, err = v.Verify(signHS256(t, "", claims))
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestJWTNamespaceClaim(t *testing.T) {
+	v, err := NewJWTVerifier(config.JWT{Secret: "secret", NamespaceClaim: "namespace", GlobalNamespace: "*"})
+	require.NoError(t, err)
+
+	claims := map[string]any{"sub": "alice", "exp": time.Now().Add(time.Minute).Unix(), "namespace": "team-a"}
+
+	p, err := v.Verify(signHS256(t, "secret", claims))
+	require.NoError(t, err)
+	require.Equal(t, "team-a", p.Namespace)
+
+	claims["namespace"] = "*"
+	p, err = v.Verify(signHS256(t, "secret", claims))
+	require.NoError(t, err)
+	require.True(t, p.IsGlobal())
+
+	// tokens lacking the claim must not be mistaken for global ones
+	for _, ns := range []any{nil, "", 42} {
+		claims["namespace"] = ns
+		_, err = v.Verify(signHS256(t, "secret", claims))
+		require.ErrorIs(t, err, ErrInvalidToken, ns)
+	}
+}
+
+func TestPrincipalHasScope(t *testing.T) {
+	read := &Principal{Scopes: []Scope{ScopeRead}}
+	require.True(t, read.HasScope(ScopeRead))
+	require.False(t, read.HasScope(ScopeTrigger))
+	require.False(t, read.HasScope(ScopeWrite))
+
+	trigger := &Principal{Scopes: []Scope{ScopeTrigger}}
+	require.True(t, trigger.HasScope(ScopeTrigger))
+	require.False(t, trigger.HasScope(ScopeRead))
+
+	write := &Principal{Scopes: []Scope{ScopeWrite}}
+	require.True(t, write.HasScope(ScopeRead))
+	require.True(t, write.HasScope(ScopeTrigger))
+	require.False(t, write.HasScope(ScopeAdmin))
+}
+
+type keyVerifierFunc func(ctx context.Context, key string) (*Principal, error)
+
+func (f keyVerifierFunc) VerifyAPIKey(ctx context.Context, key string) (*Principal, error) {
+	return f(ctx, key)
+}
+
+func TestMiddleware(t *testing.T) {
+	readKey, err := GenerateAPIKey()
+	require.NoError(t, err)
+
+	keys := keyVerifierFunc(func(ctx context.Context, key string) (*Principal, error) {
+		if key == readKey {
+			return &Principal{Subject: "reader", Scopes: []Scope{ScopeRead}}, nil
+		}
+		return nil, ErrUnauthenticated
+	})
+
+	authn, err := NewAuthenticator(config.Auth{Enabled: true, AdminKey: "bootstrap"}, keys)
+	require.NoError(t, err)
+
+	handler := authn.Middleware(Require(ScopeWrite, func(w http.ResponseWriter, r *http.Request) {}))
+
+	cases := []struct {
+		header string
+		value  string
+		code   int
+	}{
+		{"", "", http.StatusUnauthorized},
+		{"Authorization", "Bearer kr_invalid", http.StatusUnauthorized},
+		{"Authorization", "Bearer " + readKey, http.StatusForbidden},
+		{"X-API-Key", readKey, http.StatusForbidden},
+		{"Authorization", "Bearer bootstrap", http.StatusOK},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/schedules", nil)
+		if c.header != "" {
+			req.Header.Set(c.header, c.value)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, c.code, rec.Code, "%s: %s", c.header, c.value)
+	}
+}
+
+func TestClientCertPrincipal(t *testing.T) {
+	authn, err := NewAuthenticator(config.Auth{
+		Enabled:  true,
+		AdminKey: "bootstrap",
+		ClientCert: config.ClientCert{
+			Scopes:     []string{"write"},
+			Namespaces: map[string]string{"billing-service": "billing", "ops": "*"},
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	var principal *Principal
+	handler := authn.Middleware(Require(ScopeWrite, func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = PrincipalFrom(r.Context())
+	}))
+
+	withCert := func(cn string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/schedules", nil)
+		req.TLS = &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: cn}}}},
+		}
+		return req
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, withCert("billing-service"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "cert:billing-service", principal.Subject)
+	require.Equal(t, MethodClientCert, principal.Method)
+	require.Equal(t, "billing", principal.Namespace)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, withCert("ops"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.True(t, principal.IsGlobal())
+
+	// certificates without a namespace are rejected, rather than granted access to every namespace
+	for _, cn := range []string{"", "reporting"} {
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, withCert(cn))
+		require.Equal(t, http.StatusUnauthorized, rec.Code, cn)
+	}
+
+	// explicit credentials take precedence over the client certificate
+	req := withCert("billing-service")
+	req.Header.Set("X-API-Key", "bootstrap")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "admin", principal.Subject)
+
+	// unverified certificates are ignored
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/schedules", nil)
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "billing-service"}}}}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
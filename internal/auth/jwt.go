@@ -0,0 +1,292 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ostafen/kronos/internal/config"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token is expired")
+)
+
+// JWTVerifier validates HS256 tokens signed with a shared secret and RS256 tokens signed
+// with one of the keys of a JWKS file.
+type JWTVerifier struct {
+	secret   []byte
+	keys     map[string]*rsa.PublicKey
+	issuer   string
+	audience string
+	leeway   time.Duration
+	// allowMissingExpiry accepts tokens which never expire, since they carry no "exp" claim.
+	allowMissingExpiry bool
+	namespaceClaim     string
+	// globalNamespace is the value of the namespace claim of the tokens which can access every namespace.
+	globalNamespace string
+}
+
+// NewJWTVerifier returns nil if neither a secret nor a JWKS file has been configured.
+func NewJWTVerifier(conf config.JWT) (*JWTVerifier, error) {
+	if conf.Secret == "" && conf.JWKSFile == "" {
+		return nil, nil
+	}
+
+	v := &JWTVerifier{
+		secret:             []byte(conf.Secret),
+		issuer:             conf.Issuer,
+		audience:           conf.Audience,
+		leeway:             conf.Leeway,
+		allowMissingExpiry: conf.AllowMissingExpiry,
+		namespaceClaim:     conf.NamespaceClaim,
+		globalNamespace:    conf.GlobalNamespace,
+	}
+
+	if conf.JWKSFile != "" {
+		keys, err := readJWKS(conf.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		v.keys = keys
+	}
+	return v, nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func readJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, fmt.Errorf("invalid jwks file %s: %w", path, err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus for key %s: %w", k.Kid, err)
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent for key %s: %w", k.Kid, err)
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no RSA signing key found in jwks file %s", path)
+	}
+	return keys, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+	Scope     string   `json:"scope"`
+	Scopes    []string `json:"scopes"`
+}
+
+// audience handles the "aud" claim, which can either be a string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(a))
+}
+
+func (a audience) contains(aud string) bool {
+	for _, s := range a {
+		if s == aud {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *JWTVerifier) Verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if err := v.verifySignature(header, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	namespace, err := v.namespace(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := v.validateClaims(&claims); err != nil {
+		return nil, err
+	}
+
+	scopeNames := claims.Scopes
+	if claims.Scope != "" {
+		scopeNames = append(scopeNames, strings.Fields(claims.Scope)...)
+	}
+
+	// the subject is prefixed, so that it can't collide with the subject of other principals
+	principal := &Principal{Subject: "jwt:" + claims.Subject, Method: MethodJWT, Namespace: namespace}
+	for _, name := range scopeNames {
+		// scopes which are not meaningful to kronos are simply ignored
+		if scope, err := ParseScope(name); err == nil {
+			principal.Scopes = append(principal.Scopes, scope)
+		}
+	}
+	return principal, nil
+}
+
+func (v *JWTVerifier) verifySignature(header jwtHeader, signed string, signature []byte) error {
+	switch header.Alg {
+	case "HS256":
+		if len(v.secret) == 0 {
+			return fmt.Errorf("%w: HS256 is not enabled", ErrInvalidToken)
+		}
+
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return fmt.Errorf("%w: signature mismatch", ErrInvalidToken)
+		}
+		return nil
+	case "RS256":
+		key, has := v.keys[header.Kid]
+		if !has {
+			return fmt.Errorf("%w: unknown key id %q", ErrInvalidToken, header.Kid)
+		}
+
+		digest := sha256.Sum256([]byte(signed))
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("%w: signature mismatch", ErrInvalidToken)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Alg)
+}
+
+func (v *JWTVerifier) validateClaims(claims *jwtClaims) error {
+	now := time.Now()
+
+	if claims.ExpiresAt == 0 && !v.allowMissingExpiry {
+		return fmt.Errorf("%w: missing exp claim", ErrInvalidToken)
+	}
+
+	if claims.ExpiresAt != 0 && now.After(time.Unix(claims.ExpiresAt, 0).Add(v.leeway)) {
+		return ErrTokenExpired
+	}
+
+	if claims.NotBefore != 0 && now.Before(time.Unix(claims.NotBefore, 0).Add(-v.leeway)) {
+		return fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+	}
+
+	// the subject identifies the principal, so that tokens lacking it would all share the same identity
+	if strings.TrimSpace(claims.Subject) == "" {
+		return fmt.Errorf("%w: missing sub claim", ErrInvalidToken)
+	}
+
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, claims.Issuer)
+	}
+
+	if v.audience != "" && !claims.Audience.contains(v.audience) {
+		return fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+	return nil
+}
+
+// namespace extracts the namespace the token is bound to, from the configured claim. Tokens lacking the claim
+// are rejected rather than treated as global, which they are only if the claim holds the configured global value.
+func (v *JWTVerifier) namespace(payload string) (string, error) {
+	if v.namespaceClaim == "" {
+		return "", nil
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(payload, &claims); err != nil {
+		return "", err
+	}
+
+	ns, _ := claims[v.namespaceClaim].(string)
+	if ns == "" {
+		return "", fmt.Errorf("%w: missing %q claim", ErrInvalidToken, v.namespaceClaim)
+	}
+
+	if v.globalNamespace != "" && ns == v.globalNamespace {
+		return "", nil
+	}
+	return ns, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrInvalidToken
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Role string
+
+const (
+	// RoleViewer can list and inspect schedules, their history and statistics.
+	RoleViewer Role = "viewer"
+	// RoleOperator can additionally pause, resume and trigger schedules.
+	RoleOperator Role = "operator"
+	// RoleEditor can additionally create and delete schedules.
+	RoleEditor Role = "editor"
+	// RoleAdmin can additionally manage the role bindings of its namespace.
+	RoleAdmin Role = "admin"
+)
+
+type Action string
+
+const (
+	ActionView    Action = "view"
+	ActionOperate Action = "operate"
+	ActionEdit    Action = "edit"
+	ActionManage  Action = "manage"
+)
+
+var roleActions = map[Role][]Action{
+	RoleViewer:   {ActionView},
+	RoleOperator: {ActionView, ActionOperate},
+	RoleEditor:   {ActionView, ActionOperate, ActionEdit},
+	RoleAdmin:    {ActionView, ActionOperate, ActionEdit, ActionManage},
+}
+
+func ParseRole(s string) (Role, error) {
+	role := Role(strings.ToLower(s))
+	if _, has := roleActions[role]; !has {
+		return "", fmt.Errorf("unknown role %s", s)
+	}
+	return role, nil
+}
+
+func (r Role) Allows(action Action) bool {
+	for _, a := range roleActions[r] {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+var ErrPermissionDenied = errors.New("permission denied")
+
+// Binding grants a role on the schedules of a namespace (all namespaces, if empty)
+// whose metadata matches the selector (all schedules, if empty).
+type Binding struct {
+	Role      Role
+	Namespace string
+	Selector  map[string]string
+}
+
+func (b *Binding) covers(namespace string) bool {
+	return b.Namespace == "" || b.Namespace == namespace
+}
+
+func (b *Binding) matches(labels map[string]string) bool {
+	for k, v := range b.Selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Grants are the role bindings of the principal which issued a request.
+// A nil value grants every permission: this is the case when RBAC is disabled,
+// for principals holding the admin scope and for internal calls.
+type Grants struct {
+	bindings []Binding
+}
+
+func NewGrants(bindings ...Binding) *Grants {
+	return &Grants{bindings: bindings}
+}
+
+// Allows reports whether action is permitted on a schedule of the given namespace and labels.
+func (g *Grants) Allows(action Action, namespace string, labels map[string]string) bool {
+	if g == nil {
+		return true
+	}
+
+	for _, b := range g.bindings {
+		if b.Role.Allows(action) && b.covers(namespace) && b.matches(labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsAll reports whether action is permitted on every schedule of the given namespace.
+// An empty namespace stands for all namespaces.
+func (g *Grants) AllowsAll(action Action, namespace string) bool {
+	if g == nil {
+		return true
+	}
+
+	for _, b := range g.bindings {
+		if b.Role.Allows(action) && len(b.Selector) == 0 && (b.Namespace == "" || (namespace != "" && b.Namespace == namespace)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsAny reports whether action is permitted on at least some of the schedules of the given namespace.
+// An empty namespace stands for any namespace.
+func (g *Grants) AllowsAny(action Action, namespace string) bool {
+	if g == nil {
+		return true
+	}
+
+	for _, b := range g.bindings {
+		if b.Role.Allows(action) && (namespace == "" || b.covers(namespace)) {
+			return true
+		}
+	}
+	return false
+}
+
+type grantsKey struct{}
+
+func WithGrants(ctx context.Context, g *Grants) context.Context {
+	return context.WithValue(ctx, grantsKey{}, g)
+}
+
+// GrantsFrom returns the grants attached to the context, or nil if RBAC doesn't apply to it.
+func GrantsFrom(ctx context.Context) *Grants {
+	g, _ := ctx.Value(grantsKey{}).(*Grants)
+	return g
+}
+
+// BindingResolver returns the role bindings of a subject.
+type BindingResolver interface {
+	RoleBindings(ctx context.Context, subject string) ([]Binding, error)
+}
+
+type Authorizer struct {
+	enabled  bool
+	bindings BindingResolver
+}
+
+func NewAuthorizer(enabled bool, bindings BindingResolver) *Authorizer {
+	return &Authorizer{
+		enabled:  enabled,
+		bindings: bindings,
+	}
+}
+
+// Middleware resolves the role bindings of the authenticated principal and attaches them to the request context.
+// It must be installed after the authentication middleware.
+func (a *Authorizer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := PrincipalFrom(r.Context())
+		if !a.enabled || !ok || p.HasScope(ScopeAdmin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		bindings, err := a.bindings.RoleBindings(r.Context(), p.Subject)
+		if err != nil {
+			log.WithError(err).
+				WithField("subject", p.Subject).
+				Error("unable to resolve role bindings")
+
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		ctx := WithGrants(r.Context(), NewGrants(bindings...))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Deny writes a permission denied response, naming the action which was required.
+func Deny(w http.ResponseWriter, action Action) {
+	http.Error(w, fmt.Sprintf("%s: %s permission required", ErrPermissionDenied, action), http.StatusForbidden)
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrants(t *testing.T) {
+	grants := NewGrants(
+		Binding{Role: RoleOperator, Namespace: "team-a"},
+		Binding{Role: RoleEditor, Namespace: "team-a", Selector: map[string]string{"env": "staging"}},
+		Binding{Role: RoleViewer},
+	)
+
+	staging := map[string]string{"env": "staging", "owner": "bob"}
+	prod := map[string]string{"env": "prod"}
+
+	cases := []struct {
+		name     string
+		action   Action
+		ns       string
+		labels   map[string]string
+		expected bool
+	}{
+		{"view anywhere", ActionView, "team-b", nil, true},
+		{"operate in bound namespace", ActionOperate, "team-a", prod, true},
+		{"operate in other namespace", ActionOperate, "team-b", prod, false},
+		{"edit matching selector", ActionEdit, "team-a", staging, true},
+		{"edit not matching selector", ActionEdit, "team-a", prod, false},
+		{"edit without labels", ActionEdit, "team-a", nil, false},
+		{"manage", ActionManage, "team-a", staging, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.expected, grants.Allows(c.action, c.ns, c.labels))
+		})
+	}
+
+	require.True(t, grants.AllowsAll(ActionView, ""))
+	require.True(t, grants.AllowsAll(ActionOperate, "team-a"))
+	require.False(t, grants.AllowsAll(ActionOperate, ""))
+	require.False(t, grants.AllowsAll(ActionEdit, "team-a"))
+
+	require.True(t, grants.AllowsAny(ActionEdit, "team-a"))
+	require.True(t, grants.AllowsAny(ActionEdit, ""))
+	require.False(t, grants.AllowsAny(ActionEdit, "team-b"))
+
+	var none *Grants
+	require.True(t, none.Allows(ActionManage, "team-a", nil))
+}
+
+func TestParseRole(t *testing.T) {
+	role, err := ParseRole("Operator")
+	require.NoError(t, err)
+	require.Equal(t, RoleOperator, role)
+
+	_, err = ParseRole("owner")
+	require.Error(t, err)
+}
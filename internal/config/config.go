@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strings"
+	"time"
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
@@ -17,10 +18,157 @@ type Log struct {
 	Format string `mapstructure:"format"`
 }
 
+type Tracing struct {
+	Exporter    string  `mapstructure:"exporter"`
+	Endpoint    string  `mapstructure:"endpoint"`
+	Insecure    bool    `mapstructure:"insecure"`
+	ServiceName string  `mapstructure:"serviceName"`
+	SampleRatio float64 `mapstructure:"sampleRatio"`
+}
+
+type JWT struct {
+	Secret   string        `mapstructure:"secret"`
+	JWKSFile string        `mapstructure:"jwksFile"`
+	Issuer   string        `mapstructure:"issuer"`
+	Audience string        `mapstructure:"audience"`
+	Leeway   time.Duration `mapstructure:"leeway"`
+	// AllowMissingExpiry accepts tokens without an "exp" claim, which never expire.
+	AllowMissingExpiry bool `mapstructure:"allowMissingExpiry"`
+	// NamespaceClaim is the claim holding the namespace the token is bound to. Tokens without it are rejected,
+	// while all the tokens are global if it is empty.
+	NamespaceClaim string `mapstructure:"namespaceClaim"`
+	// GlobalNamespace is the value of the namespace claim granting access to every namespace, such as "*".
+	// Empty means that no token is global.
+	GlobalNamespace string `mapstructure:"globalNamespace"`
+}
+
+type Auth struct {
+	Enabled    bool       `mapstructure:"enabled"`
+	AdminKey   string     `mapstructure:"adminKey"`
+	JWT        JWT        `mapstructure:"jwt"`
+	RBAC       RBAC       `mapstructure:"rbac"`
+	ClientCert ClientCert `mapstructure:"clientCert"`
+}
+
+// ClientCert configures the authentication of callers presenting a verified client certificate,
+// whose subject is the common name of the certificate.
+type ClientCert struct {
+	Scopes []string `mapstructure:"scopes"`
+	// Namespaces maps the common names of certificates, which are case insensitive, to the namespace they are bound to,
+	// or to "*" for access to every namespace. Certificates whose common name is not mapped are rejected.
+	Namespaces map[string]string `mapstructure:"namespaces"`
+}
+
+type RBAC struct {
+	// Enabled restricts the schedule operations of principals lacking the admin scope to their role bindings.
+	Enabled bool `mapstructure:"enabled"`
+}
+
+type Quota struct {
+	MaxSchedules           int           `mapstructure:"maxSchedules"`
+	MinInterval            time.Duration `mapstructure:"minInterval"`
+	MaxDeliveriesPerMinute int           `mapstructure:"maxDeliveriesPerMinute"`
+}
+
+type Tenancy struct {
+	// DefaultQuota applies to namespaces which don't define their own limits. Zero means unlimited.
+	DefaultQuota Quota `mapstructure:"defaultQuota"`
+}
+
+type Idempotency struct {
+	// Retention is how long responses are replayed to requests carrying the same Idempotency-Key.
+	Retention time.Duration `mapstructure:"retention"`
+}
+
+type Egress struct {
+	AllowedSchemes []string `mapstructure:"allowedSchemes"`
+	// AllowedPorts restricts the ports webhooks can target. Empty means any port.
+	AllowedPorts []int `mapstructure:"allowedPorts"`
+	// AllowedHosts and DeniedHosts are glob patterns, such as "*.example.com". Empty AllowedHosts means any host.
+	AllowedHosts []string `mapstructure:"allowedHosts"`
+	DeniedHosts  []string `mapstructure:"deniedHosts"`
+	// AllowedCIDRs take precedence over the private ranges, which are otherwise blocked.
+	AllowedCIDRs []string `mapstructure:"allowedCIDRs"`
+	DeniedCIDRs  []string `mapstructure:"deniedCIDRs"`
+}
+
+type TLSProfile struct {
+	// CertFile and KeyFile hold the client certificate presented to receivers requiring mutual TLS.
+	CertFile string `mapstructure:"certFile"`
+	KeyFile  string `mapstructure:"keyFile"`
+	// CAFile holds the certificates used to verify receivers, in place of the system pool.
+	CAFile             string `mapstructure:"caFile"`
+	ServerName         string `mapstructure:"serverName"`
+	InsecureSkipVerify bool   `mapstructure:"insecureSkipVerify"`
+}
+
+// OAuth2Credentials are exchanged for bearer tokens through the client credentials grant.
+type OAuth2Credentials struct {
+	TokenURL     string   `mapstructure:"tokenURL"`
+	ClientID     string   `mapstructure:"clientID"`
+	ClientSecret string   `mapstructure:"clientSecret"`
+	Scopes       []string `mapstructure:"scopes"`
+	// AuthStyle is either HEADER (default), which sends the client credentials through basic authentication,
+	// or PARAMS, which sends them in the request body.
+	AuthStyle string `mapstructure:"authStyle"`
+	// AllowedURLs are the url prefixes, such as "https://billing.example.com/hooks/", tokens may be sent to.
+	// At least one is required, so that schedules can't send tokens to urls of their choice.
+	AllowedURLs []string `mapstructure:"allowedURLs"`
+	// AllowedNamespaces restricts the namespaces whose schedules may use the credentials. Empty means any namespace.
+	AllowedNamespaces []string `mapstructure:"allowedNamespaces"`
+}
+
+type Webhooks struct {
+	// TLS applies to schedules which don't reference any profile.
+	TLS         TLSProfile                   `mapstructure:"tls"`
+	TLSProfiles map[string]TLSProfile        `mapstructure:"tlsProfiles"`
+	Credentials map[string]OAuth2Credentials `mapstructure:"credentials"`
+}
+
+// Secrets configures the encryption of secrets at rest. Keys are the base64 encoding of 32 random bytes,
+// and are either given inline or read from a file.
+type Secrets struct {
+	Key     string `mapstructure:"key"`
+	KeyFile string `mapstructure:"keyFile"`
+	// PreviousKeys are only used to decrypt secrets, which are re-encrypted with the current key on startup.
+	PreviousKeys     []string `mapstructure:"previousKeys"`
+	PreviousKeyFiles []string `mapstructure:"previousKeyFiles"`
+}
+
+// ServerTLS enables HTTPS on the api server, when CertFile is set.
+type ServerTLS struct {
+	CertFile string `mapstructure:"certFile"`
+	KeyFile  string `mapstructure:"keyFile"`
+	// ClientCAFile holds the CAs client certificates are verified against.
+	ClientCAFile string `mapstructure:"clientCAFile"`
+	// ClientAuth is one of NONE (default), OPTIONAL or REQUIRE.
+	ClientAuth string `mapstructure:"clientAuth"`
+}
+
+type Metrics struct {
+	// Port of a separate listener serving metrics and health probes. Zero serves them on the api port.
+	Port int64 `mapstructure:"port"`
+}
+
+type Cors struct {
+	AllowedOrigins []string `mapstructure:"allowedOrigins"`
+}
+
 type Config struct {
-	Port    int64 `mapstructure:"port"`
-	Logging Log   `mapstructure:"logging"`
-	Store   Store `mapstructure:"store"`
+	Port            int64         `mapstructure:"port"`
+	TLS             ServerTLS     `mapstructure:"tls"`
+	Metrics         Metrics       `mapstructure:"metrics"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdownTimeout"`
+	Logging         Log           `mapstructure:"logging"`
+	Store           Store         `mapstructure:"store"`
+	Tracing         Tracing       `mapstructure:"tracing"`
+	Auth            Auth          `mapstructure:"auth"`
+	Cors            Cors          `mapstructure:"cors"`
+	Tenancy         Tenancy       `mapstructure:"tenancy"`
+	Idempotency     Idempotency   `mapstructure:"idempotency"`
+	Egress          Egress        `mapstructure:"egress"`
+	Webhooks        Webhooks      `mapstructure:"webhooks"`
+	Secrets         Secrets       `mapstructure:"secrets"`
 }
 
 func Read() (*Config, error) {
@@ -48,6 +196,17 @@ func Read() (*Config, error) {
 func viperDefaults() {
 	viper.SetDefault("store.path", "kronos.db")
 	viper.SetDefault("port", 9175)
+	viper.SetDefault("shutdownTimeout", "30s")
+	viper.SetDefault("cors.allowedOrigins", []string{"*"})
+	viper.SetDefault("idempotency.retention", "24h")
+	viper.SetDefault("egress.allowedSchemes", []string{"http", "https"})
+	viper.SetDefault("auth.jwt.namespaceClaim", "namespace")
+	viper.SetDefault("auth.clientCert.scopes", []string{"read"})
+	viper.SetDefault("tls.clientAuth", "NONE")
+	viper.SetDefault("tracing.exporter", "NONE")
+	viper.SetDefault("tracing.endpoint", "localhost:4318")
+	viper.SetDefault("tracing.serviceName", "kronos")
+	viper.SetDefault("tracing.sampleRatio", 1.0)
 }
 
 func bindEnv(v any) error {
@@ -1,18 +1,40 @@
+// Package cron parses and evaluates the expressions of recurring schedules.
+//
+// Expressions have five fields (minute, hour, day of month, month and day of week), optionally preceded
+// by a seconds field and followed by a year field, as in Quartz. Besides lists, ranges and steps, fields support:
+//
+//   - names of months (JAN-DEC) and days of week (SUN-SAT), which are numbered from 0 (or 7) for Sunday
+//   - ? in the day of month and day of week fields, meaning no specific value
+//   - L in the day of month field, for the last day of the month, and L-n for n days before it
+//   - nW in the day of month field, for the weekday nearest to the n-th day, and LW for the last weekday
+//   - dL in the day of week field, for the last d day of the month, such as 5L or FRIL for the last Friday
+//   - d#n in the day of week field, for the n-th d day of the month, such as MON#2 for the second Monday
+//
+// When both the day of month and the day of week fields are restricted, a day matching either of them matches,
+// as in standard cron; a field starting with * or ? is not considered restricted.
+//
+// Expressions can also be one of the macros @yearly (or @annually), @monthly, @weekly, @daily (or @midnight)
+// and @hourly, or @every followed by a duration, such as @every 1h30m, which activates at fixed intervals.
+// Fields also accept H tokens, as in Jenkins, which stand for a value picked by hashing a key, such as
+// the name of a schedule, so that expressions like "H * * * *" spread over the hour: see Resolve.
+// A TZ= or CRON_TZ= prefix, such as CRON_TZ=Europe/Rome, evaluates the expression in the given time zone
+// rather than in the one of the time it is evaluated from.
 package cron
 
 import (
 	"time"
 
-	"github.com/robfig/cron/v3"
 	log "github.com/sirupsen/logrus"
 )
 
-var parser = cron.NewParser(
-	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow,
-)
+// Schedule computes the activations of an expression.
+type Schedule interface {
+	// Next returns the first activation strictly after t, or the zero time if there are no more activations.
+	Next(t time.Time) time.Time
+}
 
 func Next(cronExpr string, start time.Time) time.Time {
-	s, err := parser.Parse(cronExpr)
+	s, err := Parse(cronExpr)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -20,6 +42,25 @@ func Next(cronExpr string, start time.Time) time.Time {
 }
 
 func IsValid(cronExpr string) bool {
-	_, err := parser.Parse(cronExpr)
+	_, err := Parse(cronExpr)
 	return err == nil
 }
+
+// MinInterval returns the shortest interval between two consecutive activations
+// of the schedule, among the first samples activations following from.
+func MinInterval(s Schedule, from time.Time, samples int) time.Duration {
+	var min time.Duration
+	prev := s.Next(from)
+	for i := 0; i < samples && !prev.IsZero(); i++ {
+		next := s.Next(prev)
+		if next.IsZero() {
+			break
+		}
+
+		if d := next.Sub(prev); min == 0 || d < min {
+			min = d
+		}
+		prev = next
+	}
+	return min
+}
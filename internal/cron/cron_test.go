@@ -0,0 +1,393 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func date(year int, month time.Month, day, hour, min, sec int) time.Time {
+	return time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+}
+
+// 2024-01-01 is a Monday, and 2024 is a leap year
+var jan1 = date(2024, time.January, 1, 0, 0, 0)
+
+func TestNext(t *testing.T) {
+	cases := []struct {
+		expr string
+		from time.Time
+		want []time.Time
+	}{
+		// standard fields
+		{"* * * * *", jan1, []time.Time{
+			date(2024, 1, 1, 0, 1, 0), date(2024, 1, 1, 0, 2, 0),
+		}},
+		{"*/15 * * * * *", jan1, []time.Time{
+			date(2024, 1, 1, 0, 0, 15), date(2024, 1, 1, 0, 0, 30), date(2024, 1, 1, 0, 0, 45), date(2024, 1, 1, 0, 1, 0),
+		}},
+		{"5/20 * * * * *", jan1, []time.Time{
+			date(2024, 1, 1, 0, 0, 5), date(2024, 1, 1, 0, 0, 25), date(2024, 1, 1, 0, 0, 45), date(2024, 1, 1, 0, 1, 5),
+		}},
+		{"0 30 9-17/4 * * *", jan1, []time.Time{
+			date(2024, 1, 1, 9, 30, 0), date(2024, 1, 1, 13, 30, 0), date(2024, 1, 1, 17, 30, 0), date(2024, 1, 2, 9, 30, 0),
+		}},
+		{"0 0 1,15 * *", jan1, []time.Time{
+			date(2024, 1, 15, 0, 0, 0), date(2024, 2, 1, 0, 0, 0), date(2024, 2, 15, 0, 0, 0),
+		}},
+		{"0 0 * JAN-MAR MON-FRI", date(2024, 1, 5, 12, 0, 0), []time.Time{
+			date(2024, 1, 8, 0, 0, 0), date(2024, 1, 9, 0, 0, 0),
+		}},
+		{"0 12 * feb sun", jan1, []time.Time{
+			date(2024, 2, 4, 12, 0, 0), date(2024, 2, 11, 12, 0, 0),
+		}},
+		{"0 0 * * 7", jan1, []time.Time{
+			date(2024, 1, 7, 0, 0, 0), date(2024, 1, 14, 0, 0, 0),
+		}},
+		{"0 0 * * 5-7", jan1, []time.Time{
+			date(2024, 1, 5, 0, 0, 0), date(2024, 1, 6, 0, 0, 0), date(2024, 1, 7, 0, 0, 0), date(2024, 1, 12, 0, 0, 0),
+		}},
+		{"0 0 0 29 2 *", jan1, []time.Time{
+			date(2024, 2, 29, 0, 0, 0), date(2028, 2, 29, 0, 0, 0),
+		}},
+
+		// restricting both days matches either of them, unless one of the fields starts with * or ?
+		{"0 0 13 * 5", jan1, []time.Time{
+			date(2024, 1, 5, 0, 0, 0), date(2024, 1, 12, 0, 0, 0), date(2024, 1, 13, 0, 0, 0), date(2024, 1, 19, 0, 0, 0),
+		}},
+		{"0 0 ? * 5", jan1, []time.Time{
+			date(2024, 1, 5, 0, 0, 0), date(2024, 1, 12, 0, 0, 0), date(2024, 1, 19, 0, 0, 0),
+		}},
+		{"0 0 13 * ?", jan1, []time.Time{
+			date(2024, 1, 13, 0, 0, 0), date(2024, 2, 13, 0, 0, 0),
+		}},
+		{"0 0 */10 * 1", jan1, []time.Time{
+			date(2024, 3, 11, 0, 0, 0), date(2024, 4, 1, 0, 0, 0), date(2024, 7, 1, 0, 0, 0),
+		}},
+
+		// macros
+		{"@hourly", jan1, []time.Time{
+			date(2024, 1, 1, 1, 0, 0), date(2024, 1, 1, 2, 0, 0),
+		}},
+		{"@daily", jan1, []time.Time{
+			date(2024, 1, 2, 0, 0, 0), date(2024, 1, 3, 0, 0, 0),
+		}},
+		{"@midnight", jan1, []time.Time{
+			date(2024, 1, 2, 0, 0, 0),
+		}},
+		{"@weekly", jan1, []time.Time{
+			date(2024, 1, 7, 0, 0, 0), date(2024, 1, 14, 0, 0, 0),
+		}},
+		{"@monthly", jan1, []time.Time{
+			date(2024, 2, 1, 0, 0, 0), date(2024, 3, 1, 0, 0, 0),
+		}},
+		{"@yearly", jan1, []time.Time{
+			date(2025, 1, 1, 0, 0, 0), date(2026, 1, 1, 0, 0, 0),
+		}},
+		{"@annually", jan1, []time.Time{
+			date(2025, 1, 1, 0, 0, 0),
+		}},
+		{"@DAILY", jan1, []time.Time{
+			date(2024, 1, 2, 0, 0, 0),
+		}},
+		{"@every 90m", jan1.Add(250 * time.Millisecond), []time.Time{
+			date(2024, 1, 1, 1, 30, 0), date(2024, 1, 1, 3, 0, 0),
+		}},
+		{"@every 1m30.5s", jan1, []time.Time{
+			date(2024, 1, 1, 0, 1, 30), date(2024, 1, 1, 0, 3, 0),
+		}},
+
+		// last day of the month
+		{"0 0 L * *", jan1, []time.Time{
+			date(2024, 1, 31, 0, 0, 0), date(2024, 2, 29, 0, 0, 0), date(2024, 3, 31, 0, 0, 0), date(2024, 4, 30, 0, 0, 0),
+		}},
+		{"0 0 L-2 * *", jan1, []time.Time{
+			date(2024, 1, 29, 0, 0, 0), date(2024, 2, 27, 0, 0, 0), date(2024, 3, 29, 0, 0, 0),
+		}},
+		{"0 0 1,L * *", date(2024, 1, 15, 0, 0, 0), []time.Time{
+			date(2024, 1, 31, 0, 0, 0), date(2024, 2, 1, 0, 0, 0), date(2024, 2, 29, 0, 0, 0),
+		}},
+		{"0 0 L 2 ?", date(2025, 1, 1, 0, 0, 0), []time.Time{
+			date(2025, 2, 28, 0, 0, 0), date(2026, 2, 28, 0, 0, 0), date(2027, 2, 28, 0, 0, 0), date(2028, 2, 29, 0, 0, 0),
+		}},
+
+		// weekdays
+		{"0 0 LW * *", jan1, []time.Time{
+			date(2024, 1, 31, 0, 0, 0), date(2024, 2, 29, 0, 0, 0), date(2024, 3, 29, 0, 0, 0), date(2024, 4, 30, 0, 0, 0),
+		}},
+		{"0 0 LW * *", date(2024, 6, 1, 0, 0, 0), []time.Time{
+			date(2024, 6, 28, 0, 0, 0), date(2024, 7, 31, 0, 0, 0), date(2024, 8, 30, 0, 0, 0),
+		}},
+		{"0 0 15W * *", date(2024, 6, 1, 0, 0, 0), []time.Time{
+			date(2024, 6, 14, 0, 0, 0), date(2024, 7, 15, 0, 0, 0), date(2024, 8, 15, 0, 0, 0), date(2024, 9, 16, 0, 0, 0),
+		}},
+		{"0 0 1W * *", date(2024, 6, 1, 0, 0, 0), []time.Time{
+			date(2024, 6, 3, 0, 0, 0), date(2024, 7, 1, 0, 0, 0), date(2024, 8, 1, 0, 0, 0), date(2024, 9, 2, 0, 0, 0),
+		}},
+		{"0 0 31W * *", date(2024, 3, 1, 0, 0, 0), []time.Time{
+			date(2024, 3, 29, 0, 0, 0), date(2024, 5, 31, 0, 0, 0), date(2024, 7, 31, 0, 0, 0), date(2024, 8, 30, 0, 0, 0),
+		}},
+
+		// last and n-th days of week
+		{"0 0 * * 5L", jan1, []time.Time{
+			date(2024, 1, 26, 0, 0, 0), date(2024, 2, 23, 0, 0, 0), date(2024, 3, 29, 0, 0, 0),
+		}},
+		{"0 0 ? * FRIL", jan1, []time.Time{
+			date(2024, 1, 26, 0, 0, 0), date(2024, 2, 23, 0, 0, 0),
+		}},
+		{"0 0 * * 7L", jan1, []time.Time{
+			date(2024, 1, 28, 0, 0, 0), date(2024, 2, 25, 0, 0, 0),
+		}},
+		{"0 0 * * MON#2", jan1, []time.Time{
+			date(2024, 1, 8, 0, 0, 0), date(2024, 2, 12, 0, 0, 0), date(2024, 3, 11, 0, 0, 0),
+		}},
+		{"0 0 * * 4#5", jan1, []time.Time{
+			date(2024, 2, 29, 0, 0, 0), date(2024, 5, 30, 0, 0, 0), date(2024, 8, 29, 0, 0, 0),
+		}},
+		{"0 0 * * 1#1,5L", jan1, []time.Time{
+			date(2024, 1, 26, 0, 0, 0), date(2024, 2, 5, 0, 0, 0), date(2024, 2, 23, 0, 0, 0), date(2024, 3, 4, 0, 0, 0),
+		}},
+		{"0 0 1 * MON#1", jan1, []time.Time{
+			date(2024, 2, 1, 0, 0, 0), date(2024, 2, 5, 0, 0, 0), date(2024, 3, 1, 0, 0, 0), date(2024, 3, 4, 0, 0, 0),
+		}},
+
+		// years
+		{"0 0 0 1 1 ? 2030", jan1, []time.Time{
+			date(2030, 1, 1, 0, 0, 0), {},
+		}},
+		{"0 0 12 29 2 ? 2025-2030", jan1, []time.Time{
+			date(2028, 2, 29, 12, 0, 0), {},
+		}},
+		{"0 0 0 * * * 2024/2", date(2024, 12, 30, 0, 0, 0), []time.Time{
+			date(2024, 12, 31, 0, 0, 0), date(2026, 1, 1, 0, 0, 0),
+		}},
+		{"0 0 0 1 1 * *", jan1, []time.Time{
+			date(2025, 1, 1, 0, 0, 0),
+		}},
+		{"0 0 0 1 1 * 2020", jan1, []time.Time{
+			{},
+		}},
+
+		// expressions which never activate
+		{"0 0 30 2 *", jan1, []time.Time{
+			{},
+		}},
+		{"0 0 ? 2 MON#5", jan1, []time.Time{
+			date(2044, 2, 29, 0, 0, 0),
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			s, err := Parse(c.expr)
+			require.NoError(t, err)
+
+			from := c.from
+			for i, want := range c.want {
+				next := s.Next(from)
+				require.True(t, want.Equal(next), "activation %d: expected %s, found %s", i, want, next)
+				from = next
+			}
+		})
+	}
+}
+
+func TestNextTimeZones(t *testing.T) {
+	rome, err := time.LoadLocation("Europe/Rome")
+	require.NoError(t, err)
+
+	cases := []struct {
+		expr string
+		from time.Time
+		want []time.Time
+	}{
+		{"CRON_TZ=Europe/Rome 0 9 * * *", jan1, []time.Time{
+			date(2024, 1, 1, 8, 0, 0), date(2024, 1, 2, 8, 0, 0),
+		}},
+		{"TZ=Europe/Rome 0 9 * * *", date(2024, 7, 1, 0, 0, 0), []time.Time{
+			date(2024, 7, 1, 7, 0, 0),
+		}},
+
+		// without a time zone, expressions are evaluated in the one of the time
+		{"0 9 * * *", time.Date(2024, 1, 1, 0, 0, 0, 0, rome), []time.Time{
+			date(2024, 1, 1, 8, 0, 0),
+		}},
+
+		// times skipped when clocks move forward are skipped
+		{"0 30 2 * * *", time.Date(2024, 3, 30, 12, 0, 0, 0, rome), []time.Time{
+			time.Date(2024, 4, 1, 2, 30, 0, 0, rome),
+		}},
+		{"0 */30 * * * *", time.Date(2024, 3, 31, 1, 30, 0, 0, rome), []time.Time{
+			time.Date(2024, 3, 31, 3, 0, 0, 0, rome), time.Date(2024, 3, 31, 3, 30, 0, 0, rome),
+		}},
+
+		// elapsed time is preserved when clocks move back
+		{"0 0 * * * *", time.Date(2024, 10, 27, 1, 0, 0, 0, rome), []time.Time{
+			date(2024, 10, 27, 0, 0, 0), date(2024, 10, 27, 1, 0, 0), date(2024, 10, 27, 2, 0, 0),
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			s, err := Parse(c.expr)
+			require.NoError(t, err)
+
+			from := c.from
+			for i, want := range c.want {
+				next := s.Next(from)
+				require.True(t, want.Equal(next), "activation %d: expected %s, found %s", i, want, next)
+				require.Equal(t, from.Location(), next.Location())
+				from = next
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	cases := []string{
+		"",
+		"* * * *",
+		"* * * * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 0 *",
+		"* * * 13 *",
+		"* * * * 8",
+		"* * * * * * 1969",
+		"* * * * * * 2100",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"5-1 * * * *",
+		"*-5 * * * *",
+		"1-2-3 * * * *",
+		"? * * * *",
+		"* * * FOO *",
+		"* * * * FOO",
+		"* * L-31 * *",
+		"* * L-x * *",
+		"* * 32W * *",
+		"* * W * *",
+		"* * * * L",
+		"* * * * 8L",
+		"* * * * 5#0",
+		"* * * * 5#6",
+		"* * * * FOO#1",
+		"* * * * * * ?",
+		"@fortnightly",
+		"@every",
+		"@every 500ms",
+		"@every 1 hour",
+		"CRON_TZ=Mars/Olympus 0 0 * * *",
+		"HH * * * *",
+		"H(5) * * * *",
+		"H(30-10) * * * *",
+		"H(0-60) * * * *",
+		"H(0-10 * * * *",
+		"H/0 * * * *",
+		"H-5 * * * *",
+		"* * * * * * H",
+	}
+
+	for _, expr := range cases {
+		t.Run(expr, func(t *testing.T) {
+			_, err := Parse(expr)
+			require.Error(t, err)
+			require.False(t, IsValid(expr))
+		})
+	}
+}
+
+func TestMinInterval(t *testing.T) {
+	cases := []struct {
+		expr string
+		want time.Duration
+	}{
+		{"*/10 * * * * *", 10 * time.Second},
+		{"0 9,17 * * *", 8 * time.Hour},
+		{"@every 2m", 2 * time.Minute},
+		{"0 0 L * *", 28 * 24 * time.Hour},
+		{"0 0 0 1 1 ? 2030", 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			s, err := Parse(c.expr)
+			require.NoError(t, err)
+			require.Equal(t, c.want, MinInterval(s, jan1, 24))
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	cases := []struct {
+		expr string
+		key  string
+		want string
+	}{
+		{"0 0 * * *", "a", "0 0 * * *"},
+		{"@hourly", "a", "@hourly"},
+		{"0 9 * * THU", "a", "0 9 * * THU"},
+		{"H * * * *", "a", "21 * * * *"},
+		{"H * * * *", "b", "32 * * * *"},
+		{"H/15 * * * *", "a", "6-59/15 * * * *"},
+		{"H(0-29)/10 H(9-17) * * *", "a", "1-29/10 13 * * *"},
+		{"CRON_TZ=Europe/Rome 0 H H * H(MON-FRI)", "a", "CRON_TZ=Europe/Rome 0 19 2 * 2"},
+		{"H H * * * *", "a", "49 21 * * * *"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			resolved, err := Resolve(c.expr, c.key)
+			require.NoError(t, err)
+			require.Equal(t, c.want, resolved)
+
+			// resolved expressions are stable
+			again, err := Resolve(c.expr, c.key)
+			require.NoError(t, err)
+			require.Equal(t, resolved, again)
+		})
+	}
+}
+
+func TestHashSpread(t *testing.T) {
+	minutes := make(map[int]int)
+	for i := 0; i < 6000; i++ {
+		s, err := ParseHashed("H * * * *", fmt.Sprintf("schedule-%d", i))
+		require.NoError(t, err)
+		minutes[s.Next(jan1).Minute()]++
+	}
+
+	require.Len(t, minutes, 60)
+	for minute, n := range minutes {
+		require.InDelta(t, 100, n, 50, "minute %d", minute)
+	}
+
+	// days of month picked by H exist in every month
+	for i := 0; i < 1000; i++ {
+		resolved, err := Resolve("0 0 H * *", fmt.Sprintf("schedule-%d", i))
+		require.NoError(t, err)
+
+		day, err := strconv.Atoi(strings.Fields(resolved)[2])
+		require.NoError(t, err)
+		require.True(t, day >= 1 && day <= 28, resolved)
+	}
+}
+
+func TestEvery(t *testing.T) {
+	anchor := date(2024, 1, 1, 10, 0, 0)
+	s := Every(90*time.Minute, anchor)
+
+	require.Equal(t, anchor, s.Next(jan1))
+	require.Equal(t, date(2024, 1, 1, 11, 30, 0), s.Next(anchor))
+	require.Equal(t, date(2024, 1, 1, 11, 30, 0), s.Next(date(2024, 1, 1, 11, 29, 59)))
+	require.Equal(t, date(2024, 1, 3, 10, 0, 0), s.Next(date(2024, 1, 3, 8, 45, 0)))
+
+	// without an anchor, activations start from the evaluated time
+	require.Equal(t, date(2024, 1, 1, 1, 30, 0), Every(90*time.Minute, time.Time{}).Next(jan1))
+}
@@ -0,0 +1,133 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// Resolve replaces the H tokens of an expression with the values they stand for, given the key they are hashed with.
+// H picks a value of the range of its field, such as H(0-29), or of the whole field if the range is omitted, and
+// may be followed by a step, such as H/15, which picks the first value of the range. Different keys spread over
+// the range, while the same key always picks the same value. H of the day of month field stands for a day
+// from 1 to 28, so that it matches every month. Expressions without H tokens are returned unchanged.
+func Resolve(expr, key string) (string, error) {
+	expr = strings.TrimSpace(expr)
+
+	prefix := ""
+	if strings.HasPrefix(expr, "TZ=") || strings.HasPrefix(expr, "CRON_TZ=") {
+		tz, rest, _ := strings.Cut(expr, " ")
+		prefix, expr = tz+" ", strings.TrimSpace(rest)
+	}
+
+	if strings.HasPrefix(expr, "@") || !strings.ContainsAny(expr, "Hh") {
+		return prefix + expr, nil
+	}
+
+	fields := strings.Fields(expr)
+
+	all := []bounds{secondBounds, minuteBounds, hourBounds, domBounds, monthBounds, dowBounds, yearBounds}
+	switch len(fields) {
+	case 5:
+		all = all[1:6]
+	case 6:
+		all = all[:6]
+	case 7:
+	default:
+		// Parse reports the wrong number of fields
+		return prefix + expr, nil
+	}
+
+	for i, field := range fields {
+		resolved, err := resolveField(field, all[i], key)
+		if err != nil {
+			return "", err
+		}
+		fields[i] = resolved
+	}
+	return prefix + strings.Join(fields, " "), nil
+}
+
+func resolveField(field string, b bounds, key string) (string, error) {
+	items := strings.Split(field, ",")
+	for i, item := range items {
+		if !strings.HasPrefix(strings.ToUpper(item), "H") {
+			continue
+		}
+
+		if b.name == yearBounds.name {
+			return "", fmt.Errorf("invalid %s field %q: H is not supported", b.name, field)
+		}
+
+		resolved, err := resolveHash(item[1:], b, hash(key, b.name, i))
+		if err != nil {
+			return "", fmt.Errorf("invalid %s field %q: %w", b.name, field, err)
+		}
+		items[i] = resolved
+	}
+	return strings.Join(items, ","), nil
+}
+
+// resolveHash resolves an H token, given what follows the H and the hash picking the value.
+func resolveHash(rest string, b bounds, h uint64) (string, error) {
+	low, high := b.min, b.max
+	if b.hashMax != 0 {
+		high = b.hashMax
+	}
+
+	if strings.HasPrefix(rest, "(") {
+		r, after, found := strings.Cut(rest[1:], ")")
+		if !found {
+			return "", errors.New("unterminated range of H")
+		}
+
+		lowPart, highPart, found := strings.Cut(r, "-")
+		if !found {
+			return "", fmt.Errorf("invalid range of H %q: low-high is expected", r)
+		}
+
+		var err error
+		if low, err = b.value(lowPart); err != nil {
+			return "", err
+		}
+
+		if high, err = b.value(highPart); err != nil {
+			return "", err
+		}
+
+		if low > high {
+			return "", fmt.Errorf("the range %d-%d is empty", low, high)
+		}
+		rest = after
+	}
+
+	if rest == "" {
+		return strconv.Itoa(low + int(h%uint64(high-low+1))), nil
+	}
+
+	stepPart, found := strings.CutPrefix(rest, "/")
+	if !found {
+		return "", fmt.Errorf("unexpected %q after H", rest)
+	}
+
+	step, err := strconv.Atoi(stepPart)
+	if err != nil || step <= 0 {
+		return "", fmt.Errorf("invalid step %q", stepPart)
+	}
+
+	// the first value must be within the range, even if the step is longer
+	offset := step
+	if offset > high-low+1 {
+		offset = high - low + 1
+	}
+	return fmt.Sprintf("%d-%d/%d", low+int(h%uint64(offset)), high, step), nil
+}
+
+// hash hashes the key along with the position of an H token, so that tokens of different fields pick unrelated values.
+func hash(key, field string, item int) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s\x00%s\x00%d", key, field, item)
+	return h.Sum64()
+}
@@ -0,0 +1,327 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	minYear = 1970
+	maxYear = 2099
+)
+
+// bounds describes the values accepted by a field.
+type bounds struct {
+	name     string
+	min, max int
+	names    map[string]int
+	// question reports whether ? is accepted in place of *.
+	question bool
+	// hashMax is the highest value H stands for without a range, if lower than max.
+	hashMax int
+}
+
+var (
+	secondBounds = bounds{name: "seconds", min: 0, max: 59}
+	minuteBounds = bounds{name: "minutes", min: 0, max: 59}
+	hourBounds   = bounds{name: "hours", min: 0, max: 23}
+	domBounds    = bounds{name: "day of month", min: 1, max: 31, question: true, hashMax: 28}
+	monthBounds  = bounds{name: "month", min: 1, max: 12, names: map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}}
+	dowBounds = bounds{name: "day of week", min: 0, max: 7, question: true, hashMax: 6, names: map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}}
+	yearBounds = bounds{name: "year", min: minYear, max: maxYear}
+)
+
+var macros = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+const everyPrefix = "@every "
+
+// Parse parses an expression, as described by the package documentation.
+// H tokens are hashed with an empty key: see ParseHashed.
+func Parse(expr string) (Schedule, error) {
+	return ParseHashed(expr, "")
+}
+
+// ParseHashed parses an expression, whose H tokens are resolved with the given key, as described by Resolve.
+func ParseHashed(expr, key string) (Schedule, error) {
+	expr, err := Resolve(expr, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var loc *time.Location
+	if strings.HasPrefix(expr, "TZ=") || strings.HasPrefix(expr, "CRON_TZ=") {
+		prefix, rest, _ := strings.Cut(expr, " ")
+		_, name, _ := strings.Cut(prefix, "=")
+
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone %q: %w", name, err)
+		}
+		loc, expr = l, strings.TrimSpace(rest)
+	}
+
+	if strings.HasPrefix(expr, everyPrefix) {
+		return parseEvery(strings.TrimSpace(strings.TrimPrefix(expr, everyPrefix)))
+	}
+
+	if strings.HasPrefix(expr, "@") {
+		macro, has := macros[strings.ToLower(expr)]
+		if !has {
+			return nil, fmt.Errorf("unknown macro %s", expr)
+		}
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6, 7:
+	default:
+		return nil, fmt.Errorf("expected 5 to 7 fields, found %d in %q", len(fields), expr)
+	}
+
+	s := &spec{loc: loc}
+
+	for _, f := range []struct {
+		field  string
+		bounds bounds
+		bits   *uint64
+	}{
+		{fields[0], secondBounds, &s.second},
+		{fields[1], minuteBounds, &s.minute},
+		{fields[2], hourBounds, &s.hour},
+		{fields[4], monthBounds, &s.month},
+	} {
+		if _, err := parseField(f.field, f.bounds, setBit(f.bits)); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.domStar, err = s.parseDom(fields[3]); err != nil {
+		return nil, err
+	}
+
+	if s.dowStar, err = s.parseDow(fields[5]); err != nil {
+		return nil, err
+	}
+
+	if len(fields) == 7 {
+		if err := s.parseYear(fields[6]); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func parseEvery(value string) (Schedule, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration of @every: %w", err)
+	}
+
+	if d < time.Second {
+		return nil, fmt.Errorf("the duration of @every must be at least 1s, found %s", value)
+	}
+	return every{delay: d.Truncate(time.Second)}, nil
+}
+
+func setBit(bits *uint64) func(int) {
+	return func(v int) { *bits |= 1 << uint(v) }
+}
+
+// parseField parses a list of values, ranges and steps, calling set for each value it matches.
+// It reports whether any of the items is * or ?.
+func parseField(field string, b bounds, set func(int)) (bool, error) {
+	star := false
+	for _, item := range strings.Split(field, ",") {
+		itemStar, err := parseItem(item, b, set)
+		if err != nil {
+			return false, fmt.Errorf("invalid %s field %q: %w", b.name, field, err)
+		}
+		star = star || itemStar
+	}
+	return star, nil
+}
+
+func parseItem(item string, b bounds, set func(int)) (bool, error) {
+	rangePart, stepPart, hasStep := strings.Cut(item, "/")
+	lowPart, highPart, hasRange := strings.Cut(rangePart, "-")
+
+	var (
+		low, high int
+		star      bool
+		err       error
+	)
+
+	switch {
+	case lowPart == "*" || (lowPart == "?" && b.question):
+		if hasRange {
+			return false, fmt.Errorf("%s can't start a range", lowPart)
+		}
+		low, high, star = b.min, b.max, true
+	default:
+		if low, err = b.value(lowPart); err != nil {
+			return false, err
+		}
+		high = low
+
+		if hasRange {
+			if high, err = b.value(highPart); err != nil {
+				return false, err
+			}
+		} else if hasStep {
+			// a single value followed by a step, such as 5/15, runs from the value to the end of the range
+			high = b.max
+		}
+	}
+
+	if low > high {
+		return false, fmt.Errorf("the range %d-%d is empty", low, high)
+	}
+
+	step := 1
+	if hasStep {
+		if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+			return false, fmt.Errorf("invalid step %q", stepPart)
+		}
+	}
+
+	for v := low; v <= high; v += step {
+		set(v)
+	}
+	return star, nil
+}
+
+func (b bounds) value(s string) (int, error) {
+	if v, has := b.names[strings.ToUpper(s)]; has {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+
+	if v < b.min || v > b.max {
+		return 0, fmt.Errorf("%d is out of range [%d, %d]", v, b.min, b.max)
+	}
+	return v, nil
+}
+
+// parseDom parses the day of month field, which supports the L and W specials besides the common syntax.
+func (s *spec) parseDom(field string) (bool, error) {
+	star := false
+	for _, item := range strings.Split(field, ",") {
+		itemStar, err := s.parseDomItem(strings.ToUpper(item))
+		if err != nil {
+			return false, fmt.Errorf("invalid day of month field %q: %w", field, err)
+		}
+		star = star || itemStar
+	}
+	return star, nil
+}
+
+func (s *spec) parseDomItem(item string) (bool, error) {
+	switch {
+	case item == "L":
+		s.lastDays = append(s.lastDays, 0)
+		return false, nil
+	case item == "LW":
+		s.lastWeekday = true
+		return false, nil
+	case strings.HasPrefix(item, "L-"):
+		offset, err := strconv.Atoi(item[2:])
+		if err != nil || offset < 0 || offset > 30 {
+			return false, fmt.Errorf("invalid offset from the last day %q", item[2:])
+		}
+		s.lastDays = append(s.lastDays, offset)
+		return false, nil
+	case strings.HasSuffix(item, "W"):
+		day, err := domBounds.value(strings.TrimSuffix(item, "W"))
+		if err != nil {
+			return false, err
+		}
+		s.nearestWeekdays = append(s.nearestWeekdays, day)
+		return false, nil
+	}
+	return parseItem(item, domBounds, setBit(&s.dom))
+}
+
+// parseDow parses the day of week field, which supports the L and # specials besides the common syntax.
+func (s *spec) parseDow(field string) (bool, error) {
+	star := false
+	for _, item := range strings.Split(field, ",") {
+		itemStar, err := s.parseDowItem(strings.ToUpper(item))
+		if err != nil {
+			return false, fmt.Errorf("invalid day of week field %q: %w", field, err)
+		}
+		star = star || itemStar
+	}
+
+	// both 0 and 7 are Sunday
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	return star, nil
+}
+
+func (s *spec) parseDowItem(item string) (bool, error) {
+	if day, nth, found := strings.Cut(item, "#"); found {
+		weekday, err := dowBounds.value(day)
+		if err != nil {
+			return false, err
+		}
+
+		n, err := strconv.Atoi(nth)
+		if err != nil || n < 1 || n > 5 {
+			return false, fmt.Errorf("invalid occurrence %q: 1 to 5 is expected", nth)
+		}
+		s.nthDows[weekday%7] |= 1 << uint(n)
+		return false, nil
+	}
+
+	if item == "L" {
+		return false, errors.New("L must follow a day of week, such as 5L or FRIL for the last Friday of the month")
+	}
+
+	if day, found := strings.CutSuffix(item, "L"); found {
+		weekday, err := dowBounds.value(day)
+		if err != nil {
+			return false, err
+		}
+		s.lastDows |= 1 << uint(weekday%7)
+		return false, nil
+	}
+	return parseItem(item, dowBounds, setBit(&s.dow))
+}
+
+func (s *spec) parseYear(field string) error {
+	// a year field holding only * doesn't restrict the activations to the years supported by the field
+	if field == "*" {
+		return nil
+	}
+
+	years := make([]bool, maxYear-minYear+1)
+	if _, err := parseField(field, yearBounds, func(year int) { years[year-minYear] = true }); err != nil {
+		return err
+	}
+	s.years = years
+	return nil
+}
@@ -0,0 +1,172 @@
+package cron
+
+import "time"
+
+// searchYears bounds the search of the next activation of expressions without a year field,
+// which may never activate, such as "0 0 30 2 *".
+const searchYears = 100
+
+// spec is a parsed expression. Fields are bit sets, holding a bit for each value they match.
+type spec struct {
+	second, minute, hour, dom, month, dow uint64
+
+	// domStar and dowStar report whether the day of month and day of week fields start with * or ?,
+	// in which case a day must match both fields, rather than any of them.
+	domStar, dowStar bool
+
+	// lastDays holds the offsets from the last day of the month of the L and L-n items.
+	lastDays []int
+	// lastWeekday is set by the LW item.
+	lastWeekday bool
+	// nearestWeekdays holds the days of the nW items.
+	nearestWeekdays []int
+	// lastDows holds a bit for each weekday of the dL items.
+	lastDows uint64
+	// nthDows holds, for each weekday, a bit for each occurrence of the d#n items.
+	nthDows [7]uint64
+
+	// years holds the years matched by the year field, starting from minYear, and is nil if the field is missing.
+	years []bool
+
+	// loc is the time zone of the expression, which is the one of the evaluated time if nil.
+	loc *time.Location
+}
+
+func (s *spec) Next(t time.Time) time.Time {
+	origLoc := t.Location()
+
+	loc := s.loc
+	if loc == nil {
+		loc = origLoc
+	}
+	t = t.In(loc)
+
+	// activations are at whole seconds, strictly after t
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+
+	limit := t.Year() + searchYears
+	if s.years != nil {
+		limit = maxYear
+	}
+
+	// hours, minutes and seconds are added as durations rather than through time.Date,
+	// which is ambiguous during daylight saving transitions and could move backwards
+	for t.Year() <= limit {
+		switch {
+		case !s.yearMatches(t.Year()):
+			t = time.Date(t.Year()+1, time.January, 1, 0, 0, 0, 0, loc)
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Add(time.Duration(60-t.Minute())*time.Minute - time.Duration(t.Second())*time.Second)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute - time.Duration(t.Second())*time.Second)
+		case s.second&(1<<uint(t.Second())) == 0:
+			t = t.Add(time.Second)
+		default:
+			return t.In(origLoc)
+		}
+	}
+	return time.Time{}
+}
+
+func (s *spec) yearMatches(year int) bool {
+	if s.years == nil {
+		return true
+	}
+	return year >= minYear && year <= maxYear && s.years[year-minYear]
+}
+
+func (s *spec) dayMatches(t time.Time) bool {
+	domMatch, dowMatch := s.domMatches(t), s.dowMatches(t)
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+func (s *spec) domMatches(t time.Time) bool {
+	day := t.Day()
+	if s.dom&(1<<uint(day)) != 0 {
+		return true
+	}
+
+	last := daysIn(t.Year(), t.Month())
+	for _, offset := range s.lastDays {
+		if day == last-offset {
+			return true
+		}
+	}
+
+	if s.lastWeekday && day == nearestWeekday(t.Year(), t.Month(), last, last) {
+		return true
+	}
+
+	for _, n := range s.nearestWeekdays {
+		if n <= last && day == nearestWeekday(t.Year(), t.Month(), n, last) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *spec) dowMatches(t time.Time) bool {
+	weekday := uint(t.Weekday())
+	if s.dow&(1<<weekday) != 0 {
+		return true
+	}
+
+	if s.lastDows&(1<<weekday) != 0 && t.Day()+7 > daysIn(t.Year(), t.Month()) {
+		return true
+	}
+
+	nth := uint((t.Day()-1)/7 + 1)
+	return s.nthDows[weekday]&(1<<nth) != 0
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// nearestWeekday returns the weekday closest to the given day, without leaving the month.
+func nearestWeekday(year int, month time.Month, day, last int) int {
+	switch time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Weekday() {
+	case time.Saturday:
+		if day == 1 {
+			return day + 2
+		}
+		return day - 1
+	case time.Sunday:
+		if day == last {
+			return day - 2
+		}
+		return day + 1
+	}
+	return day
+}
+
+// every activates at fixed intervals, starting from the anchor or, if it is zero, from the time it is evaluated from.
+type every struct {
+	delay  time.Duration
+	anchor time.Time
+}
+
+// Every returns a schedule activating every d, at the anchor and at whole multiples of d away from it.
+// The zero anchor activates d after the time the schedule is evaluated from, as @every does.
+func Every(d time.Duration, anchor time.Time) Schedule {
+	return every{delay: d, anchor: anchor}
+}
+
+func (e every) Next(t time.Time) time.Time {
+	if e.anchor.IsZero() {
+		return t.Add(e.delay - time.Duration(t.Nanosecond()))
+	}
+
+	if t.Before(e.anchor) {
+		return e.anchor.In(t.Location())
+	}
+	n := t.Sub(e.anchor)/e.delay + 1
+	return e.anchor.Add(n * e.delay).In(t.Location())
+}
@@ -0,0 +1,342 @@
+// Package crontab converts the entries of crontab files to schedules, and schedules back to crontab lines.
+package crontab
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ostafen/kronos/internal/cron"
+	"github.com/ostafen/kronos/model"
+)
+
+// DefaultTitleTemplate names schedules after their command and a hash of the entry, which stays the same
+// as long as the schedule and the command of the entry don't change.
+const DefaultTitleTemplate = "{{.Slug}}-{{.ID}}"
+
+// CommandKey is the metadata key holding the command of an imported entry.
+const CommandKey = "command"
+
+// UserKey is the metadata key holding the user of an entry of a system crontab.
+const UserKey = "user"
+
+var ErrInvalidTemplate = errors.New("invalid template")
+
+// macros maps the @-prefixed shorthands of crontab to the equivalent expressions.
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Entry is a line of a crontab scheduling a command, and is the data of url and title templates.
+type Entry struct {
+	// Line is the number of the line, starting from 1.
+	Line int
+	// Spec is the schedule as written in the crontab, such as "@daily" or "0 2 * * *".
+	Spec string
+	// CronExpr is the expression equivalent to Spec, prefixed by the CRON_TZ of the entry, if any.
+	CronExpr string
+	// User is the user the command runs as, only set for system crontabs.
+	User    string
+	Command string
+	// Comment is the text of the comment lines right above the entry.
+	Comment string
+	// Env holds the variables defined by the environment lines preceding the entry.
+	Env map[string]string
+	// ID is a short hash of the schedule and the command of the entry.
+	ID string
+	// Slug is the command reduced to lowercase letters, digits and dashes.
+	Slug string
+}
+
+type Options struct {
+	URLTemplate string
+	// TitleTemplate is DefaultTitleTemplate if empty.
+	TitleTemplate string
+	// System enables the format of system crontabs, whose entries have a user field before the command.
+	System bool
+	// Metadata is added to the metadata of every schedule.
+	Metadata map[string]string
+}
+
+// Result holds the schedules of the supported entries of a crontab, and the lines which were not imported.
+type Result struct {
+	Schedules   []*model.ScheduleRegisterInput
+	Unsupported []model.CrontabLine
+}
+
+var (
+	envRegexp = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*)$`)
+	slugChars = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+const maxSlugLength = 40
+
+// annotationPrefix starts the comments written by Format which are not part of the description of schedules.
+const annotationPrefix = "##"
+
+// Parse converts the entries of a crontab to recurring webhook schedules, whose url and title are rendered
+// by the templates of opts. Lines which can't be converted are reported, rather than failing the whole crontab.
+func Parse(r io.Reader, opts Options) (*Result, error) {
+	titleTemplate := opts.TitleTemplate
+	if titleTemplate == "" {
+		titleTemplate = DefaultTitleTemplate
+	}
+
+	urlTmpl, err := newTemplate("url", opts.URLTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	titleTmpl, err := newTemplate("title", titleTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		res     Result
+		env     = make(map[string]string)
+		comment []string
+		titles  = make(map[string]int)
+	)
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "":
+			comment = nil
+			continue
+		case strings.HasPrefix(line, annotationPrefix):
+			continue
+		case strings.HasPrefix(line, "#"):
+			comment = append(comment, strings.TrimSpace(strings.TrimPrefix(line, "#")))
+			continue
+		}
+
+		unsupported := func(reason string) {
+			res.Unsupported = append(res.Unsupported, model.CrontabLine{Line: n, Text: line, Reason: reason})
+			comment = nil
+		}
+
+		if m := envRegexp.FindStringSubmatch(line); m != nil {
+			name, value := m[1], unquote(m[2])
+
+			if name == "CRON_TZ" || name == "TZ" {
+				if _, err := time.LoadLocation(value); value != "" && err != nil {
+					unsupported(fmt.Sprintf("invalid time zone %q: the line is ignored", value))
+					continue
+				}
+			}
+			env[name] = value
+			continue
+		}
+
+		entry, reason := parseEntry(line, opts.System)
+		if reason != "" {
+			unsupported(reason)
+			continue
+		}
+
+		// the id is computed before the zone is applied, so that titles don't change along with it
+		if zone := timeZone(env); zone != "" {
+			entry.CronExpr = "CRON_TZ=" + zone + " " + entry.CronExpr
+		}
+
+		entry.Line = n
+		entry.Comment = strings.Join(comment, " ")
+		entry.Env = copyEnv(env)
+		comment = nil
+
+		sched, err := newSchedule(entry, urlTmpl, titleTmpl, opts)
+		if err != nil {
+			unsupported(err.Error())
+			continue
+		}
+
+		if prev, has := titles[sched.Title]; has {
+			unsupported(fmt.Sprintf("title %q is the same as the one of line %d", sched.Title, prev))
+			continue
+		}
+		titles[sched.Title] = n
+
+		res.Schedules = append(res.Schedules, sched)
+	}
+	return &res, scanner.Err()
+}
+
+func newTemplate(name, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, fmt.Errorf("%w: the %s template is required", ErrInvalidTemplate, name)
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTemplate, err)
+	}
+	return tmpl, nil
+}
+
+// parseEntry splits an entry into its fields, and returns the reason why it is not supported, if any.
+func parseEntry(line string, system bool) (*Entry, string) {
+	var (
+		entry  Entry
+		fields []string
+		rest   = line
+	)
+
+	nFields := 5
+	if strings.HasPrefix(line, "@") {
+		nFields = 1
+	}
+
+	if system {
+		nFields++
+	}
+
+	for i := 0; i < nFields; i++ {
+		field, remaining := nextField(rest)
+		if field == "" {
+			return nil, "missing fields"
+		}
+		fields = append(fields, field)
+		rest = remaining
+	}
+
+	entry.Command = strings.TrimSpace(rest)
+	if entry.Command == "" {
+		return nil, "missing command"
+	}
+
+	if system {
+		entry.User = fields[len(fields)-1]
+		fields = fields[:len(fields)-1]
+	}
+
+	entry.Spec = strings.Join(fields, " ")
+	entry.CronExpr = entry.Spec
+
+	if strings.HasPrefix(entry.Spec, "@") {
+		expr, has := macros[entry.Spec]
+		if !has {
+			return nil, fmt.Sprintf("unsupported macro %s", entry.Spec)
+		}
+		entry.CronExpr = expr
+	}
+
+	if !cron.IsValid(entry.CronExpr) {
+		return nil, fmt.Sprintf("invalid schedule %q", entry.Spec)
+	}
+
+	sum := sha1.Sum([]byte(entry.CronExpr + "\x00" + entry.Command))
+	entry.ID = hex.EncodeToString(sum[:4])
+	entry.Slug = slug(entry.Command)
+	return &entry, ""
+}
+
+// nextField returns the first whitespace separated field of s, and what follows it.
+func nextField(s string) (string, string) {
+	s = strings.TrimLeft(s, " \t")
+
+	end := strings.IndexAny(s, " \t")
+	if end < 0 {
+		return s, ""
+	}
+	return s[:end], s[end:]
+}
+
+func slug(command string) string {
+	s := strings.Trim(slugChars.ReplaceAllString(strings.ToLower(command), "-"), "-")
+	if len(s) > maxSlugLength {
+		s = strings.TrimRight(s[:maxSlugLength], "-")
+	}
+
+	if s == "" {
+		return "crontab"
+	}
+	return s
+}
+
+func unquote(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// timeZone returns the zone the entries following the given environment are evaluated in, that is
+// the one of CRON_TZ as in cronie, or else the one of TZ.
+func timeZone(env map[string]string) string {
+	if zone := env["CRON_TZ"]; zone != "" {
+		return zone
+	}
+	return env["TZ"]
+}
+
+func copyEnv(env map[string]string) map[string]string {
+	res := make(map[string]string, len(env))
+	for k, v := range env {
+		res[k] = v
+	}
+	return res
+}
+
+func newSchedule(entry *Entry, urlTmpl, titleTmpl *template.Template, opts Options) (*model.ScheduleRegisterInput, error) {
+	url, err := render(urlTmpl, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	title, err := render(titleTmpl, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := make(map[string]string, len(opts.Metadata)+2)
+	for k, v := range opts.Metadata {
+		metadata[k] = v
+	}
+	metadata[CommandKey] = entry.Command
+
+	if entry.User != "" {
+		metadata[UserKey] = entry.User
+	}
+
+	recurring := true
+	return &model.ScheduleRegisterInput{
+		Title:       title,
+		Description: entry.Comment,
+		CronExpr:    entry.CronExpr,
+		URL:         url,
+		IsRecurring: &recurring,
+		Metadata:    metadata,
+	}, nil
+}
+
+func render(tmpl *template.Template, entry *Entry) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, entry); err != nil {
+		return "", fmt.Errorf("unable to render the %s template: %w", tmpl.Name(), err)
+	}
+
+	res := strings.TrimSpace(buf.String())
+	if res == "" {
+		return "", fmt.Errorf("the %s template rendered an empty string", tmpl.Name())
+	}
+	return res, nil
+}
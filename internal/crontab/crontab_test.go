@@ -0,0 +1,275 @@
+package crontab
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ostafen/kronos/model"
+	"github.com/stretchr/testify/require"
+)
+
+const userCrontab = `SHELL=/bin/bash
+MAILTO="ops@example.com"
+
+# rotate the logs
+# of the web server
+0 2 * * * /usr/sbin/logrotate /etc/logrotate.conf
+*/15 9-17 * * 1-5 /opt/app/bin/sync --full
+
+@daily /opt/app/bin/report
+@reboot /opt/app/bin/warmup
+CRON_TZ=Europe/Rome
+61 * * * * /opt/app/bin/invalid
+0 * * * *
+0 2 * * * /usr/sbin/logrotate /etc/logrotate.conf
+`
+
+func TestParse(t *testing.T) {
+	res, err := Parse(strings.NewReader(userCrontab), Options{
+		URLTemplate: "https://runner.example.com/run/{{.ID}}?mail={{index .Env \"MAILTO\"}}",
+		Metadata:    map[string]string{"source": "crontab"},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, res.Schedules, 3)
+
+	rotate := res.Schedules[0]
+	require.Equal(t, "0 2 * * *", rotate.CronExpr)
+	require.Equal(t, "rotate the logs of the web server", rotate.Description)
+	require.True(t, *rotate.IsRecurring)
+	require.Equal(t, map[string]string{"source": "crontab", CommandKey: "/usr/sbin/logrotate /etc/logrotate.conf"}, rotate.Metadata)
+	require.True(t, strings.HasPrefix(rotate.Title, "usr-sbin-logrotate-etc-logrotate-conf-"))
+	require.True(t, strings.HasSuffix(rotate.URL, "?mail=ops@example.com"))
+
+	sync := res.Schedules[1]
+	require.Equal(t, "*/15 9-17 * * 1-5", sync.CronExpr)
+	require.Empty(t, sync.Description)
+
+	report := res.Schedules[2]
+	require.Equal(t, "0 0 * * *", report.CronExpr)
+
+	reasons := make(map[int]string)
+	for _, line := range res.Unsupported {
+		reasons[line.Line] = line.Reason
+	}
+
+	require.Len(t, reasons, 4)
+	require.Contains(t, reasons[10], "@reboot")
+	require.Contains(t, reasons[12], "invalid schedule")
+	require.Equal(t, "missing command", reasons[13])
+	require.Contains(t, reasons[14], "line 6")
+}
+
+func TestParseTimeZones(t *testing.T) {
+	res, err := Parse(strings.NewReader(`0 1 * * * backup
+CRON_TZ=Europe/Rome
+0 2 * * * backup
+TZ=Asia/Tokyo
+@daily report
+CRON_TZ=
+0 3 * * * backup
+TZ=Mars/Olympus
+CRON_TZ="America/New_York"
+0 4 * * * backup
+`), Options{URLTemplate: "http://localhost/{{.Slug}}"})
+	require.NoError(t, err)
+
+	var exprs []string
+	for _, sched := range res.Schedules {
+		exprs = append(exprs, sched.CronExpr)
+	}
+
+	// CRON_TZ takes precedence over TZ, and an empty value resets it
+	require.Equal(t, []string{
+		"0 1 * * *",
+		"CRON_TZ=Europe/Rome 0 2 * * *",
+		"CRON_TZ=Europe/Rome 0 0 * * *",
+		"CRON_TZ=Asia/Tokyo 0 3 * * *",
+		"CRON_TZ=America/New_York 0 4 * * *",
+	}, exprs)
+
+	require.Len(t, res.Unsupported, 1)
+	require.Equal(t, 8, res.Unsupported[0].Line)
+	require.Contains(t, res.Unsupported[0].Reason, "invalid time zone")
+
+	// titles don't depend on the zone
+	plain, err := Parse(strings.NewReader("0 2 * * * backup\n"), Options{URLTemplate: "http://localhost/{{.Slug}}"})
+	require.NoError(t, err)
+	require.Equal(t, plain.Schedules[0].Title, res.Schedules[1].Title)
+}
+
+func TestParseStableTitles(t *testing.T) {
+	parse := func(crontab string) []string {
+		res, err := Parse(strings.NewReader(crontab), Options{URLTemplate: "http://localhost/{{.Slug}}"})
+		require.NoError(t, err)
+
+		var titles []string
+		for _, sched := range res.Schedules {
+			titles = append(titles, sched.Title)
+		}
+		return titles
+	}
+
+	titles := parse("0 2 * * * backup\n0 3 * * * cleanup\n")
+	require.Len(t, titles, 2)
+
+	// titles don't depend on the position or the comments of entries
+	require.Equal(t, titles, parse("# nightly\n\n0 2 * * * backup\n# cleanup\n0 3 * * * cleanup\n"))
+	require.NotEqual(t, titles[0], parse("0 4 * * * backup\n")[0])
+}
+
+func TestParseSystem(t *testing.T) {
+	res, err := Parse(strings.NewReader("17 * * * * root cd / && run-parts --report /etc/cron.hourly\n@weekly backup /usr/local/bin/backup\n* * * * *\n"), Options{
+		URLTemplate:   "https://runner.example.com/{{.User}}",
+		TitleTemplate: "{{.User}}-{{.Line}}",
+		System:        true,
+	})
+	require.NoError(t, err)
+	require.Len(t, res.Schedules, 2)
+
+	require.Equal(t, "root-1", res.Schedules[0].Title)
+	require.Equal(t, "https://runner.example.com/root", res.Schedules[0].URL)
+	require.Equal(t, "cd / && run-parts --report /etc/cron.hourly", res.Schedules[0].Metadata[CommandKey])
+	require.Equal(t, "root", res.Schedules[0].Metadata[UserKey])
+
+	require.Equal(t, "backup-2", res.Schedules[1].Title)
+	require.Equal(t, "0 0 * * 0", res.Schedules[1].CronExpr)
+
+	require.Equal(t, []model.CrontabLine{{Line: 3, Text: "* * * * *", Reason: "missing fields"}}, res.Unsupported)
+}
+
+func TestParseInvalidTemplates(t *testing.T) {
+	cases := []Options{
+		{},
+		{URLTemplate: "http://localhost/{{.ID"},
+		{URLTemplate: "http://localhost", TitleTemplate: "{{"},
+	}
+
+	for _, opts := range cases {
+		_, err := Parse(strings.NewReader("0 2 * * * backup\n"), opts)
+		require.ErrorIs(t, err, ErrInvalidTemplate)
+	}
+
+	// templates referring to unknown fields fail on the entries only
+	res, err := Parse(strings.NewReader("0 2 * * * backup\n"), Options{URLTemplate: "http://localhost/{{.Host}}"})
+	require.NoError(t, err)
+	require.Empty(t, res.Schedules)
+	require.Len(t, res.Unsupported, 1)
+}
+
+func storedSchedule(t *testing.T, id int64, input *model.ScheduleRegisterInput) *model.CronSchedule {
+	sched, err := input.ToSched(model.DefaultNamespace)
+	require.NoError(t, err)
+
+	sched.ID = id
+	return sched
+}
+
+func TestFormat(t *testing.T) {
+	recurring, oneShot := true, false
+
+	backup := storedSchedule(t, 1, &model.ScheduleRegisterInput{
+		Title:       "backup",
+		Description: "nightly backup",
+		CronExpr:    "0 2 * * *",
+		URL:         "https://runner.example.com/backup",
+		IsRecurring: &recurring,
+		Metadata:    map[string]string{CommandKey: "/usr/local/bin/backup", UserKey: "root"},
+	})
+	backup.Status = model.ScheduleStatusPaused
+
+	schedules := []*model.CronSchedule{
+		storedSchedule(t, 2, &model.ScheduleRegisterInput{
+			Title:       "webhook",
+			CronExpr:    "0 30 2 * * *",
+			URL:         "https://hooks.example.com/run?pct=50%",
+			IsRecurring: &recurring,
+		}),
+		backup,
+		storedSchedule(t, 3, &model.ScheduleRegisterInput{
+			Title:       "often",
+			CronExpr:    "*/10 * * * * *",
+			URL:         "https://hooks.example.com/often",
+			IsRecurring: &recurring,
+		}),
+		storedSchedule(t, 5, &model.ScheduleRegisterInput{
+			Title:       "month-end",
+			CronExpr:    "0 0 18 LW * ?",
+			URL:         "https://hooks.example.com/close",
+			IsRecurring: &recurring,
+		}),
+		storedSchedule(t, 4, &model.ScheduleRegisterInput{
+			Title:       "once",
+			URL:         "https://hooks.example.com/once",
+			IsRecurring: &oneShot,
+			RunAt:       time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC),
+		}),
+		storedSchedule(t, 6, &model.ScheduleRegisterInput{
+			Title:       "board",
+			RRule:       "FREQ=MONTHLY;BYDAY=-1FR",
+			URL:         "https://hooks.example.com/board",
+			IsRecurring: &recurring,
+		}),
+		storedSchedule(t, 7, &model.ScheduleRegisterInput{
+			Title:       "spread",
+			CronExpr:    "H H(0-5) * * *",
+			URL:         "https://hooks.example.com/spread",
+			IsRecurring: &recurring,
+			Jitter:      model.Duration(time.Minute),
+		}),
+	}
+
+	var sb strings.Builder
+	require.NoError(t, Format(&sb, schedules))
+
+	expected := `## backup (id 1)
+# nightly backup
+## runs as root
+## (paused) 0 2 * * * /usr/local/bin/backup
+
+## webhook (id 2)
+30 2 * * * curl -fsS -X POST 'https://hooks.example.com/run?pct=50\%'
+
+## often (id 3)
+## "*/10 * * * * *" runs at seconds other than zero, which crontab can't express
+
+## once (id 4)
+## one-shot schedule running at 2030-06-01T12:00:00Z, which crontab can't express
+
+## month-end (id 5)
+## "0 0 18 LW * ?" uses the L, W or # specials, which crontab can't express
+
+## board (id 6)
+## follows the recurrence rule "FREQ=MONTHLY;BYDAY=-1FR", which crontab can't express
+
+## spread (id 7)
+## delayed at random by up to 1m0s, which crontab ignores
+25 3 * * * curl -fsS -X POST 'https://hooks.example.com/spread'
+`
+	require.Equal(t, expected, sb.String())
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	res, err := Parse(strings.NewReader(userCrontab), Options{URLTemplate: "https://runner.example.com/{{.ID}}"})
+	require.NoError(t, err)
+
+	var schedules []*model.CronSchedule
+	for i, input := range res.Schedules {
+		schedules = append(schedules, storedSchedule(t, int64(i+1), input))
+	}
+
+	var sb strings.Builder
+	require.NoError(t, Format(&sb, schedules))
+
+	again, err := Parse(strings.NewReader(sb.String()), Options{URLTemplate: "https://runner.example.com/{{.ID}}"})
+	require.NoError(t, err)
+	require.Empty(t, again.Unsupported)
+	require.Len(t, again.Schedules, len(res.Schedules))
+
+	for i, sched := range again.Schedules {
+		require.Equal(t, res.Schedules[i].Title, sched.Title)
+		require.Equal(t, res.Schedules[i].Description, sched.Description)
+		require.Equal(t, res.Schedules[i].Metadata, sched.Metadata)
+	}
+}
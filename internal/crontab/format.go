@@ -0,0 +1,194 @@
+package crontab
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ostafen/kronos/model"
+)
+
+// Format renders schedules as crontab lines, for review. Each schedule runs its imported command,
+// or delivers its webhook through curl, and is preceded by its description and by annotations,
+// such as its title. Annotations start with "##", so that Parse doesn't take them for descriptions.
+// Schedules which crontab can't run, such as one-shot, paused or expired ones, are rendered as annotations.
+func Format(w io.Writer, schedules []*model.CronSchedule) error {
+	schedules = append([]*model.CronSchedule(nil), schedules...)
+	sort.Slice(schedules, func(i, j int) bool {
+		if schedules[i].Namespace != schedules[j].Namespace {
+			return schedules[i].Namespace < schedules[j].Namespace
+		}
+		return schedules[i].ID < schedules[j].ID
+	})
+
+	bw := bufio.NewWriter(w)
+
+	namespace := ""
+	for i, sched := range schedules {
+		if i > 0 {
+			fmt.Fprintln(bw)
+		}
+
+		if sched.Namespace != namespace && schedules[0].Namespace != schedules[len(schedules)-1].Namespace {
+			fmt.Fprintf(bw, "## namespace: %s\n\n", sched.Namespace)
+		}
+		namespace = sched.Namespace
+
+		for _, line := range formatSchedule(sched) {
+			fmt.Fprintln(bw, line)
+		}
+	}
+	return bw.Flush()
+}
+
+func formatSchedule(sched *model.CronSchedule) []string {
+	lines := []string{fmt.Sprintf("## %s (id %d)", sched.Title, sched.ID)}
+	if sched.Description != "" {
+		for _, line := range strings.Split(sched.Description, "\n") {
+			lines = append(lines, "# "+line)
+		}
+	}
+
+	if user := sched.Metadata[UserKey]; user != "" {
+		lines = append(lines, "## runs as "+user)
+	}
+
+	if !sched.IsRecurring {
+		return append(lines, fmt.Sprintf("## one-shot schedule running at %s, which crontab can't express", sched.RunAt.Format(time.RFC3339)))
+	}
+
+	input := sched.ToInput()
+	if !input.StartAt.IsZero() && input.StartAt.After(sched.CreatedAt) {
+		lines = append(lines, "## active from "+input.StartAt.Format(time.RFC3339))
+	}
+
+	if !input.EndAt.IsZero() {
+		lines = append(lines, "## active until "+input.EndAt.Format(time.RFC3339))
+	}
+
+	if len(sched.Calendars) > 0 {
+		lines = append(lines, fmt.Sprintf("## doesn't run in the dates and blackout windows of calendars %s, which crontab ignores",
+			strings.Join(sched.Calendars, ", ")))
+	}
+
+	if sched.Jitter > 0 {
+		lines = append(lines, fmt.Sprintf("## delayed at random by up to %s, which crontab ignores", sched.Jitter))
+	}
+
+	if sched.MaxRuns > 0 {
+		lines = append(lines, fmt.Sprintf("## stops after %d %s runs, which crontab ignores", sched.MaxRuns, sched.MaxRunsMode))
+	}
+
+	command, reason := scheduleCommand(sched)
+	if reason != "" {
+		return append(lines, "## "+reason)
+	}
+
+	if sched.RRule != "" {
+		return append(lines, fmt.Sprintf("## follows the recurrence rule %q, which crontab can't express", sched.RRule))
+	}
+
+	if sched.IsFixedDelay() {
+		return append(lines, fmt.Sprintf("## runs %s after the end of each run, which crontab can't express", sched.Interval))
+	}
+
+	if sched.Interval > 0 {
+		return append(lines, fmt.Sprintf("## runs every %s from %s, which crontab can't express", sched.Interval, sched.Anchor.Format(time.RFC3339)))
+	}
+
+	// H tokens are replaced by the values they stand for, which crontab doesn't pick by itself
+	resolved, err := sched.ResolvedCronExpr()
+	if err != nil {
+		return append(lines, fmt.Sprintf("## %q is not a valid cron expression: %s", sched.CronExpr, err))
+	}
+
+	expr, reason := crontabExpr(resolved)
+	if reason != "" {
+		return append(lines, fmt.Sprintf("## %q %s, which crontab can't express", sched.CronExpr, reason))
+	}
+
+	line := expr + " " + command
+	switch {
+	case sched.Expired():
+		line = "## (expired) " + line
+	case !sched.IsActive():
+		line = fmt.Sprintf("## (%s) %s", sched.Status, line)
+	}
+	return append(lines, line)
+}
+
+// scheduleCommand returns the command run by the crontab line of a schedule,
+// or the reason why the schedule can't be run by crontab.
+func scheduleCommand(sched *model.CronSchedule) (string, string) {
+	if command := sched.Metadata[CommandKey]; command != "" {
+		return command, ""
+	}
+
+	if sched.Action != "" {
+		return "", fmt.Sprintf("runs the %q action of the program embedding Kronos, which crontab can't run", sched.Action)
+	}
+
+	// % is a special character of crontab commands, which ends the command line
+	return "curl -fsS -X POST " + strings.ReplaceAll(shellQuote(sched.URL), "%", `\%`), ""
+}
+
+// crontabExpr converts an expression to the five fields of crontab, or returns why crontab can't express it.
+func crontabExpr(expr string) (string, string) {
+	switch {
+	case strings.HasPrefix(expr, "@every"):
+		return "", "runs at fixed intervals"
+	case strings.HasPrefix(expr, "@"):
+		return expr, ""
+	case strings.HasPrefix(expr, "TZ=") || strings.HasPrefix(expr, "CRON_TZ="):
+		return "", "runs in its own time zone"
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) == 7 {
+		if fields[6] != "*" {
+			return "", "runs in specific years"
+		}
+		fields = fields[:6]
+	}
+
+	if len(fields) == 6 {
+		if fields[0] != "0" {
+			return "", "runs at seconds other than zero"
+		}
+		fields = fields[1:]
+	}
+
+	if hasSpecials(fields[2], fields[4]) {
+		return "", "uses the L, W or # specials"
+	}
+
+	for i, field := range fields {
+		if field == "?" {
+			fields[i] = "*"
+		}
+	}
+	return strings.Join(fields, " "), ""
+}
+
+// hasSpecials reports whether the day of month or the day of week fields use the specials of Quartz.
+func hasSpecials(dom, dow string) bool {
+	for _, item := range strings.Split(strings.ToUpper(dom), ",") {
+		if strings.HasPrefix(item, "L") || strings.HasSuffix(item, "W") {
+			return true
+		}
+	}
+
+	for _, item := range strings.Split(strings.ToUpper(dow), ",") {
+		if strings.Contains(item, "#") || strings.HasSuffix(item, "L") {
+			return true
+		}
+	}
+	return false
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
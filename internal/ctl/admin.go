@@ -0,0 +1,506 @@
+package ctl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ostafen/kronos/model"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func namespaceTable(namespaces []*model.Namespace) *table {
+	t := newTable("NAME", "DESCRIPTION", "MAX SCHEDULES", "MIN INTERVAL", "MAX DELIVERIES/MIN", "CREATED")
+	for _, ns := range namespaces {
+		t.add(
+			ns.Name,
+			orDash(ns.Description),
+			strconv.Itoa(ns.Quota.MaxSchedules),
+			ns.Quota.MinInterval.String(),
+			strconv.Itoa(ns.Quota.MaxDeliveriesPerMinute),
+			formatTime(ns.CreatedAt),
+		)
+	}
+	return t
+}
+
+func oneNamespace(ns *model.Namespace) *table {
+	return namespaceTable([]*model.Namespace{ns})
+}
+
+type namespaceFlags struct {
+	description string
+	quota       model.Quota
+}
+
+func (f *namespaceFlags) register(flags *pflag.FlagSet) {
+	flags.StringVar(&f.description, "description", "", "description of the namespace")
+	flags.IntVar(&f.quota.MaxSchedules, "max-schedules", 0, "maximum number of schedules (0 falls back to the default)")
+	flags.DurationVar(&f.quota.MinInterval, "min-interval", 0, "minimum interval between the activations of recurring schedules")
+	flags.IntVar(&f.quota.MaxDeliveriesPerMinute, "max-deliveries-per-minute", 0, "maximum number of deliveries per minute")
+}
+
+func (f *namespaceFlags) apply(flags *pflag.FlagSet, input *model.NamespaceInput) {
+	setIfChanged(flags.Changed("description"), &input.Description, f.description)
+	setIfChanged(flags.Changed("max-schedules"), &input.Quota.MaxSchedules, f.quota.MaxSchedules)
+	setIfChanged(flags.Changed("min-interval"), &input.Quota.MinInterval, f.quota.MinInterval)
+	setIfChanged(flags.Changed("max-deliveries-per-minute"), &input.Quota.MaxDeliveriesPerMinute, f.quota.MaxDeliveriesPerMinute)
+}
+
+func newNamespacesCommand(c *cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "namespaces",
+		Aliases: []string{"namespace", "ns"},
+		Short:   "Manage namespaces and their quotas",
+	}
+
+	completeNamespace := func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return c.completeNamespaces(cmd, args, toComplete)
+	}
+
+	list := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the namespaces",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			namespaces, err := kc.ListNamespaces(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printResult(c, namespaces, namespaceTable)
+		},
+	}
+
+	get := &cobra.Command{
+		Use:               "get NAME",
+		Short:             "Get a namespace and its quota",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeNamespace,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			ns, err := kc.GetNamespace(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return printResult(c, ns, oneNamespace)
+		},
+	}
+
+	var createFlags namespaceFlags
+	create := &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create a namespace",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			input := &model.NamespaceInput{Name: args[0]}
+			createFlags.apply(cmd.Flags(), input)
+
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			ns, err := kc.CreateNamespace(cmd.Context(), input)
+			if err != nil {
+				return err
+			}
+			return printResult(c, ns, oneNamespace)
+		},
+	}
+	createFlags.register(create.Flags())
+
+	var updateFlags namespaceFlags
+	update := &cobra.Command{
+		Use:               "update NAME",
+		Short:             "Update the given fields of the description and quota of a namespace",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeNamespace,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			ns, err := kc.GetNamespace(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			input := &model.NamespaceInput{Name: ns.Name, Description: ns.Description, Quota: ns.Quota}
+			updateFlags.apply(cmd.Flags(), input)
+
+			ns, err = kc.UpdateNamespace(cmd.Context(), input)
+			if err != nil {
+				return err
+			}
+			return printResult(c, ns, oneNamespace)
+		},
+	}
+	updateFlags.register(update.Flags())
+
+	del := &cobra.Command{
+		Use:               "delete NAME",
+		Aliases:           []string{"rm"},
+		Short:             "Delete an empty namespace",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeNamespace,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			if err := kc.DeleteNamespace(cmd.Context(), args[0]); err != nil {
+				return err
+			}
+			fmt.Fprintf(c.stdout, "namespace %s deleted\n", args[0])
+			return nil
+		},
+	}
+
+	cmd.AddCommand(list, get, create, update, del)
+	return cmd
+}
+
+func apiKeyTable(keys []*model.APIKey) *table {
+	t := newTable("ID", "NAME", "NAMESPACE", "PREFIX", "SCOPES", "CREATED", "EXPIRES")
+	for _, key := range keys {
+		t.add(
+			strconv.FormatInt(key.ID, 10),
+			key.Name,
+			orDash(key.Namespace),
+			key.Prefix,
+			strings.Join(key.Scopes, ","),
+			formatTime(key.CreatedAt),
+			formatTime(key.ExpiresAt),
+		)
+	}
+	return t
+}
+
+func createdAPIKeyLines(key *model.CreatedAPIKey) lines {
+	return lines{
+		fmt.Sprintf("api key %q created with id %d", key.Name, key.ID),
+		"the key is shown only once, store it safely:",
+		key.Key,
+	}
+}
+
+func newAPIKeysCommand(c *cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "apikeys",
+		Aliases: []string{"apikey"},
+		Short:   "Manage api keys",
+	}
+
+	list := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the api keys",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			keys, err := kc.ListAPIKeys(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printResult(c, keys, apiKeyTable)
+		},
+	}
+
+	var (
+		input     model.APIKeyCreateInput
+		expiresIn time.Duration
+	)
+	create := &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create an api key",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			input.Name = args[0]
+			if expiresIn > 0 {
+				input.ExpiresAt = time.Now().Add(expiresIn)
+			}
+
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			key, err := kc.CreateAPIKey(cmd.Context(), &input)
+			if err != nil {
+				return err
+			}
+			return printResult(c, key, createdAPIKeyLines)
+		},
+	}
+	create.Flags().StringSliceVar(&input.Scopes, "scopes", []string{"read"}, "scopes granted to the key: read, write, trigger, admin")
+	create.Flags().StringVar(&input.Namespace, "key-namespace", "", "namespace the key is confined to (unconfined if empty)")
+	create.Flags().DurationVar(&expiresIn, "expires-in", 0, "lifetime of the key (never expires if zero)")
+	create.RegisterFlagCompletionFunc("scopes", cobra.FixedCompletions([]string{"read", "write", "trigger", "admin"}, cobra.ShellCompDirectiveNoFileComp))
+	create.RegisterFlagCompletionFunc("key-namespace", c.completeNamespaces)
+
+	del := &cobra.Command{
+		Use:     "delete ID",
+		Aliases: []string{"rm", "revoke"},
+		Short:   "Revoke an api key",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			if err := kc.DeleteAPIKey(cmd.Context(), id); err != nil {
+				return err
+			}
+			fmt.Fprintf(c.stdout, "api key %d revoked\n", id)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(list, create, del)
+	return cmd
+}
+
+func roleBindingTable(bindings []*model.RoleBinding) *table {
+	t := newTable("ID", "SUBJECT", "ROLE", "NAMESPACE", "SELECTOR", "CREATED")
+	for _, b := range bindings {
+		t.add(
+			strconv.FormatInt(b.ID, 10),
+			b.Subject,
+			b.Role,
+			orDash(b.Namespace),
+			formatLabels(b.Selector),
+			formatTime(b.CreatedAt),
+		)
+	}
+	return t
+}
+
+func oneRoleBinding(binding *model.RoleBinding) *table {
+	return roleBindingTable([]*model.RoleBinding{binding})
+}
+
+func newRoleBindingsCommand(c *cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "rolebindings",
+		Aliases: []string{"rolebinding", "rb"},
+		Short:   "Manage the roles bound to subjects",
+	}
+
+	list := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the role bindings",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			bindings, err := kc.ListRoleBindings(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printResult(c, bindings, roleBindingTable)
+		},
+	}
+
+	var input model.RoleBindingInput
+	create := &cobra.Command{
+		Use:   "create SUBJECT",
+		Short: "Bind a role to a subject, that is apikey:<id>, jwt:<sub> or cert:<common name>",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			input.Subject = args[0]
+
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			binding, err := kc.CreateRoleBinding(cmd.Context(), &input)
+			if err != nil {
+				return err
+			}
+			return printResult(c, binding, oneRoleBinding)
+		},
+	}
+	create.Flags().StringVar(&input.Role, "role", "", "role granted to the subject: viewer, operator, editor, admin")
+	create.Flags().StringVar(&input.Namespace, "binding-namespace", "", "namespace the binding applies to (every namespace if empty)")
+	create.Flags().StringToStringVar(&input.Selector, "selector", nil, "labels the metadata of schedules must match, as key=value pairs")
+	create.MarkFlagRequired("role")
+	create.RegisterFlagCompletionFunc("role", cobra.FixedCompletions([]string{"viewer", "operator", "editor", "admin"}, cobra.ShellCompDirectiveNoFileComp))
+	create.RegisterFlagCompletionFunc("binding-namespace", c.completeNamespaces)
+
+	del := &cobra.Command{
+		Use:     "delete ID",
+		Aliases: []string{"rm"},
+		Short:   "Delete a role binding",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			if err := kc.DeleteRoleBinding(cmd.Context(), id); err != nil {
+				return err
+			}
+			fmt.Fprintf(c.stdout, "role binding %d deleted\n", id)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(list, create, del)
+	return cmd
+}
+
+func secretTable(secrets []*model.Secret) *table {
+	t := newTable("NAME", "NAMESPACE", "CREATED", "UPDATED")
+	for _, s := range secrets {
+		t.add(s.Name, s.Namespace, formatTime(s.CreatedAt), formatTime(s.UpdatedAt))
+	}
+	return t
+}
+
+func oneSecret(secret *model.Secret) *table {
+	return secretTable([]*model.Secret{secret})
+}
+
+func newSecretsCommand(c *cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "secrets",
+		Aliases: []string{"secret"},
+		Short:   "Manage the secrets referenced by schedule headers",
+	}
+
+	completeSecrets := func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		kc, err := c.client()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		secrets, err := kc.ListSecrets(cmd.Context())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		names := make([]string, 0, len(secrets))
+		for _, s := range secrets {
+			names = append(names, s.Name)
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	list := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the secrets, without their values",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			secrets, err := kc.ListSecrets(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printResult(c, secrets, secretTable)
+		},
+	}
+
+	var value, fromFile string
+	set := &cobra.Command{
+		Use:               "set NAME",
+		Short:             "Create a secret, or replace its value",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeSecrets,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (value == "") == (fromFile == "") {
+				return fmt.Errorf("exactly one of --value and --from-file is required")
+			}
+
+			if fromFile != "" {
+				data, err := readInput(c, fromFile)
+				if err != nil {
+					return err
+				}
+				value = strings.TrimSuffix(string(data), "\n")
+			}
+
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			secret, err := kc.PutSecret(cmd.Context(), args[0], value)
+			if err != nil {
+				return err
+			}
+			return printResult(c, secret, oneSecret)
+		},
+	}
+	set.Flags().StringVar(&value, "value", "", "value of the secret, which may end up in the shell history: prefer --from-file")
+	set.Flags().StringVar(&fromFile, "from-file", "", `file holding the value of the secret, or "-" for standard input`)
+
+	del := &cobra.Command{
+		Use:               "delete NAME",
+		Aliases:           []string{"rm"},
+		Short:             "Delete a secret not referenced by any schedule",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeSecrets,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			if err := kc.DeleteSecret(cmd.Context(), args[0]); err != nil {
+				return err
+			}
+			fmt.Fprintf(c.stdout, "secret %s deleted\n", args[0])
+			return nil
+		},
+	}
+
+	cmd.AddCommand(list, set, del)
+	return cmd
+}
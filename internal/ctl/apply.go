@@ -0,0 +1,195 @@
+package ctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ostafen/kronos/model"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// ExitDrift is the exit code of apply with --detailed-exitcode when the stored schedules differ from the desired ones.
+const ExitDrift = 2
+
+// ScheduleFile is the format of the files read by apply, whose schedules mirror the body of POST /schedules.
+type ScheduleFile struct {
+	Namespace string                         `json:"namespace"`
+	Schedules []*model.ScheduleRegisterInput `json:"schedules"`
+}
+
+func readInput(c *cli, name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(c.stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// decodeYAML decodes a yaml (or json) document into v. The document is converted to json first,
+// so that the field names and formats are exactly those accepted by the api.
+func decodeYAML(data []byte, v any) error {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	jsonData, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// readScheduleFiles merges the schedules of the given files, and returns the namespace they refer to, if any.
+func readScheduleFiles(c *cli, files []string) (string, []*model.ScheduleRegisterInput, error) {
+	var (
+		namespace string
+		schedules []*model.ScheduleRegisterInput
+	)
+
+	for _, name := range files {
+		data, err := readInput(c, name)
+		if err != nil {
+			return "", nil, err
+		}
+
+		var file ScheduleFile
+		if err := decodeYAML(data, &file); err != nil {
+			return "", nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		if file.Namespace != "" {
+			if namespace != "" && namespace != file.Namespace {
+				return "", nil, fmt.Errorf("%s: files refer to different namespaces (%s and %s)", name, namespace, file.Namespace)
+			}
+			namespace = file.Namespace
+		}
+		schedules = append(schedules, file.Schedules...)
+	}
+	return namespace, schedules, nil
+}
+
+func newApplyCommand(c *cli) *cobra.Command {
+	var (
+		files            []string
+		req              model.ApplyRequest
+		detailedExitCode bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "apply -f FILE",
+		Short: "Reconcile the schedules of a namespace with those defined in yaml files",
+		Long: `Reconcile the schedules of a namespace with those defined in yaml files.
+
+Missing schedules are created and those whose definition differs are updated, matching them by title.
+Stored schedules which are not defined in the files are reported as unmanaged, and deleted only with --prune.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, schedules, err := readScheduleFiles(c, files)
+			if err != nil {
+				return err
+			}
+			req.Schedules = schedules
+
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			// the namespace given on the command line takes precedence over the one of the files
+			if namespace != "" && !cmd.Flags().Changed("namespace") {
+				kc = kc.WithNamespace(namespace)
+			}
+
+			// a failed apply still reports the changes made before the failure
+			res, err := kc.Apply(cmd.Context(), &req)
+			if err != nil {
+				if res != nil {
+					printResult(c, res, applyLines)
+				}
+				return err
+			}
+
+			if err := printResult(c, res, applyLines); err != nil {
+				return err
+			}
+
+			if detailedExitCode && res.Drift {
+				return &ExitError{Code: ExitDrift}
+			}
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringArrayVarP(&files, "filename", "f", nil, `file defining the desired schedules, or "-" for standard input (repeatable)`)
+	flags.BoolVar(&req.DryRun, "dry-run", false, "only print the changes, without applying them")
+	flags.BoolVar(&req.Prune, "prune", false, "delete the schedules of the namespace which are not defined in the files")
+	flags.BoolVar(&detailedExitCode, "detailed-exitcode", false, fmt.Sprintf("exit with code %d when the server drifted from the files", ExitDrift))
+	cmd.MarkFlagRequired("filename")
+	cmd.MarkFlagFilename("filename", "yaml", "yml", "json")
+	return cmd
+}
+
+var changeSymbols = map[model.ApplyAction]string{
+	model.ApplyActionCreate:    "+",
+	model.ApplyActionUpdate:    "~",
+	model.ApplyActionDelete:    "-",
+	model.ApplyActionUnmanaged: "!",
+}
+
+// applyLines prints the changes of an apply, one per line, followed by the field differences of updates
+// and by a summary. When the apply failed, the changes which were not made are marked.
+func applyLines(res *model.ApplyResult) lines {
+	var l lines
+	counts := make(map[model.ApplyAction]int)
+
+	for _, change := range res.Changes {
+		counts[change.Action]++
+
+		symbol, has := changeSymbols[change.Action]
+		if !has {
+			continue
+		}
+
+		line := fmt.Sprintf("%s %s %q", symbol, change.Action, change.Title)
+		if change.ID > 0 {
+			line += fmt.Sprintf(" (id %d)", change.ID)
+		}
+		if res.Error != "" && !change.Applied {
+			line += " (not applied)"
+		}
+		l = append(l, line)
+
+		for _, diff := range change.Diff {
+			l = append(l, fmt.Sprintf("    %s: %s -> %s", diff.Field, formatValue(diff.From), formatValue(diff.To)))
+		}
+	}
+
+	summary := fmt.Sprintf("%d create, %d update, %d delete, %d unchanged, %d unmanaged",
+		counts[model.ApplyActionCreate],
+		counts[model.ApplyActionUpdate],
+		counts[model.ApplyActionDelete],
+		counts[model.ApplyActionUnchanged],
+		counts[model.ApplyActionUnmanaged],
+	)
+
+	if res.DryRun {
+		summary += " (dry run)"
+	}
+	return append(l, summary)
+}
+
+func formatValue(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
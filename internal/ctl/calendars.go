@@ -0,0 +1,191 @@
+package ctl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ostafen/kronos/model"
+	"github.com/spf13/cobra"
+)
+
+func calendarTable(calendars []*model.Calendar) *table {
+	t := newTable("NAME", "NAMESPACE", "DESCRIPTION", "TIME ZONE", "DATES", "BLACKOUTS", "UPDATED")
+	for _, cal := range calendars {
+		blackouts := make([]string, 0, len(cal.Blackouts))
+		for _, window := range cal.Blackouts {
+			blackouts = append(blackouts, fmt.Sprintf("%s for %s", window.CronExpr, window.Duration))
+		}
+
+		t.add(
+			cal.Name,
+			cal.Namespace,
+			orDash(cal.Description),
+			orDash(cal.TimeZone),
+			strconv.Itoa(len(cal.Dates)),
+			orDash(strings.Join(blackouts, "; ")),
+			formatTime(cal.UpdatedAt),
+		)
+	}
+	return t
+}
+
+func oneCalendar(cal *model.Calendar) *table {
+	return calendarTable([]*model.Calendar{cal})
+}
+
+// parseBlackout parses a blackout window given as "cron expression=duration".
+// The last '=' separates the duration, since cron expressions don't contain any.
+func parseBlackout(s string) (model.BlackoutWindow, error) {
+	i := strings.LastIndex(s, "=")
+	if i < 0 {
+		return model.BlackoutWindow{}, fmt.Errorf("invalid blackout window %q: cron=duration is expected", s)
+	}
+
+	d, err := time.ParseDuration(strings.TrimSpace(s[i+1:]))
+	if err != nil {
+		return model.BlackoutWindow{}, fmt.Errorf("invalid duration of blackout window %q: %w", s, err)
+	}
+	return model.BlackoutWindow{CronExpr: strings.TrimSpace(s[:i]), Duration: model.Duration(d)}, nil
+}
+
+func newCalendarsCommand(c *cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "calendars",
+		Aliases: []string{"calendar", "cal"},
+		Short:   "Manage the holiday calendars and blackout windows referenced by schedules",
+	}
+
+	completeCalendars := func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		kc, err := c.client()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		calendars, err := kc.ListCalendars(cmd.Context())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		names := make([]string, 0, len(calendars))
+		for _, cal := range calendars {
+			names = append(names, cal.Name)
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	list := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the calendars",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			calendars, err := kc.ListCalendars(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printResult(c, calendars, calendarTable)
+		},
+	}
+
+	get := &cobra.Command{
+		Use:               "get NAME",
+		Short:             "Get a calendar, whose dates are shown with -o yaml or -o json",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeCalendars,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			cal, err := kc.GetCalendar(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return printResult(c, cal, oneCalendar)
+		},
+	}
+
+	var (
+		input     model.CalendarInput
+		blackouts []string
+		icsFile   string
+	)
+	put := &cobra.Command{
+		Use:   "put NAME",
+		Short: "Create a calendar, or replace an existing one",
+		Example: `  kronosctl calendars put us-holidays --ics us-holidays.ics --timezone America/New_York
+  kronosctl calendars put freeze --date 2030-12-24 --date 2030-12-31 --blackout "0 18 * * 5=62h"`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeCalendars,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			input.Name = args[0]
+
+			for _, s := range blackouts {
+				window, err := parseBlackout(s)
+				if err != nil {
+					return err
+				}
+				input.Blackouts = append(input.Blackouts, window)
+			}
+
+			if icsFile != "" {
+				data, err := readInput(c, icsFile)
+				if err != nil {
+					return err
+				}
+				input.ICS = string(data)
+			}
+
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			cal, err := kc.PutCalendar(cmd.Context(), &input)
+			if err != nil {
+				return err
+			}
+			return printResult(c, cal, oneCalendar)
+		},
+	}
+	put.Flags().StringVar(&input.Description, "description", "", "description of the calendar")
+	put.Flags().StringVar(&input.TimeZone, "timezone", "", "IANA time zone of the dates and blackout windows, the one of the server if empty")
+	put.Flags().StringSliceVar(&input.Dates, "date", nil, "excluded date, as YYYY-MM-DD")
+	put.Flags().StringArrayVar(&blackouts, "blackout", nil, `recurring blackout window, as "cron expression=duration"`)
+	put.Flags().StringVar(&icsFile, "ics", "", `iCalendar file whose events are excluded, or "-" for standard input`)
+
+	del := &cobra.Command{
+		Use:               "delete NAME",
+		Aliases:           []string{"rm"},
+		Short:             "Delete a calendar not referenced by any schedule",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeCalendars,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			if err := kc.DeleteCalendar(cmd.Context(), args[0]); err != nil {
+				return err
+			}
+			fmt.Fprintf(c.stdout, "calendar %s deleted\n", args[0])
+			return nil
+		},
+	}
+
+	cmd.AddCommand(list, get, put, del)
+	return cmd
+}
@@ -0,0 +1,321 @@
+package ctl
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultServer = "http://localhost:9175"
+
+	configEnv = "KRONOSCTL_CONFIG"
+)
+
+// Context holds the connection settings of a server, and is selected by name through --context
+// or the current context of the configuration file.
+type Context struct {
+	Server    string     `yaml:"server"`
+	APIKey    string     `yaml:"apiKey,omitempty"`
+	Token     string     `yaml:"token,omitempty"`
+	Namespace string     `yaml:"namespace,omitempty"`
+	TLS       ContextTLS `yaml:"tls,omitempty"`
+}
+
+type ContextTLS struct {
+	// CAFile holds the certificates used to verify the server, in place of the system ones.
+	CAFile string `yaml:"caFile,omitempty"`
+	// CertFile and KeyFile hold the client certificate presented to servers requiring one.
+	CertFile           string `yaml:"certFile,omitempty"`
+	KeyFile            string `yaml:"keyFile,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify,omitempty"`
+}
+
+func (t ContextTLS) isZero() bool {
+	return t == ContextTLS{}
+}
+
+// httpClient returns a client trusting the certificates of the context.
+func (t ContextTLS) httpClient() (*http.Client, error) {
+	client := &http.Client{Timeout: time.Minute}
+	if t.isZero() {
+		return client, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+
+	if t.CAFile != "" {
+		data, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificate found in %s", t.CAFile)
+		}
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	client.Transport = &http.Transport{
+		Proxy:             http.ProxyFromEnvironment,
+		TLSClientConfig:   tlsConfig,
+		ForceAttemptHTTP2: true,
+	}
+	return client, nil
+}
+
+type Config struct {
+	CurrentContext string              `yaml:"currentContext,omitempty"`
+	Contexts       map[string]*Context `yaml:"contexts,omitempty"`
+}
+
+// configPath returns the path of the configuration file: the one given through --config or KRONOSCTL_CONFIG,
+// or config.yaml in the kronosctl folder of the user configuration directory.
+func configPath(flag string) (string, error) {
+	if flag != "" {
+		return flag, nil
+	}
+
+	if path := os.Getenv(configEnv); path != "" {
+		return path, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "kronosctl", "config.yaml"), nil
+}
+
+// loadConfig reads the configuration file, which is not required to exist.
+func loadConfig(path string) (*Config, error) {
+	conf := &Config{Contexts: make(map[string]*Context)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return conf, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, conf); err != nil {
+		return nil, fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+
+	if conf.Contexts == nil {
+		conf.Contexts = make(map[string]*Context)
+	}
+	return conf, nil
+}
+
+func saveConfig(path string, conf *Config) error {
+	data, err := yaml.Marshal(conf)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	// contexts may hold credentials
+	return os.WriteFile(path, data, 0600)
+}
+
+func (conf *Config) contextNames() []string {
+	names := make([]string, 0, len(conf.Contexts))
+	for name := range conf.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newConfigCommand(c *cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the contexts of the configuration file",
+	}
+
+	completeContexts := func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		conf, err := c.loadConfig()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return conf.contextNames(), cobra.ShellCompDirectiveNoFileComp
+	}
+
+	getContexts := &cobra.Command{
+		Use:   "get-contexts",
+		Short: "List the contexts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf, err := c.loadConfig()
+			if err != nil {
+				return err
+			}
+
+			rows := make([]contextRow, 0, len(conf.Contexts))
+			for _, name := range conf.contextNames() {
+				ctx := conf.Contexts[name]
+				rows = append(rows, contextRow{
+					Name:      name,
+					Current:   name == conf.CurrentContext,
+					Server:    ctx.Server,
+					Namespace: ctx.Namespace,
+				})
+			}
+			return printResult(c, rows, contextTable)
+		},
+	}
+
+	currentContext := &cobra.Command{
+		Use:   "current-context",
+		Short: "Print the current context",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf, err := c.loadConfig()
+			if err != nil {
+				return err
+			}
+
+			if conf.CurrentContext == "" {
+				return fmt.Errorf("no current context is set")
+			}
+			fmt.Fprintln(c.stdout, conf.CurrentContext)
+			return nil
+		},
+	}
+
+	useContext := &cobra.Command{
+		Use:               "use-context NAME",
+		Short:             "Set the current context",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeContexts,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.updateConfig(func(conf *Config) error {
+				if _, has := conf.Contexts[args[0]]; !has {
+					return fmt.Errorf("no context named %q", args[0])
+				}
+				conf.CurrentContext = args[0]
+				return nil
+			})
+		},
+	}
+
+	var settings Context
+	setContext := &cobra.Command{
+		Use:               "set-context NAME",
+		Short:             "Create a context, or update the given settings of an existing one",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeContexts,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.updateConfig(func(conf *Config) error {
+				ctx, has := conf.Contexts[args[0]]
+				if !has {
+					ctx = &Context{}
+					conf.Contexts[args[0]] = ctx
+				}
+
+				flags := cmd.Flags()
+				setIfChanged(flags.Changed("server"), &ctx.Server, settings.Server)
+				setIfChanged(flags.Changed("api-key"), &ctx.APIKey, settings.APIKey)
+				setIfChanged(flags.Changed("token"), &ctx.Token, settings.Token)
+				setIfChanged(flags.Changed("namespace"), &ctx.Namespace, settings.Namespace)
+				setIfChanged(flags.Changed("ca-file"), &ctx.TLS.CAFile, settings.TLS.CAFile)
+				setIfChanged(flags.Changed("cert-file"), &ctx.TLS.CertFile, settings.TLS.CertFile)
+				setIfChanged(flags.Changed("key-file"), &ctx.TLS.KeyFile, settings.TLS.KeyFile)
+				setIfChanged(flags.Changed("insecure-skip-verify"), &ctx.TLS.InsecureSkipVerify, settings.TLS.InsecureSkipVerify)
+
+				if conf.CurrentContext == "" {
+					conf.CurrentContext = args[0]
+				}
+				return nil
+			})
+		},
+	}
+
+	// the flags of set-context shadow the global ones, so that they are stored rather than used
+	flags := setContext.Flags()
+	flags.StringVar(&settings.Server, "server", "", "url of the server")
+	flags.StringVar(&settings.APIKey, "api-key", "", "api key used to authenticate")
+	flags.StringVar(&settings.Token, "token", "", "bearer token used to authenticate")
+	flags.StringVar(&settings.Namespace, "namespace", "", "namespace requests operate on")
+	flags.StringVar(&settings.TLS.CAFile, "ca-file", "", "certificates used to verify the server")
+	flags.StringVar(&settings.TLS.CertFile, "cert-file", "", "client certificate presented to the server")
+	flags.StringVar(&settings.TLS.KeyFile, "key-file", "", "private key of the client certificate")
+	flags.BoolVar(&settings.TLS.InsecureSkipVerify, "insecure-skip-verify", false, "skip the verification of the server certificate")
+
+	deleteContext := &cobra.Command{
+		Use:               "delete-context NAME",
+		Short:             "Delete a context",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeContexts,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.updateConfig(func(conf *Config) error {
+				if _, has := conf.Contexts[args[0]]; !has {
+					return fmt.Errorf("no context named %q", args[0])
+				}
+
+				delete(conf.Contexts, args[0])
+				if conf.CurrentContext == args[0] {
+					conf.CurrentContext = ""
+				}
+				return nil
+			})
+		},
+	}
+
+	cmd.AddCommand(getContexts, currentContext, useContext, setContext, deleteContext)
+	return cmd
+}
+
+func setIfChanged[T any](changed bool, dst *T, value T) {
+	if changed {
+		*dst = value
+	}
+}
+
+type contextRow struct {
+	Name      string `json:"name"`
+	Current   bool   `json:"current"`
+	Server    string `json:"server"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+func contextTable(rows []contextRow) *table {
+	t := newTable("CURRENT", "NAME", "SERVER", "NAMESPACE")
+	for _, row := range rows {
+		current := ""
+		if row.Current {
+			current = "*"
+		}
+		t.add(current, row.Name, row.Server, row.Namespace)
+	}
+	return t
+}
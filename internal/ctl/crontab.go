@@ -0,0 +1,105 @@
+package ctl
+
+import (
+	"fmt"
+
+	"github.com/ostafen/kronos/model"
+	"github.com/spf13/cobra"
+)
+
+func newCrontabCommand(c *cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "crontab",
+		Short: "Import schedules from crontab files, and export them back",
+	}
+
+	cmd.AddCommand(newCrontabImportCommand(c), newCrontabExportCommand(c))
+	return cmd
+}
+
+func newCrontabImportCommand(c *cli) *cobra.Command {
+	var (
+		file string
+		req  model.CrontabImportRequest
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import -f FILE --url-template TEMPLATE",
+		Short: "Create or update a schedule for each entry of a crontab",
+		Long: `Create or update a schedule for each entry of a crontab.
+
+The url and the title of each schedule are Go templates rendered with the fields of the entry:
+.Command, .User, .Spec, .CronExpr, .Comment, .Env, .Line, .ID and .Slug.
+Schedules are matched by title, so importing the same crontab again only updates the entries which changed,
+and stored schedules are never deleted. Lines which can't be imported are listed with the reason.`,
+		Example: `  kronosctl crontab import -f /etc/crontab --system \
+    --url-template 'https://runner.example.com/run?user={{.User}}&id={{.ID}}'`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := readInput(c, file)
+			if err != nil {
+				return err
+			}
+			req.Crontab = string(data)
+
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			res, err := kc.ImportCrontab(cmd.Context(), &req)
+			if err != nil {
+				if res != nil {
+					printResult(c, res, crontabImportLines)
+				}
+				return err
+			}
+			return printResult(c, res, crontabImportLines)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&file, "filename", "f", "", `crontab file, or "-" for standard input`)
+	flags.StringVar(&req.URLTemplate, "url-template", "", "template of the url notified by each schedule")
+	flags.StringVar(&req.TitleTemplate, "title-template", "", "template of the title of each schedule (default {{.Slug}}-{{.ID}})")
+	flags.BoolVar(&req.System, "system", false, "read the format of system crontabs, whose entries have a user field")
+	flags.StringToStringVar(&req.Metadata, "metadata", nil, "metadata added to every schedule, as key=value pairs")
+	flags.BoolVar(&req.DryRun, "dry-run", false, "only print the changes, without applying them")
+	cmd.MarkFlagRequired("filename")
+	cmd.MarkFlagRequired("url-template")
+	return cmd
+}
+
+// crontabImportLines prints the changes of an import as apply does, preceded by the lines which were not imported.
+func crontabImportLines(res *model.CrontabImportResult) lines {
+	var l lines
+	for _, line := range res.Unsupported {
+		l = append(l, fmt.Sprintf("? line %d skipped: %s", line.Line, line.Reason))
+		l = append(l, "    "+line.Text)
+	}
+	return append(l, applyLines(&res.ApplyResult)...)
+}
+
+func newCrontabExportCommand(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "export",
+		Short: "Print the schedules as crontab lines",
+		Long: `Print the schedules as crontab lines, for review.
+Imported schedules run their original command, and the others deliver their webhook through curl.
+Schedules which crontab can't run, such as one-shot or paused ones, are printed as comments.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			crontab, err := kc.ExportCrontab(cmd.Context())
+			if err != nil {
+				return err
+			}
+			fmt.Fprint(c.stdout, crontab)
+			return nil
+		},
+	}
+}
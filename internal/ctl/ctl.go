@@ -0,0 +1,274 @@
+// Package ctl implements kronosctl, the command line client of the Kronos api.
+package ctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ostafen/kronos/client"
+	"github.com/spf13/cobra"
+)
+
+// ExitError makes a command exit with the given code, after printing its message if any.
+type ExitError struct {
+	Code    int
+	Message string
+}
+
+func (e *ExitError) Error() string {
+	return e.Message
+}
+
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	configFile  string
+	contextName string
+	server      string
+	apiKey      string
+	token       string
+	namespace   string
+	output      string
+}
+
+// Run executes the command line given by args, and returns the exit code of the process.
+// name is the name of the executable, as shown in usage messages.
+func Run(name string, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c := &cli{
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+	}
+
+	root := newRootCommand(c, name)
+	root.SetArgs(args)
+	root.SetIn(stdin)
+	root.SetOut(stdout)
+	root.SetErr(stderr)
+
+	err := root.Execute()
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		if exitErr.Message != "" {
+			fmt.Fprintln(stderr, exitErr.Message)
+		}
+		return exitErr.Code
+	}
+
+	fmt.Fprintln(stderr, "Error:", err)
+	return 1
+}
+
+func newRootCommand(c *cli, name string) *cobra.Command {
+	root := &cobra.Command{
+		Use:           name,
+		Short:         "Command line client of the Kronos api",
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			for _, format := range outputFormats {
+				if c.output == format {
+					return nil
+				}
+			}
+			return fmt.Errorf("invalid output format %q: one of %s is expected", c.output, strings.Join(outputFormats, ", "))
+		},
+	}
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&c.configFile, "config", "", "configuration file (default is $"+configEnv+", or kronosctl/config.yaml in the user configuration directory)")
+	flags.StringVar(&c.contextName, "context", "", "context of the configuration file to use, in place of the current one")
+	flags.StringVar(&c.server, "server", "", "url of the server (env KRONOS_SERVER, default "+defaultServer+")")
+	flags.StringVar(&c.apiKey, "api-key", "", "api key used to authenticate (env KRONOS_API_KEY)")
+	flags.StringVar(&c.token, "token", "", "bearer token used to authenticate (env KRONOS_TOKEN)")
+	flags.StringVarP(&c.namespace, "namespace", "n", "", "namespace requests operate on (env KRONOS_NAMESPACE)")
+	flags.StringVarP(&c.output, "output", "o", outputTable, "output format: "+strings.Join(outputFormats, ", "))
+
+	root.RegisterFlagCompletionFunc("output", cobra.FixedCompletions(outputFormats, cobra.ShellCompDirectiveNoFileComp))
+	root.RegisterFlagCompletionFunc("context", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		conf, err := c.loadConfig()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return conf.contextNames(), cobra.ShellCompDirectiveNoFileComp
+	})
+	root.RegisterFlagCompletionFunc("namespace", c.completeNamespaces)
+
+	root.AddCommand(
+		newSchedulesCommand(c),
+		newApplyCommand(c),
+		newCrontabCommand(c),
+		newHistoryCommand(c),
+		newStatsCommand(c),
+		newAuditCommand(c),
+		newNamespacesCommand(c),
+		newAPIKeysCommand(c),
+		newRoleBindingsCommand(c),
+		newSecretsCommand(c),
+		newCalendarsCommand(c),
+		newHealthCommand(c),
+		newMetricsCommand(c),
+		newConfigCommand(c),
+	)
+	return root
+}
+
+func (c *cli) loadConfig() (*Config, error) {
+	path, err := configPath(c.configFile)
+	if err != nil {
+		return nil, err
+	}
+	return loadConfig(path)
+}
+
+// updateConfig applies update to the configuration file, and saves it.
+func (c *cli) updateConfig(update func(*Config) error) error {
+	path, err := configPath(c.configFile)
+	if err != nil {
+		return err
+	}
+
+	conf, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	if err := update(conf); err != nil {
+		return err
+	}
+	return saveConfig(path, conf)
+}
+
+// currentContext returns the context selected through --context, or the current one,
+// which is empty if the configuration file doesn't define any.
+func (c *cli) currentContext() (*Context, error) {
+	conf, err := c.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	name := c.contextName
+	if name == "" {
+		name = conf.CurrentContext
+	}
+
+	if name == "" {
+		return &Context{}, nil
+	}
+
+	ctx, has := conf.Contexts[name]
+	if !has {
+		return nil, fmt.Errorf("no context named %q", name)
+	}
+	return ctx, nil
+}
+
+// client returns a client configured from flags, environment variables and the selected context,
+// in order of precedence.
+func (c *cli) client() (*client.Client, error) {
+	ctx, err := c.currentContext()
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient, err := ctx.TLS.httpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey := firstOf(c.apiKey, os.Getenv("KRONOS_API_KEY"))
+	token := firstOf(c.token, os.Getenv("KRONOS_TOKEN"))
+
+	// credentials given explicitly replace those of the context, rather than being combined with them
+	if apiKey == "" && token == "" {
+		apiKey, token = ctx.APIKey, ctx.Token
+	}
+
+	return client.New(client.Options{
+		Server:     firstOf(c.server, os.Getenv("KRONOS_SERVER"), ctx.Server, defaultServer),
+		APIKey:     apiKey,
+		Token:      token,
+		Namespace:  firstOf(c.namespace, os.Getenv("KRONOS_NAMESPACE"), ctx.Namespace),
+		HTTPClient: httpClient,
+	})
+}
+
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// printResult writes v in the selected output format, where the table format is produced by render.
+func printResult[T any, R renderer](c *cli, v T, render func(T) R) error {
+	switch c.output {
+	case outputJSON:
+		return writeJSON(c.stdout, v)
+	case outputYAML:
+		return writeYAML(c.stdout, v)
+	}
+	return render(v).write(c.stdout)
+}
+
+func parseID(arg string) (int64, error) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid id %q", arg)
+	}
+	return id, nil
+}
+
+// completeSchedules completes the ids of the schedules, described by their titles.
+func (c *cli) completeSchedules(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	kc, err := c.client()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	schedules, err := kc.ListSchedules(context.Background())
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	res := make([]string, 0, len(schedules))
+	for _, sched := range schedules {
+		res = append(res, fmt.Sprintf("%d\t%s", sched.ID, sched.Title))
+	}
+	return res, cobra.ShellCompDirectiveNoFileComp
+}
+
+func (c *cli) completeNamespaces(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	kc, err := c.client()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	namespaces, err := kc.ListNamespaces(context.Background())
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	res := make([]string, 0, len(namespaces))
+	for _, ns := range namespaces {
+		res = append(res, ns.Name)
+	}
+	return res, cobra.ShellCompDirectiveNoFileComp
+}
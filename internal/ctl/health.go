@@ -0,0 +1,75 @@
+package ctl
+
+import (
+	"fmt"
+
+	"github.com/ostafen/kronos/client"
+	"github.com/spf13/cobra"
+)
+
+type healthReport struct {
+	Liveness  *client.HealthStatus `json:"liveness"`
+	Readiness *client.HealthStatus `json:"readiness"`
+}
+
+func healthTable(report *healthReport) *table {
+	t := newTable("PROBE", "STATUS", "ERROR")
+	t.add("liveness", report.Liveness.Status, orDash(report.Liveness.Error))
+	t.add("readiness", report.Readiness.Status, orDash(report.Readiness.Error))
+	return t
+}
+
+func newHealthCommand(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "health",
+		Short: "Show the liveness and readiness of the server, exiting with code 1 if not ready",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			var report healthReport
+			if report.Liveness, err = kc.Liveness(cmd.Context()); err != nil {
+				return err
+			}
+
+			if report.Readiness, err = kc.Readiness(cmd.Context()); err != nil {
+				return err
+			}
+
+			if err := printResult(c, &report, healthTable); err != nil {
+				return err
+			}
+
+			if report.Readiness.Status != "ok" {
+				return &ExitError{Code: 1}
+			}
+			return nil
+		},
+	}
+}
+
+func newMetricsCommand(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "metrics",
+		Short: "Print the metrics of the server, in the Prometheus text format",
+		Long: `Print the metrics of the server, in the Prometheus text format.
+Metrics are not available through the api port when served on a separate listener (metrics.port).`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			metrics, err := kc.Metrics(cmd.Context())
+			if err != nil {
+				return err
+			}
+			fmt.Fprint(c.stdout, metrics)
+			return nil
+		},
+	}
+}
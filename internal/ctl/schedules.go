@@ -0,0 +1,591 @@
+package ctl
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ostafen/kronos/client"
+	"github.com/ostafen/kronos/model"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func scheduleTable(schedules []*model.CronSchedule) *table {
+	t := newTable("ID", "NAMESPACE", "TITLE", "STATUS", "SCHEDULE", "RUNS", "TARGET")
+	for _, sched := range schedules {
+		status := string(sched.Status)
+		if sched.Expired() {
+			status = string(model.ScheduleStatusExpired)
+		}
+
+		schedule := sched.CronExpr
+		switch {
+		case sched.RRule != "":
+			schedule = strings.Join(strings.Fields(sched.RRule), " ")
+		case sched.IsFixedDelay():
+			schedule = fmt.Sprintf("%s after each run", sched.Interval)
+		case sched.Interval > 0:
+			schedule = "every " + sched.Interval.String()
+		}
+
+		if !sched.IsRecurring {
+			schedule = "at " + formatTime(sched.RunAt)
+		}
+
+		runs := strconv.Itoa(sched.Runs)
+		if sched.MaxRuns > 0 {
+			runs = fmt.Sprintf("%d/%d", sched.Runs, sched.MaxRuns)
+			if sched.MaxRunsMode == model.MaxRunsModeSuccessful {
+				runs = fmt.Sprintf("%d/%d ok", sched.SuccessfulRuns, sched.MaxRuns)
+			}
+		}
+
+		// schedules of programs embedding Kronos may run a Go action rather than delivering a webhook
+		target := sched.URL
+		if sched.Action != "" {
+			target = "action:" + sched.Action
+		}
+		t.add(strconv.FormatInt(sched.ID, 10), sched.Namespace, sched.Title, status, schedule, runs, target)
+	}
+	return t
+}
+
+func oneSchedule(sched *model.CronSchedule) *table {
+	return scheduleTable([]*model.CronSchedule{sched})
+}
+
+// scheduleFlags holds the flags describing a schedule, which are shared by create and update.
+type scheduleFlags struct {
+	file          string
+	title         string
+	description   string
+	url           string
+	cronExpr      string
+	rrule         string
+	interval      time.Duration
+	intervalMode  string
+	runAt         string
+	runAfter      time.Duration
+	startAt       string
+	endAt         string
+	metadata      map[string]string
+	tlsProfile    string
+	credentials   string
+	secretHeaders map[string]string
+	calendars     []string
+	policy        string
+	jitter        time.Duration
+	maxRuns       int
+	maxRunsMode   string
+}
+
+func (f *scheduleFlags) register(flags *pflag.FlagSet) {
+	flags.StringVar(&f.description, "description", "", "description of the schedule")
+	flags.StringVar(&f.url, "url", "", "webhook notification endpoint")
+	flags.StringVar(&f.cronExpr, "cron", "", "cron expression of a recurring schedule")
+	flags.StringVar(&f.rrule, "rrule", "", "RFC 5545 recurrence rule of a recurring schedule, in place of --cron")
+	flags.DurationVar(&f.interval, "interval", 0, "interval between the activations of a recurring schedule, in place of --cron")
+	flags.StringVar(&f.intervalMode, "interval-mode", "", `"fixedRate" (default) to run at whole intervals from the start, or "fixedDelay" to wait an interval after each run`)
+	flags.StringVar(&f.runAt, "run-at", "", "RFC 3339 instant a one-shot schedule runs at")
+	flags.DurationVar(&f.runAfter, "run-after", 0, "delay after which a one-shot schedule runs, in place of --run-at")
+	flags.StringVar(&f.startAt, "start-at", "", "RFC 3339 start date of a recurring schedule")
+	flags.StringVar(&f.endAt, "end-at", "", "RFC 3339 end date of a recurring schedule")
+	flags.StringToStringVar(&f.metadata, "metadata", nil, "metadata sent with the notifications, as key=value pairs")
+	flags.StringVar(&f.tlsProfile, "tls-profile", "", "TLS profile used to connect to the webhook endpoint")
+	flags.StringVar(&f.credentials, "credentials", "", "OAuth2 credentials used to authenticate the deliveries")
+	flags.StringToStringVar(&f.secretHeaders, "secret-header", nil, "headers mapped to the secrets holding their values, as header=secret pairs")
+	flags.StringSliceVar(&f.calendars, "calendar", nil, "calendars whose dates and blackout windows the schedule doesn't run in")
+	flags.DurationVar(&f.jitter, "jitter", 0, "random delay of each activation, up to the given duration")
+	flags.IntVar(&f.maxRuns, "max-runs", 0, "number of runs after which a recurring schedule expires")
+	flags.StringVar(&f.maxRunsMode, "max-runs-mode", "", `runs counting towards --max-runs, "total" (default) or "successful"`)
+	flags.StringVar(&f.policy, "blackout-policy", "", `what happens to the excluded occurrences, "skip" (default) or "shift" to the next business day`)
+}
+
+// apply overwrites the fields of input with the flags given on the command line.
+func (f *scheduleFlags) apply(flags *pflag.FlagSet, input *model.ScheduleRegisterInput) error {
+	setIfChanged(flags.Changed("title"), &input.Title, f.title)
+	setIfChanged(flags.Changed("description"), &input.Description, f.description)
+	setIfChanged(flags.Changed("url"), &input.URL, f.url)
+	setIfChanged(flags.Changed("metadata"), &input.Metadata, f.metadata)
+	setIfChanged(flags.Changed("tls-profile"), &input.TLSProfile, f.tlsProfile)
+	setIfChanged(flags.Changed("credentials"), &input.Credentials, f.credentials)
+	setIfChanged(flags.Changed("secret-header"), &input.SecretHeaders, f.secretHeaders)
+	setIfChanged(flags.Changed("calendar"), &input.Calendars, f.calendars)
+	setIfChanged(flags.Changed("blackout-policy"), &input.BlackoutPolicy, model.BlackoutPolicy(f.policy))
+	setIfChanged(flags.Changed("jitter"), &input.Jitter, model.Duration(f.jitter))
+	setIfChanged(flags.Changed("max-runs"), &input.MaxRuns, f.maxRuns)
+	setIfChanged(flags.Changed("max-runs-mode"), &input.MaxRunsMode, model.MaxRunsMode(f.maxRunsMode))
+
+	recurrences := 0
+	for _, name := range []string{"cron", "rrule", "interval"} {
+		if flags.Changed(name) {
+			recurrences++
+		}
+	}
+
+	if recurrences > 1 {
+		return fmt.Errorf("--cron, --rrule and --interval are mutually exclusive")
+	}
+
+	if recurrences > 0 {
+		recurring := true
+		input.IsRecurring = &recurring
+		input.CronExpr = f.cronExpr
+		input.RRule = f.rrule
+		input.Interval = model.Duration(f.interval)
+		input.RunAt = time.Time{}
+		input.RunAfter = 0
+	}
+
+	if input.Interval == 0 {
+		input.IntervalMode = ""
+	}
+	setIfChanged(flags.Changed("interval-mode"), &input.IntervalMode, model.IntervalMode(f.intervalMode))
+
+	if flags.Changed("run-at") || flags.Changed("run-after") {
+		if recurrences > 0 {
+			return fmt.Errorf("--cron, --rrule, --interval and --run-at or --run-after are mutually exclusive")
+		}
+
+		if flags.Changed("run-at") && flags.Changed("run-after") {
+			return fmt.Errorf("--run-at and --run-after are mutually exclusive")
+		}
+
+		var runAt time.Time
+		if flags.Changed("run-at") {
+			var err error
+			if runAt, err = time.Parse(time.RFC3339, f.runAt); err != nil {
+				return fmt.Errorf("invalid --run-at: %w", err)
+			}
+		}
+
+		recurring := false
+		input.IsRecurring = &recurring
+		input.CronExpr = ""
+		input.RRule = ""
+		input.Interval = 0
+		input.IntervalMode = ""
+		input.RunAt = runAt
+		input.RunAfter = model.Duration(f.runAfter)
+		input.MaxRuns = 0
+		input.MaxRunsMode = ""
+		input.StartAt = time.Time{}
+		input.EndAt = time.Time{}
+	}
+
+	for name, dst := range map[string]*time.Time{"start-at": &input.StartAt, "end-at": &input.EndAt} {
+		if !flags.Changed(name) {
+			continue
+		}
+
+		value, _ := flags.GetString(name)
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return fmt.Errorf("invalid --%s: %w", name, err)
+		}
+		*dst = t
+	}
+	return nil
+}
+
+func newSchedulesCommand(c *cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "schedules",
+		Aliases: []string{"schedule", "sched"},
+		Short:   "Manage schedules",
+	}
+
+	list := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the schedules",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			schedules, err := kc.ListSchedules(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printResult(c, schedules, scheduleTable)
+		},
+	}
+
+	get := &cobra.Command{
+		Use:               "get ID",
+		Short:             "Get a schedule",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: c.completeSchedules,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			sched, err := kc.GetSchedule(cmd.Context(), id)
+			if err != nil {
+				return err
+			}
+			return printResult(c, sched, oneSchedule)
+		},
+	}
+
+	var createFlags scheduleFlags
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Register a schedule, described by flags or by a yaml or json file",
+		Example: `  kronosctl schedules create --title nightly-report --url https://example.com/hook --cron "0 2 * * *"
+  kronosctl schedules create -f schedule.yaml`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			input := &model.ScheduleRegisterInput{}
+			if createFlags.file != "" {
+				data, err := readInput(c, createFlags.file)
+				if err != nil {
+					return err
+				}
+
+				if err := decodeYAML(data, input); err != nil {
+					return fmt.Errorf("%s: %w", createFlags.file, err)
+				}
+			}
+
+			if err := createFlags.apply(cmd.Flags(), input); err != nil {
+				return err
+			}
+
+			if input.IsRecurring == nil {
+				return fmt.Errorf("either --cron or --run-at is required")
+			}
+
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			sched, err := kc.RegisterSchedule(cmd.Context(), input)
+			if err != nil {
+				return err
+			}
+			return printResult(c, sched, oneSchedule)
+		},
+	}
+	create.Flags().StringVarP(&createFlags.file, "filename", "f", "", `file describing the schedule, or "-" for standard input`)
+	create.Flags().StringVar(&createFlags.title, "title", "", "title of the schedule, unique within its namespace")
+	createFlags.register(create.Flags())
+
+	var updateFlags scheduleFlags
+	update := &cobra.Command{
+		Use:   "update ID",
+		Short: "Update the given fields of a schedule, preserving its id, status and history",
+		Long: `Update the given fields of a schedule, preserving its id, status and history.
+The title of a schedule identifies it, and cannot be changed.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: c.completeSchedules,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			sched, err := kc.GetSchedule(cmd.Context(), id)
+			if err != nil {
+				return err
+			}
+
+			input := sched.ToInput()
+			if err := updateFlags.apply(cmd.Flags(), input); err != nil {
+				return err
+			}
+
+			// schedules are updated by applying their new definition, which matches them by title
+			kc = kc.WithNamespace(sched.Namespace)
+			if _, err := kc.Apply(cmd.Context(), &model.ApplyRequest{Schedules: []*model.ScheduleRegisterInput{input}}); err != nil {
+				return err
+			}
+
+			sched, err = kc.GetSchedule(cmd.Context(), id)
+			if err != nil {
+				return err
+			}
+			return printResult(c, sched, oneSchedule)
+		},
+	}
+	updateFlags.register(update.Flags())
+
+	del := &cobra.Command{
+		Use:               "delete ID",
+		Aliases:           []string{"rm"},
+		Short:             "Delete a schedule",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: c.completeSchedules,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			if err := kc.DeleteSchedule(cmd.Context(), id); err != nil {
+				return err
+			}
+			fmt.Fprintf(c.stdout, "schedule %d deleted\n", id)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(list, get, create, update, del,
+		scheduleActionCommand(c, "pause", "Pause an active schedule", (*client.Client).PauseSchedule),
+		scheduleActionCommand(c, "resume", "Resume a paused schedule", (*client.Client).ResumeSchedule),
+		scheduleActionCommand(c, "trigger", "Immediately trigger a notification of a schedule", (*client.Client).TriggerSchedule),
+	)
+	return cmd
+}
+
+type scheduleAction func(kc *client.Client, ctx context.Context, id int64) (*model.CronSchedule, error)
+
+// scheduleActionCommand returns a command invoking an action on the schedule whose id is given as argument.
+func scheduleActionCommand(c *cli, name, short string, action scheduleAction) *cobra.Command {
+	return &cobra.Command{
+		Use:               name + " ID",
+		Short:             short,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: c.completeSchedules,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			sched, err := action(kc, cmd.Context(), id)
+			if err != nil {
+				return err
+			}
+			return printResult(c, sched, oneSchedule)
+		},
+	}
+}
+
+func historyTable(statuses []*model.CronStatus) *table {
+	t := newTable("SCHEDULE", "NAMESPACE", "AT", "STATUS", "DURATION", "LAG")
+	for _, s := range statuses {
+		status := strconv.Itoa(s.StatusCode)
+		if s.Skipped != "" {
+			status = "skipped: " + s.Skipped
+		}
+
+		t.add(
+			strconv.FormatInt(s.CronID, 10),
+			s.Namespace,
+			formatTime(s.At),
+			status,
+			s.Duration.String(),
+			s.Lag.String(),
+		)
+	}
+	return t
+}
+
+func newHistoryCommand(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:               "history [ID]",
+		Short:             "Show the latest deliveries of all schedules, or of the given one",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: c.completeSchedules,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			var statuses []*model.CronStatus
+			if len(args) == 0 {
+				statuses, err = kc.GetHistory(cmd.Context())
+			} else {
+				id, idErr := parseID(args[0])
+				if idErr != nil {
+					return idErr
+				}
+				statuses, err = kc.GetScheduleHistory(cmd.Context(), id)
+			}
+
+			if err != nil {
+				return err
+			}
+			return printResult(c, statuses, historyTable)
+		},
+	}
+}
+
+func statsTable(stats *model.CronStats) *table {
+	t := newTable("FIELD", "VALUE")
+	if stats.CronID != 0 {
+		t.add("schedule", strconv.FormatInt(stats.CronID, 10))
+	}
+	t.add("window", stats.Window)
+	if stats.Truncated {
+		t.add("truncated", "samples since "+stats.From.Format(time.RFC3339))
+	}
+	t.add("runs", strconv.Itoa(stats.Runs))
+	t.add("skipped", strconv.Itoa(stats.Skipped))
+	t.add("successes", strconv.Itoa(stats.Successes))
+	t.add("failures", strconv.Itoa(stats.Failures))
+	t.add("success rate", strconv.FormatFloat(stats.SuccessRate*100, 'f', 2, 64)+"%")
+	t.add("latency (p50/p95/p99/max)", formatPercentiles(stats.Latency))
+	t.add("lag (p50/p95/p99/max)", formatPercentiles(stats.Lag))
+	t.add("last success", formatTimePtr(stats.LastSuccessAt))
+	t.add("last failure", formatTimePtr(stats.LastFailureAt))
+	return t
+}
+
+func formatPercentiles(p model.Percentiles) string {
+	values := []string{p.P50.String(), p.P95.String(), p.P99.String(), p.Max.String()}
+	return strings.Join(values, " / ")
+}
+
+func newStatsCommand(c *cli) *cobra.Command {
+	var window string
+
+	cmd := &cobra.Command{
+		Use:               "stats [ID]",
+		Short:             "Show delivery statistics of all schedules, or of the given one",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: c.completeSchedules,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			var stats *model.CronStats
+			if len(args) == 0 {
+				stats, err = kc.GetStats(cmd.Context(), window)
+			} else {
+				id, idErr := parseID(args[0])
+				if idErr != nil {
+					return idErr
+				}
+				stats, err = kc.GetScheduleStats(cmd.Context(), id, window)
+			}
+
+			if err != nil {
+				return err
+			}
+			return printResult(c, stats, statsTable)
+		},
+	}
+	cmd.Flags().StringVar(&window, "window", "", `time window of the statistics, such as "1h" or "7d" (default 24h)`)
+	return cmd
+}
+
+func auditTable(page *model.AuditPage) *table {
+	t := newTable("ID", "AT", "NAMESPACE", "ACTOR", "METHOD", "ACTION", "SCHEDULE")
+	for _, e := range page.Entries {
+		t.add(
+			strconv.FormatInt(e.ID, 10),
+			formatTime(e.At),
+			e.Namespace,
+			e.Actor,
+			e.Method,
+			string(e.Action),
+			strconv.FormatInt(e.ScheduleID, 10),
+		)
+	}
+	return t
+}
+
+func newAuditCommand(c *cli) *cobra.Command {
+	var (
+		query    model.AuditQuery
+		action   string
+		from, to string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Query the audit log, from the most recent entry",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query.Action = model.AuditAction(action)
+
+			for _, bound := range []struct {
+				name  string
+				value string
+				dst   *time.Time
+			}{{"from", from, &query.From}, {"to", to, &query.To}} {
+				if bound.value == "" {
+					continue
+				}
+
+				t, err := time.Parse(time.RFC3339, bound.value)
+				if err != nil {
+					return fmt.Errorf("invalid --%s: %w", bound.name, err)
+				}
+				*bound.dst = t
+			}
+
+			kc, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			page, err := kc.QueryAudit(cmd.Context(), &query)
+			if err != nil {
+				return err
+			}
+
+			if err := printResult(c, page, auditTable); err != nil {
+				return err
+			}
+
+			if c.output == outputTable && page.NextCursor != 0 {
+				fmt.Fprintf(c.stderr, "more entries are available with --cursor %d\n", page.NextCursor)
+			}
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&query.Actor, "actor", "", "only return operations issued by the given actor")
+	flags.StringVar(&action, "action", "", "only return operations of the given type")
+	flags.Int64Var(&query.ScheduleID, "schedule", 0, "only return operations on the given schedule")
+	flags.StringVar(&from, "from", "", "RFC 3339 lower bound of the time of the operations")
+	flags.StringVar(&to, "to", "", "RFC 3339 upper bound of the time of the operations")
+	flags.IntVar(&query.Limit, "limit", 0, "maximum number of entries")
+	flags.Int64Var(&query.Cursor, "cursor", 0, "cursor returned by a previous query")
+
+	cmd.RegisterFlagCompletionFunc("action", cobra.FixedCompletions([]string{
+		string(model.AuditActionRegister),
+		string(model.AuditActionUpdate),
+		string(model.AuditActionDelete),
+		string(model.AuditActionPause),
+		string(model.AuditActionResume),
+		string(model.AuditActionTrigger),
+	}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
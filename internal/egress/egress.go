@@ -0,0 +1,272 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/ostafen/kronos/internal/config"
+)
+
+var ErrDenied = errors.New("egress denied")
+
+// privateRanges are blocked unless explicitly allowed: they include loopback, private networks,
+// link-local addresses (and thus cloud metadata endpoints), shared and reserved address space, and the NAT64
+// prefixes, whose embedded IPv4 address may be any of them.
+var privateRanges = mustParsePrefixes(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"64:ff9b::/96",
+	"64:ff9b:1::/48",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+func mustParsePrefixes(cidrs ...string) []netip.Prefix {
+	prefixes, err := parsePrefixes(cidrs)
+	if err != nil {
+		panic(err)
+	}
+	return prefixes
+}
+
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		p, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr %s: %w", cidr, err)
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
+
+// Policy decides which webhook targets Kronos is allowed to connect to.
+// A nil policy allows everything.
+type Policy struct {
+	schemes      map[string]bool
+	ports        map[int]bool
+	allowedHosts []string
+	deniedHosts  []string
+	allowedCIDRs []netip.Prefix
+	deniedCIDRs  []netip.Prefix
+}
+
+func NewPolicy(conf config.Egress) (*Policy, error) {
+	allowed, err := parsePrefixes(conf.AllowedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	denied, err := parsePrefixes(conf.DeniedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Policy{
+		schemes:      make(map[string]bool),
+		ports:        make(map[int]bool),
+		allowedHosts: lowerAll(conf.AllowedHosts),
+		deniedHosts:  lowerAll(conf.DeniedHosts),
+		allowedCIDRs: allowed,
+		deniedCIDRs:  denied,
+	}
+
+	for _, scheme := range conf.AllowedSchemes {
+		p.schemes[strings.ToLower(scheme)] = true
+	}
+
+	for _, port := range conf.AllowedPorts {
+		p.ports[port] = true
+	}
+
+	for _, pattern := range append(p.allowedHosts, p.deniedHosts...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid host pattern %s: %w", pattern, err)
+		}
+	}
+	return p, nil
+}
+
+func lowerAll(s []string) []string {
+	res := make([]string, 0, len(s))
+	for _, v := range s {
+		res = append(res, strings.ToLower(v))
+	}
+	return res
+}
+
+func denied(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrDenied, fmt.Sprintf(format, args...))
+}
+
+// CheckURL validates the scheme, host and port of a webhook url. When the host is a literal address,
+// or resolves through the given resolver, its addresses are checked as well.
+// Since DNS records may change over time, addresses are checked again on each connection.
+func (p *Policy) CheckURL(ctx context.Context, resolver *net.Resolver, rawURL string) error {
+	if p == nil {
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url %s: %w", rawURL, err)
+	}
+
+	if len(p.schemes) > 0 && !p.schemes[strings.ToLower(u.Scheme)] {
+		return denied("scheme %q is not allowed", u.Scheme)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return fmt.Errorf("invalid url %s: missing host", rawURL)
+	}
+
+	if err := p.checkHost(host); err != nil {
+		return err
+	}
+
+	port, err := urlPort(u)
+	if err != nil {
+		return err
+	}
+
+	if err := p.checkPort(port); err != nil {
+		return err
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return p.CheckAddr(addr)
+	}
+
+	if resolver == nil {
+		return nil
+	}
+
+	addrs, err := resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		// unresolvable hosts are not rejected, since they may become resolvable later on
+		return nil
+	}
+
+	for _, addr := range addrs {
+		if err := p.CheckAddr(addr); err != nil {
+			return fmt.Errorf("%w (%s resolves to %s)", err, host, addr)
+		}
+	}
+	return nil
+}
+
+func urlPort(u *url.URL) (int, error) {
+	if s := u.Port(); s != "" {
+		port, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid port %s", s)
+		}
+		return port, nil
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+		return 80, nil
+	case "https":
+		return 443, nil
+	}
+	return 0, fmt.Errorf("unable to infer the port of scheme %q", u.Scheme)
+}
+
+func (p *Policy) checkHost(host string) error {
+	for _, pattern := range p.deniedHosts {
+		if ok, _ := path.Match(pattern, host); ok {
+			return denied("host %s is denied", host)
+		}
+	}
+
+	if len(p.allowedHosts) == 0 {
+		return nil
+	}
+
+	for _, pattern := range p.allowedHosts {
+		if ok, _ := path.Match(pattern, host); ok {
+			return nil
+		}
+	}
+	return denied("host %s is not allowed", host)
+}
+
+func (p *Policy) checkPort(port int) error {
+	if len(p.ports) > 0 && !p.ports[port] {
+		return denied("port %d is not allowed", port)
+	}
+	return nil
+}
+
+// CheckAddr validates an address against the configured CIDRs.
+// Denied CIDRs take precedence over allowed ones, which in turn take precedence over the private ranges.
+func (p *Policy) CheckAddr(addr netip.Addr) error {
+	if p == nil {
+		return nil
+	}
+
+	addr = addr.Unmap()
+
+	if containsAddr(p.deniedCIDRs, addr) {
+		return denied("address %s is denied", addr)
+	}
+
+	if containsAddr(p.allowedCIDRs, addr) {
+		return nil
+	}
+
+	if containsAddr(privateRanges, addr) {
+		return denied("address %s belongs to a private or reserved range", addr)
+	}
+	return nil
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Control is meant to be installed as the Control function of a net.Dialer.
+// It is invoked after DNS resolution, right before connecting, which prevents DNS rebinding attacks.
+func (p *Policy) Control(network, address string, _ syscall.RawConn) error {
+	if p == nil {
+		return nil
+	}
+
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return denied("invalid address %s", address)
+	}
+
+	if err := p.checkPort(int(addrPort.Port())); err != nil {
+		return err
+	}
+	return p.CheckAddr(addrPort.Addr())
+}
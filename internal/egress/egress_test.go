@@ -0,0 +1,93 @@
+package egress
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/ostafen/kronos/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckURL(t *testing.T) {
+	policy, err := NewPolicy(config.Egress{
+		AllowedSchemes: []string{"http", "https"},
+		AllowedPorts:   []int{80, 443, 8443},
+		DeniedHosts:    []string{"*.internal.example.com"},
+		AllowedCIDRs:   []string{"10.1.0.0/16"},
+		DeniedCIDRs:    []string{"203.0.113.0/24", "10.1.2.0/24"},
+	})
+	require.NoError(t, err)
+
+	cases := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://hooks.example.com/notify", true},
+		{"http://hooks.example.com:8443/notify", true},
+		{"ftp://hooks.example.com/notify", false},
+		{"gopher://hooks.example.com:80/notify", false},
+		{"http://hooks.example.com:8080/notify", false},
+		{"https://db.internal.example.com/notify", false},
+		{"https://DB.INTERNAL.EXAMPLE.COM/notify", false},
+		{"http://127.0.0.1/notify", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://192.168.1.10/notify", false},
+		{"http://100.64.0.1/notify", false},
+		{"http://[::1]/notify", false},
+		{"http://[fd00:ec2::254]/notify", false},
+		{"http://[::ffff:127.0.0.1]/notify", false},
+		{"http://[64:ff9b::a9fe:a9fe]/notify", false},
+		{"http://[64:ff9b:1::7f00:1]/notify", false},
+		{"http://10.1.0.5/notify", true},
+		{"http://10.1.2.5/notify", false},
+		{"http://10.2.0.5/notify", false},
+		{"http://203.0.113.10/notify", false},
+		{"http://8.8.8.8/notify", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.url, func(t *testing.T) {
+			err := policy.CheckURL(context.Background(), nil, c.url)
+			if c.allowed {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, ErrDenied)
+			}
+		})
+	}
+}
+
+func TestAllowedHosts(t *testing.T) {
+	policy, err := NewPolicy(config.Egress{AllowedHosts: []string{"*.example.com"}})
+	require.NoError(t, err)
+
+	require.NoError(t, policy.CheckURL(context.Background(), nil, "https://hooks.example.com"))
+	require.ErrorIs(t, policy.CheckURL(context.Background(), nil, "https://example.org"), ErrDenied)
+}
+
+func TestInvalidPolicy(t *testing.T) {
+	_, err := NewPolicy(config.Egress{AllowedCIDRs: []string{"10.0.0.0/33"}})
+	require.Error(t, err)
+
+	_, err = NewPolicy(config.Egress{AllowedHosts: []string{"[a-"}})
+	require.Error(t, err)
+}
+
+func TestControl(t *testing.T) {
+	policy, err := NewPolicy(config.Egress{AllowedPorts: []int{443}})
+	require.NoError(t, err)
+
+	require.NoError(t, policy.Control("tcp", "8.8.8.8:443", nil))
+	require.ErrorIs(t, policy.Control("tcp", "8.8.8.8:80", nil), ErrDenied)
+	require.ErrorIs(t, policy.Control("tcp", "127.0.0.1:443", nil), ErrDenied)
+	require.ErrorIs(t, policy.Control("tcp6", "[fe80::1]:443", nil), ErrDenied)
+}
+
+func TestNilPolicy(t *testing.T) {
+	var policy *Policy
+
+	require.NoError(t, policy.CheckURL(context.Background(), nil, "ftp://127.0.0.1"))
+	require.NoError(t, policy.CheckAddr(netip.MustParseAddr("127.0.0.1")))
+	require.NoError(t, policy.Control("tcp", "127.0.0.1:80", nil))
+}
@@ -0,0 +1,232 @@
+// Package ical extracts the days covered by the events of iCalendar (RFC 5545) files,
+// such as the holiday calendars published by banks and calendar applications.
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ostafen/kronos/internal/rrule"
+)
+
+const dateLayout = "2006-01-02"
+
+// Dates returns the days, as YYYY-MM-DD, covered by the events of an iCalendar file. Recurring events,
+// such as yearly holidays, are expanded until the given time. Times without a time zone, and the days of
+// timed events, are evaluated in loc. Cancelled events are ignored.
+func Dates(r io.Reader, loc *time.Location, until time.Time) ([]string, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	days := make(map[string]bool)
+
+	var event []string
+	inEvent := false
+	for i, line := range lines {
+		switch strings.ToUpper(line) {
+		case "BEGIN:VEVENT":
+			if inEvent {
+				return nil, fmt.Errorf("line %d: nested VEVENT", i+1)
+			}
+			inEvent, event = true, nil
+			continue
+		case "END:VEVENT":
+			if !inEvent {
+				return nil, fmt.Errorf("line %d: END:VEVENT without BEGIN:VEVENT", i+1)
+			}
+			inEvent = false
+
+			if err := addEvent(days, event, loc, until); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if inEvent {
+			event = append(event, line)
+		}
+	}
+
+	if inEvent {
+		return nil, errors.New("unterminated VEVENT")
+	}
+
+	dates := make([]string, 0, len(days))
+	for day := range days {
+		dates = append(dates, day)
+	}
+	sort.Strings(dates)
+	return dates, nil
+}
+
+// addEvent adds to days those covered by the occurrences of an event, given its content lines.
+func addEvent(days map[string]bool, lines []string, loc *time.Location, until time.Time) error {
+	var (
+		summary     string
+		start, end  property
+		recurrence  []string
+		isRecurring bool
+	)
+
+	for _, line := range lines {
+		p, err := parseProperty(line)
+		if err != nil {
+			return err
+		}
+
+		switch p.name {
+		case "SUMMARY":
+			summary = p.value
+		case "STATUS":
+			if strings.EqualFold(p.value, "CANCELLED") {
+				return nil
+			}
+		case "DTSTART":
+			start = p
+			if p.params["TZID"] == "" && !strings.HasSuffix(p.value, "Z") {
+				// floating times, which the recurrence evaluates in the zone of DTSTART, refer to loc
+				line = "DTSTART;TZID=" + loc.String() + ":" + p.value
+			}
+			recurrence = append(recurrence, line)
+		case "DTEND":
+			end = p
+		case "RRULE", "RDATE":
+			isRecurring = true
+			recurrence = append(recurrence, line)
+		case "EXDATE":
+			recurrence = append(recurrence, line)
+		}
+	}
+
+	if start.name == "" {
+		return fmt.Errorf("event %q has no DTSTART", summary)
+	}
+
+	first, isDate, err := start.time(loc)
+	if err != nil {
+		return fmt.Errorf("invalid DTSTART of event %q: %w", summary, err)
+	}
+
+	// events last a day if they are dates, and an instant otherwise, unless they have an end
+	length := time.Duration(0)
+	if isDate {
+		length = 24 * time.Hour
+	}
+
+	if end.name != "" {
+		last, _, err := end.time(loc)
+		if err != nil {
+			return fmt.Errorf("invalid DTEND of event %q: %w", summary, err)
+		}
+		length = last.Sub(first)
+	}
+
+	occurrences := []time.Time{first}
+	if isRecurring {
+		set, err := rrule.Parse(strings.Join(recurrence, "\n"), time.Time{})
+		if err != nil {
+			return fmt.Errorf("invalid recurrence of event %q: %w", summary, err)
+		}
+		occurrences = set.All(until)
+	}
+
+	for _, occ := range occurrences {
+		if isDate {
+			// dates are days of the calendar, whatever the zone they have been evaluated in
+			occ = time.Date(occ.Year(), occ.Month(), occ.Day(), 0, 0, 0, 0, loc)
+		}
+		addDays(days, occ.In(loc), length)
+	}
+	return nil
+}
+
+// addDays adds the days from the one of start to the one of start + length, exclusive unless length is zero.
+func addDays(days map[string]bool, start time.Time, length time.Duration) {
+	last := start
+	if length > 0 {
+		last = start.Add(length - time.Nanosecond)
+	}
+
+	for day := start; ; day = day.AddDate(0, 0, 1) {
+		days[day.Format(dateLayout)] = true
+		if day.Format(dateLayout) >= last.Format(dateLayout) {
+			return
+		}
+	}
+}
+
+// unfold reads the content lines of a file, joining those folded over multiple lines.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+func parseProperty(line string) (property, error) {
+	head, value, found := strings.Cut(line, ":")
+	if !found {
+		return property{}, fmt.Errorf("invalid line %q: NAME:VALUE is expected", line)
+	}
+
+	parts := strings.Split(head, ";")
+	p := property{
+		name:   strings.ToUpper(parts[0]),
+		params: make(map[string]string),
+		value:  strings.TrimSpace(value),
+	}
+
+	for _, param := range parts[1:] {
+		name, value, _ := strings.Cut(param, "=")
+		p.params[strings.ToUpper(name)] = strings.Trim(value, `"`)
+	}
+	return p, nil
+}
+
+// time parses the DATE or DATE-TIME value of the property, and reports whether it is a DATE.
+func (p property) time(loc *time.Location) (time.Time, bool, error) {
+	if tzid := p.params["TZID"]; tzid != "" {
+		l, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("unknown TZID %s", tzid)
+		}
+		loc = l
+	}
+
+	switch {
+	case len(p.value) == 8:
+		t, err := time.ParseInLocation("20060102", p.value, loc)
+		return t, true, err
+	case strings.HasSuffix(p.value, "Z"):
+		t, err := time.Parse("20060102T150405Z", p.value)
+		return t, false, err
+	}
+
+	t, err := time.ParseInLocation("20060102T150405", p.value, loc)
+	return t, false, err
+}
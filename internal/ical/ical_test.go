@@ -0,0 +1,96 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func dates(t *testing.T, ics string, until time.Time) []string {
+	loc, err := time.LoadLocation("Europe/Rome")
+	require.NoError(t, err)
+
+	dates, err := Dates(strings.NewReader(ics), loc, until)
+	require.NoError(t, err)
+	return dates
+}
+
+func TestDates(t *testing.T) {
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"SUMMARY:Liberation Day",
+		"DTSTART;VALUE=DATE:20300425",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Christmas holidays",
+		"DTSTART;VALUE=DATE:20301224",
+		"DTEND;VALUE=DATE:20301227",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:New Year",
+		"DTSTART;VALUE=DATE:20300101",
+		"RRULE:FREQ=YEARLY",
+		"EXDATE;VALUE=DATE:20310101",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Cancelled",
+		"DTSTART;VALUE=DATE:20300601",
+		"STATUS:CANCELLED",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	until := time.Date(2032, 6, 1, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, []string{
+		"2030-01-01",
+		"2030-04-25",
+		"2030-12-24",
+		"2030-12-25",
+		"2030-12-26",
+		"2032-01-01",
+	}, dates(t, ics, until))
+}
+
+func TestTimedEvents(t *testing.T) {
+	ics := strings.Join([]string{
+		"BEGIN:VEVENT",
+		// 23:30 UTC is already the following day in Rome
+		"DTSTART:20300301T233000Z",
+		"DTEND:20300302T010000Z",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;TZID=America/New_York:20300310T220000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		// floating times are in Rome
+		"DTSTART:20300320T233000",
+		"DTEND:20300321T003000",
+		"END:VEVENT",
+	}, "\n")
+
+	require.Equal(t, []string{"2030-03-02", "2030-03-11", "2030-03-20", "2030-03-21"}, dates(t, ics, time.Time{}))
+}
+
+func TestFoldedLines(t *testing.T) {
+	ics := "BEGIN:VEVENT\nSUMMARY:A long\n  summary\nDTSTART;VALUE=DATE:2030\n 0704\nEND:VEVENT\n"
+
+	require.Equal(t, []string{"2030-07-04"}, dates(t, ics, time.Time{}))
+}
+
+func TestInvalid(t *testing.T) {
+	for _, ics := range []string{
+		"BEGIN:VEVENT\nSUMMARY:no start\nEND:VEVENT",
+		"BEGIN:VEVENT\nDTSTART:2030\nEND:VEVENT",
+		"BEGIN:VEVENT\nDTSTART;VALUE=DATE:20300101",
+		"BEGIN:VEVENT\nBEGIN:VEVENT\nDTSTART;VALUE=DATE:20300101\nEND:VEVENT",
+		"BEGIN:VEVENT\nDTSTART;TZID=Mars/Olympus:20300101T000000\nEND:VEVENT",
+		"BEGIN:VEVENT\nDTSTART;VALUE=DATE:20300101\nRRULE:FREQ=SOMETIMES\nEND:VEVENT",
+	} {
+		_, err := Dates(strings.NewReader(ics), time.UTC, time.Now())
+		require.Error(t, err, ics)
+	}
+}
@@ -2,40 +2,194 @@ package metrics
 
 import (
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-var webhookRequestsTotal = prometheus.NewCounterVec(
+const namespace = "kronos"
+
+var webhookDeliveriesTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Total number of webhook deliveries, by schedule and response status class",
+	},
+	[]string{"schedule", "status_class"},
+)
+
+var webhookRetriesTotal = prometheus.NewCounterVec(
 	prometheus.CounterOpts{
-		Name: "webhook_requests_total",
-		Help: "Total number of webhook requests",
+		Namespace: namespace,
+		Name:      "webhook_retries_total",
+		Help:      "Total number of retried webhook requests, by reason",
 	},
-	[]string{"url", "code"},
+	[]string{"reason"},
+)
+
+var webhookDeliveryDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "webhook_delivery_duration_seconds",
+		Help:      "Duration of webhook deliveries, by response status class",
+		Buckets:   prometheus.DefBuckets,
+	},
+	[]string{"status_class"},
 )
 
 var scheduleFailures = prometheus.NewGaugeVec(
 	prometheus.GaugeOpts{
-		Name: "webhook_notifications",
-		Help: "Total number of webhook notifications",
+		Namespace: namespace,
+		Name:      "schedule_consecutive_failures",
+		Help:      "Number of consecutive failed deliveries of a schedule",
+	},
+	[]string{"schedule"},
+)
+
+var schedulerLag = prometheus.NewHistogram(
+	prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "scheduler_lag_seconds",
+		Help:      "Delay between the planned and the actual fire time of a schedule",
+		Buckets:   []float64{.001, .005, .01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
 	},
-	[]string{"id"},
 )
 
-// schedule_failures_total, schedule_success_total
+var storeOperationDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "store_operation_duration_seconds",
+		Help:      "Duration of store operations, by repository and operation",
+		Buckets:   []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1},
+	},
+	[]string{"repository", "operation"},
+)
+
+var (
+	schedulesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "schedules"),
+		"Number of schedules, by status",
+		[]string{"status"},
+		nil,
+	)
+
+	queueDepthDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "scheduler_queue_depth"),
+		"Number of schedules waiting to be fired",
+		nil,
+		nil,
+	)
+)
 
 func init() {
-	prometheus.MustRegister(webhookRequestsTotal, scheduleFailures)
+	prometheus.MustRegister(
+		webhookDeliveriesTotal,
+		webhookRetriesTotal,
+		webhookDeliveryDuration,
+		scheduleFailures,
+		schedulerLag,
+		storeOperationDuration,
+		&engineCollector{},
+	)
+}
+
+// StatusClass maps an HTTP status code to a low-cardinality label value ("2xx", "4xx", ...).
+// Codes outside of the HTTP range are reported as "error".
+func StatusClass(code int) string {
+	if code < 100 || code > 599 {
+		return "error"
+	}
+	return strconv.Itoa(code/100) + "xx"
+}
+
+func ObserveWebhookDelivery(scheduleID int64, code int, duration time.Duration) {
+	class := StatusClass(code)
+
+	webhookDeliveriesTotal.WithLabelValues(strconv.FormatInt(scheduleID, 10), class).Inc()
+	webhookDeliveryDuration.WithLabelValues(class).Observe(duration.Seconds())
+}
+
+func IncWebhookRetries(reason string) {
+	webhookRetriesTotal.WithLabelValues(reason).Inc()
+}
+
+func IncScheduleFailures(scheduleID int64) {
+	scheduleFailures.WithLabelValues(strconv.FormatInt(scheduleID, 10)).Inc()
+}
+
+func ResetScheduleFailures(scheduleID int64) {
+	scheduleFailures.WithLabelValues(strconv.FormatInt(scheduleID, 10)).Set(0)
+}
+
+// ForgetSchedule drops all the series labeled with the given schedule.
+func ForgetSchedule(scheduleID int64) {
+	id := strconv.FormatInt(scheduleID, 10)
+
+	scheduleFailures.DeleteLabelValues(id)
+	webhookDeliveriesTotal.DeletePartialMatch(prometheus.Labels{"schedule": id})
+}
+
+func ObserveSchedulerLag(lag time.Duration) {
+	schedulerLag.Observe(lag.Seconds())
+}
+
+// ObserveStoreOperation is meant to be deferred at the beginning of a store operation.
+func ObserveStoreOperation(repository, operation string, start time.Time) {
+	storeOperationDuration.WithLabelValues(repository, operation).Observe(time.Since(start).Seconds())
 }
 
-func IncWebhookRequests(url string, code int) {
-	webhookRequestsTotal.WithLabelValues(url, strconv.Itoa(code)).Inc()
+// engineCallbacks compute the metrics of an engine at scrape time.
+type engineCallbacks struct {
+	countSchedules func() (map[string]int, error)
+	queueDepth     func() int
 }
 
-func IncScheduleFailures(id string) {
-	scheduleFailures.WithLabelValues(id).Inc()
+var (
+	mtx    sync.RWMutex
+	engine *engineCallbacks
+)
+
+// SetEngine installs the callbacks used to compute, at scrape time,
+// the number of schedules by status and the depth of the scheduler queue.
+// Metrics are process-wide, so they describe the engine installed last. The returned function uninstalls
+// its callbacks, unless those of another engine replaced them in the meantime.
+func SetEngine(schedules func() (map[string]int, error), depth func() int) (release func()) {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	callbacks := &engineCallbacks{countSchedules: schedules, queueDepth: depth}
+	engine = callbacks
+
+	return func() {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		if engine == callbacks {
+			engine = nil
+		}
+	}
 }
 
-func ResetScheduleFailures(id string) {
-	scheduleFailures.WithLabelValues(id).Set(0)
+type engineCollector struct{}
+
+func (c *engineCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- schedulesDesc
+	ch <- queueDepthDesc
+}
+
+func (c *engineCollector) Collect(ch chan<- prometheus.Metric) {
+	mtx.RLock()
+	defer mtx.RUnlock()
+
+	if engine == nil {
+		return
+	}
+
+	if counts, err := engine.countSchedules(); err == nil {
+		for status, n := range counts {
+			ch <- prometheus.MustNewConstMetric(schedulesDesc, prometheus.GaugeValue, float64(n), status)
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(engine.queueDepth()))
 }
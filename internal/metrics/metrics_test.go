@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+// observations returns the number of samples observed by a histogram.
+func observations(t *testing.T, h prometheus.Observer) uint64 {
+	var m dto.Metric
+	require.NoError(t, h.(prometheus.Metric).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestStatusClass(t *testing.T) {
+	require.Equal(t, "2xx", StatusClass(204))
+	require.Equal(t, "5xx", StatusClass(503))
+	require.Equal(t, "error", StatusClass(0))
+	require.Equal(t, "error", StatusClass(600))
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	deliveries := webhookDeliveriesTotal.WithLabelValues("1001", "2xx")
+	durations := webhookDeliveryDuration.WithLabelValues("2xx")
+	before := observations(t, durations)
+
+	ObserveWebhookDelivery(1001, 200, 10*time.Millisecond)
+	ObserveWebhookDelivery(1001, 201, 20*time.Millisecond)
+	ObserveWebhookDelivery(1001, 500, 30*time.Millisecond)
+
+	require.Equal(t, 2.0, testutil.ToFloat64(deliveries))
+	require.Equal(t, 1.0, testutil.ToFloat64(webhookDeliveriesTotal.WithLabelValues("1001", "5xx")))
+	require.Equal(t, before+2, observations(t, durations))
+
+	IncScheduleFailures(1001)
+	IncScheduleFailures(1001)
+	require.Equal(t, 2.0, testutil.ToFloat64(scheduleFailures.WithLabelValues("1001")))
+
+	ResetScheduleFailures(1001)
+	require.Zero(t, testutil.ToFloat64(scheduleFailures.WithLabelValues("1001")))
+
+	// the series of deleted schedules are dropped
+	require.Contains(t, seriesOf(t, webhookDeliveriesTotal), "1001")
+	ForgetSchedule(1001)
+	require.NotContains(t, seriesOf(t, webhookDeliveriesTotal), "1001")
+	require.NotContains(t, seriesOf(t, scheduleFailures), "1001")
+}
+
+// seriesOf returns the exposition of the series collected by c.
+func seriesOf(t *testing.T, c prometheus.Collector) string {
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, reg.Register(c))
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	var b strings.Builder
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			b.WriteString(m.String())
+		}
+	}
+	return b.String()
+}
+
+func TestWebhookRetries(t *testing.T) {
+	retries := webhookRetriesTotal.WithLabelValues("unauthorized")
+	before := testutil.ToFloat64(retries)
+
+	IncWebhookRetries("unauthorized")
+	require.Equal(t, before+1, testutil.ToFloat64(retries))
+}
+
+func TestSchedulerLag(t *testing.T) {
+	before := observations(t, schedulerLag)
+
+	ObserveSchedulerLag(30 * time.Millisecond)
+	require.Equal(t, before+1, observations(t, schedulerLag))
+}
+
+func TestStoreOperations(t *testing.T) {
+	latency := storeOperationDuration.WithLabelValues("schedules", "get")
+	before := observations(t, latency)
+
+	ObserveStoreOperation("schedules", "get", time.Now().Add(-time.Millisecond))
+	require.Equal(t, before+1, observations(t, latency))
+}
+
+func TestEngineCollector(t *testing.T) {
+	stale := SetEngine(
+		func() (map[string]int, error) { return map[string]int{"active": 1}, nil },
+		func() int { return 1 },
+	)
+
+	release := SetEngine(
+		func() (map[string]int, error) { return map[string]int{"active": 3, "paused": 1}, nil },
+		func() int { return 2 },
+	)
+
+	// releasing a replaced engine leaves the current one in place
+	stale()
+
+	expected := `
+# HELP kronos_schedules Number of schedules, by status
+# TYPE kronos_schedules gauge
+kronos_schedules{status="active"} 3
+kronos_schedules{status="paused"} 1
+# HELP kronos_scheduler_queue_depth Number of schedules waiting to be fired
+# TYPE kronos_scheduler_queue_depth gauge
+kronos_scheduler_queue_depth 2
+`
+	require.NoError(t, testutil.CollectAndCompare(&engineCollector{}, strings.NewReader(expected)))
+
+	release()
+	require.Zero(t, testutil.CollectAndCount(&engineCollector{}))
+}
@@ -0,0 +1,288 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ostafen/kronos/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrUnknownCredentials    = errors.New("unknown credentials")
+	ErrCredentialsNotAllowed = errors.New("credentials not allowed")
+)
+
+// ExpiryDelta is how long before their expiration tokens are refreshed,
+// so that they don't expire while a delivery is in flight.
+var ExpiryDelta = 30 * time.Second
+
+const (
+	authStyleHeader = "HEADER"
+	authStyleParams = "PARAMS"
+
+	tokenTimeout = 30 * time.Second
+)
+
+type token struct {
+	accessToken string
+	// expiry is zero if the token endpoint didn't report any lifetime.
+	expiry time.Time
+}
+
+func (t *token) valid() bool {
+	return t != nil && (t.expiry.IsZero() || time.Now().Add(ExpiryDelta).Before(t.expiry))
+}
+
+// TokenSource obtains bearer tokens through the OAuth2 client credentials grant, and caches them until they expire.
+type TokenSource struct {
+	name      string
+	conf      config.OAuth2Credentials
+	authStyle string
+	client    *http.Client
+	// allowedURLs are the parsed url prefixes tokens may be sent to.
+	allowedURLs []*url.URL
+
+	mtx   sync.Mutex
+	token *token
+}
+
+func NewTokenSource(name string, conf config.OAuth2Credentials) (*TokenSource, error) {
+	if conf.TokenURL == "" || conf.ClientID == "" {
+		return nil, fmt.Errorf("credentials %q: tokenURL and clientID are required", name)
+	}
+
+	if _, err := url.ParseRequestURI(conf.TokenURL); err != nil {
+		return nil, fmt.Errorf("credentials %q: invalid tokenURL: %w", name, err)
+	}
+
+	if len(conf.AllowedURLs) == 0 {
+		return nil, fmt.Errorf("credentials %q: allowedURLs is required", name)
+	}
+
+	allowedURLs := make([]*url.URL, 0, len(conf.AllowedURLs))
+	for _, prefix := range conf.AllowedURLs {
+		u, err := url.Parse(prefix)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("credentials %q: invalid allowed url %q: an absolute url is expected", name, prefix)
+		}
+		allowedURLs = append(allowedURLs, u)
+	}
+
+	authStyle := strings.ToUpper(conf.AuthStyle)
+	switch authStyle {
+	case "":
+		authStyle = authStyleHeader
+	case authStyleHeader, authStyleParams:
+	default:
+		return nil, fmt.Errorf("credentials %q: invalid authStyle %s: one of HEADER or PARAMS is expected", name, conf.AuthStyle)
+	}
+
+	return &TokenSource{
+		name:        name,
+		conf:        conf,
+		authStyle:   authStyle,
+		client:      &http.Client{Timeout: tokenTimeout},
+		allowedURLs: allowedURLs,
+	}, nil
+}
+
+func (s *TokenSource) Name() string {
+	return s.name
+}
+
+// Allows checks that the tokens of the credentials may be sent to the given url, on behalf of a schedule of the given namespace.
+// The url must have the scheme and host of an allowed url, and a path starting with its path, at a segment boundary.
+func (s *TokenSource) Allows(rawURL, namespace string) error {
+	if len(s.conf.AllowedNamespaces) > 0 && !containsFold(s.conf.AllowedNamespaces, namespace) {
+		return fmt.Errorf("%w: %q can't be used in namespace %q", ErrCredentialsNotAllowed, s.name, namespace)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	for _, prefix := range s.allowedURLs {
+		if strings.EqualFold(u.Scheme, prefix.Scheme) && strings.EqualFold(u.Host, prefix.Host) && hasPathPrefix(cleanPath(u.Path), prefix.Path) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q can't be sent to %s", ErrCredentialsNotAllowed, s.name, u.Redacted())
+}
+
+// cleanPath resolves the dot segments of a url path, keeping its trailing slash, so that a path such as
+// /hooks/../admin can't pass for one below /hooks/.
+func cleanPath(p string) string {
+	if p == "" {
+		return p
+	}
+
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// hasPathPrefix reports whether path is prefix, or lies below it.
+func hasPathPrefix(path, prefix string) bool {
+	if prefix == "" || prefix == "/" {
+		return true
+	}
+
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Token returns the cached access token, fetching a new one if missing or about to expire.
+func (s *TokenSource) Token(ctx context.Context) (string, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.token.valid() {
+		return s.token.accessToken, nil
+	}
+
+	t, err := s.fetch(ctx)
+	if err != nil {
+		return "", fmt.Errorf("credentials %q: %w", s.name, err)
+	}
+
+	log.WithField("credentials", s.name).
+		WithField("expiry", t.expiry).
+		Debug("access token obtained")
+
+	s.token = t
+	return t.accessToken, nil
+}
+
+// Invalidate discards the given access token, if still cached, so that the next call to Token fetches a new one.
+// It is meant to be called when a receiver rejects the token, which may have been revoked before its expiration.
+func (s *TokenSource) Invalidate(accessToken string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.token != nil && s.token.accessToken == accessToken {
+		s.token = nil
+	}
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func (s *TokenSource) fetch(ctx context.Context) (*token, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(s.conf.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.conf.Scopes, " "))
+	}
+
+	if s.authStyle == authStyleParams {
+		form.Set("client_id", s.conf.ClientID)
+		form.Set("client_secret", s.conf.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.conf.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if s.authStyle == authStyleHeader {
+		// client credentials are form encoded before being sent through basic authentication (RFC 6749, section 2.3.1)
+		req.SetBasicAuth(url.QueryEscape(s.conf.ClientID), url.QueryEscape(s.conf.ClientSecret))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("token endpoint failed with status: %s", resp.Status)
+	}
+
+	var res tokenResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, fmt.Errorf("invalid token response: %w", err)
+	}
+
+	if res.AccessToken == "" {
+		return nil, fmt.Errorf("invalid token response: missing access_token")
+	}
+
+	if res.TokenType != "" && !strings.EqualFold(res.TokenType, "bearer") {
+		return nil, fmt.Errorf("unsupported token type %s", res.TokenType)
+	}
+
+	t := &token{accessToken: res.AccessToken}
+	if res.ExpiresIn > 0 {
+		t.expiry = time.Now().Add(time.Duration(res.ExpiresIn) * time.Second)
+	}
+	return t, nil
+}
+
+// Registry holds the named credentials schedules can reference.
+type Registry struct {
+	sources map[string]*TokenSource
+}
+
+func NewRegistry(conf config.Webhooks) (*Registry, error) {
+	r := &Registry{
+		sources: make(map[string]*TokenSource),
+	}
+
+	for name, credentialsConf := range conf.Credentials {
+		s, err := NewTokenSource(name, credentialsConf)
+		if err != nil {
+			return nil, err
+		}
+		// viper lowercases map keys, which makes names case insensitive
+		r.sources[strings.ToLower(name)] = s
+	}
+	return r, nil
+}
+
+// Get returns the token source of the given credentials, or nil if name is empty.
+func (r *Registry) Get(name string) (*TokenSource, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	if r != nil {
+		if s, has := r.sources[strings.ToLower(name)]; has {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownCredentials, name)
+}
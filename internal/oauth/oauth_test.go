@@ -0,0 +1,209 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ostafen/kronos/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// newTokenServer issues tokens numbered by request, expiring after the given number of seconds.
+func newTokenServer(t *testing.T, expiresIn int, check func(r *http.Request)) (*httptest.Server, *atomic.Int32) {
+	var issued atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		check(r)
+
+		n := issued.Add(1)
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "Bearer",
+			"expires_in":   expiresIn,
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &issued
+}
+
+var allowedURLs = []string{"https://billing.example.com/hooks"}
+
+func TestTokenCaching(t *testing.T) {
+	server, issued := newTokenServer(t, 3600, func(r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "kronos", id)
+		require.Equal(t, "s%3Acret", secret)
+		require.Equal(t, "jobs:write jobs:read", r.PostForm.Get("scope"))
+	})
+
+	s, err := NewTokenSource("billing", config.OAuth2Credentials{
+		TokenURL:     server.URL,
+		ClientID:     "kronos",
+		ClientSecret: "s:cret",
+		AllowedURLs:  allowedURLs,
+		Scopes:       []string{"jobs:write", "jobs:read"},
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		token, err := s.Token(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "token-1", token)
+	}
+	require.Equal(t, int32(1), issued.Load())
+
+	// invalidating a stale token doesn't discard the current one
+	s.Invalidate("token-0")
+
+	token, err := s.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "token-1", token)
+
+	s.Invalidate("token-1")
+
+	token, err = s.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "token-2", token)
+}
+
+func TestTokenRefreshBeforeExpiry(t *testing.T) {
+	server, issued := newTokenServer(t, 20, func(r *http.Request) {
+		require.Equal(t, "kronos", r.PostForm.Get("client_id"))
+		require.Equal(t, "secret", r.PostForm.Get("client_secret"))
+	})
+
+	s, err := NewTokenSource("billing", config.OAuth2Credentials{
+		TokenURL:     server.URL,
+		ClientID:     "kronos",
+		ClientSecret: "secret",
+		AuthStyle:    "params",
+		AllowedURLs:  allowedURLs,
+	})
+	require.NoError(t, err)
+
+	// tokens expiring within ExpiryDelta are never reused
+	_, err = s.Token(context.Background())
+	require.NoError(t, err)
+
+	_, err = s.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int32(2), issued.Load())
+
+	expiryDelta := ExpiryDelta
+	ExpiryDelta = 10 * time.Second
+	t.Cleanup(func() { ExpiryDelta = expiryDelta })
+
+	token, err := s.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "token-2", token)
+	require.Equal(t, int32(2), issued.Load())
+}
+
+func TestTokenEndpointErrors(t *testing.T) {
+	cases := []func(w http.ResponseWriter){
+		func(w http.ResponseWriter) { http.Error(w, `{"error": "invalid_client"}`, http.StatusUnauthorized) },
+		func(w http.ResponseWriter) { w.Write([]byte(`{"token_type": "Bearer"}`)) },
+		func(w http.ResponseWriter) { w.Write([]byte(`{"access_token": "t", "token_type": "mac"}`)) },
+		func(w http.ResponseWriter) { w.Write([]byte(`not json`)) },
+	}
+
+	for _, c := range cases {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { c(w) }))
+
+		s, err := NewTokenSource("billing", config.OAuth2Credentials{TokenURL: server.URL, ClientID: "kronos", AllowedURLs: allowedURLs})
+		require.NoError(t, err)
+
+		_, err = s.Token(context.Background())
+		require.Error(t, err)
+
+		server.Close()
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	_, err := NewRegistry(config.Webhooks{
+		Credentials: map[string]config.OAuth2Credentials{"billing": {ClientID: "kronos"}},
+	})
+	require.Error(t, err)
+
+	_, err = NewRegistry(config.Webhooks{
+		Credentials: map[string]config.OAuth2Credentials{"billing": {TokenURL: "https://auth.example.com/token", ClientID: "kronos", AuthStyle: "cookie", AllowedURLs: allowedURLs}},
+	})
+	require.Error(t, err)
+
+	_, err = NewRegistry(config.Webhooks{
+		Credentials: map[string]config.OAuth2Credentials{"billing": {TokenURL: "https://auth.example.com/token", ClientID: "kronos"}},
+	})
+	require.Error(t, err)
+
+	r, err := NewRegistry(config.Webhooks{
+		Credentials: map[string]config.OAuth2Credentials{"billing": {TokenURL: "https://auth.example.com/token", ClientID: "kronos", AllowedURLs: allowedURLs}},
+	})
+	require.NoError(t, err)
+
+	s, err := r.Get("")
+	require.NoError(t, err)
+	require.Nil(t, s)
+
+	s, err = r.Get("Billing")
+	require.NoError(t, err)
+	require.Equal(t, "billing", s.Name())
+
+	_, err = r.Get("shipping")
+	require.ErrorIs(t, err, ErrUnknownCredentials)
+
+	var none *Registry
+	_, err = none.Get("billing")
+	require.ErrorIs(t, err, ErrUnknownCredentials)
+}
+
+func TestAllows(t *testing.T) {
+	s, err := NewTokenSource("billing", config.OAuth2Credentials{
+		TokenURL:          "https://auth.example.com/token",
+		ClientID:          "kronos",
+		AllowedURLs:       []string{"https://billing.example.com/hooks", "http://localhost:8080/"},
+		AllowedNamespaces: []string{"billing"},
+	})
+	require.NoError(t, err)
+
+	for _, u := range []string{
+		"https://billing.example.com/hooks",
+		"https://Billing.example.com/hooks/invoices?id=1",
+		"http://localhost:8080/anything",
+		"https://billing.example.com/hooks/./invoices/",
+	} {
+		require.NoError(t, s.Allows(u, "billing"), u)
+	}
+
+	for _, u := range []string{
+		"https://attacker.example/hooks",
+		"https://billing.example.com.attacker.example/hooks",
+		"https://billing.example.com/hooksmith",
+		"https://billing.example.com/other",
+		"http://billing.example.com/hooks",
+		"http://localhost:8081/",
+		"https://billing.example.com/hooks/../admin",
+		"https://billing.example.com/hooks/%2e%2e/admin",
+	} {
+		require.ErrorIs(t, s.Allows(u, "billing"), ErrCredentialsNotAllowed, u)
+	}
+
+	require.ErrorIs(t, s.Allows("https://billing.example.com/hooks", "default"), ErrCredentialsNotAllowed)
+
+	_, err = NewTokenSource("billing", config.OAuth2Credentials{
+		TokenURL:    "https://auth.example.com/token",
+		ClientID:    "kronos",
+		AllowedURLs: []string{"billing.example.com"},
+	})
+	require.Error(t, err)
+}
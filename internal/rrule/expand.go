@@ -0,0 +1,317 @@
+package rrule
+
+import (
+	"sort"
+	"time"
+)
+
+// searchYears bounds the search of the next occurrence of rules which may never recur,
+// such as FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30.
+const searchYears = 100
+
+var units = map[Frequency]time.Duration{
+	Hourly:   time.Hour,
+	Minutely: time.Minute,
+	Secondly: time.Second,
+}
+
+// iterate calls yield with the occurrences of the rule in chronological order, until it returns false.
+// Periods ending before after are skipped when possible, that is when the rule has no COUNT.
+func (r *Rule) iterate(dtstart, after time.Time, yield func(time.Time) bool) {
+	loc := dtstart.Location()
+	base := r.periodStart(dtstart)
+
+	from := dtstart
+	if after.After(from) {
+		from = after.In(loc)
+	}
+	limit := from.AddDate(searchYears, 0, 0)
+
+	k := 0
+	if r.Count == 0 && after.After(dtstart) {
+		k = r.periodsBetween(base, from) / r.Interval * r.Interval
+	}
+
+	count := 0
+	for {
+		p := r.period(base, k)
+		if p.After(limit) || (!r.Until.IsZero() && p.After(r.Until)) {
+			return
+		}
+
+		// rules more frequent than daily skip whole days which don't match
+		if r.Freq < Daily && !r.dayMatches(dateOf(p)) {
+			k = r.indexAtOrAfter(base, k, time.Date(p.Year(), p.Month(), p.Day()+1, 0, 0, 0, 0, loc))
+			continue
+		}
+
+		for _, occ := range r.occurrences(p) {
+			if occ.Before(dtstart) {
+				continue
+			}
+
+			if !r.Until.IsZero() && occ.After(r.Until) {
+				return
+			}
+
+			count++
+			if !yield(occ) || (r.Count > 0 && count >= r.Count) {
+				return
+			}
+		}
+		k += r.Interval
+	}
+}
+
+// periodStart returns the start of the period, of the frequency of the rule, which contains t.
+func (r *Rule) periodStart(t time.Time) time.Time {
+	loc := t.Location()
+	switch r.Freq {
+	case Yearly:
+		return time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, loc)
+	case Monthly:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	case Weekly:
+		offset := (int(t.Weekday()) - int(r.WeekStart) + 7) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, loc)
+	case Daily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	case Hourly:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	case Minutely:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+	}
+	return t.Truncate(time.Second)
+}
+
+// period returns the start of the k-th period following the one starting at base. Periods longer than an hour
+// follow the calendar, while shorter ones are a fixed duration, so that they are not affected by daylight saving.
+func (r *Rule) period(base time.Time, k int) time.Time {
+	switch r.Freq {
+	case Yearly:
+		return time.Date(base.Year()+k, time.January, 1, 0, 0, 0, 0, base.Location())
+	case Monthly:
+		return time.Date(base.Year(), base.Month()+time.Month(k), 1, 0, 0, 0, 0, base.Location())
+	case Weekly:
+		return time.Date(base.Year(), base.Month(), base.Day()+7*k, 0, 0, 0, 0, base.Location())
+	case Daily:
+		return time.Date(base.Year(), base.Month(), base.Day()+k, 0, 0, 0, 0, base.Location())
+	}
+	return base.Add(time.Duration(k) * units[r.Freq])
+}
+
+// periodsBetween returns the number of periods between base and the one containing t.
+func (r *Rule) periodsBetween(base, t time.Time) int {
+	switch r.Freq {
+	case Yearly:
+		return t.Year() - base.Year()
+	case Monthly:
+		return (t.Year()-base.Year())*12 + int(t.Month()-base.Month())
+	case Weekly:
+		return daysBetween(dateOf(base), dateOf(t)) / 7
+	case Daily:
+		return daysBetween(dateOf(base), dateOf(t))
+	}
+	return int(t.Sub(base) / units[r.Freq])
+}
+
+// indexAtOrAfter returns the index, following k, of the first period of the rule starting at or after t.
+// It is only used for frequencies shorter than a day.
+func (r *Rule) indexAtOrAfter(base time.Time, k int, t time.Time) int {
+	step := time.Duration(r.Interval) * units[r.Freq]
+	next := int((t.Sub(base)+step-1)/step) * r.Interval
+	if next <= k {
+		return k + r.Interval
+	}
+	return next
+}
+
+// occurrences returns the occurrences of the period starting at p, in chronological order.
+func (r *Rule) occurrences(p time.Time) []time.Time {
+	var res []time.Time
+
+	switch {
+	case r.Freq >= Daily:
+		for _, d := range r.days(p) {
+			for _, hour := range r.ByHour {
+				for _, minute := range r.ByMinute {
+					for _, second := range r.BySecond {
+						res = append(res, time.Date(d.year, d.month, d.day, hour, minute, second, 0, p.Location()))
+					}
+				}
+			}
+		}
+	// shorter periods are limited by the BY* parts of the longer ones, and are expanded by those of the shorter ones
+	case r.Freq == Hourly:
+		if matches(r.ByHour, p.Hour()) {
+			for _, minute := range r.ByMinute {
+				for _, second := range r.BySecond {
+					res = append(res, p.Add(time.Duration(minute)*time.Minute+time.Duration(second)*time.Second))
+				}
+			}
+		}
+	case r.Freq == Minutely:
+		if matches(r.ByHour, p.Hour()) && matches(r.ByMinute, p.Minute()) {
+			for _, second := range r.BySecond {
+				res = append(res, p.Add(time.Duration(second)*time.Second))
+			}
+		}
+	default:
+		if matches(r.ByHour, p.Hour()) && matches(r.ByMinute, p.Minute()) && matches(r.BySecond, p.Second()) {
+			res = append(res, p)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Before(res[j]) })
+	return r.selectSetPos(res)
+}
+
+// days returns the days of the period starting at p which match the rule.
+func (r *Rule) days(p time.Time) []civilDate {
+	first, n := dateOf(p), 1
+	switch r.Freq {
+	case Yearly:
+		n = daysInYear(p.Year())
+	case Monthly:
+		n = daysIn(p.Year(), p.Month())
+	case Weekly:
+		n = 7
+	}
+
+	var res []civilDate
+	for i := 0; i < n; i++ {
+		d := first.addDays(i)
+		if r.dayMatches(d) {
+			res = append(res, d)
+		}
+	}
+	return res
+}
+
+func (r *Rule) dayMatches(d civilDate) bool {
+	t := d.time()
+
+	switch {
+	case r.ByMonth != nil && !matches(r.ByMonth, int(d.month)):
+		return false
+	case r.ByWeekNo != nil && !r.weekNoMatches(t):
+		return false
+	case r.ByYearDay != nil && !matchesFromEnd(r.ByYearDay, t.YearDay(), daysInYear(d.year)):
+		return false
+	case r.ByMonthDay != nil && !matchesFromEnd(r.ByMonthDay, d.day, daysIn(d.year, d.month)):
+		return false
+	case r.ByDay != nil && !r.weekdayMatches(t):
+		return false
+	}
+	return true
+}
+
+func (r *Rule) weekdayMatches(t time.Time) bool {
+	for _, wd := range r.ByDay {
+		if wd.Weekday != t.Weekday() {
+			continue
+		}
+
+		if wd.N == 0 {
+			return true
+		}
+
+		// numbered weekdays count within the year for yearly rules without BYMONTH, and within the month otherwise
+		day, n := t.Day(), daysIn(t.Year(), t.Month())
+		if r.Freq == Yearly && r.ByMonth == nil {
+			day, n = t.YearDay(), daysInYear(t.Year())
+		}
+
+		if wd.N == (day-1)/7+1 || wd.N == -((n-day)/7+1) {
+			return true
+		}
+	}
+	return false
+}
+
+// weekNoMatches reports whether the week of t is one of BYWEEKNO. Weeks start on WKST, and the first week
+// of a year is the first one with at least four days in the year, as in ISO 8601.
+func (r *Rule) weekNoMatches(t time.Time) bool {
+	year := t.Year()
+	start := week1Start(year, r.WeekStart)
+	if t.Before(start) {
+		year--
+		start = week1Start(year, r.WeekStart)
+	} else if next := week1Start(year+1, r.WeekStart); !t.Before(next) {
+		year++
+		start = next
+	}
+
+	week := daysBetween(dateOf(start), dateOf(t))/7 + 1
+	weeks := daysBetween(dateOf(start), dateOf(week1Start(year+1, r.WeekStart))) / 7
+	return matchesFromEnd(r.ByWeekNo, week, weeks)
+}
+
+func week1Start(year int, weekStart time.Weekday) time.Time {
+	jan1 := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	offset := (int(jan1.Weekday()) - int(weekStart) + 7) % 7
+	if offset <= 3 {
+		return jan1.AddDate(0, 0, -offset)
+	}
+	return jan1.AddDate(0, 0, 7-offset)
+}
+
+// selectSetPos returns the occurrences of a period selected by BYSETPOS.
+func (r *Rule) selectSetPos(occurrences []time.Time) []time.Time {
+	if r.BySetPos == nil {
+		return occurrences
+	}
+
+	var res []time.Time
+	for i, occ := range occurrences {
+		if matchesFromEnd(r.BySetPos, i+1, len(occurrences)) {
+			res = append(res, occ)
+		}
+	}
+	return res
+}
+
+// matches reports whether v is in values, or whether values is nil.
+func matches(values []int, v int) bool {
+	if values == nil {
+		return true
+	}
+
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesFromEnd reports whether the v-th of n elements is in values, where negative values count from the end.
+func matchesFromEnd(values []int, v, n int) bool {
+	for _, value := range values {
+		if value == v || (value < 0 && n+1+value == v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d civilDate) time() time.Time {
+	return time.Date(d.year, d.month, d.day, 0, 0, 0, 0, time.UTC)
+}
+
+func (d civilDate) addDays(n int) civilDate {
+	return dateOf(d.time().AddDate(0, 0, n))
+}
+
+func daysBetween(from, to civilDate) int {
+	return int(to.time().Sub(from.time()) / (24 * time.Hour))
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func daysInYear(year int) int {
+	return time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+}
@@ -0,0 +1,293 @@
+// Package rrule evaluates RFC 5545 recurrences, made of an RRULE, optionally together with RDATE and EXDATE
+// properties, starting from a DTSTART.
+//
+// A recurrence is written either as a bare RRULE value, such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH",
+// whose DTSTART is given separately, or as iCalendar content lines:
+//
+//	DTSTART;TZID=America/New_York:19970902T090000
+//	RRULE:FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13
+//	EXDATE;TZID=America/New_York:19980213T090000
+//
+// As in most implementations, DTSTART is an occurrence only if it matches the rule, while it always bounds
+// the occurrences from below. Times without a TZID or a trailing Z are evaluated in the time zone of DTSTART.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Set is a recurrence set: the occurrences of its rule and its RDATEs, except for its EXDATEs.
+type Set struct {
+	DTStart time.Time
+	Rule    *Rule
+	RDates  []time.Time
+	ExDates []time.Time
+	// exDays holds the dates of the EXDATEs with a DATE value, which exclude whole days.
+	exDays map[civilDate]bool
+}
+
+// Parse parses a recurrence. dtstart is used if the recurrence doesn't define its own DTSTART.
+func Parse(text string, dtstart time.Time) (*Set, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, errors.New("empty recurrence")
+	}
+
+	if strings.HasPrefix(strings.ToUpper(text), "FREQ=") {
+		text = "RRULE:" + text
+	}
+
+	var (
+		set       = &Set{exDays: make(map[civilDate]bool)}
+		ruleValue string
+		exDates   []contentLine
+		rDates    []contentLine
+	)
+
+	for _, line := range unfold(text) {
+		cl, err := parseContentLine(line)
+		if err != nil {
+			return nil, err
+		}
+
+		switch cl.name {
+		case "DTSTART":
+			if !set.DTStart.IsZero() {
+				return nil, errors.New("DTSTART is defined more than once")
+			}
+
+			values, err := cl.times(time.Local)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(values) != 1 {
+				return nil, errors.New("DTSTART must hold a single value")
+			}
+			set.DTStart = values[0].time
+		case "RRULE":
+			if ruleValue != "" {
+				return nil, errors.New("only one RRULE is supported")
+			}
+			ruleValue = cl.value
+		case "RDATE":
+			rDates = append(rDates, cl)
+		case "EXDATE":
+			exDates = append(exDates, cl)
+		default:
+			return nil, fmt.Errorf("unsupported property %s: DTSTART, RRULE, RDATE and EXDATE are expected", cl.name)
+		}
+	}
+
+	if set.DTStart.IsZero() {
+		if dtstart.IsZero() {
+			return nil, errors.New("DTSTART is required")
+		}
+		set.DTStart = dtstart.Truncate(time.Second)
+	}
+
+	if ruleValue != "" {
+		rule, err := ParseRule(ruleValue, set.DTStart)
+		if err != nil {
+			return nil, err
+		}
+		set.Rule = rule
+	}
+
+	// times without a time zone refer to the one of DTSTART
+	loc := set.DTStart.Location()
+	for _, cl := range rDates {
+		values, err := cl.times(loc)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, v := range values {
+			set.RDates = append(set.RDates, v.time)
+		}
+	}
+
+	for _, cl := range exDates {
+		values, err := cl.times(loc)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, v := range values {
+			if v.isDate {
+				set.exDays[dateOf(v.time)] = true
+			} else {
+				set.ExDates = append(set.ExDates, v.time)
+			}
+		}
+	}
+
+	if set.Rule == nil && len(set.RDates) == 0 {
+		return nil, errors.New("either RRULE or RDATE is required")
+	}
+
+	sort.Slice(set.RDates, func(i, j int) bool { return set.RDates[i].Before(set.RDates[j]) })
+	return set, nil
+}
+
+// Next returns the first occurrence strictly after t, or the zero time if there are no more occurrences.
+func (s *Set) Next(t time.Time) time.Time {
+	var next time.Time
+	for _, rdate := range s.RDates {
+		if rdate.After(t) && !s.excluded(rdate) {
+			next = rdate
+			break
+		}
+	}
+
+	if s.Rule != nil {
+		s.Rule.iterate(s.DTStart, t, func(occ time.Time) bool {
+			if !next.IsZero() && !occ.Before(next) {
+				return false
+			}
+
+			if occ.After(t) && !s.excluded(occ) {
+				next = occ
+				return false
+			}
+			return true
+		})
+	}
+
+	if next.IsZero() {
+		return next
+	}
+	return next.In(t.Location())
+}
+
+// All returns the occurrences of the set until the given time, which is mostly useful to inspect finite sets.
+func (s *Set) All(until time.Time) []time.Time {
+	var res []time.Time
+	for t := s.DTStart.Add(-time.Nanosecond); ; {
+		t = s.Next(t)
+		if t.IsZero() || t.After(until) {
+			return res
+		}
+		res = append(res, t)
+	}
+}
+
+func (s *Set) excluded(t time.Time) bool {
+	if s.exDays[dateOf(t.In(s.DTStart.Location()))] {
+		return true
+	}
+
+	for _, exdate := range s.ExDates {
+		if exdate.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// unfold joins the content lines folded over multiple lines, which start with a space or a tab.
+func unfold(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+type contentLine struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+func parseContentLine(line string) (contentLine, error) {
+	head, value, found := strings.Cut(line, ":")
+	if !found {
+		return contentLine{}, fmt.Errorf("invalid line %q: NAME:VALUE is expected", line)
+	}
+
+	parts := strings.Split(head, ";")
+	cl := contentLine{
+		name:   strings.ToUpper(parts[0]),
+		params: make(map[string]string),
+		value:  strings.TrimSpace(value),
+	}
+
+	for _, param := range parts[1:] {
+		name, value, found := strings.Cut(param, "=")
+		if !found {
+			return contentLine{}, fmt.Errorf("invalid parameter %q of %s", param, cl.name)
+		}
+		cl.params[strings.ToUpper(name)] = strings.Trim(value, `"`)
+	}
+	return cl, nil
+}
+
+type timeValue struct {
+	time   time.Time
+	isDate bool
+}
+
+// times parses the comma separated DATE or DATE-TIME values of the line.
+func (cl contentLine) times(defaultLoc *time.Location) ([]timeValue, error) {
+	loc := defaultLoc
+	if tzid := cl.params["TZID"]; tzid != "" {
+		l, err := time.LoadLocation(tzid)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TZID of %s: %w", cl.name, err)
+		}
+		loc = l
+	}
+
+	valueType := cl.params["VALUE"]
+	if valueType != "" && valueType != "DATE" && valueType != "DATE-TIME" {
+		return nil, fmt.Errorf("unsupported value type %s of %s", valueType, cl.name)
+	}
+
+	var res []timeValue
+	for _, value := range strings.Split(cl.value, ",") {
+		t, isDate, err := parseTime(strings.TrimSpace(value), loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", cl.name, err)
+		}
+		res = append(res, timeValue{time: t, isDate: isDate})
+	}
+	return res, nil
+}
+
+// parseTime parses a DATE (19970902) or DATE-TIME (19970902T090000, or 19970902T130000Z in UTC) value.
+func parseTime(value string, loc *time.Location) (time.Time, bool, error) {
+	switch {
+	case len(value) == 8:
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	case strings.HasSuffix(value, "Z"):
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// civilDate is a day of the calendar, regardless of time zones.
+type civilDate struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+func dateOf(t time.Time) civilDate {
+	return civilDate{t.Year(), t.Month(), t.Day()}
+}
@@ -0,0 +1,283 @@
+package rrule
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const layout = "20060102T150405"
+
+func newYork(t *testing.T) *time.Location {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	return loc
+}
+
+// first returns the first n occurrences of the set, formatted in the time zone of DTSTART.
+func first(set *Set, n int) []string {
+	var res []string
+	for t := set.DTStart.Add(-time.Nanosecond); len(res) < n; {
+		t = set.Next(t)
+		if t.IsZero() {
+			break
+		}
+		res = append(res, t.In(set.DTStart.Location()).Format(layout))
+	}
+	return res
+}
+
+// days returns the given days of the month at 9 AM, as they are written by the examples.
+func days(month string, days ...string) []string {
+	var res []string
+	for _, day := range days {
+		res = append(res, month+day+"T090000")
+	}
+	return res
+}
+
+func concat(lists ...[]string) []string {
+	var res []string
+	for _, list := range lists {
+		res = append(res, list...)
+	}
+	return res
+}
+
+// TestRFC5545Examples checks the examples of RFC 5545, section 3.8.5.3. DTSTART is always an occurrence there,
+// while here it is only if it matches the rule, which changes the examples where it doesn't.
+func TestRFC5545Examples(t *testing.T) {
+	cases := []struct {
+		name    string
+		dtstart string
+		rule    string
+		n       int
+		want    []string
+	}{
+		{"daily for 10 occurrences", "19970902T090000", "FREQ=DAILY;COUNT=10", 20,
+			days("199709", "02", "03", "04", "05", "06", "07", "08", "09", "10", "11")},
+		{"every other day", "19970902T090000", "FREQ=DAILY;INTERVAL=2", 4,
+			days("199709", "02", "04", "06", "08")},
+		{"every 10 days, 5 occurrences", "19970902T090000", "FREQ=DAILY;INTERVAL=10;COUNT=5", 10,
+			concat(days("199709", "02", "12", "22"), days("199710", "02", "12"))},
+		{"every day in January, for 3 years", "19980101T090000",
+			"FREQ=YEARLY;UNTIL=20000131T140000Z;BYMONTH=1;BYDAY=SU,MO,TU,WE,TH,FR,SA", 4,
+			days("199801", "01", "02", "03", "04")},
+		{"weekly for 10 occurrences", "19970902T090000", "FREQ=WEEKLY;COUNT=10", 20,
+			concat(days("199709", "02", "09", "16", "23", "30"), days("199710", "07", "14", "21", "28"), days("199711", "04"))},
+		{"weekly on Tuesday and Thursday for five weeks", "19970902T090000",
+			"FREQ=WEEKLY;UNTIL=19971007T000000Z;WKST=SU;BYDAY=TU,TH", 20,
+			concat(days("199709", "02", "04", "09", "11", "16", "18", "23", "25", "30"), days("199710", "02"))},
+		{"every other week on Monday, Wednesday and Friday", "19970901T090000",
+			"FREQ=WEEKLY;INTERVAL=2;UNTIL=19971224T000000Z;WKST=SU;BYDAY=MO,WE,FR", 30,
+			concat(days("199709", "01", "03", "05", "15", "17", "19", "29"), days("199710", "01", "03", "13", "15", "17", "27", "29", "31"),
+				days("199711", "10", "12", "14", "24", "26", "28"), days("199712", "08", "10", "12", "22"))},
+		{"monthly on the first Friday", "19970905T090000", "FREQ=MONTHLY;COUNT=10;BYDAY=1FR", 20,
+			concat(days("1997", "0905", "1003", "1107", "1205"), days("1998", "0102", "0206", "0306", "0403", "0501", "0605"))},
+		{"every other month on the first and last Sunday", "19970907T090000",
+			"FREQ=MONTHLY;INTERVAL=2;COUNT=10;BYDAY=1SU,-1SU", 20,
+			concat(days("1997", "0907", "0928", "1102", "1130"), days("1998", "0104", "0125", "0301", "0329", "0503", "0531"))},
+		{"monthly on the second-to-last Monday", "19970922T090000", "FREQ=MONTHLY;COUNT=6;BYDAY=-2MO", 20,
+			concat(days("1997", "0922", "1020", "1117", "1222"), days("1998", "0119", "0216"))},
+		{"monthly on the third-to-the-last day", "19970928T090000", "FREQ=MONTHLY;BYMONTHDAY=-3", 6,
+			concat(days("1997", "0928", "1029", "1128", "1229"), days("1998", "0129", "0226"))},
+		{"monthly on the 2nd and 15th", "19970902T090000", "FREQ=MONTHLY;COUNT=10;BYMONTHDAY=2,15", 20,
+			concat(days("1997", "0902", "0915", "1002", "1015", "1102", "1115", "1202", "1215"), days("1998", "0102", "0115"))},
+		{"monthly on the first and last day", "19970930T090000", "FREQ=MONTHLY;COUNT=10;BYMONTHDAY=1,-1", 20,
+			concat(days("1997", "0930", "1001", "1031", "1101", "1130", "1201", "1231"), days("1998", "0101", "0131", "0201"))},
+		{"every 18 months on the 10th to 15th", "19970910T090000",
+			"FREQ=MONTHLY;INTERVAL=18;COUNT=10;BYMONTHDAY=10,11,12,13,14,15", 20,
+			concat(days("199709", "10", "11", "12", "13", "14", "15"), days("199903", "10", "11", "12", "13"))},
+		{"yearly in June and July", "19970610T090000", "FREQ=YEARLY;COUNT=10;BYMONTH=6,7", 20,
+			concat(days("1997", "0610", "0710"), days("1998", "0610", "0710"), days("1999", "0610", "0710"),
+				days("2000", "0610", "0710"), days("2001", "0610", "0710"))},
+		{"every third year on the 1st, 100th and 200th day", "19970101T090000",
+			"FREQ=YEARLY;INTERVAL=3;COUNT=10;BYYEARDAY=1,100,200", 20,
+			concat(days("1997", "0101", "0410", "0719"), days("2000", "0101", "0409", "0718"),
+				days("2003", "0101", "0410", "0719"), days("2006", "0101"))},
+		{"every 20th Monday of the year", "19970519T090000", "FREQ=YEARLY;BYDAY=20MO", 3,
+			[]string{"19970519T090000", "19980518T090000", "19990517T090000"}},
+		{"Monday of week number 20", "19970512T090000", "FREQ=YEARLY;BYWEEKNO=20;BYDAY=MO", 3,
+			[]string{"19970512T090000", "19980511T090000", "19990517T090000"}},
+		{"every Thursday in March", "19970313T090000", "FREQ=YEARLY;BYMONTH=3;BYDAY=TH", 7,
+			concat(days("199703", "13", "20", "27"), days("199803", "05", "12", "19", "26"))},
+		{"every Friday the 13th", "19970902T090000", "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13", 5,
+			[]string{"19980213T090000", "19980313T090000", "19981113T090000", "19990813T090000", "20001013T090000"}},
+		{"the first Saturday following the first Sunday", "19970913T090000",
+			"FREQ=MONTHLY;BYDAY=SA;BYMONTHDAY=7,8,9,10,11,12,13", 6,
+			concat(days("1997", "0913", "1011", "1108", "1213"), days("1998", "0110", "0207"))},
+		{"US presidential elections", "19961105T090000",
+			"FREQ=YEARLY;INTERVAL=4;BYMONTH=11;BYDAY=TU;BYMONTHDAY=2,3,4,5,6,7,8", 3,
+			[]string{"19961105T090000", "20001107T090000", "20041102T090000"}},
+		{"the third instance of Tuesday, Wednesday or Thursday", "19970904T090000",
+			"FREQ=MONTHLY;COUNT=3;BYDAY=TU,WE,TH;BYSETPOS=3", 10,
+			days("1997", "0904", "1007", "1106")},
+		{"the second-to-last weekday of the month", "19970929T090000",
+			"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-2", 7,
+			concat(days("1997", "0929", "1030", "1127", "1230"), days("1998", "0129", "0226", "0330"))},
+		{"every 3 hours", "19970902T090000", "FREQ=HOURLY;INTERVAL=3;UNTIL=19970902T170000Z", 10,
+			[]string{"19970902T090000", "19970902T120000"}},
+		{"every 15 minutes for 6 occurrences", "19970902T090000", "FREQ=MINUTELY;INTERVAL=15;COUNT=6", 10,
+			[]string{"19970902T090000", "19970902T091500", "19970902T093000", "19970902T094500", "19970902T100000", "19970902T101500"}},
+		{"every hour and a half for 4 occurrences", "19970902T090000", "FREQ=MINUTELY;INTERVAL=90;COUNT=4", 10,
+			[]string{"19970902T090000", "19970902T103000", "19970902T120000", "19970902T133000"}},
+		{"every 20 minutes from 9 to 16:40", "19970902T090000", "FREQ=DAILY;BYHOUR=9,10,11,12,13,14,15,16;BYMINUTE=0,20,40", 26,
+			append(first0920("19970902"), "19970903T090000", "19970903T092000")},
+		{"every 20 minutes from 9 to 16:40, minutely", "19970902T090000", "FREQ=MINUTELY;INTERVAL=20;BYHOUR=9,10,11,12,13,14,15,16", 26,
+			append(first0920("19970902"), "19970903T090000", "19970903T092000")},
+		{"week start on Monday", "19970805T090000", "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO", 10,
+			days("199708", "05", "10", "19", "24")},
+		{"week start on Sunday", "19970805T090000", "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU", 10,
+			days("199708", "05", "17", "19", "31")},
+		{"invalid dates are ignored", "20070115T090000", "FREQ=MONTHLY;BYMONTHDAY=15,30;COUNT=5", 10,
+			days("2007", "0115", "0130", "0215", "0315", "0330")},
+	}
+
+	loc := newYork(t)
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dtstart, err := time.ParseInLocation(layout, c.dtstart, loc)
+			require.NoError(t, err)
+
+			set, err := Parse(c.rule, dtstart)
+			require.NoError(t, err)
+			require.Equal(t, c.want, first(set, c.n))
+		})
+	}
+}
+
+// first0920 returns the times between 9:00 and 16:40 every 20 minutes of the given day.
+func first0920(day string) []string {
+	var res []string
+	for hour := 9; hour <= 16; hour++ {
+		for minute := 0; minute < 60; minute += 20 {
+			res = append(res, day+"T"+time.Date(0, 1, 1, hour, minute, 0, 0, time.UTC).Format("150405"))
+		}
+	}
+	return res
+}
+
+func TestDaylightSaving(t *testing.T) {
+	loc := newYork(t)
+
+	// daily occurrences keep their local time, while hourly ones keep their interval
+	set, err := Parse("FREQ=DAILY", time.Date(1997, 10, 25, 9, 0, 0, 0, loc))
+	require.NoError(t, err)
+	require.Equal(t, []string{"19971025T090000", "19971026T090000", "19971027T090000"}, first(set, 3))
+
+	set, err = Parse("FREQ=HOURLY", time.Date(1997, 10, 26, 0, 0, 0, 0, loc))
+	require.NoError(t, err)
+	require.Equal(t, []string{"19971026T000000", "19971026T010000", "19971026T010000", "19971026T020000"}, first(set, 4))
+}
+
+func TestContentLines(t *testing.T) {
+	loc := newYork(t)
+
+	set, err := Parse(strings.Join([]string{
+		"DTSTART;TZID=America/New_York:19970902T090000",
+		"RRULE:FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13",
+		"EXDATE;TZID=America/New_York:19980313T090000",
+		"EXDATE;VALUE=DATE:19981113",
+		"RDATE:19980101T120000,19980102T120000",
+		"RDATE:19980103T120000Z",
+	}, "\n"), time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, time.Date(1997, 9, 2, 9, 0, 0, 0, loc), set.DTStart)
+	require.Equal(t, []string{
+		"19980101T120000", "19980102T120000", "19980103T070000", "19980213T090000", "19990813T090000", "20001013T090000",
+	}, first(set, 6))
+
+	// folded lines
+	set, err = Parse("DTSTART:20240101T100000Z\nRRULE:FREQ=DAILY;\n COUNT=2", time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"20240101T100000", "20240102T100000"}, first(set, 10))
+
+	// only RDATEs
+	set, err = Parse("RDATE:20240301T100000,20240201T100000", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, []string{"20240201T100000", "20240301T100000"}, first(set, 10))
+}
+
+func TestNextSkipsAhead(t *testing.T) {
+	dtstart := time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)
+
+	for _, rule := range []string{
+		"FREQ=YEARLY;BYMONTH=2;BYDAY=-1MO",
+		"FREQ=MONTHLY;INTERVAL=5;BYMONTHDAY=-1",
+		"FREQ=WEEKLY;INTERVAL=3;BYDAY=WE,SA;WKST=SU",
+		"FREQ=DAILY;INTERVAL=13",
+		"FREQ=HOURLY;INTERVAL=7;BYDAY=TU",
+		"FREQ=MINUTELY;INTERVAL=45;BYHOUR=6,18",
+	} {
+		set, err := Parse(rule, dtstart)
+		require.NoError(t, err)
+
+		// the occurrences following each other match the ones found from any time between them
+		prev := set.Next(dtstart.Add(-time.Nanosecond))
+		for i := 0; i < 30; i++ {
+			next := set.Next(prev)
+			require.True(t, next.After(prev), rule)
+			require.Equal(t, next, set.Next(prev.Add(next.Sub(prev)/2)), rule)
+			prev = next
+		}
+
+		far := dtstart.AddDate(50, 0, 0)
+		next := set.Next(far)
+		require.False(t, next.IsZero(), rule)
+
+		var brute time.Time
+		set.Rule.iterate(dtstart, dtstart, func(occ time.Time) bool {
+			brute = occ
+			return !occ.After(far)
+		})
+		require.Equal(t, brute, next, rule)
+	}
+}
+
+func TestNoMoreOccurrences(t *testing.T) {
+	dtstart := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, rule := range []string{
+		"FREQ=DAILY;COUNT=3",
+		"FREQ=DAILY;UNTIL=20240103",
+		"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30",
+	} {
+		set, err := Parse(rule, dtstart)
+		require.NoError(t, err)
+		require.True(t, set.Next(dtstart.AddDate(0, 0, 5)).IsZero(), rule)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	dtstart := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, text := range []string{
+		"",
+		"FREQ=FORTNIGHTLY",
+		"INTERVAL=2",
+		"RRULE:INTERVAL=2;FREQ=DAILY;INTERVAL=3",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20240201",
+		"FREQ=DAILY;BYHOUR=24",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYDAY=5XX",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYWEEKNO=1",
+		"FREQ=MONTHLY;BYSETPOS=1",
+		"FREQ=DAILY;BYEASTER=0",
+		"RRULE:FREQ=DAILY\nRRULE:FREQ=WEEKLY",
+		"DTSTART;TZID=Mars/Olympus:20240101T000000\nRRULE:FREQ=DAILY",
+		"DTSTART:2024\nRRULE:FREQ=DAILY",
+		"DTSTART:20240101T000000",
+		"SUMMARY:meeting",
+	} {
+		_, err := Parse(text, dtstart)
+		require.Error(t, err, text)
+	}
+
+	_, err := Parse("FREQ=DAILY", time.Time{})
+	require.Error(t, err)
+}
@@ -0,0 +1,265 @@
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency int
+
+const (
+	Secondly Frequency = iota
+	Minutely
+	Hourly
+	Daily
+	Weekly
+	Monthly
+	Yearly
+)
+
+var frequencies = map[string]Frequency{
+	"SECONDLY": Secondly,
+	"MINUTELY": Minutely,
+	"HOURLY":   Hourly,
+	"DAILY":    Daily,
+	"WEEKLY":   Weekly,
+	"MONTHLY":  Monthly,
+	"YEARLY":   Yearly,
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// WeekdayNum is an item of BYDAY, such as MO, or -1FR for the last Friday of the month or year.
+type WeekdayNum struct {
+	Weekday time.Weekday
+	// N is the occurrence of the weekday within the month or year, counting from the end if negative.
+	// Zero matches every occurrence.
+	N int
+}
+
+// Rule is an RRULE. Unset BY* parts are nil.
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	Count    int
+	Until    time.Time
+
+	BySecond   []int
+	ByMinute   []int
+	ByHour     []int
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByYearDay  []int
+	ByWeekNo   []int
+	ByMonth    []int
+	BySetPos   []int
+	WeekStart  time.Weekday
+}
+
+// ParseRule parses the value of an RRULE, such as "FREQ=MONTHLY;BYDAY=-1FR".
+// dtstart provides the time zone of UNTIL values without one, and the parts of the occurrences
+// which the rule doesn't define, such as the time of the day.
+func ParseRule(value string, dtstart time.Time) (*Rule, error) {
+	r := &Rule{Freq: -1, Interval: 1, WeekStart: time.Monday}
+
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ";") {
+		name, v, found := strings.Cut(part, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if !found || v == "" {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+
+		if seen[name] {
+			return nil, fmt.Errorf("%s is defined more than once", name)
+		}
+		seen[name] = true
+
+		if err := r.setPart(name, strings.ToUpper(strings.TrimSpace(v)), dtstart); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+
+	r.setDefaults(dtstart)
+	return r, nil
+}
+
+func (r *Rule) setPart(name, v string, dtstart time.Time) error {
+	var err error
+	switch name {
+	case "FREQ":
+		freq, has := frequencies[v]
+		if !has {
+			return fmt.Errorf("unknown frequency %s", v)
+		}
+		r.Freq = freq
+	case "INTERVAL":
+		r.Interval, err = strconv.Atoi(v)
+		if err == nil && r.Interval < 1 {
+			err = errors.New("must be positive")
+		}
+	case "COUNT":
+		r.Count, err = strconv.Atoi(v)
+		if err == nil && r.Count < 1 {
+			err = errors.New("must be positive")
+		}
+	case "UNTIL":
+		var isDate bool
+		r.Until, isDate, err = parseTime(v, dtstart.Location())
+		if err == nil && isDate {
+			// a date includes the occurrences of the whole day
+			r.Until = r.Until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+	case "BYSECOND":
+		r.BySecond, err = parseInts(v, 0, 59, false)
+	case "BYMINUTE":
+		r.ByMinute, err = parseInts(v, 0, 59, false)
+	case "BYHOUR":
+		r.ByHour, err = parseInts(v, 0, 23, false)
+	case "BYDAY":
+		r.ByDay, err = parseWeekdays(v)
+	case "BYMONTHDAY":
+		r.ByMonthDay, err = parseInts(v, 1, 31, true)
+	case "BYYEARDAY":
+		r.ByYearDay, err = parseInts(v, 1, 366, true)
+	case "BYWEEKNO":
+		r.ByWeekNo, err = parseInts(v, 1, 53, true)
+	case "BYMONTH":
+		r.ByMonth, err = parseInts(v, 1, 12, false)
+	case "BYSETPOS":
+		r.BySetPos, err = parseInts(v, 1, 366, true)
+	case "WKST":
+		weekday, has := weekdays[v]
+		if !has {
+			return fmt.Errorf("unknown weekday %s", v)
+		}
+		r.WeekStart = weekday
+	default:
+		return errors.New("unsupported rule part")
+	}
+	return err
+}
+
+func (r *Rule) validate() error {
+	switch {
+	case r.Freq < 0:
+		return errors.New("FREQ is required")
+	case r.Count > 0 && !r.Until.IsZero():
+		return errors.New("COUNT and UNTIL are mutually exclusive")
+	case r.ByWeekNo != nil && r.Freq != Yearly:
+		return errors.New("BYWEEKNO is only allowed with FREQ=YEARLY")
+	case r.ByYearDay != nil && (r.Freq == Daily || r.Freq == Weekly || r.Freq == Monthly):
+		return errors.New("BYYEARDAY is not allowed with FREQ=DAILY, WEEKLY or MONTHLY")
+	case r.ByMonthDay != nil && r.Freq == Weekly:
+		return errors.New("BYMONTHDAY is not allowed with FREQ=WEEKLY")
+	case r.BySetPos != nil && r.BySecond == nil && r.ByMinute == nil && r.ByHour == nil && r.ByDay == nil &&
+		r.ByMonthDay == nil && r.ByYearDay == nil && r.ByWeekNo == nil && r.ByMonth == nil:
+		return errors.New("BYSETPOS requires another BY* rule part")
+	}
+
+	for _, wd := range r.ByDay {
+		if wd.N != 0 && r.Freq != Monthly && r.Freq != Yearly {
+			return errors.New("numbered BYDAY values are only allowed with FREQ=MONTHLY or YEARLY")
+		}
+
+		if wd.N != 0 && r.Freq == Yearly && r.ByWeekNo != nil {
+			return errors.New("numbered BYDAY values are not allowed together with BYWEEKNO")
+		}
+	}
+	return nil
+}
+
+// setDefaults derives the parts of the occurrences which the rule doesn't define from DTSTART,
+// so that, for example, a weekly rule without BYDAY recurs on the weekday of DTSTART.
+func (r *Rule) setDefaults(dtstart time.Time) {
+	if r.ByWeekNo == nil && r.ByYearDay == nil && r.ByMonthDay == nil && r.ByDay == nil {
+		switch r.Freq {
+		case Yearly:
+			if r.ByMonth == nil {
+				r.ByMonth = []int{int(dtstart.Month())}
+			}
+			r.ByMonthDay = []int{dtstart.Day()}
+		case Monthly:
+			r.ByMonthDay = []int{dtstart.Day()}
+		case Weekly:
+			r.ByDay = []WeekdayNum{{Weekday: dtstart.Weekday()}}
+		}
+	}
+
+	if r.Freq > Hourly && r.ByHour == nil {
+		r.ByHour = []int{dtstart.Hour()}
+	}
+
+	if r.Freq > Minutely && r.ByMinute == nil {
+		r.ByMinute = []int{dtstart.Minute()}
+	}
+
+	if r.Freq > Secondly && r.BySecond == nil {
+		r.BySecond = []int{dtstart.Second()}
+	}
+}
+
+// parseInts parses a list of integers in [min, max], or in [-max, -min] as well if negative is set.
+func parseInts(v string, min, max int, negative bool) ([]int, error) {
+	var res []int
+	for _, item := range strings.Split(v, ",") {
+		n, err := strconv.Atoi(strings.TrimPrefix(item, "+"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q", item)
+		}
+
+		abs := n
+		if negative && n < 0 {
+			abs = -n
+		}
+
+		if abs < min || abs > max {
+			return nil, fmt.Errorf("%d is out of range", n)
+		}
+		res = append(res, n)
+	}
+
+	sort.Ints(res)
+	return res, nil
+}
+
+func parseWeekdays(v string) ([]WeekdayNum, error) {
+	var res []WeekdayNum
+	for _, item := range strings.Split(v, ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("invalid weekday %q", item)
+		}
+
+		weekday, has := weekdays[item[len(item)-2:]]
+		if !has {
+			return nil, fmt.Errorf("invalid weekday %q", item)
+		}
+
+		wd := WeekdayNum{Weekday: weekday}
+		if prefix := item[:len(item)-2]; prefix != "" {
+			n, err := strconv.Atoi(strings.TrimPrefix(prefix, "+"))
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, fmt.Errorf("invalid weekday %q", item)
+			}
+			wd.N = n
+		}
+		res = append(res, wd)
+	}
+	return res, nil
+}
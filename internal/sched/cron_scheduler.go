@@ -3,6 +3,7 @@ package sched
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -12,11 +13,18 @@ import (
 
 var MaxTime = time.Date(9999, 12, 31, 23, 59, 59, 999999999, time.UTC)
 
+// MaxTickDuration is the time after which a tick which is still being processed
+// is considered stuck, and the scheduler loop reported as not alive.
+const MaxTickDuration = time.Minute
+
 func (s *cronScheduler) Start(ctx context.Context) {
 	go s.run(ctx)
 }
 
 func (s *cronScheduler) run(ctx context.Context) {
+	s.running.Add(1)
+	defer s.running.Add(-1)
+
 	duration := time.Duration(0)
 
 	for {
@@ -27,7 +35,10 @@ func (s *cronScheduler) run(ctx context.Context) {
 		case <-time.After(duration):
 		}
 
+		s.busySince.Store(time.Now().UnixMilli())
 		nextTick := s.onTick()
+		s.busySince.Store(0)
+
 		duration = time.Until(nextTick)
 		if nextTick.Equal(MaxTime) {
 			duration = time.Hour
@@ -49,6 +60,8 @@ type CronScheduler interface {
 	Start(ctx context.Context)
 	Schedule(id int64, at time.Time)
 	Remove(id int64) bool
+	Len() int
+	Alive() bool
 }
 
 type cronScheduler struct {
@@ -56,10 +69,13 @@ type cronScheduler struct {
 
 	index      *btree.BTree
 	signalCh   chan struct{}
-	onCronTick func(id int64) time.Time
+	onCronTick func(id int64, scheduledAt time.Time) time.Time
+
+	running   atomic.Int32
+	busySince atomic.Int64
 }
 
-func NewCronScheduler(onCronTick func(cronID int64) time.Time) CronScheduler {
+func NewCronScheduler(onCronTick func(cronID int64, scheduledAt time.Time) time.Time) CronScheduler {
 	return &cronScheduler{
 		index:      btree.New(64),
 		signalCh:   make(chan struct{}, 1),
@@ -67,7 +83,12 @@ func NewCronScheduler(onCronTick func(cronID int64) time.Time) CronScheduler {
 	}
 }
 
+// Schedule schedules the next tick of a schedule. The zero time means that the schedule has no more ticks.
 func (s *cronScheduler) Schedule(id int64, at time.Time) {
+	if at.IsZero() {
+		return
+	}
+
 	s.mtx.Lock()
 
 	s.index.ReplaceOrInsert(&item{
@@ -111,7 +132,7 @@ func (s *cronScheduler) onTick() time.Time {
 
 		s.index.DeleteMin()
 
-		nextTick := s.onCronTick(it.id)
+		nextTick := s.onCronTick(it.id, time.UnixMilli(it.nextTickAt))
 
 		if nextTick.UnixMicro() > now.UnixMilli() {
 			s.index.ReplaceOrInsert(&item{
@@ -144,3 +165,20 @@ func (s *cronScheduler) Remove(id int64) bool {
 	}
 	return false
 }
+
+func (s *cronScheduler) Len() int {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.index.Len()
+}
+
+// Alive reports whether the scheduler loop is running and not stuck processing a tick.
+func (s *cronScheduler) Alive() bool {
+	if s.running.Load() == 0 {
+		return false
+	}
+
+	busySince := s.busySince.Load()
+	return busySince == 0 || time.Since(time.UnixMilli(busySince)) < MaxTickDuration
+}
@@ -28,7 +28,7 @@ func (s *CronSchedulerServiceSuite) TestOnTickIsCalledProperly() {
 	schedules := make(map[int64]bool)
 
 	calls := 0
-	scheduler := NewCronScheduler(func(id int64) time.Time {
+	scheduler := NewCronScheduler(func(id int64, _ time.Time) time.Time {
 		s.True(schedules[id])
 
 		calls++
@@ -56,7 +56,7 @@ func (s *CronSchedulerServiceSuite) TestOnTickReschedule() {
 	now := time.Now().Truncate(time.Second)
 
 	rescheduled := 0
-	scheduler := NewCronScheduler(func(id int64) time.Time {
+	scheduler := NewCronScheduler(func(id int64, _ time.Time) time.Time {
 		if rand.Int()%2 == 0 {
 			rescheduled++
 			return now.Add(time.Second * time.Duration(rescheduled))
@@ -84,7 +84,7 @@ func (s *CronSchedulerServiceSuite) TestRemove() {
 	frequency := time.Millisecond * 100
 
 	var nReschedules atomic.Uint64
-	scheduler := NewCronScheduler(func(id int64) time.Time {
+	scheduler := NewCronScheduler(func(id int64, _ time.Time) time.Time {
 		nReschedules.Add(1)
 		return time.Now().Add(frequency)
 	})
@@ -0,0 +1,141 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ostafen/kronos/internal/config"
+)
+
+var ErrUnknownKey = errors.New("unknown encryption key")
+
+const keySize = 32
+
+type key struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// Keyring encrypts secrets with AES-256-GCM under its current key, and decrypts them with any of its keys.
+type Keyring struct {
+	current *key
+	keys    map[string]*key
+}
+
+// NewKeyring returns the keyring described by the configuration, or nil if no key is configured.
+func NewKeyring(conf config.Secrets) (*Keyring, error) {
+	current, err := loadKey(conf.Key, conf.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if current == nil {
+		if len(conf.PreviousKeys) > 0 || len(conf.PreviousKeyFiles) > 0 {
+			return nil, fmt.Errorf("previous secret keys require a current key")
+		}
+		return nil, nil
+	}
+
+	k := &Keyring{
+		current: current,
+		keys:    map[string]*key{current.id: current},
+	}
+
+	for _, encoded := range conf.PreviousKeys {
+		if err := k.add(loadKey(encoded, "")); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, file := range conf.PreviousKeyFiles {
+		if err := k.add(loadKey("", file)); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+func (k *Keyring) add(key *key, err error) error {
+	if err != nil {
+		return err
+	}
+	k.keys[key.id] = key
+	return nil
+}
+
+func loadKey(encoded, file string) (*key, error) {
+	if encoded != "" && file != "" {
+		return nil, fmt.Errorf("only one of key and keyFile can be set")
+	}
+
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		encoded = string(data)
+	}
+
+	if encoded == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret key: %w", err)
+	}
+
+	if len(raw) != keySize {
+		return nil, fmt.Errorf("invalid secret key: expected %d bytes, got %d", keySize, len(raw))
+	}
+
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// the id identifies the key a secret has been encrypted with, without revealing anything about the key itself
+	sum := sha256.Sum256(append([]byte("kronos-secret-key:"), raw...))
+	return &key{id: hex.EncodeToString(sum[:4]), aead: aead}, nil
+}
+
+// KeyID returns the id of the key new secrets are encrypted with.
+func (k *Keyring) KeyID() string {
+	return k.current.id
+}
+
+// Encrypt encrypts plaintext with the current key. The additional data binds the ciphertext to its context,
+// and must be passed unchanged to Decrypt.
+func (k *Keyring) Encrypt(plaintext, additionalData []byte) (keyID string, ciphertext []byte, err error) {
+	nonce := make([]byte, k.current.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+	return k.current.id, k.current.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func (k *Keyring) Decrypt(keyID string, ciphertext, additionalData []byte) ([]byte, error) {
+	key, has := k.keys[keyID]
+	if !has {
+		return nil, fmt.Errorf("%w %s", ErrUnknownKey, keyID)
+	}
+
+	nonceSize := key.aead.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, fmt.Errorf("invalid ciphertext")
+	}
+	return key.aead.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], additionalData)
+}
@@ -0,0 +1,80 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ostafen/kronos/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func newKey(t *testing.T) string {
+	raw := make([]byte, keySize)
+	_, err := rand.Read(raw)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	k, err := NewKeyring(config.Secrets{Key: newKey(t)})
+	require.NoError(t, err)
+
+	keyID, ciphertext, err := k.Encrypt([]byte("s3cret"), []byte("default/token"))
+	require.NoError(t, err)
+	require.Equal(t, k.KeyID(), keyID)
+	require.NotContains(t, string(ciphertext), "s3cret")
+
+	plaintext, err := k.Decrypt(keyID, ciphertext, []byte("default/token"))
+	require.NoError(t, err)
+	require.Equal(t, "s3cret", string(plaintext))
+
+	// ciphertexts are bound to their additional data
+	_, err = k.Decrypt(keyID, ciphertext, []byte("other/token"))
+	require.Error(t, err)
+
+	_, err = k.Decrypt("unknown", ciphertext, []byte("default/token"))
+	require.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestRotation(t *testing.T) {
+	oldKey, newKeyValue := newKey(t), newKey(t)
+
+	previous, err := NewKeyring(config.Secrets{Key: oldKey})
+	require.NoError(t, err)
+
+	keyID, ciphertext, err := previous.Encrypt([]byte("s3cret"), nil)
+	require.NoError(t, err)
+
+	keyFile := filepath.Join(t.TempDir(), "previous.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte(oldKey+"\n"), 0600))
+
+	current, err := NewKeyring(config.Secrets{Key: newKeyValue, PreviousKeyFiles: []string{keyFile}})
+	require.NoError(t, err)
+	require.NotEqual(t, keyID, current.KeyID())
+
+	plaintext, err := current.Decrypt(keyID, ciphertext, nil)
+	require.NoError(t, err)
+	require.Equal(t, "s3cret", string(plaintext))
+}
+
+func TestInvalidKeyrings(t *testing.T) {
+	k, err := NewKeyring(config.Secrets{})
+	require.NoError(t, err)
+	require.Nil(t, k)
+
+	invalid := []config.Secrets{
+		{Key: "not base64!"},
+		{Key: base64.StdEncoding.EncodeToString([]byte("too short"))},
+		{Key: newKey(t), KeyFile: "/etc/kronos/secrets.key"},
+		{KeyFile: filepath.Join(t.TempDir(), "missing.key")},
+		{PreviousKeys: []string{newKey(t)}},
+	}
+
+	for _, conf := range invalid {
+		_, err := NewKeyring(conf)
+		require.Error(t, err)
+	}
+}
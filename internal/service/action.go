@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/ostafen/kronos/internal/metrics"
+	"github.com/ostafen/kronos/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Action is a Go function run by the schedules referring to it, in place of a webhook delivery.
+// It runs in its own goroutine, and ctx is canceled when the deliveries are aborted by Stop.
+type Action func(ctx context.Context, sched *model.CronSchedule) error
+
+var ErrUnknownAction = errors.New("unknown action")
+
+type actionRegistry struct {
+	mtx     sync.RWMutex
+	actions map[string]Action
+}
+
+func (r *actionRegistry) register(name string, action Action) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.actions == nil {
+		r.actions = make(map[string]Action)
+	}
+	r.actions[name] = action
+}
+
+func (r *actionRegistry) get(name string) (Action, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	action, has := r.actions[name]
+	if !has {
+		return nil, fmt.Errorf("%w %q", ErrUnknownAction, name)
+	}
+	return action, nil
+}
+
+func (s *schedService) RegisterAction(name string, action Action) {
+	s.actions.register(name, action)
+}
+
+// checkDelivery checks that the action of a schedule is registered, or that its webhook target is allowed.
+func (s *schedService) checkDelivery(ctx context.Context, sched *model.CronSchedule) error {
+	if sched.Action != "" {
+		_, err := s.actions.get(sched.Action)
+		return err
+	}
+
+	target, err := s.target(ctx, sched)
+	if err != nil {
+		return err
+	}
+	return s.notificationSvc.CheckTarget(ctx, target)
+}
+
+// deliver runs the action of a schedule, or delivers its webhook, and returns the status code recorded to history.
+func (s *schedService) deliver(ctx context.Context, sched *model.CronSchedule) (int, error) {
+	if sched.Action == "" {
+		return s.sendWebhookNotification(ctx, sched)
+	}
+
+	log.WithField("scheduleId", sched.ID).
+		WithField("action", sched.Action).
+		Info("running action")
+
+	// outcomes of actions are recorded as the status codes of a webhook answering with or without an error
+	err := s.runAction(ctx, sched)
+	if err != nil {
+		log.WithError(err).
+			WithField("scheduleId", sched.ID).
+			WithField("action", sched.Action).
+			Error("action failed")
+
+		metrics.IncScheduleFailures(sched.ID)
+		return http.StatusInternalServerError, err
+	}
+
+	metrics.ResetScheduleFailures(sched.ID)
+	return http.StatusOK, nil
+}
+
+func (s *schedService) runAction(ctx context.Context, sched *model.CronSchedule) (err error) {
+	action, err := s.actions.get(sched.Action)
+	if err != nil {
+		return err
+	}
+
+	// a panicking action must not bring down the scheduler
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("action %q panicked: %v", sched.Action, r)
+		}
+	}()
+	return action(ctx, sched)
+}
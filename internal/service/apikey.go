@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ostafen/kronos/internal/auth"
+	"github.com/ostafen/kronos/model"
+	"github.com/ostafen/kronos/store"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, input *model.APIKeyCreateInput) (*model.CreatedAPIKey, error)
+	ListAPIKeys(ctx context.Context) ([]*model.APIKey, error)
+	DeleteAPIKey(ctx context.Context, id int64) error
+
+	auth.KeyVerifier
+}
+
+func NewAPIKeyService(store store.Store) APIKeyService {
+	return &apiKeyService{
+		repo:          store.APIKeyRepository(),
+		namespaceRepo: store.NamespaceRepository(),
+	}
+}
+
+type apiKeyService struct {
+	repo          store.APIKeyRepository
+	namespaceRepo store.NamespaceRepository
+}
+
+func (s *apiKeyService) CreateAPIKey(ctx context.Context, input *model.APIKeyCreateInput) (_ *model.CreatedAPIKey, err error) {
+	ctx, span := startSpan(ctx, "CreateAPIKey")
+	defer func() { endSpan(span, err) }()
+
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	// keys created by principals bound to a namespace are confined to the same namespace
+	namespace := input.Namespace
+	if ns := namespaceOf(ctx); ns != "" {
+		if namespace != "" && namespace != ns {
+			return nil, auth.ErrNamespaceDenied
+		}
+		namespace = ns
+	}
+
+	if namespace != "" {
+		if _, err := s.namespaceRepo.Get(ctx, namespace); err != nil {
+			return nil, err
+		}
+	}
+
+	scopes := make([]string, 0, len(input.Scopes))
+	for _, name := range input.Scopes {
+		scope, err := auth.ParseScope(name)
+		if err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, string(scope))
+	}
+
+	secret, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &model.APIKey{
+		Name:      input.Name,
+		Namespace: namespace,
+		Prefix:    secret[:auth.APIKeyDisplayLen],
+		Hash:      auth.HashAPIKey(secret),
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+		ExpiresAt: input.ExpiresAt,
+	}
+
+	key.ID, err = s.repo.Save(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithField("keyId", key.ID).
+		WithField("name", key.Name).
+		WithField("namespace", key.Namespace).
+		WithField("scopes", key.Scopes).
+		Info("api key created")
+
+	return &model.CreatedAPIKey{APIKey: key, Key: secret}, nil
+}
+
+func (s *apiKeyService) ListAPIKeys(ctx context.Context) (_ []*model.APIKey, err error) {
+	ctx, span := startSpan(ctx, "ListAPIKeys")
+	defer func() { endSpan(span, err) }()
+
+	return s.repo.List(ctx, namespaceOf(ctx))
+}
+
+func (s *apiKeyService) DeleteAPIKey(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "DeleteAPIKey")
+	defer func() { endSpan(span, err) }()
+
+	return s.repo.Delete(ctx, namespaceOf(ctx), id)
+}
+
+func (s *apiKeyService) VerifyAPIKey(ctx context.Context, secret string) (*auth.Principal, error) {
+	key, err := s.repo.GetByHash(ctx, auth.HashAPIKey(secret))
+	if errors.Is(err, store.ErrAPIKeyNotExist) {
+		return nil, auth.ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if key.Expired() {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	principal := &auth.Principal{
+		Subject:   auth.APIKeySubject(key.ID),
+		Method:    auth.MethodAPIKey,
+		Namespace: key.Namespace,
+	}
+	for _, s := range key.Scopes {
+		principal.Scopes = append(principal.Scopes, auth.Scope(s))
+	}
+	return principal, nil
+}
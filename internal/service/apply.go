@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ostafen/kronos/internal/auth"
+	"github.com/ostafen/kronos/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// plannedChange is a change computed by ApplySchedules, together with the schedules it operates on.
+type plannedChange struct {
+	model.ScheduleChange
+
+	current *model.CronSchedule
+	input   *model.ScheduleRegisterInput
+	desired *model.CronSchedule
+}
+
+func (s *schedService) ApplySchedules(ctx context.Context, req *model.ApplyRequest) (_ *model.ApplyResult, err error) {
+	ctx, span := startSpan(ctx, "ApplySchedules")
+	defer func() { endSpan(span, err) }()
+
+	plan, err := s.planApply(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &model.ApplyResult{
+		DryRun:  req.DryRun,
+		Changes: make([]model.ScheduleChange, 0, len(plan)),
+	}
+
+	for _, change := range plan {
+		if change.Action != model.ApplyActionUnchanged {
+			res.Drift = true
+		}
+		res.Changes = append(res.Changes, change.ScheduleChange)
+	}
+
+	if req.DryRun {
+		return res, nil
+	}
+
+	// changes are not made atomically: a failure stops the apply, and the changes made before it are reported
+	// along with the error, so that the caller can tell the state the namespace has been left in.
+	var applied []int
+
+	// deletions go first, so that the quota they release is available to creations
+	for _, action := range []model.ApplyAction{model.ApplyActionDelete, model.ApplyActionUpdate, model.ApplyActionCreate} {
+		for i, change := range plan {
+			if change.Action != action {
+				continue
+			}
+
+			if err := s.applyChange(ctx, &change); err != nil {
+				for _, j := range applied {
+					res.Changes[j].Applied = true
+				}
+				return res, fmt.Errorf("schedule %q: %w", change.Title, err)
+			}
+			res.Changes[i].ID = change.ID
+			applied = append(applied, i)
+		}
+	}
+
+	log.WithField("namespace", targetNamespace(ctx)).
+		WithField("schedules", len(req.Schedules)).
+		WithField("drift", res.Drift).
+		Info("schedules applied")
+
+	return res, nil
+}
+
+// planApply computes the changes required to reconcile the schedules of the target namespace with the desired ones,
+// and validates them before anything is modified.
+func (s *schedService) planApply(ctx context.Context, req *model.ApplyRequest) ([]plannedChange, error) {
+	namespace := targetNamespace(ctx)
+	grants := auth.GrantsFrom(ctx)
+
+	var existing []*model.CronSchedule
+	byTitle := make(map[string]*model.CronSchedule)
+
+	err := s.cronRepo.Iter(ctx, namespace, func(sched *model.CronSchedule) error {
+		existing = append(existing, sched)
+		byTitle[sched.Title] = sched
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	plan := make([]plannedChange, 0, len(req.Schedules))
+	desired := make(map[string]bool, len(req.Schedules))
+
+	for _, input := range req.Schedules {
+		if desired[input.Title] {
+			return nil, fmt.Errorf("duplicate schedule title %q", input.Title)
+		}
+		desired[input.Title] = true
+
+		change := plannedChange{
+			ScheduleChange: model.ScheduleChange{Title: input.Title},
+			current:        byTitle[input.Title],
+			input:          input,
+		}
+
+		if change.current != nil {
+			change.ID = change.current.ID
+			change.Diff = model.DiffSchedule(change.current, input)
+
+			// unchanged schedules are not validated again, since a one-shot schedule which has already run
+			// is kept in the desired set even though its runAt is now in the past.
+			if len(change.Diff) == 0 {
+				change.Action = model.ApplyActionUnchanged
+				plan = append(plan, change)
+				continue
+			}
+			change.Action = model.ApplyActionUpdate
+
+			if !grants.Allows(auth.ActionEdit, namespace, change.current.Metadata) {
+				return nil, fmt.Errorf("schedule %q: %w", input.Title, auth.ErrPermissionDenied)
+			}
+		} else {
+			change.Action = model.ApplyActionCreate
+		}
+
+		if err := s.checkDesired(ctx, &change); err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", input.Title, err)
+		}
+		plan = append(plan, change)
+	}
+
+	for _, sched := range existing {
+		if desired[sched.Title] {
+			continue
+		}
+
+		change := plannedChange{
+			ScheduleChange: model.ScheduleChange{
+				Action: model.ApplyActionUnmanaged,
+				Title:  sched.Title,
+				ID:     sched.ID,
+			},
+			current: sched,
+		}
+
+		if req.Prune {
+			if !grants.Allows(auth.ActionEdit, namespace, sched.Metadata) {
+				return nil, fmt.Errorf("schedule %q: %w", sched.Title, auth.ErrPermissionDenied)
+			}
+			change.Action = model.ApplyActionDelete
+		}
+		plan = append(plan, change)
+	}
+
+	if err := s.checkPlanQuota(ctx, namespace, plan, len(existing)); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// checkPlanQuota verifies that the schedules created and updated by a plan don't exceed the quota of the namespace,
+// once the schedules it deletes have been released, so that a plan exceeding it is rejected before anything is changed.
+func (s *schedService) checkPlanQuota(ctx context.Context, namespace string, plan []plannedChange, existing int) error {
+	quota, err := s.namespaceSvc.Quota(ctx, namespace)
+	if err != nil {
+		return err
+	}
+
+	n, created := existing, 0
+	for _, change := range plan {
+		switch change.Action {
+		case model.ApplyActionCreate:
+			n++
+			created++
+		case model.ApplyActionDelete:
+			n--
+		}
+
+		if change.desired == nil {
+			continue
+		}
+
+		if err := checkMinInterval(change.desired, quota); err != nil {
+			return fmt.Errorf("schedule %q: %w", change.Title, err)
+		}
+	}
+
+	// namespaces already exceeding a lowered quota can still update their schedules
+	if quota.MaxSchedules > 0 && created > 0 && n > quota.MaxSchedules {
+		return fmt.Errorf("%w: at most %d schedules are allowed, %d would exist", ErrQuotaExceeded, quota.MaxSchedules, n)
+	}
+	return nil
+}
+
+// checkDesired validates the schedule a change is going to create or update.
+func (s *schedService) checkDesired(ctx context.Context, change *plannedChange) error {
+	sched, err := change.input.ToSched(targetNamespace(ctx))
+	if err != nil {
+		return err
+	}
+
+	if !auth.GrantsFrom(ctx).Allows(auth.ActionEdit, sched.Namespace, sched.Metadata) {
+		return auth.ErrPermissionDenied
+	}
+
+	if err := s.checkDelivery(ctx, sched); err != nil {
+		return err
+	}
+
+	if err := s.checkCalendars(ctx, sched); err != nil {
+		return err
+	}
+
+	change.desired = sched
+	return nil
+}
+
+func (s *schedService) applyChange(ctx context.Context, change *plannedChange) error {
+	switch change.Action {
+	case model.ApplyActionCreate:
+		sched, err := s.RegisterSchedule(ctx, change.input)
+		if err != nil {
+			return err
+		}
+		change.ID = sched.ID
+		return nil
+	case model.ApplyActionDelete:
+		return s.DeleteSchedule(ctx, change.ID)
+	case model.ApplyActionUpdate:
+		return s.updateSchedule(ctx, change.current, change.desired)
+	}
+	return nil
+}
+
+// updateSchedule replaces the definition of a schedule, preserving its identity and status.
+func (s *schedService) updateSchedule(ctx context.Context, current, updated *model.CronSchedule) error {
+	updated.ID = current.ID
+	updated.Status = current.Status
+	updated.CreatedAt = current.CreatedAt
+	updated.Failures = current.Failures
+	updated.Runs = current.Runs
+	updated.SuccessfulRuns = current.SuccessfulRuns
+
+	// raising the maxRuns of an expired schedule lets it run again
+	if updated.Status == model.ScheduleStatusExpired && !updated.RunsExhausted() {
+		updated.Status = model.ScheduleStatusActive
+	}
+
+	// interval schedules keep counting from their anchor, unless they are moved to a different start
+	if updated.Interval > 0 && current.Interval > 0 && updated.IntervalMode == current.IntervalMode &&
+		updated.StartAt.Equal(current.StartAt) {
+		updated.Anchor = current.Anchor
+	}
+
+	if _, err := s.cronRepo.Save(ctx, updated); err != nil {
+		return err
+	}
+
+	s.scheduler.Remove(updated.ID)
+	if updated.IsActive() {
+		s.scheduler.Schedule(updated.ID, updated.NextTick())
+	}
+
+	s.audit(ctx, model.AuditActionUpdate, current, updated)
+	return nil
+}
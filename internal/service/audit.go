@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/ostafen/kronos/internal/auth"
+	"github.com/ostafen/kronos/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// actorSystem is recorded for operations which are not issued through the api.
+const actorSystem = "system"
+
+// audit appends an entry to the audit log. The operation being recorded has already taken place,
+// so a failure is logged rather than returned to the caller.
+func (s *schedService) audit(ctx context.Context, action model.AuditAction, before, after *model.CronSchedule) {
+	entry := &model.AuditEntry{
+		At:       time.Now(),
+		Actor:    actorSystem,
+		Method:   actorSystem,
+		SourceIP: auth.SourceIPFrom(ctx),
+		Action:   action,
+	}
+
+	if p, ok := auth.PrincipalFrom(ctx); ok {
+		entry.Actor = p.Subject
+		entry.Method = p.Method
+	}
+
+	if before != nil {
+		snapshot := *before
+		entry.Before = &snapshot
+		entry.ScheduleID = before.ID
+		entry.Namespace = before.Namespace
+	}
+
+	if after != nil {
+		snapshot := *after
+		entry.After = &snapshot
+		entry.ScheduleID = after.ID
+		entry.Namespace = after.Namespace
+	}
+
+	if err := s.auditRepo.Insert(context.WithoutCancel(ctx), entry); err != nil {
+		log.WithError(err).
+			WithField("scheduleId", entry.ScheduleID).
+			WithField("action", action).
+			Error("unable to record audit entry")
+	}
+}
+
+func (s *schedService) QueryAudit(ctx context.Context, query *model.AuditQuery) (_ *model.AuditPage, err error) {
+	ctx, span := startSpan(ctx, "QueryAudit")
+	defer func() { endSpan(span, err) }()
+
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	if ns := namespaceOf(ctx); ns != "" {
+		if query.Namespace != "" && query.Namespace != ns {
+			return nil, auth.ErrNamespaceDenied
+		}
+		query.Namespace = ns
+	}
+
+	entries, err := s.auditRepo.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.AuditPage{Entries: entries}
+	if len(entries) == query.Limit {
+		page.NextCursor = entries[len(entries)-1].ID
+	}
+	return page, nil
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ostafen/kronos/internal/ical"
+	"github.com/ostafen/kronos/model"
+	"github.com/ostafen/kronos/store"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrUnknownCalendar = errors.New("unknown calendar")
+	ErrCalendarInUse   = errors.New("calendar is referenced by some schedule")
+)
+
+// icsHorizon bounds the expansion of the recurring events of imported iCalendar files.
+const icsHorizon = 10 * 365 * 24 * time.Hour
+
+type CalendarService interface {
+	// PutCalendar creates the calendar, or replaces an existing one.
+	PutCalendar(ctx context.Context, input *model.CalendarInput) (*model.Calendar, error)
+	GetCalendar(ctx context.Context, name string) (*model.Calendar, error)
+	ListCalendars(ctx context.Context) ([]*model.Calendar, error)
+	DeleteCalendar(ctx context.Context, name string) error
+}
+
+func NewCalendarService(store store.Store) CalendarService {
+	return &calendarService{
+		repo:          store.CalendarRepository(),
+		namespaceRepo: store.NamespaceRepository(),
+		cronRepo:      store.CronScheduleRepository(),
+	}
+}
+
+type calendarService struct {
+	repo          store.CalendarRepository
+	namespaceRepo store.NamespaceRepository
+	cronRepo      store.CronScheduleRepository
+}
+
+func (s *calendarService) PutCalendar(ctx context.Context, input *model.CalendarInput) (_ *model.Calendar, err error) {
+	ctx, span := startSpan(ctx, "PutCalendar")
+	defer func() { endSpan(span, err) }()
+
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	namespace := targetNamespace(ctx)
+	if _, err := s.namespaceRepo.Get(ctx, namespace); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	calendar := &model.Calendar{
+		Namespace:   namespace,
+		Name:        input.Name,
+		Description: input.Description,
+		TimeZone:    input.TimeZone,
+		Dates:       append([]string{}, input.Dates...),
+		Blackouts:   input.Blackouts,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if input.ICS != "" {
+		dates, err := ical.Dates(strings.NewReader(input.ICS), calendar.Location(), now.Add(icsHorizon))
+		if err != nil {
+			return nil, fmt.Errorf("invalid ics: %w", err)
+		}
+		calendar.Dates = append(calendar.Dates, dates...)
+	}
+	calendar.NormalizeDates()
+
+	if existing, err := s.repo.Get(ctx, namespace, input.Name); err == nil {
+		calendar.CreatedAt = existing.CreatedAt
+	} else if !errors.Is(err, store.ErrCalendarNotExist) {
+		return nil, err
+	}
+
+	if err := s.repo.Save(ctx, calendar); err != nil {
+		return nil, err
+	}
+
+	log.WithField("namespace", namespace).
+		WithField("calendar", input.Name).
+		WithField("dates", len(calendar.Dates)).
+		WithField("blackouts", len(calendar.Blackouts)).
+		Info("calendar saved")
+
+	return calendar, nil
+}
+
+func (s *calendarService) GetCalendar(ctx context.Context, name string) (_ *model.Calendar, err error) {
+	ctx, span := startSpan(ctx, "GetCalendar")
+	defer func() { endSpan(span, err) }()
+
+	return s.repo.Get(ctx, targetNamespace(ctx), name)
+}
+
+func (s *calendarService) ListCalendars(ctx context.Context) (_ []*model.Calendar, err error) {
+	ctx, span := startSpan(ctx, "ListCalendars")
+	defer func() { endSpan(span, err) }()
+
+	return s.repo.List(ctx, namespaceOf(ctx))
+}
+
+func (s *calendarService) DeleteCalendar(ctx context.Context, name string) (err error) {
+	ctx, span := startSpan(ctx, "DeleteCalendar")
+	defer func() { endSpan(span, err) }()
+
+	namespace := targetNamespace(ctx)
+
+	err = s.cronRepo.Iter(ctx, namespace, func(sched *model.CronSchedule) error {
+		for _, calendar := range sched.Calendars {
+			if calendar == name {
+				return fmt.Errorf("%w (schedule %d)", ErrCalendarInUse, sched.ID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, namespace, name)
+}
+
+// checkCalendars checks that the calendars referenced by a schedule exist in its namespace.
+func (s *schedService) checkCalendars(ctx context.Context, sched *model.CronSchedule) error {
+	for _, name := range sched.Calendars {
+		_, err := s.calendarRepo.Get(ctx, sched.Namespace, name)
+		if errors.Is(err, store.ErrCalendarNotExist) {
+			return fmt.Errorf("%w %q", ErrUnknownCalendar, name)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exclusion returns the reason why an occurrence of a schedule is excluded by its calendars, or an empty string.
+// Calendars which can't be read don't exclude anything, so that they never prevent deliveries.
+func (s *schedService) exclusion(ctx context.Context, sched *model.CronSchedule, at time.Time) string {
+	for _, name := range sched.Calendars {
+		calendar, err := s.calendarRepo.Get(ctx, sched.Namespace, name)
+		if err != nil {
+			log.WithError(err).
+				WithField("scheduleId", sched.ID).
+				WithField("calendar", name).
+				Error("unable to read calendar")
+			continue
+		}
+
+		if reason := calendar.Excludes(at); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// maxShiftDays bounds the search of the next business day, past which occurrences are skipped.
+const maxShiftDays = 366
+
+// nextBusinessTime returns the same time as at on the next business day, that is the next day
+// from Monday to Friday which the calendars of the schedule don't exclude, or the zero time if there is none.
+// Days are counted in the time zone of the first calendar of the schedule.
+func (s *schedService) nextBusinessTime(ctx context.Context, sched *model.CronSchedule, at time.Time) time.Time {
+	at = at.In(s.businessLocation(ctx, sched, at))
+
+	for i := 1; i <= maxShiftDays; i++ {
+		t := at.AddDate(0, 0, i)
+		if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+			continue
+		}
+
+		if s.exclusion(ctx, sched, t) == "" {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// businessLocation returns the time zone of the first readable calendar of a schedule, or the one of at.
+func (s *schedService) businessLocation(ctx context.Context, sched *model.CronSchedule, at time.Time) *time.Location {
+	for _, name := range sched.Calendars {
+		calendar, err := s.calendarRepo.Get(ctx, sched.Namespace, name)
+		if err == nil {
+			return calendar.Location()
+		}
+	}
+	return at.Location()
+}
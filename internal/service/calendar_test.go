@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ostafen/kronos/model"
+	"github.com/ostafen/kronos/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlackoutPolicies(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "kronos.db"))
+	require.NoError(t, err)
+	defer st.Close()
+
+	ctx := context.Background()
+
+	_, err = NewCalendarService(st).PutCalendar(ctx, &model.CalendarInput{
+		Name:     "holidays",
+		TimeZone: "UTC",
+		Dates:    []string{"2030-12-25", "2030-12-27"},
+		// from friday evening to monday morning
+		Blackouts: []model.BlackoutWindow{{CronExpr: "0 18 * * FRI", Duration: model.Duration(64 * time.Hour)}},
+	})
+	require.NoError(t, err)
+
+	svc := NewScheduleService(st, NewNotificationService(nil, nil, nil), NewNamespaceService(st, model.Quota{}), nil).(*schedService)
+
+	sched := &model.CronSchedule{
+		Namespace:   model.DefaultNamespace,
+		Title:       "payroll",
+		Status:      model.ScheduleStatusActive,
+		CronExpr:    "CRON_TZ=UTC 0 9 * * *",
+		URL:         "http://localhost",
+		IsRecurring: true,
+		StartAt:     time.Now(),
+		EndAt:       time.Date(2031, 6, 1, 0, 0, 0, 0, time.UTC),
+		CreatedAt:   time.Now(),
+		Calendars:   []string{"holidays"},
+	}
+	sched.ID, err = st.CronScheduleRepository().Save(ctx, sched)
+	require.NoError(t, err)
+
+	require.ErrorIs(t, NewCalendarService(st).DeleteCalendar(ctx, "holidays"), ErrCalendarInUse)
+
+	christmas := time.Date(2030, 12, 25, 9, 0, 0, 0, time.UTC)
+
+	next := svc.OnTick(sched.ID, christmas)
+	require.Equal(t, sched.NextTick(), next)
+
+	history, err := st.HistoryRepository().GetCronHistory(ctx, model.DefaultNamespace, sched.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.True(t, history[0].At.Equal(christmas))
+	require.Contains(t, history[0].Skipped, "2030-12-25")
+
+	sched.BlackoutPolicy = model.BlackoutPolicyShift
+	_, err = st.CronScheduleRepository().Save(ctx, sched)
+	require.NoError(t, err)
+
+	require.Equal(t, christmas.AddDate(0, 0, 1), svc.OnTick(sched.ID, christmas))
+
+	// the weekend is skipped, as well as monday, which is inside the blackout window
+	require.Equal(t, time.Date(2030, 12, 31, 9, 0, 0, 0, time.UTC), svc.OnTick(sched.ID, christmas.AddDate(0, 0, 2)))
+
+	history, err = st.HistoryRepository().GetCronHistory(ctx, model.DefaultNamespace, sched.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+}
+
+func TestShiftInCalendarTimeZone(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "kronos.db"))
+	require.NoError(t, err)
+	defer st.Close()
+
+	ctx := context.Background()
+
+	_, err = NewCalendarService(st).PutCalendar(ctx, &model.CalendarInput{Name: "kiribati", TimeZone: "Pacific/Kiritimati"})
+	require.NoError(t, err)
+
+	svc := NewScheduleService(st, NewNotificationService(nil, nil, nil), NewNamespaceService(st, model.Quota{}), nil).(*schedService)
+
+	sched := &model.CronSchedule{Namespace: model.DefaultNamespace, Calendars: []string{"kiribati"}}
+
+	// friday at noon in UTC is already saturday in Kiribati, whose next business day is monday
+	friday := time.Date(2030, 12, 20, 12, 0, 0, 0, time.UTC)
+	require.True(t, time.Date(2030, 12, 22, 12, 0, 0, 0, time.UTC).Equal(svc.nextBusinessTime(ctx, sched, friday)))
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ostafen/kronos/internal/crontab"
+	"github.com/ostafen/kronos/model"
+)
+
+// ImportCrontab converts the entries of a crontab to schedules, and applies them to the target namespace
+// without pruning, so that importing the same crontab again only updates the changed entries.
+func (s *schedService) ImportCrontab(ctx context.Context, req *model.CrontabImportRequest) (_ *model.CrontabImportResult, err error) {
+	ctx, span := startSpan(ctx, "ImportCrontab")
+	defer func() { endSpan(span, err) }()
+
+	parsed, err := crontab.Parse(strings.NewReader(req.Crontab), crontab.Options{
+		URLTemplate:   req.URLTemplate,
+		TitleTemplate: req.TitleTemplate,
+		System:        req.System,
+		Metadata:      req.Metadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// a partially applied import is reported along with the error, as apply does
+	res, err := s.ApplySchedules(ctx, &model.ApplyRequest{Schedules: parsed.Schedules, DryRun: req.DryRun})
+	if res == nil {
+		return nil, err
+	}
+	return &model.CrontabImportResult{ApplyResult: *res, Unsupported: parsed.Unsupported}, err
+}
+
+// ExportCrontab renders the schedules visible to the caller as crontab lines.
+func (s *schedService) ExportCrontab(ctx context.Context) (_ string, err error) {
+	ctx, span := startSpan(ctx, "ExportCrontab")
+	defer func() { endSpan(span, err) }()
+
+	var schedules []*model.CronSchedule
+	err = s.IterSchedules(ctx, func(sched *model.CronSchedule) error {
+		schedules = append(schedules, sched)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if err := crontab.Format(&sb, schedules); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ostafen/kronos/internal/auth"
+	"github.com/ostafen/kronos/model"
+	"github.com/ostafen/kronos/store"
+)
+
+var (
+	ErrIdempotencyKeyInProgress = errors.New("a request with the same idempotency key is in progress")
+	ErrIdempotencyKeyReused     = errors.New("idempotency key reused for a different request")
+)
+
+// abandonAfter bounds how long a reserved key waits for the response of its request,
+// after which the request is assumed to have been interrupted (e.g. by a crash) and the key released.
+const abandonAfter = time.Minute
+
+type IdempotencyService interface {
+	// Begin reserves a key for the request identified by fingerprint.
+	// If a request with the same key has already completed, its record is returned and the request must not be executed again.
+	Begin(ctx context.Context, key, fingerprint string) (*model.IdempotencyRecord, error)
+	// Complete stores the response to be replayed to repeated requests.
+	Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte) error
+	// Abort releases a key whose request failed, so that it can be retried.
+	Abort(ctx context.Context, key string) error
+}
+
+func NewIdempotencyService(store store.Store, retention time.Duration) IdempotencyService {
+	return &idempotencyService{
+		repo:      store.IdempotencyRepository(),
+		retention: retention,
+	}
+}
+
+type idempotencyService struct {
+	repo      store.IdempotencyRepository
+	retention time.Duration
+}
+
+// idempotencyScope confines keys to the caller which issued them, within the namespace of the request.
+func idempotencyScope(ctx context.Context) string {
+	subject := ""
+	if p, ok := auth.PrincipalFrom(ctx); ok {
+		subject = p.Subject
+	}
+	return subject + "@" + namespaceOf(ctx)
+}
+
+func (s *idempotencyService) Begin(ctx context.Context, key, fingerprint string) (_ *model.IdempotencyRecord, err error) {
+	ctx, span := startSpan(ctx, "BeginIdempotentRequest")
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.repo.DeleteBefore(ctx, time.Now().Add(-s.retention)); err != nil {
+		return nil, err
+	}
+
+	record := &model.IdempotencyRecord{
+		Scope:       idempotencyScope(ctx),
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   time.Now(),
+	}
+
+	err = s.repo.Reserve(ctx, record)
+	if !errors.Is(err, store.ErrIdempotencyKeyExists) {
+		return nil, err
+	}
+
+	existing, err := s.repo.Get(ctx, record.Scope, key)
+	if errors.Is(err, store.ErrIdempotencyKeyNotExist) {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if existing.Fingerprint != fingerprint {
+		return nil, ErrIdempotencyKeyReused
+	}
+
+	if existing.StatusCode != 0 {
+		return existing, nil
+	}
+
+	if time.Since(existing.CreatedAt) < abandonAfter {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+
+	if err := s.repo.Delete(ctx, record.Scope, key); err != nil && !errors.Is(err, store.ErrIdempotencyKeyNotExist) {
+		return nil, err
+	}
+
+	if err := s.repo.Reserve(ctx, record); errors.Is(err, store.ErrIdempotencyKeyExists) {
+		return nil, ErrIdempotencyKeyInProgress
+	} else if err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (s *idempotencyService) Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte) (err error) {
+	ctx, span := startSpan(ctx, "CompleteIdempotentRequest")
+	defer func() { endSpan(span, err) }()
+
+	return s.repo.Complete(ctx, &model.IdempotencyRecord{
+		Scope:       idempotencyScope(ctx),
+		Key:         key,
+		StatusCode:  statusCode,
+		ContentType: contentType,
+		Body:        body,
+	})
+}
+
+func (s *idempotencyService) Abort(ctx context.Context, key string) (err error) {
+	ctx, span := startSpan(ctx, "AbortIdempotentRequest")
+	defer func() { endSpan(span, err) }()
+
+	return s.repo.Delete(ctx, idempotencyScope(ctx), key)
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ostafen/kronos/internal/auth"
+	"github.com/ostafen/kronos/model"
+	"github.com/ostafen/kronos/store"
+)
+
+var (
+	ErrNamespaceNotEmpty = errors.New("namespace still contains schedules")
+	ErrQuotaExceeded     = errors.New("namespace quota exceeded")
+	ErrRateLimited       = errors.New("namespace delivery rate limit exceeded")
+)
+
+type NamespaceService interface {
+	CreateNamespace(ctx context.Context, input *model.NamespaceInput) (*model.Namespace, error)
+	UpdateNamespace(ctx context.Context, input *model.NamespaceInput) (*model.Namespace, error)
+	GetNamespace(ctx context.Context, name string) (*model.Namespace, error)
+	ListNamespaces(ctx context.Context) ([]*model.Namespace, error)
+	DeleteNamespace(ctx context.Context, name string) error
+
+	// Quota returns the limits which apply to a namespace, once defaults have been merged in.
+	Quota(ctx context.Context, name string) (model.Quota, error)
+}
+
+func NewNamespaceService(store store.Store, defaultQuota model.Quota) NamespaceService {
+	return &namespaceService{
+		repo:         store.NamespaceRepository(),
+		cronRepo:     store.CronScheduleRepository(),
+		secretRepo:   store.SecretRepository(),
+		calendarRepo: store.CalendarRepository(),
+		defaultQuota: defaultQuota,
+	}
+}
+
+type namespaceService struct {
+	repo         store.NamespaceRepository
+	cronRepo     store.CronScheduleRepository
+	secretRepo   store.SecretRepository
+	calendarRepo store.CalendarRepository
+	defaultQuota model.Quota
+}
+
+// namespaceOf returns the namespace the caller is confined to, or store.AllNamespaces.
+func namespaceOf(ctx context.Context) string {
+	return auth.NamespaceFrom(ctx)
+}
+
+// targetNamespace returns the namespace new resources of the caller are created in.
+func targetNamespace(ctx context.Context) string {
+	if ns := auth.NamespaceFrom(ctx); ns != "" {
+		return ns
+	}
+	return model.DefaultNamespace
+}
+
+// requireGlobal fails if the caller is bound to a namespace. Internal calls, which carry no principal, are always allowed.
+func requireGlobal(ctx context.Context) error {
+	if p, ok := auth.PrincipalFrom(ctx); ok && !p.IsGlobal() {
+		return auth.ErrNamespaceDenied
+	}
+	return nil
+}
+
+func (s *namespaceService) CreateNamespace(ctx context.Context, input *model.NamespaceInput) (_ *model.Namespace, err error) {
+	ctx, span := startSpan(ctx, "CreateNamespace")
+	defer func() { endSpan(span, err) }()
+
+	if err := requireGlobal(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	ns := &model.Namespace{
+		Name:        input.Name,
+		Description: input.Description,
+		Quota:       input.Quota,
+		CreatedAt:   time.Now(),
+	}
+	return ns, s.repo.Create(ctx, ns)
+}
+
+func (s *namespaceService) UpdateNamespace(ctx context.Context, input *model.NamespaceInput) (_ *model.Namespace, err error) {
+	ctx, span := startSpan(ctx, "UpdateNamespace")
+	defer func() { endSpan(span, err) }()
+
+	if err := requireGlobal(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	ns, err := s.repo.Get(ctx, input.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	ns.Description = input.Description
+	ns.Quota = input.Quota
+	return ns, s.repo.Update(ctx, ns)
+}
+
+func (s *namespaceService) GetNamespace(ctx context.Context, name string) (_ *model.Namespace, err error) {
+	ctx, span := startSpan(ctx, "GetNamespace")
+	defer func() { endSpan(span, err) }()
+
+	if ns := namespaceOf(ctx); ns != "" && ns != name {
+		return nil, auth.ErrNamespaceDenied
+	}
+	return s.repo.Get(ctx, name)
+}
+
+func (s *namespaceService) ListNamespaces(ctx context.Context) (_ []*model.Namespace, err error) {
+	ctx, span := startSpan(ctx, "ListNamespaces")
+	defer func() { endSpan(span, err) }()
+
+	if name := namespaceOf(ctx); name != "" {
+		ns, err := s.repo.Get(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		return []*model.Namespace{ns}, nil
+	}
+	return s.repo.List(ctx)
+}
+
+func (s *namespaceService) DeleteNamespace(ctx context.Context, name string) (err error) {
+	ctx, span := startSpan(ctx, "DeleteNamespace")
+	defer func() { endSpan(span, err) }()
+
+	if err := requireGlobal(ctx); err != nil {
+		return err
+	}
+
+	if name == model.DefaultNamespace {
+		return fmt.Errorf("the %s namespace cannot be deleted", model.DefaultNamespace)
+	}
+
+	n, err := s.cronRepo.Count(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		return ErrNamespaceNotEmpty
+	}
+
+	secrets, err := s.secretRepo.List(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if len(secrets) > 0 {
+		return ErrNamespaceNotEmpty
+	}
+
+	calendars, err := s.calendarRepo.List(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if len(calendars) > 0 {
+		return ErrNamespaceNotEmpty
+	}
+	return s.repo.Delete(ctx, name)
+}
+
+func (s *namespaceService) Quota(ctx context.Context, name string) (model.Quota, error) {
+	ns, err := s.repo.Get(ctx, name)
+	if err != nil {
+		return model.Quota{}, err
+	}
+	return ns.Quota.Merge(s.defaultQuota), nil
+}
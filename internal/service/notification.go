@@ -3,44 +3,319 @@ package service
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ostafen/kronos/internal/egress"
+	"github.com/ostafen/kronos/internal/metrics"
+	"github.com/ostafen/kronos/internal/oauth"
+	"github.com/ostafen/kronos/internal/tlsprofile"
+	"github.com/ostafen/kronos/model"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Target identifies where, and how, a webhook is delivered.
+type Target struct {
+	URL string
+	// Namespace is the namespace of the schedule the deliveries belong to.
+	Namespace string
+	// TLSProfile names the TLS client configuration used to connect to URL. Empty means the default one.
+	TLSProfile string
+	// Credentials names the OAuth2 credentials used to authenticate deliveries, if any.
+	Credentials string
+	// Headers are added to deliveries. Their values are redacted when logged.
+	Headers map[string]model.SecretValue
+}
+
+func scheduleTarget(sched *model.CronSchedule) Target {
+	return Target{URL: sched.URL, Namespace: sched.Namespace, TLSProfile: sched.TLSProfile, Credentials: sched.Credentials}
+}
+
 type NotificationService interface {
-	Send(ctx context.Context, url string, payload any) (int, error)
+	Send(ctx context.Context, target Target, payload any) (int, error)
+	// CheckTarget validates a webhook target against the egress policy, the configured TLS profiles and credentials.
+	CheckTarget(ctx context.Context, target Target) error
 }
 
 type httpNotificationService struct {
+	policy      *egress.Policy
+	profiles    *tlsprofile.Registry
+	credentials *oauth.Registry
+
+	mtx     sync.Mutex
+	clients map[string]*http.Client
+}
+
+const (
+	maxRedirects   = 10
+	resolveTimeout = 2 * time.Second
+)
+
+// NewNotificationService returns a service delivering webhooks over HTTP.
+// Connections are subject to the egress policy, which is checked after DNS resolution; a nil policy allows any target.
+// A nil profile registry only provides the default TLS configuration, while a nil credentials registry provides none.
+func NewNotificationService(policy *egress.Policy, profiles *tlsprofile.Registry, credentials *oauth.Registry) NotificationService {
+	return &httpNotificationService{
+		policy:      policy,
+		profiles:    profiles,
+		credentials: credentials,
+		clients:     make(map[string]*http.Client),
+	}
+}
+
+func (s *httpNotificationService) newTransport(tlsConfig *tls.Config) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   s.policy.Control,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	if s.policy != nil {
+		// a proxy would connect to the target on our behalf, bypassing the policy
+		transport.Proxy = nil
+	}
+
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	return transport
+}
+
+// client returns the client delivering webhooks through the given TLS profile.
+func (s *httpNotificationService) client(profileName string) (*http.Client, error) {
+	profile, err := s.profiles.Get(profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if client, has := s.clients[profileName]; has {
+		return client, nil
+	}
+
+	var transport http.RoundTripper
+	if profile != nil {
+		transport = &reloadingTransport{profile: profile, newTransport: s.newTransport}
+	} else {
+		transport = s.newTransport(nil)
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+
+			// secret headers and bearer tokens of https targets would otherwise be sent in clear text
+			if via[0].URL.Scheme == "https" && req.URL.Scheme != "https" {
+				return fmt.Errorf("refusing to follow a redirect from https to %s", req.URL.Scheme)
+			}
+
+			// like Authorization, secret headers are only sent to the host of the target, which
+			// http.Client would otherwise forward to any host it is redirected to
+			if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+				for _, header := range secretHeadersFrom(req.Context()) {
+					req.Header.Del(header)
+				}
+			}
+			return s.policy.CheckURL(req.Context(), nil, req.URL.String())
+		},
+	}
+	s.clients[profileName] = client
+	return client, nil
+}
+
+type secretHeadersKey struct{}
+
+// withSecretHeaders records the names of the secret headers of a delivery, which redirects must not forward to other hosts.
+func withSecretHeaders(ctx context.Context, headers map[string]model.SecretValue) context.Context {
+	if len(headers) == 0 {
+		return ctx
+	}
+
+	names := make([]string, 0, len(headers))
+	for header := range headers {
+		names = append(names, header)
+	}
+	return context.WithValue(ctx, secretHeadersKey{}, names)
+}
+
+func secretHeadersFrom(ctx context.Context) []string {
+	names, _ := ctx.Value(secretHeadersKey{}).([]string)
+	return names
+}
+
+// reloadingTransport rebuilds its underlying transport whenever its TLS profile is reloaded.
+type reloadingTransport struct {
+	profile      *tlsprofile.Profile
+	newTransport func(*tls.Config) *http.Transport
+
+	mtx        sync.Mutex
+	generation uint64
+	transport  *http.Transport
 }
 
-func NewNotificationService() NotificationService {
-	return &httpNotificationService{}
+func (t *reloadingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tlsConfig, generation := t.profile.Config()
+
+	t.mtx.Lock()
+	if t.transport == nil || t.generation != generation {
+		if t.transport != nil {
+			t.transport.CloseIdleConnections()
+		}
+		t.transport = t.newTransport(tlsConfig)
+		t.generation = generation
+	}
+	transport := t.transport
+	t.mtx.Unlock()
+
+	return transport.RoundTrip(req)
+}
+
+func (s *httpNotificationService) CheckTarget(ctx context.Context, target Target) error {
+	if _, err := s.profiles.Get(target.TLSProfile); err != nil {
+		return err
+	}
+
+	source, err := s.credentials.Get(target.Credentials)
+	if err != nil {
+		return err
+	}
+
+	if source != nil {
+		if err := source.Allows(target.URL, target.Namespace); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	return s.policy.CheckURL(ctx, net.DefaultResolver, target.URL)
+}
+
+// redactURL hides the password of urls carrying credentials, so that they can be logged.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Redacted()
 }
 
 func isSuccess(resp *http.Response) bool {
 	return resp.StatusCode >= 200 && resp.StatusCode < 300
 }
 
-func (s *httpNotificationService) Send(ctx context.Context, url string, payload any) (int, error) {
-	data, err := json.Marshal(payload)
+func (s *httpNotificationService) Send(ctx context.Context, target Target, payload any) (status int, err error) {
+	ctx, span := tracer.Start(ctx, "webhook.deliver",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("http.request.method", "POST")),
+	)
+	defer func() {
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	if err := s.policy.CheckURL(ctx, nil, target.URL); err != nil {
+		return -1, err
+	}
+
+	client, err := s.client(target.TLSProfile)
 	if err != nil {
 		return -1, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data)) // TODO: add support for other types of methods
+	source, err := s.credentials.Get(target.Credentials)
 	if err != nil {
 		return -1, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	// checked again, since the configuration may have changed since the schedule was registered
+	if source != nil {
+		if err := source.Allows(target.URL, target.Namespace); err != nil {
+			return -1, err
+		}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return -1, err
+	}
+
+	resp, err := s.do(ctx, client, source, target, data)
+	if errors.Is(err, egress.ErrDenied) {
+		return -1, err
+	}
+
 	if err != nil {
 		return http.StatusServiceUnavailable, err
 	}
+	defer resp.Body.Close()
 
 	if !isSuccess(resp) {
-		err = fmt.Errorf("webhook notification to %s failed with status: %s", url, resp.Status)
+		err = fmt.Errorf("webhook notification to %s failed with status: %s", redactURL(target.URL), resp.Status)
 	}
 	return resp.StatusCode, err
 }
+
+// do posts data to the target, authenticating the request with an access token of source, if any.
+// Requests rejected with 401 are retried once with a fresh token, since the cached one may have been revoked.
+func (s *httpNotificationService) do(ctx context.Context, client *http.Client, source *oauth.TokenSource, target Target, data []byte) (*http.Response, error) {
+	ctx = withSecretHeaders(ctx, target.Headers)
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "POST", target.URL, bytes.NewReader(data)) // TODO: add support for other types of methods
+		if err != nil {
+			return nil, err
+		}
+
+		for header, value := range target.Headers {
+			req.Header.Set(header, string(value))
+		}
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("server.address", req.URL.Hostname()))
+
+		// propagate the trace context (W3C traceparent), so that receivers can join the trace
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+		var accessToken string
+		if source != nil {
+			accessToken, err = source.Token(ctx)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil || source == nil || resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return resp, err
+		}
+		resp.Body.Close()
+
+		source.Invalidate(accessToken)
+		metrics.IncWebhookRetries("unauthorized")
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ostafen/kronos/internal/config"
+	"github.com/ostafen/kronos/internal/oauth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendWithOAuth2Credentials(t *testing.T) {
+	var issued atomic.Int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "Bearer", "expires_in": 3600}`, issued.Add(1))
+	}))
+	defer tokenServer.Close()
+
+	// the receiver revokes the first token after the first delivery
+	var deliveries atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := deliveries.Add(1)
+		if r.Header.Get("Authorization") == "Bearer token-1" && n > 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer receiver.Close()
+
+	credentials, err := oauth.NewRegistry(config.Webhooks{
+		Credentials: map[string]config.OAuth2Credentials{
+			"billing": {TokenURL: tokenServer.URL, ClientID: "kronos", ClientSecret: "secret", AllowedURLs: []string{receiver.URL}},
+		},
+	})
+	require.NoError(t, err)
+
+	svc := NewNotificationService(nil, nil, credentials)
+
+	target := Target{URL: receiver.URL, Credentials: "billing"}
+	require.NoError(t, svc.CheckTarget(context.Background(), target))
+	require.ErrorIs(t, svc.CheckTarget(context.Background(), Target{URL: receiver.URL, Credentials: "shipping"}), oauth.ErrUnknownCredentials)
+
+	status, err := svc.Send(context.Background(), target, map[string]string{})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, int32(1), issued.Load())
+
+	// the rejected token is replaced, and the delivery retried once
+	status, err = svc.Send(context.Background(), target, map[string]string{})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, int32(2), issued.Load())
+	require.Equal(t, int32(3), deliveries.Load())
+
+	// deliveries without credentials are not retried
+	status, err = svc.Send(context.Background(), Target{URL: receiver.URL}, map[string]string{})
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, status)
+	require.Equal(t, int32(4), deliveries.Load())
+}
+
+func TestSendWithUnavailableTokenEndpoint(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer tokenServer.Close()
+
+	var deliveries atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deliveries.Add(1)
+	}))
+	defer receiver.Close()
+
+	credentials, err := oauth.NewRegistry(config.Webhooks{
+		Credentials: map[string]config.OAuth2Credentials{
+			"billing": {TokenURL: tokenServer.URL, ClientID: "kronos", AllowedURLs: []string{receiver.URL}},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = NewNotificationService(nil, nil, credentials).Send(context.Background(), Target{URL: receiver.URL, Credentials: "billing"}, map[string]string{})
+	require.Error(t, err)
+	require.Zero(t, deliveries.Load())
+}
+
+func TestCredentialsBoundToURLs(t *testing.T) {
+	var issued atomic.Int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "Bearer"}`, issued.Add(1))
+	}))
+	defer tokenServer.Close()
+
+	var leaked atomic.Int32
+	attacker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			leaked.Add(1)
+		}
+	}))
+	defer attacker.Close()
+
+	credentials, err := oauth.NewRegistry(config.Webhooks{
+		Credentials: map[string]config.OAuth2Credentials{
+			"billing": {
+				TokenURL:          tokenServer.URL,
+				ClientID:          "kronos",
+				AllowedURLs:       []string{"https://billing.example.com/hooks/"},
+				AllowedNamespaces: []string{"billing"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	svc := NewNotificationService(nil, nil, credentials)
+
+	target := Target{URL: attacker.URL, Namespace: "billing", Credentials: "billing"}
+	require.ErrorIs(t, svc.CheckTarget(context.Background(), target), oauth.ErrCredentialsNotAllowed)
+
+	_, err = svc.Send(context.Background(), target, map[string]string{})
+	require.ErrorIs(t, err, oauth.ErrCredentialsNotAllowed)
+
+	target = Target{URL: "https://billing.example.com/hooks/invoices", Namespace: "team-a", Credentials: "billing"}
+	require.ErrorIs(t, svc.CheckTarget(context.Background(), target), oauth.ErrCredentialsNotAllowed)
+
+	require.Zero(t, issued.Load())
+	require.Zero(t, leaked.Load())
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ostafen/kronos/internal/config"
+	"github.com/ostafen/kronos/internal/egress"
+	"github.com/ostafen/kronos/model"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSendPropagatesTraceContext(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	provider := sdktrace.NewTracerProvider()
+	defer provider.Shutdown(context.Background())
+
+	traceparent := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent <- r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "test")
+	defer span.End()
+
+	status, err := NewNotificationService(nil, nil, nil).Send(ctx, Target{URL: server.URL}, map[string]string{})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, status)
+
+	received := propagation.TraceContext{}.Extract(
+		context.Background(),
+		propagation.HeaderCarrier(http.Header{"Traceparent": []string{<-traceparent}}),
+	)
+	require.Equal(t, span.SpanContext().TraceID(), trace.SpanContextFromContext(received).TraceID())
+}
+
+func TestSendEnforcesEgressPolicyAtDialTime(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	// "localhost" passes the static checks, but resolves to a loopback address
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	url := "http://localhost:" + port
+
+	policy, err := egress.NewPolicy(config.Egress{})
+	require.NoError(t, err)
+
+	_, err = NewNotificationService(policy, nil, nil).Send(context.Background(), Target{URL: url}, map[string]string{})
+	require.ErrorIs(t, err, egress.ErrDenied)
+	require.Zero(t, calls.Load())
+
+	policy, err = egress.NewPolicy(config.Egress{AllowedCIDRs: []string{"127.0.0.0/8", "::1/128"}})
+	require.NoError(t, err)
+
+	status, err := NewNotificationService(policy, nil, nil).Send(context.Background(), Target{URL: url}, map[string]string{})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestSendDeniesRedirectsToForbiddenTargets(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, strings.Replace(target.URL, "http://", "ftp://", 1), http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	policy, err := egress.NewPolicy(config.Egress{
+		AllowedSchemes: []string{"http"},
+		AllowedCIDRs:   []string{"127.0.0.0/8"},
+	})
+	require.NoError(t, err)
+
+	_, err = NewNotificationService(policy, nil, nil).Send(context.Background(), Target{URL: server.URL}, map[string]string{})
+	require.ErrorIs(t, err, egress.ErrDenied)
+}
+
+func TestSendDropsSecretHeadersOnRedirectsToOtherHosts(t *testing.T) {
+	received := make(chan http.Header, 2)
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+	}))
+	defer other.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/same-host" {
+			received <- r.Header.Clone()
+			return
+		}
+
+		// 127.0.0.1 and localhost are different hosts, even though they reach the same server
+		location := strings.Replace(other.URL, "127.0.0.1", "localhost", 1)
+		if r.URL.Query().Has("same") {
+			location = "/same-host"
+		}
+		http.Redirect(w, r, location, http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	target := Target{
+		URL:     server.URL,
+		Headers: map[string]model.SecretValue{"X-Api-Key": "s3cret"},
+	}
+
+	svc := NewNotificationService(nil, nil, nil)
+
+	_, err := svc.Send(context.Background(), target, map[string]string{})
+	require.NoError(t, err)
+	require.Empty(t, (<-received).Get("X-Api-Key"))
+
+	target.URL = server.URL + "?same"
+	_, err = svc.Send(context.Background(), target, map[string]string{})
+	require.NoError(t, err)
+	require.Equal(t, "s3cret", (<-received).Get("X-Api-Key"))
+}
+
+func TestSendDeniesRedirectsDowngradingToHTTP(t *testing.T) {
+	client, err := NewNotificationService(nil, nil, nil).(*httpNotificationService).client("")
+	require.NoError(t, err)
+
+	via, err := http.NewRequest(http.MethodPost, "https://hooks.example.com/run", nil)
+	require.NoError(t, err)
+
+	for url, allowed := range map[string]bool{
+		"https://hooks.example.com/other": true,
+		"http://hooks.example.com/run":    false,
+	} {
+		req, err := http.NewRequest(http.MethodPost, url, nil)
+		require.NoError(t, err)
+
+		err = client.CheckRedirect(req, []*http.Request{via})
+		if allowed {
+			require.NoError(t, err, url)
+		} else {
+			require.ErrorContains(t, err, "from https to http", url)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ostafen/kronos/internal/config"
+	"github.com/ostafen/kronos/internal/tlsprofile"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kronos test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// writeClientCert issues a client certificate and writes it, together with its key, to the given files.
+func (ca *testCA) writeClientCert(t *testing.T, certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "kronos"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+}
+
+func writeServerCA(t *testing.T, server *httptest.Server, path string) {
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(path, data, 0600))
+}
+
+func TestSendWithCustomCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	writeServerCA(t, server, caFile)
+
+	profiles, err := tlsprofile.NewRegistry(config.Webhooks{
+		TLSProfiles: map[string]config.TLSProfile{"internal": {CAFile: caFile}},
+	})
+	require.NoError(t, err)
+
+	svc := NewNotificationService(nil, profiles, nil)
+
+	// the test server certificate is not trusted by the system pool
+	_, err = svc.Send(context.Background(), Target{URL: server.URL}, map[string]string{})
+	require.Error(t, err)
+
+	status, err := svc.Send(context.Background(), Target{URL: server.URL, TLSProfile: "internal"}, map[string]string{})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, status)
+
+	require.ErrorIs(t, svc.CheckTarget(context.Background(), Target{URL: server.URL, TLSProfile: "external"}), tlsprofile.ErrUnknownProfile)
+}
+
+func TestSendWithClientCertificate(t *testing.T) {
+	trusted, untrusted := newTestCA(t), newTestCA(t)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  trusted.pool(),
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+
+	writeServerCA(t, server, caFile)
+	untrusted.writeClientCert(t, certFile, keyFile)
+
+	interval := tlsprofile.CheckInterval
+	tlsprofile.CheckInterval = 0
+	defer func() { tlsprofile.CheckInterval = interval }()
+
+	// the default profile applies to targets not referencing any profile
+	profiles, err := tlsprofile.NewRegistry(config.Webhooks{
+		TLS: config.TLSProfile{CertFile: certFile, KeyFile: keyFile, CAFile: caFile},
+	})
+	require.NoError(t, err)
+
+	svc := NewNotificationService(nil, profiles, nil)
+	target := Target{URL: server.URL}
+
+	_, err = svc.Send(context.Background(), target, map[string]string{})
+	require.Error(t, err)
+
+	// the certificate is reloaded as soon as the files change
+	trusted.writeClientCert(t, certFile, keyFile)
+	require.NoError(t, os.Chtimes(certFile, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+
+	status, err := svc.Send(context.Background(), target, map[string]string{})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, status)
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// deliveryLimiter counts the deliveries of each namespace over fixed one-minute windows.
+type deliveryLimiter struct {
+	mtx     sync.Mutex
+	windows map[string]*deliveryWindow
+}
+
+type deliveryWindow struct {
+	start time.Time
+	count int
+}
+
+func newDeliveryLimiter() *deliveryLimiter {
+	return &deliveryLimiter{
+		windows: make(map[string]*deliveryWindow),
+	}
+}
+
+// Allow records a delivery for the namespace, unless its limit has already been reached
+// in the current window. A non-positive limit disables rate limiting.
+func (l *deliveryLimiter) Allow(namespace string, limit int, now time.Time) bool {
+	if limit <= 0 {
+		return true
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	start := now.Truncate(time.Minute)
+
+	w, has := l.windows[namespace]
+	if !has || !w.start.Equal(start) {
+		w = &deliveryWindow{start: start}
+		l.windows[namespace] = w
+	}
+
+	if w.count >= limit {
+		return false
+	}
+	w.count++
+	return true
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ostafen/kronos/model"
+	"github.com/ostafen/kronos/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveryLimiter(t *testing.T) {
+	l := newDeliveryLimiter()
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	require.True(t, l.Allow("a", 2, now))
+	require.True(t, l.Allow("a", 2, now.Add(time.Second)))
+	require.False(t, l.Allow("a", 2, now.Add(2*time.Second)))
+
+	// namespaces are accounted for separately
+	require.True(t, l.Allow("b", 2, now))
+
+	// the counter is reset as soon as a new window starts
+	require.True(t, l.Allow("a", 2, now.Add(time.Minute)))
+
+	// a non-positive limit disables rate limiting
+	for i := 0; i < 10; i++ {
+		require.True(t, l.Allow("c", 0, now))
+	}
+}
+
+func TestApplyChecksQuota(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "kronos.db"))
+	require.NoError(t, err)
+	defer st.Close()
+
+	ctx := context.Background()
+	svc := NewScheduleService(st, NewNotificationService(nil, nil, nil), NewNamespaceService(st, model.Quota{MaxSchedules: 2, MinInterval: time.Hour}), nil)
+
+	schedule := func(title, cronExpr string) *model.ScheduleRegisterInput {
+		recurring := true
+		return &model.ScheduleRegisterInput{Title: title, URL: "http://localhost:8080/hooks", IsRecurring: &recurring, CronExpr: cronExpr}
+	}
+
+	titles := func() []string {
+		var res []string
+		require.NoError(t, svc.IterSchedules(ctx, func(s *model.CronSchedule) error {
+			res = append(res, s.Title+" "+s.CronExpr)
+			return nil
+		}))
+		return res
+	}
+
+	_, err = svc.ApplySchedules(ctx, &model.ApplyRequest{Schedules: []*model.ScheduleRegisterInput{
+		schedule("a", "0 * * * *"),
+		schedule("b", "0 * * * *"),
+	}})
+	require.NoError(t, err)
+
+	// plans are rejected before any of their changes is applied
+	_, err = svc.ApplySchedules(ctx, &model.ApplyRequest{Schedules: []*model.ScheduleRegisterInput{
+		schedule("a", "0 0 * * *"),
+		schedule("b", "* * * * *"),
+	}})
+	require.ErrorIs(t, err, ErrQuotaExceeded)
+
+	_, err = svc.ApplySchedules(ctx, &model.ApplyRequest{Schedules: []*model.ScheduleRegisterInput{
+		schedule("a", "0 0 * * *"),
+		schedule("b", "0 * * * *"),
+		schedule("c", "0 * * * *"),
+	}})
+	require.ErrorIs(t, err, ErrQuotaExceeded)
+	require.ElementsMatch(t, []string{"a 0 * * * *", "b 0 * * * *"}, titles())
+
+	// the schedules deleted by a plan release their quota
+	_, err = svc.ApplySchedules(ctx, &model.ApplyRequest{Prune: true, Schedules: []*model.ScheduleRegisterInput{
+		schedule("a", "0 0 * * *"),
+		schedule("c", "0 * * * *"),
+	}})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"a 0 0 * * *", "c 0 * * * *"}, titles())
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/ostafen/kronos/internal/auth"
+	"github.com/ostafen/kronos/model"
+	"github.com/ostafen/kronos/store"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type RoleBindingService interface {
+	CreateRoleBinding(ctx context.Context, input *model.RoleBindingInput) (*model.RoleBinding, error)
+	ListRoleBindings(ctx context.Context) ([]*model.RoleBinding, error)
+	DeleteRoleBinding(ctx context.Context, id int64) error
+
+	auth.BindingResolver
+}
+
+func NewRoleBindingService(store store.Store) RoleBindingService {
+	return &roleBindingService{
+		repo:          store.RoleBindingRepository(),
+		namespaceRepo: store.NamespaceRepository(),
+	}
+}
+
+type roleBindingService struct {
+	repo          store.RoleBindingRepository
+	namespaceRepo store.NamespaceRepository
+}
+
+// canManage reports whether the caller is allowed to manage the role bindings of a namespace.
+// Principals holding the admin scope are always allowed, the others need the admin role on the whole namespace.
+func canManage(ctx context.Context, namespace string) bool {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok || p.HasScope(auth.ScopeAdmin) {
+		return true
+	}
+
+	grants := auth.GrantsFrom(ctx)
+	return grants != nil && grants.AllowsAll(auth.ActionManage, namespace)
+}
+
+func (s *roleBindingService) CreateRoleBinding(ctx context.Context, input *model.RoleBindingInput) (_ *model.RoleBinding, err error) {
+	ctx, span := startSpan(ctx, "CreateRoleBinding")
+	defer func() { endSpan(span, err) }()
+
+	role, err := auth.ParseRole(input.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := input.Namespace
+	if ns := namespaceOf(ctx); ns != "" {
+		if namespace != "" && namespace != ns {
+			return nil, auth.ErrNamespaceDenied
+		}
+		namespace = ns
+	}
+
+	if !canManage(ctx, namespace) {
+		return nil, auth.ErrPermissionDenied
+	}
+
+	if namespace != "" {
+		if _, err := s.namespaceRepo.Get(ctx, namespace); err != nil {
+			return nil, err
+		}
+	}
+
+	binding := &model.RoleBinding{
+		Subject:   input.Subject,
+		Role:      string(role),
+		Namespace: namespace,
+		Selector:  input.Selector,
+		CreatedAt: time.Now(),
+	}
+
+	binding.ID, err = s.repo.Save(ctx, binding)
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithField("bindingId", binding.ID).
+		WithField("subject", binding.Subject).
+		WithField("role", binding.Role).
+		WithField("namespace", binding.Namespace).
+		Info("role binding created")
+
+	return binding, nil
+}
+
+func (s *roleBindingService) ListRoleBindings(ctx context.Context) (_ []*model.RoleBinding, err error) {
+	ctx, span := startSpan(ctx, "ListRoleBindings")
+	defer func() { endSpan(span, err) }()
+
+	namespace := namespaceOf(ctx)
+	if !canManage(ctx, namespace) {
+		return nil, auth.ErrPermissionDenied
+	}
+	return s.repo.List(ctx, namespace)
+}
+
+func (s *roleBindingService) DeleteRoleBinding(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "DeleteRoleBinding")
+	defer func() { endSpan(span, err) }()
+
+	namespace := namespaceOf(ctx)
+	if !canManage(ctx, namespace) {
+		return auth.ErrPermissionDenied
+	}
+	return s.repo.Delete(ctx, namespace, id)
+}
+
+func (s *roleBindingService) RoleBindings(ctx context.Context, subject string) ([]auth.Binding, error) {
+	bindings, err := s.repo.ListBySubject(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]auth.Binding, 0, len(bindings))
+	for _, b := range bindings {
+		res = append(res, auth.Binding{
+			Role:      auth.Role(b.Role),
+			Namespace: b.Namespace,
+			Selector:  b.Selector,
+		})
+	}
+	return res, nil
+}
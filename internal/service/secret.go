@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ostafen/kronos/internal/secrets"
+	"github.com/ostafen/kronos/model"
+	"github.com/ostafen/kronos/store"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrSecretsDisabled = errors.New("secrets are disabled: no encryption key is configured")
+	ErrUnknownSecret   = errors.New("unknown secret")
+	ErrSecretInUse     = errors.New("secret is referenced by some schedule")
+)
+
+// SecretResolver returns the plaintext of the secrets referenced by schedules.
+type SecretResolver interface {
+	ResolveSecret(ctx context.Context, namespace, name string) (model.SecretValue, error)
+}
+
+type SecretService interface {
+	PutSecret(ctx context.Context, input *model.SecretInput) (*model.Secret, error)
+	ListSecrets(ctx context.Context) ([]*model.Secret, error)
+	DeleteSecret(ctx context.Context, name string) error
+	// RotateSecrets re-encrypts with the current key the secrets encrypted with previous ones, and returns how many were rotated.
+	RotateSecrets(ctx context.Context) (int, error)
+
+	SecretResolver
+}
+
+// NewSecretService returns a service storing secrets encrypted with the keys of the keyring.
+// A nil keyring disables secrets.
+func NewSecretService(store store.Store, keyring *secrets.Keyring) SecretService {
+	return &secretService{
+		repo:          store.SecretRepository(),
+		namespaceRepo: store.NamespaceRepository(),
+		cronRepo:      store.CronScheduleRepository(),
+		keyring:       keyring,
+	}
+}
+
+type secretService struct {
+	repo          store.SecretRepository
+	namespaceRepo store.NamespaceRepository
+	cronRepo      store.CronScheduleRepository
+	keyring       *secrets.Keyring
+}
+
+// additionalData binds a ciphertext to the secret it belongs to, so that it cannot be moved to a different one.
+func additionalData(namespace, name string) []byte {
+	return []byte(namespace + "/" + name)
+}
+
+func (s *secretService) PutSecret(ctx context.Context, input *model.SecretInput) (_ *model.Secret, err error) {
+	ctx, span := startSpan(ctx, "PutSecret")
+	defer func() { endSpan(span, err) }()
+
+	if s.keyring == nil {
+		return nil, ErrSecretsDisabled
+	}
+
+	if err := model.ValidateSecretName(input.Name); err != nil {
+		return nil, err
+	}
+
+	namespace := targetNamespace(ctx)
+	if _, err := s.namespaceRepo.Get(ctx, namespace); err != nil {
+		return nil, err
+	}
+
+	keyID, ciphertext, err := s.keyring.Encrypt([]byte(input.Value), additionalData(namespace, input.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	createdAt := now
+	if existing, err := s.repo.Get(ctx, namespace, input.Name); err == nil {
+		createdAt = existing.CreatedAt
+	} else if !errors.Is(err, store.ErrSecretNotExist) {
+		return nil, err
+	}
+
+	secret := &model.EncryptedSecret{
+		Secret: model.Secret{
+			Namespace: namespace,
+			Name:      input.Name,
+			CreatedAt: createdAt,
+			UpdatedAt: now,
+		},
+		KeyID:      keyID,
+		Ciphertext: ciphertext,
+	}
+
+	if err := s.repo.Save(ctx, secret); err != nil {
+		return nil, err
+	}
+
+	log.WithField("namespace", namespace).
+		WithField("secret", input.Name).
+		Info("secret saved")
+
+	return &secret.Secret, nil
+}
+
+func (s *secretService) ListSecrets(ctx context.Context) (_ []*model.Secret, err error) {
+	ctx, span := startSpan(ctx, "ListSecrets")
+	defer func() { endSpan(span, err) }()
+
+	encrypted, err := s.repo.List(ctx, namespaceOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*model.Secret, 0, len(encrypted))
+	for _, secret := range encrypted {
+		res = append(res, &secret.Secret)
+	}
+	return res, nil
+}
+
+func (s *secretService) DeleteSecret(ctx context.Context, name string) (err error) {
+	ctx, span := startSpan(ctx, "DeleteSecret")
+	defer func() { endSpan(span, err) }()
+
+	namespace := targetNamespace(ctx)
+
+	err = s.cronRepo.Iter(ctx, namespace, func(sched *model.CronSchedule) error {
+		for _, secret := range sched.SecretHeaders {
+			if secret == name {
+				return fmt.Errorf("%w (schedule %d)", ErrSecretInUse, sched.ID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, namespace, name)
+}
+
+func (s *secretService) ResolveSecret(ctx context.Context, namespace, name string) (model.SecretValue, error) {
+	if s.keyring == nil {
+		return "", ErrSecretsDisabled
+	}
+
+	secret, err := s.repo.Get(ctx, namespace, name)
+	if errors.Is(err, store.ErrSecretNotExist) {
+		return "", fmt.Errorf("%w %q", ErrUnknownSecret, name)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := s.keyring.Decrypt(secret.KeyID, secret.Ciphertext, additionalData(namespace, name))
+	if err != nil {
+		return "", fmt.Errorf("unable to decrypt secret %q: %w", name, err)
+	}
+	return model.SecretValue(plaintext), nil
+}
+
+func (s *secretService) RotateSecrets(ctx context.Context) (_ int, err error) {
+	ctx, span := startSpan(ctx, "RotateSecrets")
+	defer func() { endSpan(span, err) }()
+
+	if s.keyring == nil {
+		return 0, nil
+	}
+
+	encrypted, err := s.repo.List(ctx, store.AllNamespaces)
+	if err != nil {
+		return 0, err
+	}
+
+	rotated := 0
+	for _, secret := range encrypted {
+		if secret.KeyID == s.keyring.KeyID() {
+			continue
+		}
+
+		aad := additionalData(secret.Namespace, secret.Name)
+
+		plaintext, err := s.keyring.Decrypt(secret.KeyID, secret.Ciphertext, aad)
+		if err != nil {
+			return rotated, fmt.Errorf("unable to decrypt secret %q of namespace %s: %w", secret.Name, secret.Namespace, err)
+		}
+
+		secret.KeyID, secret.Ciphertext, err = s.keyring.Encrypt(plaintext, aad)
+		if err != nil {
+			return rotated, err
+		}
+
+		if err := s.repo.Save(ctx, secret); err != nil {
+			return rotated, err
+		}
+		rotated++
+	}
+	return rotated, nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/ostafen/kronos/internal/config"
+	"github.com/ostafen/kronos/internal/secrets"
+	"github.com/ostafen/kronos/model"
+	"github.com/ostafen/kronos/store"
+	"github.com/stretchr/testify/require"
+)
+
+func newKeyring(t *testing.T, key string, previous ...string) *secrets.Keyring {
+	k, err := secrets.NewKeyring(config.Secrets{Key: key, PreviousKeys: previous})
+	require.NoError(t, err)
+	return k
+}
+
+func randomKey(t *testing.T) string {
+	raw := make([]byte, 32)
+	_, err := rand.Read(raw)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func TestSecretRotation(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "kronos.db"))
+	require.NoError(t, err)
+	defer st.Close()
+
+	ctx := context.Background()
+	oldKey, newKey := randomKey(t), randomKey(t)
+
+	svc := NewSecretService(st, newKeyring(t, oldKey))
+	_, err = svc.PutSecret(ctx, &model.SecretInput{Name: "token", Value: "s3cret"})
+	require.NoError(t, err)
+
+	// the value is neither stored in plaintext nor printed
+	stored, err := st.SecretRepository().Get(ctx, model.DefaultNamespace, "token")
+	require.NoError(t, err)
+	require.NotContains(t, string(stored.Ciphertext), "s3cret")
+
+	value, err := svc.ResolveSecret(ctx, model.DefaultNamespace, "token")
+	require.NoError(t, err)
+	require.Equal(t, "s3cret", string(value))
+	require.NotContains(t, fmt.Sprintf("%v %+v %#v %s", value, value, value, value), "s3cret")
+
+	// without the previous key, rotated secrets can't be decrypted
+	_, err = NewSecretService(st, newKeyring(t, newKey)).ResolveSecret(ctx, model.DefaultNamespace, "token")
+	require.Error(t, err)
+
+	svc = NewSecretService(st, newKeyring(t, newKey, oldKey))
+
+	n, err := svc.RotateSecrets(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	n, err = svc.RotateSecrets(ctx)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// once rotated, the previous key is no longer needed
+	value, err = NewSecretService(st, newKeyring(t, newKey)).ResolveSecret(ctx, model.DefaultNamespace, "token")
+	require.NoError(t, err)
+	require.Equal(t, "s3cret", string(value))
+
+	_, err = svc.ResolveSecret(ctx, model.DefaultNamespace, "missing")
+	require.ErrorIs(t, err, ErrUnknownSecret)
+
+	_, err = NewSecretService(st, nil).PutSecret(ctx, &model.SecretInput{Name: "token", Value: "s3cret"})
+	require.ErrorIs(t, err, ErrSecretsDisabled)
+}
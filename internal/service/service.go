@@ -3,187 +3,585 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"sync"
 	"time"
 
-	"github.com/ostafen/kronos/internal/model"
+	"github.com/ostafen/kronos/internal/auth"
+	"github.com/ostafen/kronos/internal/metrics"
 	"github.com/ostafen/kronos/internal/sched"
-	"github.com/ostafen/kronos/internal/store"
+	"github.com/ostafen/kronos/model"
+	"github.com/ostafen/kronos/store"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	log "github.com/sirupsen/logrus"
 )
 
 type ScheduleService interface {
-	RegisterSchedule(sched *model.ScheduleRegisterInput) (*model.CronSchedule, error)
-	GetSchedule(id int64) (*model.CronSchedule, error)
-	DeleteSchedule(id int64) error
-	IterSchedules(onSchedule func(*model.CronSchedule) error) error
-	GetHistory() ([]*model.CronStatus, error)
-	GetCronHistory(cronID int64) ([]*model.CronStatus, error)
+	RegisterSchedule(ctx context.Context, sched *model.ScheduleRegisterInput) (*model.CronSchedule, error)
+	GetSchedule(ctx context.Context, id int64) (*model.CronSchedule, error)
+	DeleteSchedule(ctx context.Context, id int64) error
+	IterSchedules(ctx context.Context, onSchedule func(*model.CronSchedule) error) error
+	GetHistory(ctx context.Context) ([]*model.CronStatus, error)
+	GetCronHistory(ctx context.Context, cronID int64) ([]*model.CronStatus, error)
+	GetStats(ctx context.Context, window time.Duration) (*model.CronStats, error)
+	GetCronStats(ctx context.Context, cronID int64, window time.Duration) (*model.CronStats, error)
 
-	PauseSchedule(id int64) (*model.CronSchedule, error)
-	ResumeSchedule(id int64) (*model.CronSchedule, error)
-	TriggerSchedule(id int64) (*model.CronSchedule, error)
+	PauseSchedule(ctx context.Context, id int64) (*model.CronSchedule, error)
+	ResumeSchedule(ctx context.Context, id int64) (*model.CronSchedule, error)
+	TriggerSchedule(ctx context.Context, id int64) (*model.CronSchedule, error)
+
+	// ApplySchedules reconciles the schedules of the target namespace with the desired ones. When applying a change
+	// fails, the result is returned along with the error, reporting the changes which have been applied.
+	ApplySchedules(ctx context.Context, req *model.ApplyRequest) (*model.ApplyResult, error)
+
+	// ImportCrontab applies the schedules converted from the entries of a crontab, and reports unsupported lines.
+	ImportCrontab(ctx context.Context, req *model.CrontabImportRequest) (*model.CrontabImportResult, error)
+	// ExportCrontab renders the schedules as crontab lines.
+	ExportCrontab(ctx context.Context) (string, error)
+
+	QueryAudit(ctx context.Context, query *model.AuditQuery) (*model.AuditPage, error)
+
+	Liveness() error
+	Readiness(ctx context.Context) error
+
+	// RegisterAction makes the given function available to schedules referring to it by name, in place of a webhook.
+	RegisterAction(name string, action Action)
 
 	Scheduler() sched.CronScheduler
-	Stop()
+	Start() error
+	Stop(ctx context.Context) error
 }
 
+var (
+	ErrStopping          = errors.New("schedule service is stopping")
+	ErrSchedulerNotAlive = errors.New("scheduler loop is not alive")
+)
+
+var tracer = otel.Tracer("github.com/ostafen/kronos/internal/service")
+
 func NewScheduleService(
-	store store.Store,
+	st store.Store,
 	notificationSvc NotificationService,
+	namespaceSvc NamespaceService,
+	secrets SecretResolver,
 ) ScheduleService {
 	svc := &schedService{
-		cronRepo:        store.CronScheduleRepository(),
-		statusRepo:      store.HistoryRepository(),
+		store:           st,
+		cronRepo:        st.CronScheduleRepository(),
+		statusRepo:      st.HistoryRepository(),
+		auditRepo:       st.AuditRepository(),
+		calendarRepo:    st.CalendarRepository(),
 		notificationSvc: notificationSvc,
+		namespaceSvc:    namespaceSvc,
+		secrets:         secrets,
+		limiter:         newDeliveryLimiter(),
+		inflight:        make(map[int64]bool),
 	}
 	svc.scheduler = sched.NewCronScheduler(svc.OnTick)
 
-	err := svc.cronRepo.Iter(func(sched *model.CronSchedule) error {
+	svc.releaseMetrics = metrics.SetEngine(svc.countSchedules, svc.scheduler.Len)
+
+	svc.deliveryCtx, svc.cancelDeliveries = context.WithCancel(context.Background())
+	return svc
+}
+
+// Start schedules the next tick of the active schedules and starts the scheduler loop.
+// Actions should be registered before, so that they are available to schedules which are already due.
+func (s *schedService) Start() error {
+	err := s.cronRepo.Iter(context.Background(), store.AllNamespaces, func(sched *model.CronSchedule) error {
 		if sched.IsActive() {
-			log.Infof("scheduling %d at %s", sched.ID, sched.NextTick())
+			// the next tick is computed once, since jitter makes every call return a different time
+			next := sched.NextTick()
+			log.Infof("scheduling %d at %s", sched.ID, next)
 
-			svc.scheduler.Schedule(sched.ID, sched.NextTick())
+			s.scheduler.Schedule(sched.ID, next)
 		}
 		return nil
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
-	svc.cancel = cancel
+	s.cancel = cancel
 
-	svc.scheduler.Start(ctx)
-	return svc
+	s.scheduler.Start(ctx)
+	return nil
 }
 
-const MaxSamplesPerCronDefault = 100
+const (
+	MaxSamplesPerCronDefault = 100
+	StatsWindowDefault       = 24 * time.Hour
+)
 
 type schedService struct {
 	notificationSvc NotificationService
+	namespaceSvc    NamespaceService
+	secrets         SecretResolver
+	limiter         *deliveryLimiter
+	actions         actionRegistry
 
+	store      store.Store
 	scheduler  sched.CronScheduler
 	cronRepo   store.CronScheduleRepository
 	statusRepo store.CronHistoryRepository
+	auditRepo  store.AuditRepository
 	cancel     context.CancelFunc
+
+	calendarRepo store.CalendarRepository
+
+	// in-flight deliveries, which are drained on Stop()
+	mtx        sync.Mutex
+	stopping   bool
+	deliveries sync.WaitGroup
+	// inflight holds the schedules counting successful runs towards their maxRuns which are being delivered
+	inflight         map[int64]bool
+	deliveryCtx      context.Context
+	cancelDeliveries context.CancelFunc
+	// releaseMetrics uninstalls the callbacks computing the metrics of the service, on Stop()
+	releaseMetrics func()
+}
+
+// startSpan starts a span for a ScheduleService operation, optionally tagged with the id of the schedule it refers to.
+func startSpan(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "ScheduleService."+operation, trace.WithAttributes(attrs...))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func scheduleIDAttr(id int64) attribute.KeyValue {
+	return attribute.Int64("kronos.schedule.id", id)
 }
 
-func (s *schedService) RegisterSchedule(input *model.ScheduleRegisterInput) (*model.CronSchedule, error) {
-	sched, err := input.ToSched()
+func (s *schedService) RegisterSchedule(ctx context.Context, input *model.ScheduleRegisterInput) (_ *model.CronSchedule, err error) {
+	ctx, span := startSpan(ctx, "RegisterSchedule")
+	defer func() { endSpan(span, err) }()
+
+	namespace := targetNamespace(ctx)
+
+	sched, err := input.ToSched(namespace)
 	if err != nil {
 		return nil, err
 	}
 
-	id, err := s.cronRepo.Save(sched)
-	if err == nil {
-		s.scheduler.Schedule(id, sched.NextTick())
+	if !auth.GrantsFrom(ctx).Allows(auth.ActionEdit, sched.Namespace, sched.Metadata) {
+		return nil, auth.ErrPermissionDenied
+	}
+
+	if err := s.checkDelivery(ctx, sched); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkCalendars(ctx, sched); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkQuota(ctx, sched); err != nil {
+		return nil, err
 	}
+
+	id, err := s.cronRepo.Save(ctx, sched)
+	if err != nil {
+		return nil, err
+	}
+	s.scheduler.Schedule(id, sched.NextTick())
+
 	sched.ID = id
-	return sched, err
+	span.SetAttributes(scheduleIDAttr(id))
+
+	s.audit(ctx, model.AuditActionRegister, nil, sched)
+	return sched, nil
 }
 
 const (
 	MaxRequestDuration = time.Second * 5
 )
 
-func (s *schedService) OnTick(cronID int64) time.Time {
-	cron, err := s.cronRepo.Get(cronID)
+func (s *schedService) OnTick(cronID int64, scheduledAt time.Time) time.Time {
+	ctx, span := tracer.Start(s.deliveryCtx, "schedule.fire",
+		trace.WithNewRoot(),
+		trace.WithAttributes(
+			scheduleIDAttr(cronID),
+			attribute.String("kronos.schedule.planned_at", scheduledAt.Format(time.RFC3339Nano)),
+		),
+	)
+
+	cron, err := s.cronRepo.Get(ctx, store.AllNamespaces, cronID)
 	if errors.Is(err, store.ErrScheduleNotExist) {
 		log.Errorf("no schedule with id %d", cronID)
+		endSpan(span, err)
 		return time.Time{}
 	}
 
 	if err != nil {
 		log.Error(err)
+		endSpan(span, err)
+		return time.Time{}
+	}
+
+	lag := time.Since(scheduledAt)
+	metrics.ObserveSchedulerLag(lag)
+	span.SetAttributes(attribute.Int64("kronos.scheduler.lag_ms", lag.Milliseconds()))
+
+	// a schedule resumed after reaching its maxRuns expires again
+	if cron.RunsExhausted() {
+		s.expire(ctx, cron)
+		endSpan(span, nil)
 		return time.Time{}
 	}
 
+	if reason := s.exclusion(ctx, cron, scheduledAt); reason != "" {
+		defer span.End()
+		return s.skipOrShift(ctx, cron, scheduledAt, reason)
+	}
+
+	s.mtx.Lock()
+	if s.stopping {
+		s.mtx.Unlock()
+		endSpan(span, ErrStopping)
+		return time.Time{}
+	}
+
+	// whether a run counts towards a maxRuns in successful mode is only known once it completes,
+	// so no other run of the schedule starts until then
+	countsSuccesses := cron.MaxRuns > 0 && cron.MaxRunsMode == model.MaxRunsModeSuccessful
+	if countsSuccesses && s.inflight[cron.ID] {
+		s.mtx.Unlock()
+
+		log.WithField("scheduleId", cron.ID).
+			WithField("namespace", cron.Namespace).
+			Warn("occurrence skipped, the previous run is still in progress")
+
+		endSpan(span, nil)
+		if cron.Expired() {
+			return time.Time{}
+		}
+		return cron.NextTick()
+	}
+	if countsSuccesses {
+		s.inflight[cron.ID] = true
+	}
+	s.deliveries.Add(1)
+	s.mtx.Unlock()
+
+	// the run is counted before being delivered, so that the ticks firing while it is in progress
+	// can't exceed the maxRuns of the schedule
+	cron.Runs, cron.SuccessfulRuns, err = s.cronRepo.IncrementRuns(ctx, cron.ID, 1, 0)
+	if err != nil {
+		log.Error(err)
+	}
+
 	go func() {
+		defer s.deliveries.Done()
+		defer span.End()
+
 		start := time.Now().Truncate(time.Second)
-		status, _ := s.sendWebhookNotification(cron)
+
+		status := http.StatusTooManyRequests
+		if s.allowDelivery(ctx, cron) {
+			status, _ = s.deliver(ctx, cron)
+		} else {
+			log.WithField("scheduleId", cron.ID).
+				WithField("namespace", cron.Namespace).
+				Warn("delivery skipped, namespace rate limit exceeded")
+		}
 
 		duration := time.Since(start)
-		err := s.statusRepo.Insert(&model.CronStatus{
+
+		run := &model.CronStatus{
 			CronID:     cronID,
+			Namespace:  cron.Namespace,
 			At:         start,
 			StatusCode: status,
 			Duration:   duration,
-		}, MaxSamplesPerCronDefault)
+			Lag:        lag,
+		}
 
-		if err != nil {
+		// history must be flushed even when the delivery has been aborted by Stop()
+		if err := s.statusRepo.Insert(context.WithoutCancel(ctx), run, MaxSamplesPerCronDefault); err != nil {
 			log.Error(err)
 		}
+
+		s.completeRun(context.WithoutCancel(ctx), run, time.Now())
+
+		if countsSuccesses {
+			s.mtx.Lock()
+			delete(s.inflight, cron.ID)
+			s.mtx.Unlock()
+		}
 	}()
 
-	if cron.Expired() {
+	// one-shot schedules run once, the next run of fixed-delay schedules is only known once this one completes,
+	// and no run follows the last one allowed by maxRuns
+	if cron.Expired() || !cron.IsRecurring || cron.IsFixedDelay() || cron.RunsExhausted() {
 		return time.Time{}
 	}
 	return cron.NextTick()
 }
 
-func (s *schedService) sendWebhookNotification(sched *model.CronSchedule) (int, error) {
+// completeRun records the outcome of a run of a schedule, expiring it once it reaches its maxRuns.
+// The following run of a fixed-delay schedule is anchored to the end of the last one, and scheduled.
+// The schedule is read again, since it may have been paused, updated or deleted during the run.
+func (s *schedService) completeRun(ctx context.Context, run *model.CronStatus, end time.Time) {
+	if run.IsSuccess() {
+		_, _, err := s.cronRepo.IncrementRuns(ctx, run.CronID, 0, 1)
+		if errors.Is(err, store.ErrScheduleNotExist) {
+			return
+		}
+
+		if err != nil {
+			log.Error(err)
+		}
+	}
+
+	sched, err := s.cronRepo.Get(ctx, store.AllNamespaces, run.CronID)
+	if errors.Is(err, store.ErrScheduleNotExist) {
+		return
+	}
+
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	if sched.RunsExhausted() {
+		s.expire(ctx, sched)
+		return
+	}
+
+	if !sched.IsFixedDelay() {
+		return
+	}
+
+	sched.Anchor = end
+	if _, err := s.cronRepo.Save(ctx, sched); err != nil {
+		log.Error(err)
+		return
+	}
+
+	if sched.IsActive() && !sched.Expired() {
+		s.scheduler.Schedule(sched.ID, sched.NextTick())
+	}
+}
+
+// expire marks a schedule which reached its maxRuns as expired, and stops scheduling it.
+func (s *schedService) expire(ctx context.Context, sched *model.CronSchedule) {
+	sched.Status = model.ScheduleStatusExpired
+	if _, err := s.cronRepo.Save(ctx, sched); err != nil {
+		log.Error(err)
+		return
+	}
+
+	s.scheduler.Remove(sched.ID)
+
 	log.WithField("scheduleId", sched.ID).
-		WithField("url", sched.URL).
+		WithField("namespace", sched.Namespace).
+		WithField("runs", sched.Runs).
+		Info("schedule expired, maxRuns reached")
+}
+
+// skipOrShift handles an occurrence excluded by the calendars of a schedule, according to its blackout policy,
+// and returns the next tick of the schedule. Shifted occurrences absorb those falling before the shifted time.
+func (s *schedService) skipOrShift(ctx context.Context, sched *model.CronSchedule, scheduledAt time.Time, reason string) time.Time {
+	logger := log.WithField("scheduleId", sched.ID).
+		WithField("namespace", sched.Namespace).
+		WithField("reason", reason)
+
+	if sched.BlackoutPolicy == model.BlackoutPolicyShift {
+		shifted := s.nextBusinessTime(ctx, sched, scheduledAt)
+		if !shifted.IsZero() && !shifted.After(sched.EndAt) {
+			logger.WithField("shiftedTo", shifted).Info("occurrence shifted to the next business day")
+			return shifted
+		}
+	}
+
+	logger.Info("occurrence skipped")
+
+	err := s.statusRepo.Insert(context.WithoutCancel(ctx), &model.CronStatus{
+		CronID:    sched.ID,
+		Namespace: sched.Namespace,
+		At:        scheduledAt,
+		Skipped:   reason,
+	}, MaxSamplesPerCronDefault)
+	if err != nil {
+		log.Error(err)
+	}
+
+	if sched.Expired() {
+		return time.Time{}
+	}
+
+	// skipped occurrences count as runs of fixed-delay schedules, which would otherwise be due again right away
+	if sched.IsFixedDelay() {
+		sched.Anchor = scheduledAt
+		if _, err := s.cronRepo.Save(ctx, sched); err != nil {
+			log.Error(err)
+		}
+	}
+	return sched.NextTick()
+}
+
+func (s *schedService) sendWebhookNotification(ctx context.Context, sched *model.CronSchedule) (int, error) {
+	log.WithField("scheduleId", sched.ID).
+		WithField("url", redactURL(sched.URL)).
 		Info("sendingNotification")
 
-	ctx, cancel := context.WithTimeout(context.Background(), MaxRequestDuration)
+	ctx, cancel := context.WithTimeout(ctx, MaxRequestDuration)
 	defer cancel()
-	return s.notificationSvc.Send(ctx, sched.URL, sched)
+
+	start := time.Now()
+
+	status := -1
+	target, err := s.target(ctx, sched)
+	if err == nil {
+		status, err = s.notificationSvc.Send(ctx, target, sched)
+	} else {
+		log.WithError(err).
+			WithField("scheduleId", sched.ID).
+			Error("unable to resolve the secrets of the schedule")
+	}
+
+	metrics.ObserveWebhookDelivery(sched.ID, status, time.Since(start))
+	if err != nil {
+		metrics.IncScheduleFailures(sched.ID)
+	} else {
+		metrics.ResetScheduleFailures(sched.ID)
+	}
+	return status, err
+}
+
+// target returns the target of the deliveries of a schedule, whose headers hold the values of the referenced secrets.
+func (s *schedService) target(ctx context.Context, sched *model.CronSchedule) (Target, error) {
+	target := scheduleTarget(sched)
+	if len(sched.SecretHeaders) == 0 {
+		return target, nil
+	}
+
+	if s.secrets == nil {
+		return target, ErrSecretsDisabled
+	}
+
+	target.Headers = make(map[string]model.SecretValue, len(sched.SecretHeaders))
+	for header, name := range sched.SecretHeaders {
+		value, err := s.secrets.ResolveSecret(ctx, sched.Namespace, name)
+		if err != nil {
+			return target, err
+		}
+		target.Headers[header] = value
+	}
+	return target, nil
 }
 
-func (s *schedService) GetSchedule(id int64) (*model.CronSchedule, error) {
-	sched, err := s.cronRepo.Get(id)
+func (s *schedService) GetSchedule(ctx context.Context, id int64) (_ *model.CronSchedule, err error) {
+	ctx, span := startSpan(ctx, "GetSchedule", scheduleIDAttr(id))
+	defer func() { endSpan(span, err) }()
+
+	sched, err := s.cronRepo.Get(ctx, namespaceOf(ctx), id)
 	if err != nil {
 		return nil, err
 	}
 	return sched, nil
 }
 
-func (s *schedService) DeleteSchedule(id int64) error {
-	return s.cronRepo.Delete(id)
+func (s *schedService) DeleteSchedule(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "DeleteSchedule", scheduleIDAttr(id))
+	defer func() { endSpan(span, err) }()
+
+	sched, err := s.cronRepo.Get(ctx, namespaceOf(ctx), id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.cronRepo.Delete(ctx, namespaceOf(ctx), id); err != nil {
+		return err
+	}
+	metrics.ForgetSchedule(id)
+
+	s.audit(ctx, model.AuditActionDelete, sched, nil)
+	return nil
 }
 
-func (s *schedService) IterSchedules(onSched func(*model.CronSchedule) error) error {
-	return s.cronRepo.Iter(onSched)
+func (s *schedService) IterSchedules(ctx context.Context, onSched func(*model.CronSchedule) error) (err error) {
+	ctx, span := startSpan(ctx, "IterSchedules")
+	defer func() { endSpan(span, err) }()
+
+	// schedules not visible to the role bindings of the caller are skipped
+	grants := auth.GrantsFrom(ctx)
+	return s.cronRepo.Iter(ctx, namespaceOf(ctx), func(sched *model.CronSchedule) error {
+		if !grants.Allows(auth.ActionView, sched.Namespace, sched.Metadata) {
+			return nil
+		}
+		return onSched(sched)
+	})
 }
 
-func (s *schedService) PauseSchedule(id int64) (*model.CronSchedule, error) {
+func (s *schedService) PauseSchedule(ctx context.Context, id int64) (_ *model.CronSchedule, err error) {
+	ctx, span := startSpan(ctx, "PauseSchedule", scheduleIDAttr(id))
+	defer func() { endSpan(span, err) }()
+
 	log.WithField("scheduleId", id).Info("pausing schedule")
 
-	sched, err := s.cronRepo.Get(id)
+	sched, err := s.cronRepo.Get(ctx, namespaceOf(ctx), id)
 	if err != nil {
 		return nil, err
 	}
 
+	before := *sched
+
 	sched.Status = model.ScheduleStatusPaused
-	if _, err := s.cronRepo.Save(sched); err != nil {
+	if _, err := s.cronRepo.Save(ctx, sched); err != nil {
 		return nil, err
 	}
 
 	s.scheduler.Remove(sched.ID)
 
+	s.audit(ctx, model.AuditActionPause, &before, sched)
 	return sched, nil
 }
 
-func (s *schedService) TriggerSchedule(id int64) (*model.CronSchedule, error) {
-	sched, err := s.cronRepo.Get(id)
+func (s *schedService) TriggerSchedule(ctx context.Context, id int64) (_ *model.CronSchedule, err error) {
+	ctx, span := startSpan(ctx, "TriggerSchedule", scheduleIDAttr(id))
+	defer func() { endSpan(span, err) }()
+
+	sched, err := s.cronRepo.Get(ctx, namespaceOf(ctx), id)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = s.sendWebhookNotification(sched)
+	if !s.allowDelivery(ctx, sched) {
+		return nil, ErrRateLimited
+	}
+
+	s.audit(ctx, model.AuditActionTrigger, sched, sched)
+
+	_, err = s.deliver(ctx, sched)
 	return sched, err
 }
 
-func (s *schedService) ResumeSchedule(id int64) (*model.CronSchedule, error) {
-	sched, err := s.cronRepo.Get(id)
+func (s *schedService) ResumeSchedule(ctx context.Context, id int64) (_ *model.CronSchedule, err error) {
+	ctx, span := startSpan(ctx, "ResumeSchedule", scheduleIDAttr(id))
+	defer func() { endSpan(span, err) }()
+
+	sched, err := s.cronRepo.Get(ctx, namespaceOf(ctx), id)
 	if err != nil {
 		return nil, err
 	}
 
+	before := *sched
+
 	sched.Status = model.ScheduleStatusActive
-	if _, err := s.cronRepo.Save(sched); err != nil {
+	if _, err := s.cronRepo.Save(ctx, sched); err != nil {
 		return nil, err
 	}
 
@@ -193,23 +591,180 @@ func (s *schedService) ResumeSchedule(id int64) (*model.CronSchedule, error) {
 
 	s.scheduler.Schedule(sched.ID, sched.NextTick())
 
+	s.audit(ctx, model.AuditActionResume, &before, sched)
 	return sched, nil
 }
 
-func (s *schedService) GetCronHistory(cronID int64) ([]*model.CronStatus, error) {
-	return s.statusRepo.GetCronHistory(cronID, MaxSamplesPerCronDefault)
+func (s *schedService) GetCronHistory(ctx context.Context, cronID int64) (_ []*model.CronStatus, err error) {
+	ctx, span := startSpan(ctx, "GetCronHistory", scheduleIDAttr(cronID))
+	defer func() { endSpan(span, err) }()
+
+	return s.statusRepo.GetCronHistory(ctx, namespaceOf(ctx), cronID, MaxSamplesPerCronDefault)
 }
 
-func (s *schedService) GetHistory() ([]*model.CronStatus, error) {
-	return s.statusRepo.GetHistory(MaxSamplesPerCronDefault)
+func (s *schedService) GetHistory(ctx context.Context) (_ []*model.CronStatus, err error) {
+	ctx, span := startSpan(ctx, "GetHistory")
+	defer func() { endSpan(span, err) }()
+
+	return s.statusRepo.GetHistory(ctx, namespaceOf(ctx), MaxSamplesPerCronDefault)
+}
+
+func (s *schedService) GetCronStats(ctx context.Context, cronID int64, window time.Duration) (_ *model.CronStats, err error) {
+	ctx, span := startSpan(ctx, "GetCronStats", scheduleIDAttr(cronID))
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.cronRepo.Get(ctx, namespaceOf(ctx), cronID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	statuses, err := s.statusRepo.GetCronHistorySince(ctx, namespaceOf(ctx), cronID, now.Add(-window))
+	if err != nil {
+		return nil, err
+	}
+
+	stats := model.ComputeStats(statuses, window, now)
+	stats.CronID = cronID
+	stats.MarkTruncated(statuses, MaxSamplesPerCronDefault)
+	return stats, nil
+}
+
+func (s *schedService) GetStats(ctx context.Context, window time.Duration) (_ *model.CronStats, err error) {
+	ctx, span := startSpan(ctx, "GetStats")
+	defer func() { endSpan(span, err) }()
+
+	now := time.Now()
+
+	statuses, err := s.statusRepo.GetHistorySince(ctx, namespaceOf(ctx), now.Add(-window))
+	if err != nil {
+		return nil, err
+	}
+	stats := model.ComputeStats(statuses, window, now)
+	stats.MarkTruncated(statuses, MaxSamplesPerCronDefault)
+	return stats, nil
+}
+
+// checkQuota verifies that registering sched doesn't exceed the quota of its namespace.
+func (s *schedService) checkQuota(ctx context.Context, sched *model.CronSchedule) error {
+	quota, err := s.namespaceSvc.Quota(ctx, sched.Namespace)
+	if err != nil {
+		return err
+	}
+
+	if quota.MaxSchedules > 0 {
+		n, err := s.cronRepo.Count(ctx, sched.Namespace)
+		if err != nil {
+			return err
+		}
+
+		if n >= quota.MaxSchedules {
+			return fmt.Errorf("%w: at most %d schedules are allowed", ErrQuotaExceeded, quota.MaxSchedules)
+		}
+	}
+	return checkMinInterval(sched, quota)
+}
+
+// checkMinInterval verifies that sched doesn't fire more often than the quota of its namespace allows.
+func checkMinInterval(sched *model.CronSchedule, quota model.Quota) error {
+	if quota.MinInterval > 0 && sched.IsRecurring {
+		interval, err := sched.MinInterval(minIntervalSamples)
+		if err != nil {
+			return err
+		}
+
+		if interval < quota.MinInterval {
+			return fmt.Errorf("%w: schedules must not fire more often than every %s", ErrQuotaExceeded, quota.MinInterval)
+		}
+	}
+	return nil
+}
+
+// minIntervalSamples is the number of activations which are inspected to determine
+// the minimum interval of a recurring schedule.
+const minIntervalSamples = 100
+
+func (s *schedService) allowDelivery(ctx context.Context, sched *model.CronSchedule) bool {
+	quota, err := s.namespaceSvc.Quota(ctx, sched.Namespace)
+	if err != nil {
+		log.WithError(err).Error("unable to read namespace quota")
+		return true
+	}
+	return s.limiter.Allow(sched.Namespace, quota.MaxDeliveriesPerMinute, time.Now())
+}
+
+func (s *schedService) countSchedules() (map[string]int, error) {
+	counts := map[string]int{
+		string(model.ScheduleStatusActive):  0,
+		string(model.ScheduleStatusPaused):  0,
+		string(model.ScheduleStatusExpired): 0,
+	}
+
+	err := s.cronRepo.Iter(context.Background(), store.AllNamespaces, func(sched *model.CronSchedule) error {
+		status := sched.Status
+		if sched.Expired() {
+			status = model.ScheduleStatusExpired
+		}
+		counts[string(status)]++
+		return nil
+	})
+	return counts, err
 }
 
 func (s *schedService) Scheduler() sched.CronScheduler {
 	return s.scheduler
 }
 
-func (s *schedService) Stop() {
+func (s *schedService) Liveness() error {
+	if !s.scheduler.Alive() {
+		return ErrSchedulerNotAlive
+	}
+	return nil
+}
+
+func (s *schedService) Readiness(ctx context.Context) error {
+	s.mtx.Lock()
+	stopping := s.stopping
+	s.mtx.Unlock()
+
+	if stopping {
+		return ErrStopping
+	}
+
+	if err := s.store.Ping(ctx); err != nil {
+		return fmt.Errorf("store is not reachable: %w", err)
+	}
+	return s.Liveness()
+}
+
+// Stop prevents new ticks from being processed and waits for in-flight deliveries to complete.
+// If ctx expires first, pending deliveries are aborted, and their outcome is recorded to history anyway.
+func (s *schedService) Stop(ctx context.Context) error {
+	s.mtx.Lock()
+	s.stopping = true
+	s.mtx.Unlock()
+
+	s.releaseMetrics()
+
 	if s.cancel != nil {
 		s.cancel()
 	}
+
+	done := make(chan struct{})
+	go func() {
+		s.deliveries.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		log.Warn("timeout expired while draining in-flight deliveries, aborting them")
+	}
+
+	s.cancelDeliveries()
+	<-done
+
+	return ctx.Err()
 }
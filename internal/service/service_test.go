@@ -3,21 +3,24 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/ostafen/kronos/internal/model"
-	"github.com/ostafen/kronos/internal/store"
+	"github.com/ostafen/kronos/model"
+	"github.com/ostafen/kronos/store"
 
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -37,11 +40,17 @@ func (s *ScheduleServiceSuite) SetupTest() {
 	s.webhookHandlerCalls.Store(0)
 
 	s.store = &mockStore{}
-	s.svc = NewScheduleService(s.store, NewNotificationService())
+	s.svc = NewScheduleService(s.store, NewNotificationService(nil, nil, nil), NewNamespaceService(s.store, model.Quota{}), nil)
+	s.Require().NoError(s.svc.Start())
 
 	s.schedules = make(map[string]*model.CronSchedule)
 }
 
+// TearDownTest stops the scheduler, so that ticks of a test don't leak into the following ones.
+func (s *ScheduleServiceSuite) TearDownTest() {
+	s.NoError(s.svc.Stop(context.Background()))
+}
+
 func (s *ScheduleServiceSuite) aSchedule(url string) *model.CronSchedule {
 	sched := &model.CronSchedule{
 		ID:          rand.Int63(),
@@ -56,7 +65,7 @@ func (s *ScheduleServiceSuite) aSchedule(url string) *model.CronSchedule {
 		Failures:    0,
 	}
 
-	_, err := s.store.CronScheduleRepository().Save(sched)
+	_, err := s.store.CronScheduleRepository().Save(context.Background(), sched)
 	s.NoError(err)
 	s.svc.Scheduler().Schedule(sched.ID, time.Now())
 	return sched
@@ -74,7 +83,7 @@ func (s *ScheduleServiceSuite) anListeningWebhookHandler(n int, ch chan struct{}
 		err = json.Unmarshal(data, &sched)
 		s.NoError(err)
 
-		_, err = s.store.CronScheduleRepository().Get(sched.ID)
+		_, err = s.store.CronScheduleRepository().Get(context.Background(), store.AllNamespaces, sched.ID)
 		s.NoError(err)
 
 		if int(calls) == n {
@@ -116,7 +125,7 @@ func (s *ScheduleServiceSuite) TestPauseResume() {
 
 	time.Sleep(time.Second + time.Second/10)
 
-	pausedSched, err := s.svc.PauseSchedule(sched.ID)
+	pausedSched, err := s.svc.PauseSchedule(context.Background(), sched.ID)
 	s.NoError(err)
 
 	s.Equal(pausedSched.Status, model.ScheduleStatusPaused)
@@ -126,7 +135,7 @@ func (s *ScheduleServiceSuite) TestPauseResume() {
 	calls := s.webhookHandlerCalls.Load()
 	s.GreaterOrEqual(calls, int32(1))
 
-	resumedSched, err := s.svc.ResumeSchedule(sched.ID)
+	resumedSched, err := s.svc.ResumeSchedule(context.Background(), sched.ID)
 	s.NoError(err)
 	s.Equal(sched, resumedSched)
 
@@ -141,7 +150,7 @@ type mockCronRepo struct {
 	m      map[int64]*model.CronSchedule
 }
 
-func (s *mockCronRepo) Get(id int64) (*model.CronSchedule, error) {
+func (s *mockCronRepo) Get(ctx context.Context, namespace string, id int64) (*model.CronSchedule, error) {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
@@ -154,7 +163,7 @@ func (s *mockCronRepo) Get(id int64) (*model.CronSchedule, error) {
 	return &copy, nil
 }
 
-func (s *mockCronRepo) Save(sched *model.CronSchedule) (int64, error) {
+func (s *mockCronRepo) Save(ctx context.Context, sched *model.CronSchedule) (int64, error) {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
@@ -170,13 +179,13 @@ func (s *mockCronRepo) Save(sched *model.CronSchedule) (int64, error) {
 	return sched.ID, nil
 }
 
-func (s *mockCronRepo) Delete(id int64) error {
+func (s *mockCronRepo) Delete(ctx context.Context, namespace string, id int64) error {
 	delete(s.m, id)
 
 	return nil
 }
 
-func (s *mockCronRepo) Iter(iterFunc func(*model.CronSchedule) error) error {
+func (s *mockCronRepo) Iter(ctx context.Context, namespace string, iterFunc func(*model.CronSchedule) error) error {
 	for _, cron := range s.m {
 		if err := iterFunc(cron); err != nil {
 			return err
@@ -185,6 +194,27 @@ func (s *mockCronRepo) Iter(iterFunc func(*model.CronSchedule) error) error {
 	return nil
 }
 
+func (s *mockCronRepo) Count(ctx context.Context, namespace string) (int, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return len(s.m), nil
+}
+
+func (s *mockCronRepo) IncrementRuns(ctx context.Context, id int64, runs, successfulRuns int) (int, int, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	sched, has := s.m[id]
+	if !has {
+		return 0, 0, store.ErrScheduleNotExist
+	}
+
+	sched.Runs += runs
+	sched.SuccessfulRuns += successfulRuns
+	return sched.Runs, sched.SuccessfulRuns, nil
+}
+
 type mockStore struct {
 	cronRepo *mockCronRepo
 }
@@ -196,14 +226,370 @@ func (s *mockStore) CronScheduleRepository() store.CronScheduleRepository {
 	return s.cronRepo
 }
 
+func (s *mockStore) APIKeyRepository() store.APIKeyRepository {
+	return nil
+}
+
+func (s *mockStore) NamespaceRepository() store.NamespaceRepository {
+	return &mockNamespaceRepo{}
+}
+
+func (s *mockStore) RoleBindingRepository() store.RoleBindingRepository {
+	return nil
+}
+
+func (s *mockStore) SecretRepository() store.SecretRepository {
+	return nil
+}
+
+func (s *mockStore) CalendarRepository() store.CalendarRepository {
+	return nil
+}
+
+func (s *mockStore) IdempotencyRepository() store.IdempotencyRepository {
+	return nil
+}
+
+func (s *mockStore) AuditRepository() store.AuditRepository {
+	return &mockAuditRepo{}
+}
+
+type mockAuditRepo struct {
+	store.AuditRepository
+}
+
+func (r *mockAuditRepo) Insert(ctx context.Context, entry *model.AuditEntry) error {
+	return nil
+}
+
+func (s *mockStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *mockStore) Close() error {
+	return nil
+}
+
 func (s *mockStore) HistoryRepository() store.CronHistoryRepository {
 	return &mockHistoryRepo{}
 }
 
+type mockNamespaceRepo struct {
+	store.NamespaceRepository
+}
+
+func (r *mockNamespaceRepo) Get(ctx context.Context, name string) (*model.Namespace, error) {
+	return &model.Namespace{Name: name}, nil
+}
+
 type mockHistoryRepo struct {
 	store.CronHistoryRepository
 }
 
-func (r *mockHistoryRepo) Insert(status *model.CronStatus, maxSamplesPerCron int) error {
+func (r *mockHistoryRepo) Insert(ctx context.Context, status *model.CronStatus, maxSamplesPerCron int) error {
 	return nil
 }
+
+func (s *ScheduleServiceSuite) TestStopAbortsPendingDeliveries() {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	s.aSchedule(server.URL)
+	<-started
+
+	s.NoError(s.svc.Readiness(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+
+	s.ErrorIs(s.svc.Stop(ctx), context.DeadlineExceeded)
+	s.ErrorIs(s.svc.Readiness(context.Background()), ErrStopping)
+}
+
+func TestFixedDelay(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "kronos.db"))
+	require.NoError(t, err)
+	defer st.Close()
+
+	received := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		<-release
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	svc := NewScheduleService(st, NewNotificationService(nil, nil, nil), NewNamespaceService(st, model.Quota{}), nil).(*schedService)
+
+	recurring := true
+	sched, err := svc.RegisterSchedule(ctx, &model.ScheduleRegisterInput{
+		Title:        "sync",
+		URL:          server.URL,
+		IsRecurring:  &recurring,
+		Interval:     model.Duration(time.Hour),
+		IntervalMode: model.IntervalModeFixedDelay,
+	})
+	require.NoError(t, err)
+
+	// the scheduler removes ticks before running them
+	fire := func() {
+		svc.scheduler.Remove(sched.ID)
+		require.True(t, svc.OnTick(sched.ID, time.Now()).IsZero())
+		<-received
+	}
+
+	// the next run is scheduled an interval after the end of the previous one
+	fire()
+	end := time.Now()
+	close(release)
+	svc.deliveries.Wait()
+
+	stored, err := st.CronScheduleRepository().Get(ctx, model.DefaultNamespace, sched.ID)
+	require.NoError(t, err)
+	require.False(t, stored.Anchor.Before(end))
+	require.True(t, stored.NextTick().After(end.Add(time.Hour-time.Second)))
+	require.Equal(t, 1, svc.scheduler.Len())
+
+	// schedules paused during a run are not scheduled again
+	release = make(chan struct{})
+	fire()
+	_, err = svc.PauseSchedule(ctx, sched.ID)
+	require.NoError(t, err)
+	close(release)
+	svc.deliveries.Wait()
+
+	require.Zero(t, svc.scheduler.Len())
+}
+
+func TestMaxRuns(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "kronos.db"))
+	require.NoError(t, err)
+	defer st.Close()
+
+	statuses := make(chan int, 3)
+	statuses <- http.StatusInternalServerError
+	statuses <- http.StatusOK
+	statuses <- http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(<-statuses)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	svc := NewScheduleService(st, NewNotificationService(nil, nil, nil), NewNamespaceService(st, model.Quota{}), nil).(*schedService)
+
+	recurring := true
+	sched, err := svc.RegisterSchedule(ctx, &model.ScheduleRegisterInput{
+		Title:       "retry",
+		URL:         server.URL,
+		IsRecurring: &recurring,
+		CronExpr:    "* * * * *",
+		MaxRuns:     2,
+		MaxRunsMode: model.MaxRunsModeSuccessful,
+	})
+	require.NoError(t, err)
+
+	fire := func() time.Time {
+		svc.scheduler.Remove(sched.ID)
+		next := svc.OnTick(sched.ID, time.Now())
+		svc.deliveries.Wait()
+		return next
+	}
+
+	// the failed run doesn't count, so the schedule expires after the third one
+	for i := 0; i < 3; i++ {
+		require.False(t, fire().IsZero())
+	}
+
+	stored, err := st.CronScheduleRepository().Get(ctx, model.DefaultNamespace, sched.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.ScheduleStatusExpired, stored.Status)
+	require.Equal(t, 3, stored.Runs)
+	require.Equal(t, 2, stored.SuccessfulRuns)
+	require.Zero(t, svc.scheduler.Len())
+
+	// resumed schedules expire again on their next tick
+	_, err = svc.ResumeSchedule(ctx, sched.ID)
+	require.NoError(t, err)
+	require.True(t, fire().IsZero())
+
+	stored, err = st.CronScheduleRepository().Get(ctx, model.DefaultNamespace, sched.ID)
+	require.NoError(t, err)
+	require.True(t, stored.Expired())
+	require.Zero(t, svc.scheduler.Len())
+}
+
+func TestMaxRunsWithSlowReceiver(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "kronos.db"))
+	require.NoError(t, err)
+	defer st.Close()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(300 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	svc := NewScheduleService(st, NewNotificationService(nil, nil, nil), NewNamespaceService(st, model.Quota{}), nil).(*schedService)
+
+	register := func(title string, mode model.MaxRunsMode) *model.CronSchedule {
+		recurring := true
+		sched, err := svc.RegisterSchedule(ctx, &model.ScheduleRegisterInput{
+			Title:       title,
+			URL:         server.URL,
+			IsRecurring: &recurring,
+			CronExpr:    "* * * * * *",
+			MaxRuns:     3,
+			MaxRunsMode: mode,
+		})
+		require.NoError(t, err)
+
+		svc.scheduler.Remove(sched.ID)
+		return sched
+	}
+
+	// ticks keep firing while the previous runs are in progress
+	sched := register("total", model.MaxRunsModeTotal)
+	for i := 0; i < 6; i++ {
+		next := svc.OnTick(sched.ID, time.Now())
+		require.Equal(t, i < 2, !next.IsZero())
+	}
+	svc.deliveries.Wait()
+
+	require.Equal(t, int32(3), calls.Load())
+
+	stored, err := st.CronScheduleRepository().Get(ctx, model.DefaultNamespace, sched.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.ScheduleStatusExpired, stored.Status)
+	require.Equal(t, 3, stored.Runs)
+	require.Equal(t, 3, stored.SuccessfulRuns)
+
+	// runs counted on success don't overlap
+	calls.Store(0)
+	sched = register("successful", model.MaxRunsModeSuccessful)
+	for i := 0; i < 6; i++ {
+		require.False(t, svc.OnTick(sched.ID, time.Now()).IsZero())
+	}
+	svc.deliveries.Wait()
+
+	require.Equal(t, int32(1), calls.Load())
+
+	stored, err = st.CronScheduleRepository().Get(ctx, model.DefaultNamespace, sched.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.ScheduleStatusActive, stored.Status)
+	require.Equal(t, 1, stored.Runs)
+	require.Equal(t, 1, stored.SuccessfulRuns)
+}
+
+func TestGetCronStats(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "kronos.db"))
+	require.NoError(t, err)
+	defer st.Close()
+
+	ctx := context.Background()
+	svc := NewScheduleService(st, NewNotificationService(nil, nil, nil), NewNamespaceService(st, model.Quota{}), nil)
+
+	_, err = svc.GetCronStats(ctx, 1, StatsWindowDefault)
+	require.ErrorIs(t, err, store.ErrScheduleNotExist)
+
+	recurring := true
+	sched, err := svc.RegisterSchedule(ctx, &model.ScheduleRegisterInput{
+		Title:       "frequent",
+		URL:         "http://localhost:8080/hooks",
+		IsRecurring: &recurring,
+		CronExpr:    "* * * * *",
+	})
+	require.NoError(t, err)
+
+	// a day of runs doesn't fit the samples retained for the schedule
+	now := time.Now()
+	for i := 2 * MaxSamplesPerCronDefault; i > 0; i-- {
+		err := st.HistoryRepository().Insert(ctx, &model.CronStatus{
+			CronID:     sched.ID,
+			Namespace:  sched.Namespace,
+			At:         now.Add(-time.Duration(i) * time.Minute),
+			StatusCode: http.StatusOK,
+		}, MaxSamplesPerCronDefault)
+		require.NoError(t, err)
+	}
+
+	stats, err := svc.GetCronStats(ctx, sched.ID, StatsWindowDefault)
+	require.NoError(t, err)
+	require.True(t, stats.Truncated)
+	require.Equal(t, MaxSamplesPerCronDefault, stats.Runs)
+	require.True(t, stats.From.After(now.Add(-time.Duration(MaxSamplesPerCronDefault+1)*time.Minute)))
+}
+
+// failingCronRepo fails to save the schedule with the given title.
+type failingCronRepo struct {
+	store.CronScheduleRepository
+
+	title string
+}
+
+func (r *failingCronRepo) Save(ctx context.Context, sched *model.CronSchedule) (int64, error) {
+	if sched.Title == r.title {
+		return 0, errors.New("store unavailable")
+	}
+	return r.CronScheduleRepository.Save(ctx, sched)
+}
+
+func TestApplyReportsAppliedChanges(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "kronos.db"))
+	require.NoError(t, err)
+	defer st.Close()
+
+	ctx := context.Background()
+	svc := NewScheduleService(st, NewNotificationService(nil, nil, nil), NewNamespaceService(st, model.Quota{}), nil).(*schedService)
+
+	schedule := func(title, cronExpr string) *model.ScheduleRegisterInput {
+		recurring := true
+		return &model.ScheduleRegisterInput{Title: title, URL: "http://localhost:8080/hooks", IsRecurring: &recurring, CronExpr: cronExpr}
+	}
+
+	_, err = svc.ApplySchedules(ctx, &model.ApplyRequest{Schedules: []*model.ScheduleRegisterInput{
+		schedule("a", "0 * * * *"),
+		schedule("b", "0 * * * *"),
+	}})
+	require.NoError(t, err)
+
+	svc.cronRepo = &failingCronRepo{CronScheduleRepository: svc.cronRepo, title: "c"}
+
+	// deletions and updates are applied before creations, which fail
+	res, err := svc.ApplySchedules(ctx, &model.ApplyRequest{Prune: true, Schedules: []*model.ScheduleRegisterInput{
+		schedule("a", "0 0 * * *"),
+		schedule("c", "0 * * * *"),
+	}})
+	require.ErrorContains(t, err, "store unavailable")
+	require.NotNil(t, res)
+
+	applied := make(map[string]bool)
+	for _, change := range res.Changes {
+		applied[change.Title] = change.Applied
+	}
+	require.Equal(t, map[string]bool{"a": true, "b": true, "c": false}, applied)
+
+	var titles []string
+	require.NoError(t, svc.IterSchedules(ctx, func(s *model.CronSchedule) error {
+		titles = append(titles, s.Title+" "+s.CronExpr)
+		return nil
+	}))
+	require.Equal(t, []string{"a 0 0 * * *"}, titles)
+}
@@ -0,0 +1,63 @@
+package tlsprofile
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"github.com/ostafen/kronos/internal/config"
+)
+
+func parseClientAuth(s string) (tls.ClientAuthType, error) {
+	switch strings.ToUpper(s) {
+	case "", "NONE":
+		return tls.NoClientCert, nil
+	case "OPTIONAL":
+		return tls.VerifyClientCertIfGiven, nil
+	case "REQUIRE":
+		return tls.RequireAndVerifyClientCert, nil
+	}
+	return tls.NoClientCert, fmt.Errorf("invalid clientAuth %s: one of NONE, OPTIONAL or REQUIRE is expected", s)
+}
+
+// NewServerConfig returns the TLS configuration of the api server, which negotiates HTTP/2 when supported by clients.
+// Like profiles, the certificate and the client CAs are reloaded whenever their files change.
+func NewServerConfig(conf config.ServerTLS) (*tls.Config, error) {
+	if conf.CertFile == "" || conf.KeyFile == "" {
+		return nil, fmt.Errorf("certFile and keyFile must be set to enable tls")
+	}
+
+	clientAuth, err := parseClientAuth(conf.ClientAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	if clientAuth != tls.NoClientCert && conf.ClientCAFile == "" {
+		return nil, fmt.Errorf("clientCAFile must be set to verify client certificates")
+	}
+
+	p, err := New("server", config.TLSProfile{
+		CertFile: conf.CertFile,
+		KeyFile:  conf.KeyFile,
+		CAFile:   conf.ClientCAFile,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			current, _ := p.Config()
+
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				NextProtos:   []string{"h2", "http/1.1"},
+				Certificates: current.Certificates,
+				ClientCAs:    current.RootCAs,
+				ClientAuth:   clientAuth,
+			}, nil
+		},
+	}, nil
+}
@@ -0,0 +1,136 @@
+package tlsprofile
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ostafen/kronos/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func writeServerCert(t *testing.T, certFile, keyFile, cn string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+}
+
+func TestServerConfig(t *testing.T) {
+	checkInterval := CheckInterval
+	CheckInterval = 0
+	t.Cleanup(func() { CheckInterval = checkInterval })
+
+	dir := t.TempDir()
+	serverCert, serverKey := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	clientCert, clientKey := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+
+	writeServerCert(t, serverCert, serverKey, "first")
+	writeSelfSigned(t, clientCert, clientKey, "billing-service")
+
+	tlsConfig, err := NewServerConfig(config.ServerTLS{
+		CertFile:     serverCert,
+		KeyFile:      serverKey,
+		ClientCAFile: clientCert,
+		ClientAuth:   "require",
+	})
+	require.NoError(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &http.Server{
+		TLSConfig: tlsConfig,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+		}),
+	}
+	go server.ServeTLS(ln, "", "")
+	t.Cleanup(func() { server.Close() })
+
+	cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+	require.NoError(t, err)
+
+	// returns the common name of the server certificate and the protocol negotiated by a new connection
+	get := func(certs ...tls.Certificate) (string, int, error) {
+		data, err := os.ReadFile(serverCert)
+		require.NoError(t, err)
+
+		roots := x509.NewCertPool()
+		roots.AppendCertsFromPEM(data)
+
+		client := &http.Client{Transport: &http.Transport{
+			ForceAttemptHTTP2: true,
+			TLSClientConfig:   &tls.Config{RootCAs: roots, Certificates: certs},
+		}}
+
+		resp, err := client.Get("https://" + ln.Addr().String())
+		if err != nil {
+			return "", 0, err
+		}
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return resp.TLS.PeerCertificates[0].Subject.CommonName, resp.ProtoMajor, nil
+	}
+
+	cn, proto, err := get(cert)
+	require.NoError(t, err)
+	require.Equal(t, "first", cn)
+	require.Equal(t, 2, proto)
+
+	_, _, err = get()
+	require.Error(t, err)
+
+	writeServerCert(t, serverCert, serverKey, "second")
+	require.NoError(t, os.Chtimes(serverCert, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+
+	cn, _, err = get(cert)
+	require.NoError(t, err)
+	require.Equal(t, "second", cn)
+}
+
+func TestInvalidServerConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	writeServerCert(t, certFile, keyFile, "server")
+
+	_, err := NewServerConfig(config.ServerTLS{CertFile: certFile})
+	require.Error(t, err)
+
+	_, err = NewServerConfig(config.ServerTLS{CertFile: certFile, KeyFile: keyFile, ClientAuth: "require"})
+	require.Error(t, err)
+
+	_, err = NewServerConfig(config.ServerTLS{CertFile: certFile, KeyFile: keyFile, ClientAuth: "sometimes"})
+	require.Error(t, err)
+
+	_, err = NewServerConfig(config.ServerTLS{CertFile: certFile, KeyFile: keyFile, ClientAuth: "none"})
+	require.NoError(t, err)
+}
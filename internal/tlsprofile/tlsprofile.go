@@ -0,0 +1,206 @@
+package tlsprofile
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ostafen/kronos/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrUnknownProfile = errors.New("unknown tls profile")
+
+// CheckInterval bounds how often the files of a profile are checked for changes.
+var CheckInterval = 5 * time.Second
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Profile is a TLS client configuration, which is reloaded whenever its certificate, key or CA files change.
+type Profile struct {
+	name          string
+	conf          config.TLSProfile
+	checkInterval time.Duration
+
+	mtx        sync.Mutex
+	tlsConfig  *tls.Config
+	generation uint64
+	stamps     []fileStamp
+	lastCheck  time.Time
+}
+
+func New(name string, conf config.TLSProfile) (*Profile, error) {
+	if (conf.CertFile == "") != (conf.KeyFile == "") {
+		return nil, fmt.Errorf("tls profile %q: certFile and keyFile must be set together", name)
+	}
+
+	p := &Profile{
+		name:          name,
+		conf:          conf,
+		checkInterval: CheckInterval,
+	}
+
+	if err := p.load(); err != nil {
+		return nil, fmt.Errorf("tls profile %q: %w", name, err)
+	}
+	return p, nil
+}
+
+func (p *Profile) Name() string {
+	return p.name
+}
+
+func (p *Profile) files() []string {
+	files := make([]string, 0, 3)
+	for _, f := range []string{p.conf.CertFile, p.conf.KeyFile, p.conf.CAFile} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+func (p *Profile) stat() ([]fileStamp, error) {
+	files := p.files()
+
+	stamps := make([]fileStamp, 0, len(files))
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return nil, err
+		}
+		stamps = append(stamps, fileStamp{modTime: info.ModTime(), size: info.Size()})
+	}
+	return stamps, nil
+}
+
+func (p *Profile) load() error {
+	stamps, err := p.stat()
+	if err != nil {
+		return err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         p.conf.ServerName,
+		InsecureSkipVerify: p.conf.InsecureSkipVerify,
+	}
+
+	if p.conf.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(p.conf.CertFile, p.conf.KeyFile)
+		if err != nil {
+			return err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if p.conf.CAFile != "" {
+		data, err := os.ReadFile(p.conf.CAFile)
+		if err != nil {
+			return err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no certificate found in %s", p.conf.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	p.tlsConfig = tlsConfig
+	p.stamps = stamps
+	p.generation++
+	return nil
+}
+
+func (p *Profile) changed() bool {
+	stamps, err := p.stat()
+	if err != nil || len(stamps) != len(p.stamps) {
+		return true
+	}
+
+	for i := range stamps {
+		if !stamps[i].modTime.Equal(p.stamps[i].modTime) || stamps[i].size != p.stamps[i].size {
+			return true
+		}
+	}
+	return false
+}
+
+// Config returns the current TLS configuration, together with a generation number
+// which is incremented each time the configuration is reloaded.
+// If reloading fails, the previous configuration is kept.
+func (p *Profile) Config() (*tls.Config, uint64) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if time.Since(p.lastCheck) >= p.checkInterval {
+		p.lastCheck = time.Now()
+
+		if p.changed() {
+			if err := p.load(); err != nil {
+				log.WithError(err).
+					WithField("profile", p.name).
+					Error("unable to reload tls profile, keeping the previous configuration")
+			} else {
+				log.WithField("profile", p.name).Info("tls profile reloaded")
+			}
+		}
+	}
+	return p.tlsConfig.Clone(), p.generation
+}
+
+// Registry holds the default profile, which is nil if not configured, and the named profiles.
+type Registry struct {
+	defaultProfile *Profile
+	profiles       map[string]*Profile
+}
+
+func NewRegistry(conf config.Webhooks) (*Registry, error) {
+	r := &Registry{
+		profiles: make(map[string]*Profile),
+	}
+
+	if conf.TLS != (config.TLSProfile{}) {
+		p, err := New("default", conf.TLS)
+		if err != nil {
+			return nil, err
+		}
+		r.defaultProfile = p
+	}
+
+	for name, profileConf := range conf.TLSProfiles {
+		p, err := New(name, profileConf)
+		if err != nil {
+			return nil, err
+		}
+		// viper lowercases map keys, which makes names case insensitive
+		r.profiles[strings.ToLower(name)] = p
+	}
+	return r, nil
+}
+
+// Get returns the profile with the given name, or the default profile if name is empty.
+func (r *Registry) Get(name string) (*Profile, error) {
+	if name == "" {
+		if r == nil {
+			return nil, nil
+		}
+		return r.defaultProfile, nil
+	}
+
+	if r != nil {
+		if p, has := r.profiles[strings.ToLower(name)]; has {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownProfile, name)
+}
@@ -0,0 +1,115 @@
+package tlsprofile
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ostafen/kronos/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSelfSigned(t *testing.T, certFile, keyFile, cn string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+}
+
+func commonName(t *testing.T, p *Profile) (string, uint64) {
+	tlsConfig, generation := p.Config()
+	require.Len(t, tlsConfig.Certificates, 1)
+
+	cert, err := x509.ParseCertificate(tlsConfig.Certificates[0].Certificate[0])
+	require.NoError(t, err)
+	return cert.Subject.CommonName, generation
+}
+
+func TestReloadOnFileChange(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+
+	writeSelfSigned(t, certFile, keyFile, "first")
+
+	p, err := New("test", config.TLSProfile{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	p.checkInterval = 0
+
+	cn, generation := commonName(t, p)
+	require.Equal(t, "first", cn)
+
+	writeSelfSigned(t, certFile, keyFile, "second")
+	// make sure the modification time changes, regardless of the resolution of the file system
+	require.NoError(t, os.Chtimes(certFile, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+
+	cn, newGeneration := commonName(t, p)
+	require.Equal(t, "second", cn)
+	require.Greater(t, newGeneration, generation)
+
+	// a broken update keeps the previous configuration
+	require.NoError(t, os.WriteFile(keyFile, []byte("garbage"), 0600))
+
+	cn, _ = commonName(t, p)
+	require.Equal(t, "second", cn)
+}
+
+func TestInvalidProfiles(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := New("test", config.TLSProfile{CertFile: filepath.Join(dir, "client.crt")})
+	require.Error(t, err)
+
+	_, err = New("test", config.TLSProfile{CAFile: filepath.Join(dir, "missing.pem")})
+	require.Error(t, err)
+
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0600))
+
+	_, err = New("test", config.TLSProfile{CAFile: caFile})
+	require.Error(t, err)
+}
+
+func TestRegistry(t *testing.T) {
+	r, err := NewRegistry(config.Webhooks{
+		TLSProfiles: map[string]config.TLSProfile{"internal": {ServerName: "internal.example.com"}},
+	})
+	require.NoError(t, err)
+
+	p, err := r.Get("")
+	require.NoError(t, err)
+	require.Nil(t, p)
+
+	p, err = r.Get("internal")
+	require.NoError(t, err)
+	require.Equal(t, "internal", p.Name())
+
+	_, err = r.Get("external")
+	require.ErrorIs(t, err, ErrUnknownProfile)
+
+	var none *Registry
+	_, err = none.Get("internal")
+	require.ErrorIs(t, err, ErrUnknownProfile)
+}
@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/ostafen/kronos/internal/config"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	recorderOnce sync.Once
+	recorder     *tracetest.SpanRecorder
+)
+
+// spanRecorder installs a recorder of the spans started by the process, only once, since tracers obtained
+// before the first tracer provider is installed keep delegating to it.
+func spanRecorder() *tracetest.SpanRecorder {
+	recorderOnce.Do(func() {
+		recorder = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	})
+	return recorder
+}
+
+func TestMiddleware(t *testing.T) {
+	_, err := Setup(context.Background(), config.Tracing{Exporter: ExporterNone}, "test")
+	require.NoError(t, err)
+
+	recorder := spanRecorder()
+	recorded := len(recorder.Ended())
+
+	router := mux.NewRouter()
+	router.Use(Middleware)
+	router.HandleFunc("/schedules/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := otel.Tracer("test").Start(r.Context(), "handler")
+		span.End()
+
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/schedules/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()[recorded:]
+	require.Len(t, spans, 2)
+
+	handler, server := spans[0], spans[1]
+
+	// the server span joins the trace of the caller, and is named after the route rather than the path
+	require.Equal(t, "GET /schedules/{id}", server.Name())
+	require.Equal(t, trace.SpanKindServer, server.SpanKind())
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	require.True(t, server.Parent().IsRemote())
+	require.Contains(t, server.Attributes(), attribute.Int("http.response.status_code", http.StatusInternalServerError))
+	require.Equal(t, codes.Error, server.Status().Code)
+
+	require.Equal(t, server.SpanContext().SpanID(), handler.Parent().SpanID())
+}
+
+func TestUnknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), config.Tracing{Exporter: "zipkin"}, "test")
+	require.Error(t, err)
+}
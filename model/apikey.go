@@ -0,0 +1,41 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+type APIKeyCreateInput struct {
+	Name      string    `json:"name" validate:"required"`
+	Namespace string    `json:"namespace"`
+	Scopes    []string  `json:"scopes" validate:"required,min=1"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (input *APIKeyCreateInput) Validate() error {
+	if !input.ExpiresAt.IsZero() && input.ExpiresAt.Before(time.Now()) {
+		return fmt.Errorf(`"expiresAt" must be a valid date in the future`)
+	}
+	return nil
+}
+
+type APIKey struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Namespace string    `json:"namespace"`
+	Prefix    string    `json:"prefix"`
+	Hash      string    `json:"-"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt,omitempty"`
+}
+
+func (k *APIKey) Expired() bool {
+	return !k.ExpiresAt.IsZero() && !k.ExpiresAt.After(time.Now())
+}
+
+// CreatedAPIKey is returned only once, on creation, and is the only representation of a key carrying its secret.
+type CreatedAPIKey struct {
+	*APIKey
+	Key string `json:"key"`
+}
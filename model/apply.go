@@ -0,0 +1,112 @@
+package model
+
+import (
+	"reflect"
+	"time"
+)
+
+type ApplyAction string
+
+const (
+	ApplyActionCreate    ApplyAction = "create"
+	ApplyActionUpdate    ApplyAction = "update"
+	ApplyActionDelete    ApplyAction = "delete"
+	ApplyActionUnchanged ApplyAction = "unchanged"
+	// ApplyActionUnmanaged reports a schedule which is not part of the desired set, but is kept since pruning is disabled.
+	ApplyActionUnmanaged ApplyAction = "unmanaged"
+)
+
+// ApplyRequest describes the desired set of schedules of a namespace, where schedules are identified by their title.
+type ApplyRequest struct {
+	Schedules []*ScheduleRegisterInput `json:"schedules" validate:"dive,required"`
+	// Prune deletes the schedules of the namespace which are not part of the desired set.
+	Prune bool `json:"prune"`
+	// DryRun only reports the changes, without applying them.
+	DryRun bool `json:"dryRun"`
+}
+
+type FieldDiff struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+type ScheduleChange struct {
+	Action ApplyAction `json:"action"`
+	Title  string      `json:"title"`
+	ID     int64       `json:"id,omitempty"`
+	Diff   []FieldDiff `json:"diff,omitempty"`
+	// Applied reports whether the change has been made, and is only set when the apply failed.
+	Applied bool `json:"applied,omitempty"`
+}
+
+type ApplyResult struct {
+	DryRun  bool             `json:"dryRun"`
+	Changes []ScheduleChange `json:"changes"`
+	// Drift reports whether the stored schedules differ from the desired ones.
+	Drift bool `json:"drift"`
+	// Error reports why the apply stopped, in which case only the changes marked as applied were made.
+	Error string `json:"error,omitempty"`
+}
+
+func diffTime(diffs []FieldDiff, field string, from, to time.Time) []FieldDiff {
+	if !from.Equal(to) {
+		return append(diffs, FieldDiff{Field: field, From: from, To: to})
+	}
+	return diffs
+}
+
+func diffValue(diffs []FieldDiff, field string, from, to any) []FieldDiff {
+	if !reflect.DeepEqual(from, to) {
+		return append(diffs, FieldDiff{Field: field, From: from, To: to})
+	}
+	return diffs
+}
+
+func diffMap(diffs []FieldDiff, field string, from, to map[string]string) []FieldDiff {
+	// nil and empty maps are equivalent
+	if len(from) == 0 && len(to) == 0 {
+		return diffs
+	}
+	return diffValue(diffs, field, from, to)
+}
+
+func diffList(diffs []FieldDiff, field string, from, to []string) []FieldDiff {
+	// nil and empty lists are equivalent
+	if len(from) == 0 && len(to) == 0 {
+		return diffs
+	}
+	return diffValue(diffs, field, from, to)
+}
+
+// DiffSchedule returns the fields of the current schedule which differ from those described by the input.
+// Fields which are not part of the input, such as the status, are ignored.
+func DiffSchedule(current *CronSchedule, input *ScheduleRegisterInput) []FieldDiff {
+	desired := input.build(current.Namespace)
+
+	var diffs []FieldDiff
+	diffs = diffValue(diffs, "description", current.Description, desired.Description)
+	diffs = diffValue(diffs, "isRecurring", current.IsRecurring, desired.IsRecurring)
+	diffs = diffValue(diffs, "cronExpr", current.CronExpr, desired.CronExpr)
+	diffs = diffValue(diffs, "rrule", current.RRule, desired.RRule)
+	diffs = diffValue(diffs, "interval", current.Interval, desired.Interval)
+	diffs = diffValue(diffs, "intervalMode", current.IntervalMode, desired.IntervalMode)
+	diffs = diffValue(diffs, "maxRuns", current.MaxRuns, desired.MaxRuns)
+	diffs = diffValue(diffs, "maxRunsMode", current.MaxRunsMode, desired.MaxRunsMode)
+	diffs = diffValue(diffs, "url", current.URL, desired.URL)
+	diffs = diffValue(diffs, "action", current.Action, desired.Action)
+	// one-shots relative to the time they are applied would otherwise be postponed by every apply
+	if input.RunAfter == 0 {
+		diffs = diffTime(diffs, "runAt", current.RunAt, desired.RunAt)
+	}
+	diffs = diffTime(diffs, "startAt", current.StartAt, desired.StartAt)
+	diffs = diffTime(diffs, "endAt", current.EndAt, desired.EndAt)
+	diffs = diffMap(diffs, "metadata", current.Metadata, desired.Metadata)
+	diffs = diffValue(diffs, "tlsProfile", current.TLSProfile, desired.TLSProfile)
+	diffs = diffValue(diffs, "credentials", current.Credentials, desired.Credentials)
+	diffs = diffMap(diffs, "secretHeaders", current.SecretHeaders, desired.SecretHeaders)
+	diffs = diffList(diffs, "calendars", current.Calendars, desired.Calendars)
+	diffs = diffValue(diffs, "blackoutPolicy", current.BlackoutPolicy, desired.BlackoutPolicy)
+	diffs = diffValue(diffs, "jitter", current.Jitter, desired.Jitter)
+	return diffs
+}
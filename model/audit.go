@@ -0,0 +1,80 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+type AuditAction string
+
+const (
+	AuditActionRegister AuditAction = "register"
+	AuditActionDelete   AuditAction = "delete"
+	AuditActionPause    AuditAction = "pause"
+	AuditActionResume   AuditAction = "resume"
+	AuditActionTrigger  AuditAction = "trigger"
+	AuditActionUpdate   AuditAction = "update"
+)
+
+var auditActions = map[AuditAction]bool{
+	AuditActionRegister: true,
+	AuditActionDelete:   true,
+	AuditActionPause:    true,
+	AuditActionResume:   true,
+	AuditActionTrigger:  true,
+	AuditActionUpdate:   true,
+}
+
+// AuditEntry records a mutating operation on a schedule, together with
+// the state of the schedule before and after the operation, where meaningful.
+type AuditEntry struct {
+	ID         int64         `json:"id"`
+	Namespace  string        `json:"namespace"`
+	At         time.Time     `json:"at"`
+	Actor      string        `json:"actor"`
+	Method     string        `json:"method"`
+	SourceIP   string        `json:"sourceIp,omitempty"`
+	Action     AuditAction   `json:"action"`
+	ScheduleID int64         `json:"scheduleId"`
+	Before     *CronSchedule `json:"before,omitempty"`
+	After      *CronSchedule `json:"after,omitempty"`
+}
+
+const (
+	AuditPageSizeDefault = 100
+	AuditPageSizeMax     = 1000
+)
+
+// AuditQuery filters the audit log. Zero values disable the corresponding filter.
+// Entries are returned from the most recent one, and the next page is requested
+// by setting Cursor to the NextCursor of the previous page.
+type AuditQuery struct {
+	Namespace  string
+	Actor      string
+	Action     AuditAction
+	ScheduleID int64
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Cursor     int64
+}
+
+func (q *AuditQuery) Validate() error {
+	if q.Action != "" && !auditActions[q.Action] {
+		return fmt.Errorf("unknown audit action %s", q.Action)
+	}
+
+	if q.Limit < 0 || q.Limit > AuditPageSizeMax {
+		return fmt.Errorf(`"limit" must be between 1 and %d`, AuditPageSizeMax)
+	}
+
+	if q.Limit == 0 {
+		q.Limit = AuditPageSizeDefault
+	}
+	return nil
+}
+
+type AuditPage struct {
+	Entries    []*AuditEntry `json:"entries"`
+	NextCursor int64         `json:"nextCursor,omitempty"`
+}
@@ -0,0 +1,139 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/ostafen/kronos/internal/cron"
+)
+
+var calendarNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9._-]{0,126}[a-zA-Z0-9])?$`)
+
+// DateLayout is the format of the dates excluded by calendars.
+const DateLayout = "2006-01-02"
+
+// BlackoutPolicy tells what happens to the occurrences of a schedule excluded by its calendars.
+type BlackoutPolicy string
+
+const (
+	// BlackoutPolicySkip drops excluded occurrences, which are recorded to history as skipped.
+	BlackoutPolicySkip BlackoutPolicy = "skip"
+	// BlackoutPolicyShift moves excluded occurrences to the same time of the next business day,
+	// that is the next day from Monday to Friday which is not excluded.
+	BlackoutPolicyShift BlackoutPolicy = "shift"
+)
+
+// BlackoutWindow is a recurring period, starting at each activation of a cron expression and lasting Duration.
+type BlackoutWindow struct {
+	CronExpr string   `json:"cronExpr"`
+	Duration Duration `json:"duration"`
+}
+
+type CalendarInput struct {
+	Name        string `json:"-"`
+	Description string `json:"description"`
+	// TimeZone is the zone of the dates and blackout windows, the one of the server if empty.
+	TimeZone  string           `json:"timeZone"`
+	Dates     []string         `json:"dates"`
+	Blackouts []BlackoutWindow `json:"blackouts"`
+	// ICS is an iCalendar file, whose events are added to the excluded dates.
+	ICS string `json:"ics,omitempty"`
+}
+
+func ValidateCalendarName(name string) error {
+	if !calendarNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid calendar name %q: only alphanumeric characters, '.', '_' and '-' are allowed", name)
+	}
+	return nil
+}
+
+func (input *CalendarInput) Validate() error {
+	if err := ValidateCalendarName(input.Name); err != nil {
+		return err
+	}
+
+	if _, err := time.LoadLocation(input.TimeZone); err != nil {
+		return fmt.Errorf("invalid timeZone %s: %w", input.TimeZone, err)
+	}
+
+	for _, date := range input.Dates {
+		if _, err := time.Parse(DateLayout, date); err != nil {
+			return fmt.Errorf("invalid date %q: YYYY-MM-DD is expected", date)
+		}
+	}
+
+	for _, window := range input.Blackouts {
+		if _, err := cron.Parse(window.CronExpr); err != nil {
+			return fmt.Errorf("invalid cronExpr %s of blackout window: %w", window.CronExpr, err)
+		}
+
+		if window.Duration <= 0 {
+			return fmt.Errorf("the duration of blackout window %s must be positive", window.CronExpr)
+		}
+	}
+	return nil
+}
+
+// Calendar holds dates and recurring blackout windows, which the schedules referring to it don't run in.
+type Calendar struct {
+	Namespace   string           `json:"namespace"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	TimeZone    string           `json:"timeZone,omitempty"`
+	Dates       []string         `json:"dates"`
+	Blackouts   []BlackoutWindow `json:"blackouts"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	UpdatedAt   time.Time        `json:"updatedAt"`
+}
+
+// NormalizeDates sorts the dates of the calendar and removes the duplicated ones.
+func (c *Calendar) NormalizeDates() {
+	sort.Strings(c.Dates)
+
+	dates := c.Dates[:0]
+	for _, date := range c.Dates {
+		if len(dates) == 0 || date != dates[len(dates)-1] {
+			dates = append(dates, date)
+		}
+	}
+	c.Dates = dates
+}
+
+// Location returns the time zone of the calendar.
+func (c *Calendar) Location() *time.Location {
+	if c.TimeZone == "" {
+		return time.Local
+	}
+
+	loc, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// Excludes returns the reason why t is excluded by the calendar, or an empty string if it isn't.
+// Dates are expected to be sorted.
+func (c *Calendar) Excludes(t time.Time) string {
+	t = t.In(c.Location())
+
+	date := t.Format(DateLayout)
+	if i := sort.SearchStrings(c.Dates, date); i < len(c.Dates) && c.Dates[i] == date {
+		return fmt.Sprintf("%s is excluded by calendar %s", date, c.Name)
+	}
+
+	for _, window := range c.Blackouts {
+		expr, err := cron.Parse(window.CronExpr)
+		if err != nil {
+			continue
+		}
+
+		// the last window starting before t, if any, starts at the first activation after t - duration
+		if start := expr.Next(t.Add(-time.Duration(window.Duration))); !start.IsZero() && !start.After(t) {
+			return fmt.Sprintf("inside the blackout window %q (%s) of calendar %s", window.CronExpr, window.Duration, c.Name)
+		}
+	}
+	return ""
+}
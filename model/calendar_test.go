@@ -0,0 +1,66 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendarExcludes(t *testing.T) {
+	cal := &Calendar{
+		Name:     "holidays",
+		TimeZone: "Europe/Rome",
+		Dates:    []string{"2030-12-26", "2030-12-25", "2030-12-25"},
+		// from friday evening to monday morning
+		Blackouts: []BlackoutWindow{{CronExpr: "0 18 * * FRI", Duration: Duration(62 * time.Hour)}},
+	}
+	cal.NormalizeDates()
+	require.Equal(t, []string{"2030-12-25", "2030-12-26"}, cal.Dates)
+
+	loc := cal.Location()
+
+	cases := []struct {
+		at       time.Time
+		excluded bool
+	}{
+		{time.Date(2030, 12, 25, 0, 0, 0, 0, loc), true},
+		{time.Date(2030, 12, 26, 23, 59, 0, 0, loc), true},
+		// still the 24th in UTC, but already the 25th in Rome
+		{time.Date(2030, 12, 24, 23, 30, 0, 0, time.UTC), true},
+		{time.Date(2030, 12, 24, 12, 0, 0, 0, loc), false},
+		{time.Date(2030, 12, 6, 17, 59, 0, 0, loc), false},
+		{time.Date(2030, 12, 6, 18, 0, 0, 0, loc), true},
+		{time.Date(2030, 12, 8, 12, 0, 0, 0, loc), true},
+		{time.Date(2030, 12, 9, 7, 59, 0, 0, loc), true},
+		{time.Date(2030, 12, 9, 8, 0, 0, 0, loc), false},
+	}
+
+	for _, c := range cases {
+		reason := cal.Excludes(c.at)
+		require.Equal(t, c.excluded, reason != "", "%s: %s", c.at, reason)
+	}
+}
+
+func TestValidateCalendar(t *testing.T) {
+	valid := CalendarInput{
+		Name:      "holidays",
+		TimeZone:  "UTC",
+		Dates:     []string{"2030-12-25"},
+		Blackouts: []BlackoutWindow{{CronExpr: "0 18 * * FRI", Duration: Duration(time.Hour)}},
+	}
+	require.NoError(t, valid.Validate())
+
+	for _, mutate := range []func(*CalendarInput){
+		func(c *CalendarInput) { c.Name = "bad name" },
+		func(c *CalendarInput) { c.TimeZone = "Mars/Olympus" },
+		func(c *CalendarInput) { c.Dates = []string{"25/12/2030"} },
+		func(c *CalendarInput) { c.Blackouts[0].CronExpr = "not a cron" },
+		func(c *CalendarInput) { c.Blackouts[0].Duration = 0 },
+	} {
+		input := valid
+		input.Blackouts = append([]BlackoutWindow(nil), valid.Blackouts...)
+		mutate(&input)
+		require.Error(t, input.Validate())
+	}
+}
@@ -0,0 +1,30 @@
+package model
+
+// CrontabImportRequest imports the entries of a crontab as recurring webhook schedules.
+type CrontabImportRequest struct {
+	Crontab string `json:"crontab" validate:"required"`
+	// URLTemplate is a Go template rendering the webhook url of each entry from its fields,
+	// such as "https://runner.example.com/run?cmd={{urlquery .Command}}".
+	URLTemplate string `json:"urlTemplate" validate:"required"`
+	// TitleTemplate renders the title of each entry, by which imported schedules are matched to the stored ones.
+	// It defaults to "{{.Slug}}-{{.ID}}", that is the command followed by a hash of the schedule and command.
+	TitleTemplate string `json:"titleTemplate"`
+	// System enables the format of system crontabs, whose entries have a user field before the command.
+	System bool `json:"system"`
+	// Metadata is added to the metadata of every schedule, along with the command of the entry.
+	Metadata map[string]string `json:"metadata"`
+	DryRun   bool              `json:"dryRun"`
+}
+
+// CrontabLine reports a line of a crontab which was not imported, or not entirely.
+type CrontabLine struct {
+	Line   int    `json:"line"`
+	Text   string `json:"text"`
+	Reason string `json:"reason"`
+}
+
+// CrontabImportResult reports the changes made by an import, which are applied as by POST /apply without pruning.
+type CrontabImportResult struct {
+	ApplyResult
+	Unsupported []CrontabLine `json:"unsupported"`
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Duration is a time.Duration encoded to json as a Go duration string, such as "1h30m".
+// Integers, holding nanoseconds as encoded by earlier versions, are still accepted.
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var ns int64
+	if err := json.Unmarshal(data, &ns); err == nil {
+		*d = Duration(ns)
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf(`invalid duration %s: a string such as "90m" is expected`, data)
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDurationJSON(t *testing.T) {
+	var input ScheduleRegisterInput
+	require.NoError(t, json.Unmarshal([]byte(`{"interval": "90m", "jitter": 30000000000}`), &input))
+	require.Equal(t, Duration(90*time.Minute), input.Interval)
+	require.Equal(t, Duration(30*time.Second), input.Jitter)
+
+	data, err := json.Marshal(&CronSchedule{Interval: input.Interval, Jitter: input.Jitter})
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"interval":"1h30m0s"`)
+	require.Contains(t, string(data), `"jitter":"30s"`)
+
+	// zero durations are omitted
+	data, err = json.Marshal(&ScheduleRegisterInput{})
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "runAfter")
+
+	for _, invalid := range []string{`{"runAfter": "soon"}`, `{"runAfter": true}`} {
+		require.Error(t, json.Unmarshal([]byte(invalid), &input), invalid)
+	}
+}
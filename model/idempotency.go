@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// IdempotencyRecord holds the response to a request carrying an Idempotency-Key, which is replayed to repeated requests.
+type IdempotencyRecord struct {
+	// Scope isolates the keys of different callers.
+	Scope string
+	Key   string
+	// Fingerprint identifies the request, so that keys reused for different requests can be detected.
+	Fingerprint string
+	// StatusCode is zero while the original request is still in progress.
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+}
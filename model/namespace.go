@@ -0,0 +1,57 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// DefaultNamespace is the namespace schedules are registered to when the caller is not bound to any namespace.
+const DefaultNamespace = "default"
+
+var namespaceRegexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// Quota bounds the resources a namespace can consume. Zero values fall back to the configured defaults.
+type Quota struct {
+	MaxSchedules           int           `json:"maxSchedules"`
+	MinInterval            time.Duration `json:"minInterval"`
+	MaxDeliveriesPerMinute int           `json:"maxDeliveriesPerMinute"`
+}
+
+// Merge returns a copy of q where unset limits are taken from defaults.
+func (q Quota) Merge(defaults Quota) Quota {
+	if q.MaxSchedules == 0 {
+		q.MaxSchedules = defaults.MaxSchedules
+	}
+	if q.MinInterval == 0 {
+		q.MinInterval = defaults.MinInterval
+	}
+	if q.MaxDeliveriesPerMinute == 0 {
+		q.MaxDeliveriesPerMinute = defaults.MaxDeliveriesPerMinute
+	}
+	return q
+}
+
+type NamespaceInput struct {
+	Name        string `json:"name" validate:"required"`
+	Description string `json:"description"`
+	Quota       Quota  `json:"quota"`
+}
+
+func (input *NamespaceInput) Validate() error {
+	if !namespaceRegexp.MatchString(input.Name) {
+		return fmt.Errorf("invalid namespace name %q: only lowercase alphanumeric characters and '-' are allowed", input.Name)
+	}
+
+	if input.Quota.MaxSchedules < 0 || input.Quota.MinInterval < 0 || input.Quota.MaxDeliveriesPerMinute < 0 {
+		return fmt.Errorf("quota limits must not be negative")
+	}
+	return nil
+}
+
+type Namespace struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Quota       Quota     `json:"quota"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+)
+
+type RoleBindingInput struct {
+	Subject   string            `json:"subject" validate:"required"`
+	Role      string            `json:"role" validate:"required"`
+	Namespace string            `json:"namespace"`
+	Selector  map[string]string `json:"selector"`
+}
+
+// RoleBinding grants a role to a subject (such as apikey:<id>, jwt:<sub> or cert:<common name>) on the schedules
+// of a namespace whose metadata match the selector. Empty namespace and selector match everything.
+type RoleBinding struct {
+	ID        int64             `json:"id"`
+	Subject   string            `json:"subject"`
+	Role      string            `json:"role"`
+	Namespace string            `json:"namespace"`
+	Selector  map[string]string `json:"selector,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
@@ -0,0 +1,539 @@
+// Package model defines the types of the Kronos api, shared by the server and the client.
+package model
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"time"
+
+	"github.com/ostafen/kronos/internal/cron"
+	"github.com/ostafen/kronos/internal/rrule"
+)
+
+type ScheduleStatus string
+
+const (
+	ScheduleStatusNotStarted ScheduleStatus = "not_started"
+	ScheduleStatusActive     ScheduleStatus = "active"
+	ScheduleStatusPaused     ScheduleStatus = "paused"
+	ScheduleStatusExpired    ScheduleStatus = "expired"
+)
+
+// IntervalMode tells how the activations of interval schedules are spaced.
+type IntervalMode string
+
+const (
+	// IntervalModeFixedRate activates schedules at whole intervals from their start.
+	IntervalModeFixedRate IntervalMode = "fixedRate"
+	// IntervalModeFixedDelay activates schedules an interval after the end of their previous run.
+	IntervalModeFixedDelay IntervalMode = "fixedDelay"
+)
+
+// MaxRunsMode tells which runs count towards the maxRuns of a schedule.
+type MaxRunsMode string
+
+const (
+	// MaxRunsModeTotal counts all the runs of a schedule, whatever their outcome.
+	MaxRunsModeTotal MaxRunsMode = "total"
+	// MaxRunsModeSuccessful only counts the runs which have been delivered successfully.
+	MaxRunsModeSuccessful MaxRunsMode = "successful"
+)
+
+type ScheduleRegisterInput struct {
+	Title       string `json:"title" validate:"required"`
+	Description string `json:"description"`
+	CronExpr    string `json:"cronExpr"`
+	// RRule is an RFC 5545 recurrence, which recurring schedules may follow in place of a cron expression.
+	RRule string `json:"rrule,omitempty"`
+	// Interval activates recurring schedules at fixed intervals, in place of a cron expression.
+	Interval     Duration     `json:"interval,omitempty"`
+	IntervalMode IntervalMode `json:"intervalMode,omitempty"`
+	URL          string       `json:"url" validate:"required_without=Action"`
+	// Action names a Go function registered by a program embedding Kronos, which is run in place of a webhook.
+	Action      string    `json:"action,omitempty"`
+	IsRecurring *bool     `json:"isRecurring" validate:"required"`
+	RunAt       time.Time `json:"runAt"`
+	// RunAfter runs a one-shot schedule the given time after its registration, in place of runAt.
+	RunAfter    Duration          `json:"runAfter,omitempty"`
+	StartAt     time.Time         `json:"startAt"`
+	EndAt       time.Time         `json:"endAt"`
+	Metadata    map[string]string `json:"metadata"`
+	TLSProfile  string            `json:"tlsProfile"`
+	Credentials string            `json:"credentials"`
+	// SecretHeaders maps header names to the secrets holding their values.
+	SecretHeaders map[string]string `json:"secretHeaders"`
+	// Calendars name the calendars whose dates and blackout windows the schedule doesn't run in.
+	Calendars      []string       `json:"calendars,omitempty"`
+	BlackoutPolicy BlackoutPolicy `json:"blackoutPolicy,omitempty"`
+	// Jitter delays each activation of a recurring schedule by a random duration, up to Jitter.
+	Jitter Duration `json:"jitter,omitempty"`
+	// MaxRuns expires a recurring schedule once it has run as many times.
+	MaxRuns     int         `json:"maxRuns,omitempty"`
+	MaxRunsMode MaxRunsMode `json:"maxRunsMode,omitempty"`
+}
+
+func (input *ScheduleRegisterInput) Recurring() bool {
+	return *input.IsRecurring
+}
+
+// runAt returns the time a one-shot schedule runs at, which is relative to now if RunAfter is set.
+func (input *ScheduleRegisterInput) runAt() time.Time {
+	if input.RunAfter > 0 && input.RunAt.IsZero() {
+		return time.Now().Add(time.Duration(input.RunAfter))
+	}
+	return input.RunAt
+}
+
+func validate(input *ScheduleRegisterInput, namespace string) error {
+	if input.Action != "" {
+		if input.URL != "" {
+			return fmt.Errorf(`"url" and "action" are mutually exclusive`)
+		}
+
+		if input.TLSProfile != "" || input.Credentials != "" || len(input.SecretHeaders) > 0 {
+			return fmt.Errorf(`"tlsProfile", "credentials" and "secretHeaders" only apply to webhooks`)
+		}
+	}
+
+	if input.Recurring() {
+		if input.CronExpr != "" && input.RRule != "" {
+			return fmt.Errorf(`"cronExpr" and "rrule" are mutually exclusive`)
+		}
+
+		if input.Interval != 0 && (input.CronExpr != "" || input.RRule != "") {
+			return fmt.Errorf(`"interval" is mutually exclusive with "cronExpr" and "rrule"`)
+		}
+
+		if input.Interval != 0 && time.Duration(input.Interval) < time.Second {
+			return fmt.Errorf(`"interval" must be at least 1s`)
+		}
+
+		from := time.Now()
+		if input.StartAt.After(from) {
+			from = input.StartAt
+		}
+
+		dtstart := input.StartAt
+		if dtstart.IsZero() {
+			dtstart = time.Now()
+		}
+
+		rec := cron.Every(time.Duration(input.Interval), time.Time{})
+		if input.Interval == 0 {
+			// H tokens are resolved as they will be at runtime, since they may pick days which don't exist
+			var err error
+			if rec, err = recurrence(input.CronExpr, input.RRule, dtstart, hashKey(namespace, input.Title)); err != nil {
+				return err
+			}
+		}
+
+		// expressions restricted to past years, or to days which don't exist, would never run
+		if rec.Next(from).IsZero() {
+			if input.RRule != "" {
+				return fmt.Errorf("rrule has no occurrences after %s", from.Format(time.RFC3339))
+			}
+			return fmt.Errorf("cronExpr %s has no activations after %s", input.CronExpr, from.Format(time.RFC3339))
+		}
+
+		if !input.EndAt.IsZero() && input.EndAt.Before(time.Now()) {
+			return fmt.Errorf(`"endAt" must be a valide date in the future`)
+		}
+
+		if input.EndAt.Before(input.StartAt) {
+			return fmt.Errorf(`"endAt" must be greater than or equal to "startAt"`)
+		}
+
+		if input.Jitter < 0 {
+			return fmt.Errorf(`"jitter" must not be negative`)
+		}
+
+		if input.RunAfter != 0 {
+			return fmt.Errorf(`"runAfter" only applies to one-shot schedules`)
+		}
+
+		if input.MaxRuns < 0 {
+			return fmt.Errorf(`"maxRuns" must not be negative`)
+		}
+
+		// longer jitters could delay an activation past the following one
+		if min := cron.MinInterval(rec, from, jitterSamples); input.Jitter > 0 && min > 0 && time.Duration(input.Jitter) >= min {
+			return fmt.Errorf(`"jitter" must be shorter than the interval between activations, which may be %s`, min)
+		}
+	} else {
+		if input.RunAfter != 0 && !input.RunAt.IsZero() {
+			return fmt.Errorf(`"runAt" and "runAfter" are mutually exclusive`)
+		}
+
+		if input.RunAfter < 0 {
+			return fmt.Errorf(`"runAfter" must be positive`)
+		}
+
+		runAt := input.runAt()
+		if runAt.IsZero() {
+			return fmt.Errorf(`"runAt" or "runAfter" must be set for non recurring schedule`)
+		}
+
+		if time.Now().After(runAt) {
+			return fmt.Errorf(`"runAt" must be a valide date in the future`)
+		}
+
+		if !input.StartAt.IsZero() || !input.EndAt.IsZero() {
+			return fmt.Errorf(`"startAt"/"endAt" should not be set together with "runAt"`)
+		}
+
+		if input.Jitter != 0 {
+			return fmt.Errorf(`"jitter" only applies to recurring schedules`)
+		}
+
+		if input.MaxRuns != 0 {
+			return fmt.Errorf(`"maxRuns" only applies to recurring schedules`)
+		}
+	}
+
+	switch input.MaxRunsMode {
+	case "", MaxRunsModeTotal, MaxRunsModeSuccessful:
+	default:
+		return fmt.Errorf(`invalid maxRunsMode %q: either "total" or "successful" is expected`, input.MaxRunsMode)
+	}
+
+	if input.MaxRunsMode != "" && input.MaxRuns == 0 {
+		return fmt.Errorf(`"maxRunsMode" only applies together with "maxRuns"`)
+	}
+
+	switch input.IntervalMode {
+	case "", IntervalModeFixedRate, IntervalModeFixedDelay:
+	default:
+		return fmt.Errorf(`invalid intervalMode %q: either "fixedRate" or "fixedDelay" is expected`, input.IntervalMode)
+	}
+
+	if input.IntervalMode != "" && input.Interval == 0 {
+		return fmt.Errorf(`"intervalMode" only applies to interval schedules`)
+	}
+
+	switch input.BlackoutPolicy {
+	case "", BlackoutPolicySkip, BlackoutPolicyShift:
+	default:
+		return fmt.Errorf(`invalid blackoutPolicy %q: either "skip" or "shift" is expected`, input.BlackoutPolicy)
+	}
+
+	for _, calendar := range input.Calendars {
+		if err := ValidateCalendarName(calendar); err != nil {
+			return err
+		}
+	}
+
+	for header, secret := range input.SecretHeaders {
+		if !headerRegexp.MatchString(header) {
+			return fmt.Errorf("invalid header name %q", header)
+		}
+
+		if err := ValidateSecretName(secret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jitterSamples is the number of activations checked to be farther apart than the jitter of a schedule.
+const jitterSamples = 100
+
+// recurrence parses the cron expression or, if set, the rrule of a recurring schedule.
+// dtstart is the DTSTART of rules which don't define their own, and key the one H tokens are hashed with.
+func recurrence(cronExpr, rule string, dtstart time.Time, key string) (cron.Schedule, error) {
+	if rule == "" {
+		expr, err := cron.ParseHashed(cronExpr, key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cronExpr %s: %w", cronExpr, err)
+		}
+		return expr, nil
+	}
+
+	set, err := rrule.Parse(rule, dtstart)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rrule: %w", err)
+	}
+	return set, nil
+}
+
+// headerRegexp matches the characters allowed in header names (RFC 9110, section 5.1).
+var headerRegexp = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9a-zA-Z-]+$")
+
+var maxTime = time.Date(9999, 12, 31, 23, 59, 59, 999999999, time.UTC)
+
+func (input *ScheduleRegisterInput) ToSched(namespace string) (*CronSchedule, error) {
+	if err := validate(input, namespace); err != nil {
+		return nil, err
+	}
+	return input.build(namespace), nil
+}
+
+// build returns the schedule described by the input, which is assumed to be valid.
+func (input *ScheduleRegisterInput) build(namespace string) *CronSchedule {
+	runAt := input.RunAt
+	if !input.Recurring() {
+		runAt = input.runAt()
+	}
+
+	startAt, endAt := input.StartAt, input.EndAt
+	if !runAt.IsZero() {
+		startAt = runAt
+		endAt = runAt
+	} else if input.EndAt.IsZero() {
+		endAt = maxTime
+	}
+
+	createdAt := time.Now()
+
+	intervalMode := input.IntervalMode
+	if input.Interval > 0 && intervalMode == "" {
+		intervalMode = IntervalModeFixedRate
+	}
+
+	maxRunsMode := input.MaxRunsMode
+	if input.MaxRuns > 0 && maxRunsMode == "" {
+		maxRunsMode = MaxRunsModeTotal
+	}
+
+	// fixed-rate schedules run at whole intervals from their start, while fixed-delay ones
+	// have no anchor until their first run, which is computed as if they were fixed-rate
+	anchor := time.Time{}
+	if input.Interval > 0 && intervalMode == IntervalModeFixedRate {
+		anchor = startAt
+		if anchor.IsZero() {
+			anchor = createdAt.Truncate(time.Second)
+		}
+	}
+
+	return &CronSchedule{
+		ID:             -1,
+		Namespace:      namespace,
+		Status:         ScheduleStatusActive,
+		Title:          input.Title,
+		Description:    input.Description,
+		CronExpr:       input.CronExpr,
+		RRule:          input.RRule,
+		Interval:       input.Interval,
+		IntervalMode:   intervalMode,
+		Anchor:         anchor,
+		IsRecurring:    input.Recurring(),
+		URL:            input.URL,
+		Action:         input.Action,
+		Metadata:       input.Metadata,
+		TLSProfile:     input.TLSProfile,
+		Credentials:    input.Credentials,
+		SecretHeaders:  input.SecretHeaders,
+		Calendars:      input.Calendars,
+		BlackoutPolicy: input.BlackoutPolicy,
+		Jitter:         input.Jitter,
+		MaxRuns:        input.MaxRuns,
+		MaxRunsMode:    maxRunsMode,
+		RunAt:          runAt,
+		StartAt:        startAt,
+		EndAt:          endAt,
+		CreatedAt:      createdAt,
+	}
+}
+
+// ToInput returns the input describing the schedule, such that registering it yields an equivalent schedule.
+func (s *CronSchedule) ToInput() *ScheduleRegisterInput {
+	isRecurring := s.IsRecurring
+
+	input := &ScheduleRegisterInput{
+		Title:          s.Title,
+		Description:    s.Description,
+		CronExpr:       s.CronExpr,
+		RRule:          s.RRule,
+		Interval:       s.Interval,
+		IntervalMode:   s.IntervalMode,
+		URL:            s.URL,
+		Action:         s.Action,
+		IsRecurring:    &isRecurring,
+		Metadata:       s.Metadata,
+		TLSProfile:     s.TLSProfile,
+		Credentials:    s.Credentials,
+		SecretHeaders:  s.SecretHeaders,
+		Calendars:      s.Calendars,
+		BlackoutPolicy: s.BlackoutPolicy,
+		Jitter:         s.Jitter,
+		MaxRuns:        s.MaxRuns,
+		MaxRunsMode:    s.MaxRunsMode,
+	}
+
+	if !s.IsRecurring {
+		input.RunAt = s.RunAt
+		return input
+	}
+
+	input.StartAt = s.StartAt
+	if !s.EndAt.Equal(maxTime) {
+		input.EndAt = s.EndAt
+	}
+	return input
+}
+
+type CronSchedule struct {
+	ID           int64          `json:"id"`
+	Namespace    string         `json:"namespace"`
+	Title        string         `json:"title"`
+	Status       ScheduleStatus `json:"status"`
+	Description  string         `json:"description"`
+	CronExpr     string         `json:"cronExpr"`
+	RRule        string         `json:"rrule,omitempty"`
+	Interval     Duration       `json:"interval,omitempty"`
+	IntervalMode IntervalMode   `json:"intervalMode,omitempty"`
+	// Anchor is the time the intervals of interval schedules are counted from: the start of fixed-rate schedules,
+	// and the end of the last run of fixed-delay ones.
+	Anchor         time.Time         `json:"anchor,omitempty"`
+	URL            string            `json:"url"`
+	Action         string            `json:"action,omitempty"`
+	Metadata       map[string]string `json:"metadata"`
+	TLSProfile     string            `json:"tlsProfile,omitempty"`
+	Credentials    string            `json:"credentials,omitempty"`
+	SecretHeaders  map[string]string `json:"secretHeaders,omitempty"`
+	Calendars      []string          `json:"calendars,omitempty"`
+	BlackoutPolicy BlackoutPolicy    `json:"blackoutPolicy,omitempty"`
+	Jitter         Duration          `json:"jitter,omitempty"`
+	MaxRuns        int               `json:"maxRuns,omitempty"`
+	MaxRunsMode    MaxRunsMode       `json:"maxRunsMode,omitempty"`
+	// Runs and SuccessfulRuns count the scheduled runs of the schedule, which exclude manual triggers.
+	Runs           int       `json:"runs"`
+	SuccessfulRuns int       `json:"successfulRuns"`
+	CreatedAt      time.Time `json:"createdAt"`
+	IsRecurring    bool      `json:"isRecurring"`
+	RunAt          time.Time `json:"runAt,omitempty"`
+	StartAt        time.Time `json:"startAt"`
+	EndAt          time.Time `json:"endAt"`
+	Failures       int       `json:"-"`
+}
+
+func (s *CronSchedule) nextTick(start time.Time) time.Time {
+	if !s.IsRecurring {
+		return s.RunAt
+	}
+
+	// fixed-delay schedules run an interval after their last run, or as soon as possible if it is past,
+	// such as after a restart
+	if s.IsFixedDelay() && !s.Anchor.IsZero() {
+		if next := s.Anchor.Add(time.Duration(s.Interval)); next.After(start) {
+			return next
+		}
+		return start
+	}
+
+	rec, err := s.recurrence()
+	if err != nil {
+		return time.Time{}
+	}
+
+	// NextTick starts from the start date of the schedule until it is reached, which is also the DTSTART,
+	// and the first occurrence, of most rules, as well as the anchor of interval schedules
+	if (s.RRule != "" || s.Interval > 0) && start.Equal(s.dtstart()) && start.After(time.Now()) {
+		start = start.Add(-time.Nanosecond)
+	}
+	return rec.Next(start)
+}
+
+// dtstart returns the DTSTART of the rrule of the schedule, unless the rule defines its own:
+// the start date of the schedule if set, and its creation date otherwise.
+func (s *CronSchedule) dtstart() time.Time {
+	if s.StartAt.IsZero() {
+		return s.CreatedAt.Truncate(time.Second)
+	}
+	return s.StartAt
+}
+
+// IsFixedDelay reports whether the schedule runs an interval after the end of its previous run.
+func (s *CronSchedule) IsFixedDelay() bool {
+	return s.Interval > 0 && s.IntervalMode == IntervalModeFixedDelay
+}
+
+func (s *CronSchedule) recurrence() (cron.Schedule, error) {
+	if s.Interval > 0 {
+		// until their first run, fixed-delay schedules are anchored like fixed-rate ones
+		anchor := s.Anchor
+		if s.IsFixedDelay() {
+			anchor = s.dtstart()
+		}
+		return cron.Every(time.Duration(s.Interval), anchor), nil
+	}
+	return recurrence(s.CronExpr, s.RRule, s.dtstart(), s.hashKey())
+}
+
+// hashKey is the key the H tokens of the cron expression of the schedule are hashed with,
+// so that they resolve to the same values as long as the schedule keeps its namespace and title.
+func (s *CronSchedule) hashKey() string {
+	return hashKey(s.Namespace, s.Title)
+}
+
+func hashKey(namespace, title string) string {
+	return namespace + "/" + title
+}
+
+// ResolvedCronExpr returns the cron expression of the schedule, whose H tokens are replaced by the values they stand for.
+func (s *CronSchedule) ResolvedCronExpr() (string, error) {
+	return cron.Resolve(s.CronExpr, s.hashKey())
+}
+
+// MinInterval returns the shortest interval between two consecutive activations of a recurring schedule,
+// among the first samples ones, which jitter may shorten.
+func (s *CronSchedule) MinInterval(samples int) (time.Duration, error) {
+	rec, err := s.recurrence()
+	if err != nil {
+		return 0, err
+	}
+
+	// the start date may be the first activation
+	min := cron.MinInterval(rec, s.dtstart().Add(-time.Nanosecond), samples)
+	if jitter := time.Duration(s.Jitter); min > jitter {
+		min -= jitter
+	}
+	return min, nil
+}
+
+// Expired reports whether the schedule won't run anymore, since it reached either its end date or its maxRuns.
+func (s *CronSchedule) Expired() bool {
+	return s.Status == ScheduleStatusExpired || !s.EndAt.After(time.Now())
+}
+
+// RunsExhausted reports whether the schedule has run as many times as its maxRuns allow.
+func (s *CronSchedule) RunsExhausted() bool {
+	if s.MaxRuns == 0 {
+		return false
+	}
+
+	if s.MaxRunsMode == MaxRunsModeSuccessful {
+		return s.SuccessfulRuns >= s.MaxRuns
+	}
+	return s.Runs >= s.MaxRuns
+}
+
+func (s *CronSchedule) NextTick() time.Time {
+	now := time.Now()
+
+	start := now
+	if s.StartAt.After(now) {
+		start = s.StartAt
+	}
+
+	next := s.nextTick(start)
+	if s.Jitter > 0 && s.IsRecurring && !next.IsZero() {
+		next = next.Add(time.Duration(rand.Int63n(int64(s.Jitter))))
+	}
+	return next
+}
+
+func (s *CronSchedule) IsActive() bool {
+	return s.Status == ScheduleStatusActive
+}
+
+type CronStatus struct {
+	CronID     int64         `json:"cronId"`
+	Namespace  string        `json:"namespace"`
+	At         time.Time     `json:"at"`
+	StatusCode int           `json:"statusCode"`
+	Duration   time.Duration `json:"duration"`
+	Lag        time.Duration `json:"lag"`
+	// Skipped is the reason why the occurrence was not delivered, if it was excluded by a calendar of the schedule.
+	Skipped string `json:"skipped,omitempty"`
+}
@@ -0,0 +1,302 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCronExpr(t *testing.T) {
+	cases := []struct {
+		expr  string
+		valid bool
+	}{
+		{"*/5 * * * *", true},
+		{"@hourly", true},
+		{"@every 10m", true},
+		{"0 0 18 LW * ?", true},
+		{"0 0 9 ? * MON#1 2099", true},
+		{"0 0 9 ? * MON#1 2020", false},
+		{"0 0 0 30 2 ?", false},
+		{"0 0 * * 5#6", false},
+		{"@reboot", false},
+	}
+
+	recurring := true
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			_, err := (&ScheduleRegisterInput{
+				Title:       "test",
+				URL:         "http://localhost",
+				IsRecurring: &recurring,
+				CronExpr:    c.expr,
+			}).ToSched(DefaultNamespace)
+
+			if c.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateRRule(t *testing.T) {
+	cases := []struct {
+		cronExpr string
+		rrule    string
+		valid    bool
+	}{
+		{"", "FREQ=WEEKLY;BYDAY=MO,WE", true},
+		{"", "DTSTART:20240101T090000Z\nRRULE:FREQ=MONTHLY;BYDAY=-1FR", true},
+		{"", "FREQ=DAILY;COUNT=3", true},
+		{"", "DTSTART:20200101T090000Z\nRRULE:FREQ=DAILY;COUNT=3", false},
+		{"", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", false},
+		{"", "FREQ=FORTNIGHTLY", false},
+		{"@daily", "FREQ=DAILY", false},
+	}
+
+	recurring := true
+	for _, c := range cases {
+		t.Run(c.rrule, func(t *testing.T) {
+			_, err := (&ScheduleRegisterInput{
+				Title:       "test",
+				URL:         "http://localhost",
+				IsRecurring: &recurring,
+				CronExpr:    c.cronExpr,
+				RRule:       c.rrule,
+			}).ToSched(DefaultNamespace)
+
+			if c.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestRRuleNextTick(t *testing.T) {
+	recurring := true
+	startAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	sched, err := (&ScheduleRegisterInput{
+		Title:       "test",
+		URL:         "http://localhost",
+		IsRecurring: &recurring,
+		RRule:       "FREQ=DAILY;INTERVAL=2;COUNT=2",
+		StartAt:     startAt,
+		EndAt:       startAt.AddDate(1, 0, 0),
+	}).ToSched(DefaultNamespace)
+	require.NoError(t, err)
+
+	// the start date is the first occurrence
+	require.True(t, startAt.Equal(sched.NextTick()))
+	require.True(t, startAt.AddDate(0, 0, 2).Equal(sched.nextTick(startAt.Add(time.Second))))
+	require.True(t, sched.nextTick(startAt.AddDate(0, 0, 2)).IsZero())
+
+	interval, err := sched.MinInterval(10)
+	require.NoError(t, err)
+	require.Equal(t, startAt.AddDate(0, 0, 2).Sub(startAt), interval)
+}
+
+func TestHashedCronExpr(t *testing.T) {
+	recurring := true
+
+	schedule := func(namespace, title string) *CronSchedule {
+		sched, err := (&ScheduleRegisterInput{
+			Title:       title,
+			URL:         "http://localhost",
+			IsRecurring: &recurring,
+			CronExpr:    "H * * * *",
+		}).ToSched(namespace)
+		require.NoError(t, err)
+		return sched
+	}
+
+	a, b := schedule(DefaultNamespace, "a"), schedule(DefaultNamespace, "b")
+
+	resolved, err := a.ResolvedCronExpr()
+	require.NoError(t, err)
+	require.NotContains(t, resolved, "H")
+	require.Equal(t, a.NextTick(), schedule(DefaultNamespace, "a").NextTick())
+
+	// schedules differing by title or namespace are spread over the hour
+	require.NotEqual(t, a.NextTick().Minute(), b.NextTick().Minute())
+	require.NotEqual(t, a.NextTick().Minute(), schedule("other", "a").NextTick().Minute())
+
+	// expressions are validated with H tokens resolved as they are at runtime, where job-2 picks the 30th of February
+	_, err = (&ScheduleRegisterInput{
+		Title:       "job-2",
+		URL:         "http://localhost",
+		IsRecurring: &recurring,
+		CronExpr:    "0 0 H(28-31) 2 *",
+	}).ToSched(DefaultNamespace)
+	require.ErrorContains(t, err, "has no activations")
+}
+
+func TestJitter(t *testing.T) {
+	recurring, oneShot := true, false
+
+	input := &ScheduleRegisterInput{
+		Title:       "test",
+		URL:         "http://localhost",
+		IsRecurring: &recurring,
+		CronExpr:    "0 * * * *",
+		Jitter:      Duration(10 * time.Minute),
+	}
+
+	sched, err := input.ToSched(DefaultNamespace)
+	require.NoError(t, err)
+
+	next := sched.nextTick(time.Now())
+	for i := 0; i < 100; i++ {
+		tick := sched.NextTick()
+		require.False(t, tick.Before(next), tick)
+		require.True(t, tick.Before(next.Add(time.Duration(sched.Jitter))), tick)
+	}
+
+	interval, err := sched.MinInterval(10)
+	require.NoError(t, err)
+	require.Equal(t, 50*time.Minute, interval)
+
+	for _, jitter := range []Duration{Duration(-time.Minute), Duration(time.Hour)} {
+		input.Jitter = jitter
+		_, err := input.ToSched(DefaultNamespace)
+		require.Error(t, err, jitter)
+	}
+
+	_, err = (&ScheduleRegisterInput{
+		Title:       "test",
+		URL:         "http://localhost",
+		IsRecurring: &oneShot,
+		RunAt:       time.Now().Add(time.Hour),
+		Jitter:      Duration(time.Minute),
+	}).ToSched(DefaultNamespace)
+	require.Error(t, err)
+}
+
+func TestIntervalNextTick(t *testing.T) {
+	recurring := true
+	startAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	input := &ScheduleRegisterInput{
+		Title:       "test",
+		URL:         "http://localhost",
+		IsRecurring: &recurring,
+		Interval:    Duration(90 * time.Minute),
+		StartAt:     startAt,
+		EndAt:       startAt.AddDate(1, 0, 0),
+	}
+
+	// fixed-rate schedules run at their start, and then at whole intervals from it
+	sched, err := input.ToSched(DefaultNamespace)
+	require.NoError(t, err)
+	require.Equal(t, IntervalModeFixedRate, sched.IntervalMode)
+	require.True(t, startAt.Equal(sched.Anchor))
+	require.True(t, startAt.Equal(sched.NextTick()))
+	require.True(t, startAt.Add(3*time.Hour).Equal(sched.nextTick(startAt.Add(100*time.Minute))))
+
+	interval, err := sched.MinInterval(10)
+	require.NoError(t, err)
+	require.Equal(t, 90*time.Minute, interval)
+
+	// without a start, they run at whole intervals from their creation
+	input.StartAt = time.Time{}
+	sched, err = input.ToSched(DefaultNamespace)
+	require.NoError(t, err)
+	require.True(t, sched.CreatedAt.Truncate(time.Second).Equal(sched.Anchor))
+	require.True(t, sched.Anchor.Add(90*time.Minute).Equal(sched.NextTick()))
+
+	// fixed-delay schedules run an interval after their last run, or right away if it is past
+	input.IntervalMode = IntervalModeFixedDelay
+	sched, err = input.ToSched(DefaultNamespace)
+	require.NoError(t, err)
+	require.True(t, sched.Anchor.IsZero())
+	require.True(t, sched.CreatedAt.Truncate(time.Second).Add(90*time.Minute).Equal(sched.NextTick()))
+
+	sched.Anchor = time.Now().Add(-time.Hour)
+	require.True(t, sched.Anchor.Add(90*time.Minute).Equal(sched.NextTick()))
+
+	sched.Anchor = time.Now().Add(-2 * time.Hour)
+	require.WithinDuration(t, time.Now(), sched.NextTick(), time.Second)
+
+	for _, invalid := range []*ScheduleRegisterInput{
+		{Interval: Duration(time.Millisecond)},
+		{Interval: Duration(time.Hour), CronExpr: "0 * * * *"},
+		{Interval: Duration(time.Hour), IntervalMode: "sometimes"},
+		{CronExpr: "0 * * * *", IntervalMode: IntervalModeFixedDelay},
+	} {
+		invalid.Title, invalid.URL, invalid.IsRecurring = "test", "http://localhost", &recurring
+		_, err := invalid.ToSched(DefaultNamespace)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestRunAfter(t *testing.T) {
+	oneShot := false
+
+	input := &ScheduleRegisterInput{
+		Title:       "test",
+		URL:         "http://localhost",
+		IsRecurring: &oneShot,
+		RunAfter:    Duration(15 * time.Minute),
+	}
+
+	sched, err := input.ToSched(DefaultNamespace)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().Add(15*time.Minute), sched.RunAt, time.Second)
+	require.True(t, sched.RunAt.Equal(sched.NextTick()))
+
+	input.RunAt = time.Now().Add(time.Hour)
+	_, err = input.ToSched(DefaultNamespace)
+	require.Error(t, err)
+
+	input.RunAt = time.Time{}
+	input.RunAfter = Duration(-time.Minute)
+	_, err = input.ToSched(DefaultNamespace)
+	require.Error(t, err)
+}
+
+func TestMaxRuns(t *testing.T) {
+	recurring, oneShot := true, false
+
+	input := &ScheduleRegisterInput{
+		Title:       "test",
+		URL:         "http://localhost",
+		IsRecurring: &recurring,
+		CronExpr:    "0 * * * *",
+		MaxRuns:     2,
+	}
+
+	sched, err := input.ToSched(DefaultNamespace)
+	require.NoError(t, err)
+	require.Equal(t, MaxRunsModeTotal, sched.MaxRunsMode)
+
+	sched.Runs = 2
+	require.True(t, sched.RunsExhausted())
+
+	// failed runs don't count towards the successful ones
+	sched.MaxRunsMode = MaxRunsModeSuccessful
+	sched.SuccessfulRuns = 1
+	require.False(t, sched.RunsExhausted())
+
+	sched.Status = ScheduleStatusExpired
+	require.True(t, sched.Expired())
+
+	for _, mutate := range []func(*ScheduleRegisterInput){
+		func(i *ScheduleRegisterInput) { i.MaxRuns = -1 },
+		func(i *ScheduleRegisterInput) { i.MaxRunsMode = "sometimes" },
+		func(i *ScheduleRegisterInput) { i.MaxRuns, i.MaxRunsMode = 0, MaxRunsModeSuccessful },
+		func(i *ScheduleRegisterInput) { i.RunAfter = Duration(time.Minute) },
+		func(i *ScheduleRegisterInput) {
+			i.IsRecurring, i.CronExpr, i.RunAt = &oneShot, "", time.Now().Add(time.Hour)
+		},
+	} {
+		in := *input
+		mutate(&in)
+		_, err := in.ToSched(DefaultNamespace)
+		require.Error(t, err)
+	}
+}
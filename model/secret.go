@@ -0,0 +1,64 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var secretNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9._-]{0,126}[a-zA-Z0-9])?$`)
+
+const redacted = "[REDACTED]"
+
+// SecretValue holds the plaintext of a secret. It is redacted whenever formatted or marshaled,
+// so that it never ends up in api responses or logs.
+type SecretValue string
+
+func (v SecretValue) String() string {
+	return redacted
+}
+
+func (v SecretValue) GoString() string {
+	return redacted
+}
+
+func (v SecretValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(redacted)
+}
+
+func (v *SecretValue) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*v = SecretValue(s)
+	return nil
+}
+
+type SecretInput struct {
+	Name  string      `json:"-"`
+	Value SecretValue `json:"value" validate:"required"`
+}
+
+func ValidateSecretName(name string) error {
+	if !secretNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q: only alphanumeric characters, '.', '_' and '-' are allowed", name)
+	}
+	return nil
+}
+
+// Secret describes a secret, whose value is never returned.
+type Secret struct {
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// EncryptedSecret is the stored form of a secret, whose value is encrypted with the key identified by KeyID.
+type EncryptedSecret struct {
+	Secret
+	KeyID      string
+	Ciphertext []byte
+}
@@ -0,0 +1,128 @@
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+type Percentiles struct {
+	P50 Duration `json:"p50"`
+	P95 Duration `json:"p95"`
+	P99 Duration `json:"p99"`
+	Max Duration `json:"max"`
+}
+
+type CronStats struct {
+	CronID        int64       `json:"cronId,omitempty"`
+	Window        string      `json:"window"`
+	From          time.Time   `json:"from"`
+	To            time.Time   `json:"to"`
+	Runs          int         `json:"runs"`
+	Successes     int         `json:"successes"`
+	Failures      int         `json:"failures"`
+	Skipped       int         `json:"skipped"`
+	SuccessRate   float64     `json:"successRate"`
+	Latency       Percentiles `json:"latency"`
+	Lag           Percentiles `json:"lag"`
+	LastSuccessAt *time.Time  `json:"lastSuccessAt"`
+	LastFailureAt *time.Time  `json:"lastFailureAt"`
+	// Truncated reports that the history retained for some schedules, which is limited to their latest samples,
+	// doesn't cover the whole window. The statistics of a single schedule then start from its oldest sample.
+	Truncated bool `json:"truncated"`
+}
+
+func (s *CronStatus) IsSuccess() bool {
+	return s.StatusCode >= 200 && s.StatusCode < 300
+}
+
+// ComputeStats aggregates the given statuses, which are expected to fall within the [to - window, to] interval.
+func ComputeStats(statuses []*CronStatus, window time.Duration, to time.Time) *CronStats {
+	stats := &CronStats{
+		Window: window.String(),
+		From:   to.Add(-window),
+		To:     to,
+	}
+
+	durations := make([]time.Duration, 0, len(statuses))
+	lags := make([]time.Duration, 0, len(statuses))
+	for _, s := range statuses {
+		// occurrences excluded by calendars are not runs
+		if s.Skipped != "" {
+			stats.Skipped++
+			continue
+		}
+		stats.Runs++
+
+		at := s.At
+
+		if s.IsSuccess() {
+			stats.Successes++
+			if stats.LastSuccessAt == nil || at.After(*stats.LastSuccessAt) {
+				stats.LastSuccessAt = &at
+			}
+		} else {
+			stats.Failures++
+			if stats.LastFailureAt == nil || at.After(*stats.LastFailureAt) {
+				stats.LastFailureAt = &at
+			}
+		}
+		durations = append(durations, s.Duration)
+		lags = append(lags, s.Lag)
+	}
+
+	if stats.Runs > 0 {
+		stats.SuccessRate = float64(stats.Successes) / float64(stats.Runs)
+	}
+	stats.Latency = percentiles(durations)
+	stats.Lag = percentiles(lags)
+	return stats
+}
+
+// MarkTruncated flags the statistics computed from statuses as truncated if the window holds all the samples retained
+// for a schedule, since older ones, which may fall within the window as well, have then been discarded.
+func (stats *CronStats) MarkTruncated(statuses []*CronStatus, maxSamplesPerCron int) {
+	counts := make(map[int64]int)
+	oldest := make(map[int64]time.Time)
+	for _, s := range statuses {
+		counts[s.CronID]++
+		if at, ok := oldest[s.CronID]; !ok || s.At.Before(at) {
+			oldest[s.CronID] = s.At
+		}
+	}
+
+	for id, n := range counts {
+		if n < maxSamplesPerCron {
+			continue
+		}
+		stats.Truncated = true
+
+		if stats.CronID == id && oldest[id].After(stats.From) {
+			stats.From = oldest[id]
+		}
+	}
+}
+
+func percentiles(values []time.Duration) Percentiles {
+	if len(values) == 0 {
+		return Percentiles{}
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		return values[i] < values[j]
+	})
+
+	return Percentiles{
+		P50: Duration(nearestRank(values, 50)),
+		P95: Duration(nearestRank(values, 95)),
+		P99: Duration(nearestRank(values, 99)),
+		Max: Duration(values[len(values)-1]),
+	}
+}
+
+func nearestRank(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
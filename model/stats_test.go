@@ -0,0 +1,77 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeStats(t *testing.T) {
+	now := time.Now()
+
+	statuses := make([]*CronStatus, 0, 100)
+	for i := 1; i <= 100; i++ {
+		code := 200
+		if i%10 == 0 {
+			code = 500
+		}
+
+		statuses = append(statuses, &CronStatus{
+			CronID:     1,
+			At:         now.Add(-time.Duration(i) * time.Minute),
+			StatusCode: code,
+			Duration:   time.Duration(i) * time.Millisecond,
+			Lag:        time.Duration(100-i) * time.Millisecond,
+		})
+	}
+
+	stats := ComputeStats(statuses, time.Hour*2, now)
+	require.Equal(t, 100, stats.Runs)
+	require.Equal(t, 90, stats.Successes)
+	require.Equal(t, 10, stats.Failures)
+	require.InDelta(t, 0.9, stats.SuccessRate, 1e-9)
+
+	require.Equal(t, Duration(50*time.Millisecond), stats.Latency.P50)
+	require.Equal(t, Duration(95*time.Millisecond), stats.Latency.P95)
+	require.Equal(t, Duration(99*time.Millisecond), stats.Latency.P99)
+	require.Equal(t, Duration(100*time.Millisecond), stats.Latency.Max)
+	require.Equal(t, Duration(99*time.Millisecond), stats.Lag.Max)
+
+	// percentiles are encoded as the other durations of the api
+	data, err := json.Marshal(stats.Latency)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"p50":"50ms","p95":"95ms","p99":"99ms","max":"100ms"}`, string(data))
+
+	require.Equal(t, now.Add(-time.Minute), *stats.LastSuccessAt)
+	require.Equal(t, now.Add(-10*time.Minute), *stats.LastFailureAt)
+}
+
+func TestComputeStatsEmpty(t *testing.T) {
+	stats := ComputeStats(nil, time.Hour, time.Now())
+	require.Zero(t, stats.Runs)
+	require.Zero(t, stats.SuccessRate)
+	require.Nil(t, stats.LastSuccessAt)
+	require.Nil(t, stats.LastFailureAt)
+}
+
+func TestMarkTruncated(t *testing.T) {
+	now := time.Now()
+
+	statuses := make([]*CronStatus, 0, 10)
+	for i := 1; i <= 10; i++ {
+		statuses = append(statuses, &CronStatus{CronID: 1, At: now.Add(-time.Duration(i) * time.Minute), StatusCode: 200})
+	}
+
+	stats := ComputeStats(statuses, time.Hour, now)
+	stats.MarkTruncated(statuses, 20)
+	require.False(t, stats.Truncated)
+	require.Equal(t, now.Add(-time.Hour), stats.From)
+
+	// the window holds all the retained samples, so older ones may have been discarded
+	stats.CronID = 1
+	stats.MarkTruncated(statuses, 10)
+	require.True(t, stats.Truncated)
+	require.Equal(t, now.Add(-10*time.Minute), stats.From)
+}
@@ -0,0 +1,160 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ostafen/kronos/model"
+)
+
+var ErrAPIKeyNotExist = errors.New("api key does not exist")
+
+type APIKeyRepository interface {
+	Save(ctx context.Context, key *model.APIKey) (int64, error)
+	GetByHash(ctx context.Context, hash string) (*model.APIKey, error)
+	List(ctx context.Context, namespace string) ([]*model.APIKey, error)
+	Delete(ctx context.Context, namespace string, id int64) error
+}
+
+var apiKeysCols = []string{
+	"id",
+	"name",
+	"namespace",
+	"prefix",
+	"key_hash",
+	"scopes",
+	"created_at",
+	"expires_at",
+}
+
+func (s *sqlStore) migrateAPIKeys() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS api_keys (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name VARCHAR NOT NULL,
+			prefix VARCHAR NOT NULL,
+			key_hash VARCHAR NOT NULL UNIQUE,
+			scopes VARCHAR NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	// an empty namespace denotes global keys, which are not bound to any namespace
+	return s.addColumn("api_keys", "namespace", "VARCHAR NOT NULL DEFAULT ''")
+}
+
+func (s *sqlStore) APIKeyRepository() APIKeyRepository {
+	return &apiKeyRepo{db: s.db}
+}
+
+type apiKeyRepo struct {
+	db *sql.DB
+}
+
+func (r *apiKeyRepo) Save(ctx context.Context, key *model.APIKey) (int64, error) {
+	ctx, done := observe(ctx, "api_keys", "save")
+	defer done()
+
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return -1, err
+	}
+
+	row := r.db.QueryRowContext(ctx,
+		fmt.Sprintf(
+			`INSERT INTO api_keys(%s) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+			strings.Join(apiKeysCols[1:], ","),
+		),
+		key.Name,
+		key.Namespace,
+		key.Prefix,
+		key.Hash,
+		scopes,
+		key.CreatedAt,
+		key.ExpiresAt,
+	)
+
+	var id int64
+	err = row.Scan(&id)
+	return id, err
+}
+
+func (r *apiKeyRepo) GetByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	ctx, done := observe(ctx, "api_keys", "get_by_hash")
+	defer done()
+
+	row := r.db.QueryRowContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM api_keys WHERE key_hash = $1", strings.Join(apiKeysCols, ",")),
+		hash,
+	)
+
+	key, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotExist
+	}
+	return key, err
+}
+
+func (r *apiKeyRepo) List(ctx context.Context, namespace string) ([]*model.APIKey, error) {
+	ctx, done := observe(ctx, "api_keys", "list")
+	defer done()
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM api_keys WHERE $1 = '' OR namespace = $1 ORDER BY id", strings.Join(apiKeysCols, ",")),
+		namespace,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]*model.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *apiKeyRepo) Delete(ctx context.Context, namespace string, id int64) error {
+	ctx, done := observe(ctx, "api_keys", "delete")
+	defer done()
+
+	res, err := r.db.ExecContext(ctx, "DELETE FROM api_keys WHERE id = $1 AND ($2 = '' OR namespace = $2)", id, namespace)
+	return checkAffected(res, err, ErrAPIKeyNotExist)
+}
+
+func scanAPIKey[T interface{ Scan(...any) error }](row T) (*model.APIKey, error) {
+	var key model.APIKey
+	var scopes string
+
+	err := row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.Namespace,
+		&key.Prefix,
+		&key.Hash,
+		&scopes,
+		&key.CreatedAt,
+		&key.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal([]byte(scopes), &key.Scopes)
+	return &key, err
+}
@@ -0,0 +1,195 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ostafen/kronos/model"
+)
+
+// AuditRepository is append-only: entries can never be modified nor deleted.
+type AuditRepository interface {
+	Insert(ctx context.Context, entry *model.AuditEntry) error
+	Query(ctx context.Context, query *model.AuditQuery) ([]*model.AuditEntry, error)
+}
+
+var auditLogCols = []string{
+	"id",
+	"namespace",
+	"at",
+	"actor",
+	"method",
+	"source_ip",
+	"action",
+	"schedule_id",
+	"before",
+	"after",
+}
+
+func (s *sqlStore) migrateAuditLog() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			namespace VARCHAR NOT NULL,
+			at TIMESTAMP NOT NULL,
+			actor VARCHAR NOT NULL,
+			method VARCHAR NOT NULL,
+			source_ip VARCHAR NOT NULL,
+			action VARCHAR NOT NULL,
+			schedule_id INTEGER NOT NULL,
+			before TEXT,
+			after TEXT
+		);
+
+		CREATE INDEX IF NOT EXISTS audit_log_schedule ON audit_log(schedule_id);
+
+		CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+		BEGIN
+			SELECT RAISE(ABORT, 'audit log is append-only');
+		END;
+
+		CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+		BEGIN
+			SELECT RAISE(ABORT, 'audit log is append-only');
+		END;
+	`)
+	return err
+}
+
+func (s *sqlStore) AuditRepository() AuditRepository {
+	return &auditRepo{db: s.db}
+}
+
+type auditRepo struct {
+	db *sql.DB
+}
+
+func (r *auditRepo) Insert(ctx context.Context, entry *model.AuditEntry) error {
+	ctx, done := observe(ctx, "audit_log", "insert")
+	defer done()
+
+	before, err := marshalSnapshot(entry.Before)
+	if err != nil {
+		return err
+	}
+
+	after, err := marshalSnapshot(entry.After)
+	if err != nil {
+		return err
+	}
+
+	row := r.db.QueryRowContext(ctx,
+		fmt.Sprintf(
+			`INSERT INTO audit_log(%s) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+			strings.Join(auditLogCols[1:], ","),
+		),
+		entry.Namespace,
+		entry.At,
+		entry.Actor,
+		entry.Method,
+		entry.SourceIP,
+		entry.Action,
+		entry.ScheduleID,
+		before,
+		after,
+	)
+	return row.Scan(&entry.ID)
+}
+
+func (r *auditRepo) Query(ctx context.Context, query *model.AuditQuery) ([]*model.AuditEntry, error) {
+	ctx, done := observe(ctx, "audit_log", "query")
+	defer done()
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		fmt.Sprintf(`
+			SELECT %s FROM audit_log
+			WHERE ($1 = '' OR namespace = $1)
+				AND ($2 = '' OR actor = $2)
+				AND ($3 = '' OR action = $3)
+				AND ($4 = 0 OR schedule_id = $4)
+				AND ($5 = 0 OR id < $5)
+				AND ($6 OR at >= $7)
+				AND ($8 OR at <= $9)
+			ORDER BY id DESC
+			LIMIT $10`,
+			strings.Join(auditLogCols, ","),
+		),
+		query.Namespace,
+		query.Actor,
+		query.Action,
+		query.ScheduleID,
+		query.Cursor,
+		query.From.IsZero(),
+		query.From,
+		query.To.IsZero(),
+		query.To,
+		query.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]*model.AuditEntry, 0)
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func marshalSnapshot(sched *model.CronSchedule) (sql.NullString, error) {
+	if sched == nil {
+		return sql.NullString{}, nil
+	}
+
+	data, err := json.Marshal(sched)
+	return sql.NullString{String: string(data), Valid: err == nil}, err
+}
+
+func unmarshalSnapshot(s sql.NullString) (*model.CronSchedule, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+
+	var sched model.CronSchedule
+	if err := json.Unmarshal([]byte(s.String), &sched); err != nil {
+		return nil, err
+	}
+	return &sched, nil
+}
+
+func scanAuditEntry[T interface{ Scan(...any) error }](row T) (*model.AuditEntry, error) {
+	var entry model.AuditEntry
+	var before, after sql.NullString
+
+	err := row.Scan(
+		&entry.ID,
+		&entry.Namespace,
+		&entry.At,
+		&entry.Actor,
+		&entry.Method,
+		&entry.SourceIP,
+		&entry.Action,
+		&entry.ScheduleID,
+		&before,
+		&after,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Before, err = unmarshalSnapshot(before); err != nil {
+		return nil, err
+	}
+
+	entry.After, err = unmarshalSnapshot(after)
+	return &entry, err
+}
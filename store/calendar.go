@@ -0,0 +1,169 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ostafen/kronos/model"
+)
+
+var ErrCalendarNotExist = errors.New("calendar does not exist")
+
+type CalendarRepository interface {
+	// Save creates the calendar, or replaces an existing one.
+	Save(ctx context.Context, calendar *model.Calendar) error
+	Get(ctx context.Context, namespace, name string) (*model.Calendar, error)
+	List(ctx context.Context, namespace string) ([]*model.Calendar, error)
+	Delete(ctx context.Context, namespace, name string) error
+}
+
+var calendarsCols = []string{
+	"namespace",
+	"name",
+	"description",
+	"time_zone",
+	"dates",
+	"blackouts",
+	"created_at",
+	"updated_at",
+}
+
+func (s *sqlStore) migrateCalendars() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS calendars (
+			namespace VARCHAR NOT NULL,
+			name VARCHAR NOT NULL,
+			description VARCHAR NOT NULL,
+			time_zone VARCHAR NOT NULL,
+			dates VARCHAR NOT NULL,
+			blackouts VARCHAR NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (namespace, name)
+		)
+	`)
+	return err
+}
+
+func (s *sqlStore) CalendarRepository() CalendarRepository {
+	return &calendarRepo{db: s.db}
+}
+
+type calendarRepo struct {
+	db *sql.DB
+}
+
+func (r *calendarRepo) Save(ctx context.Context, calendar *model.Calendar) error {
+	ctx, done := observe(ctx, "calendars", "save")
+	defer done()
+
+	dates, err := json.Marshal(calendar.Dates)
+	if err != nil {
+		return err
+	}
+
+	blackouts, err := json.Marshal(calendar.Blackouts)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		fmt.Sprintf(
+			`INSERT INTO calendars(%s) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (namespace, name) DO UPDATE
+			SET description = excluded.description, time_zone = excluded.time_zone, dates = excluded.dates,
+				blackouts = excluded.blackouts, updated_at = excluded.updated_at`,
+			strings.Join(calendarsCols, ","),
+		),
+		calendar.Namespace,
+		calendar.Name,
+		calendar.Description,
+		calendar.TimeZone,
+		dates,
+		blackouts,
+		calendar.CreatedAt,
+		calendar.UpdatedAt,
+	)
+	return err
+}
+
+func (r *calendarRepo) Get(ctx context.Context, namespace, name string) (*model.Calendar, error) {
+	ctx, done := observe(ctx, "calendars", "get")
+	defer done()
+
+	row := r.db.QueryRowContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM calendars WHERE namespace = $1 AND name = $2", strings.Join(calendarsCols, ",")),
+		namespace,
+		name,
+	)
+
+	calendar, err := scanCalendar(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCalendarNotExist
+	}
+	return calendar, err
+}
+
+func (r *calendarRepo) List(ctx context.Context, namespace string) ([]*model.Calendar, error) {
+	ctx, done := observe(ctx, "calendars", "list")
+	defer done()
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM calendars WHERE $1 = '' OR namespace = $1 ORDER BY namespace, name", strings.Join(calendarsCols, ",")),
+		namespace,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	calendars := make([]*model.Calendar, 0)
+	for rows.Next() {
+		calendar, err := scanCalendar(rows)
+		if err != nil {
+			return nil, err
+		}
+		calendars = append(calendars, calendar)
+	}
+	return calendars, rows.Err()
+}
+
+func (r *calendarRepo) Delete(ctx context.Context, namespace, name string) error {
+	ctx, done := observe(ctx, "calendars", "delete")
+	defer done()
+
+	res, err := r.db.ExecContext(ctx, "DELETE FROM calendars WHERE namespace = $1 AND name = $2", namespace, name)
+	return checkAffected(res, err, ErrCalendarNotExist)
+}
+
+func scanCalendar[T interface{ Scan(...any) error }](row T) (*model.Calendar, error) {
+	var calendar model.Calendar
+	var dates, blackouts string
+
+	err := row.Scan(
+		&calendar.Namespace,
+		&calendar.Name,
+		&calendar.Description,
+		&calendar.TimeZone,
+		&dates,
+		&blackouts,
+		&calendar.CreatedAt,
+		&calendar.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(dates), &calendar.Dates); err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal([]byte(blackouts), &calendar.Blackouts)
+	return &calendar, err
+}
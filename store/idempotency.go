@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ostafen/kronos/model"
+)
+
+var (
+	ErrIdempotencyKeyNotExist = errors.New("idempotency key does not exist")
+	ErrIdempotencyKeyExists   = errors.New("idempotency key already exists")
+)
+
+type IdempotencyRepository interface {
+	// Reserve stores a record which is still in progress, failing if the key is already taken.
+	Reserve(ctx context.Context, record *model.IdempotencyRecord) error
+	Get(ctx context.Context, scope, key string) (*model.IdempotencyRecord, error)
+	// Complete stores the response of a reserved record.
+	Complete(ctx context.Context, record *model.IdempotencyRecord) error
+	Delete(ctx context.Context, scope, key string) error
+	// DeleteBefore deletes the records created before t, and returns how many were deleted.
+	DeleteBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+var idempotencyCols = []string{
+	"scope",
+	"key",
+	"fingerprint",
+	"status_code",
+	"content_type",
+	"body",
+	"created_at",
+}
+
+func (s *sqlStore) migrateIdempotencyKeys() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS idempotency_keys (
+			scope VARCHAR NOT NULL,
+			key VARCHAR NOT NULL,
+			fingerprint VARCHAR NOT NULL,
+			status_code INTEGER NOT NULL,
+			content_type VARCHAR NOT NULL,
+			body BLOB,
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (scope, key)
+		);
+
+		CREATE INDEX IF NOT EXISTS idempotency_keys_created_at ON idempotency_keys(created_at);
+	`)
+	return err
+}
+
+func (s *sqlStore) IdempotencyRepository() IdempotencyRepository {
+	return &idempotencyRepo{db: s.db}
+}
+
+type idempotencyRepo struct {
+	db *sql.DB
+}
+
+func (r *idempotencyRepo) Reserve(ctx context.Context, record *model.IdempotencyRecord) error {
+	ctx, done := observe(ctx, "idempotency_keys", "reserve")
+	defer done()
+
+	res, err := r.db.ExecContext(
+		ctx,
+		fmt.Sprintf(`INSERT INTO idempotency_keys(%s) VALUES ($1, $2, $3, 0, '', NULL, $4) ON CONFLICT (scope, key) DO NOTHING`,
+			strings.Join(idempotencyCols, ","),
+		),
+		record.Scope,
+		record.Key,
+		record.Fingerprint,
+		record.CreatedAt,
+	)
+	return checkAffected(res, err, ErrIdempotencyKeyExists)
+}
+
+func (r *idempotencyRepo) Get(ctx context.Context, scope, key string) (*model.IdempotencyRecord, error) {
+	ctx, done := observe(ctx, "idempotency_keys", "get")
+	defer done()
+
+	row := r.db.QueryRowContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM idempotency_keys WHERE scope = $1 AND key = $2", strings.Join(idempotencyCols, ",")),
+		scope,
+		key,
+	)
+
+	var record model.IdempotencyRecord
+	err := row.Scan(
+		&record.Scope,
+		&record.Key,
+		&record.Fingerprint,
+		&record.StatusCode,
+		&record.ContentType,
+		&record.Body,
+		&record.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIdempotencyKeyNotExist
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *idempotencyRepo) Complete(ctx context.Context, record *model.IdempotencyRecord) error {
+	ctx, done := observe(ctx, "idempotency_keys", "complete")
+	defer done()
+
+	res, err := r.db.ExecContext(
+		ctx,
+		`UPDATE idempotency_keys SET status_code = $1, content_type = $2, body = $3 WHERE scope = $4 AND key = $5`,
+		record.StatusCode,
+		record.ContentType,
+		record.Body,
+		record.Scope,
+		record.Key,
+	)
+	return checkAffected(res, err, ErrIdempotencyKeyNotExist)
+}
+
+func (r *idempotencyRepo) Delete(ctx context.Context, scope, key string) error {
+	ctx, done := observe(ctx, "idempotency_keys", "delete")
+	defer done()
+
+	res, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2", scope, key)
+	return checkAffected(res, err, ErrIdempotencyKeyNotExist)
+}
+
+func (r *idempotencyRepo) DeleteBefore(ctx context.Context, t time.Time) (int64, error) {
+	ctx, done := observe(ctx, "idempotency_keys", "delete_before")
+	defer done()
+
+	res, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE created_at < $1", t)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ostafen/kronos/model"
+)
+
+var (
+	ErrNamespaceNotExist = errors.New("namespace does not exist")
+	ErrNamespaceExists   = errors.New("namespace already exists")
+)
+
+type NamespaceRepository interface {
+	Get(ctx context.Context, name string) (*model.Namespace, error)
+	Create(ctx context.Context, ns *model.Namespace) error
+	Update(ctx context.Context, ns *model.Namespace) error
+	Delete(ctx context.Context, name string) error
+	List(ctx context.Context) ([]*model.Namespace, error)
+}
+
+var namespacesCols = []string{
+	"name",
+	"description",
+	"max_schedules",
+	"min_interval",
+	"max_deliveries_per_minute",
+	"created_at",
+}
+
+func (s *sqlStore) migrateNamespaces() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS namespaces (
+			name VARCHAR PRIMARY KEY,
+			description VARCHAR NOT NULL,
+			max_schedules INTEGER NOT NULL,
+			min_interval INTEGER NOT NULL,
+			max_deliveries_per_minute INTEGER NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(
+		fmt.Sprintf(`INSERT INTO namespaces(%s) VALUES ($1, '', 0, 0, 0, $2) ON CONFLICT (name) DO NOTHING`, strings.Join(namespacesCols, ",")),
+		model.DefaultNamespace,
+		time.Now(),
+	)
+	return err
+}
+
+func (s *sqlStore) NamespaceRepository() NamespaceRepository {
+	return &namespaceRepo{db: s.db}
+}
+
+type namespaceRepo struct {
+	db *sql.DB
+}
+
+func (r *namespaceRepo) Get(ctx context.Context, name string) (*model.Namespace, error) {
+	ctx, done := observe(ctx, "namespaces", "get")
+	defer done()
+
+	row := r.db.QueryRowContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM namespaces WHERE name = $1", strings.Join(namespacesCols, ",")),
+		name,
+	)
+
+	ns, err := scanNamespace(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNamespaceNotExist
+	}
+	return ns, err
+}
+
+func (r *namespaceRepo) Create(ctx context.Context, ns *model.Namespace) error {
+	ctx, done := observe(ctx, "namespaces", "create")
+	defer done()
+
+	res, err := r.db.ExecContext(
+		ctx,
+		fmt.Sprintf(`INSERT INTO namespaces(%s) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (name) DO NOTHING`, strings.Join(namespacesCols, ",")),
+		ns.Name,
+		ns.Description,
+		ns.Quota.MaxSchedules,
+		ns.Quota.MinInterval,
+		ns.Quota.MaxDeliveriesPerMinute,
+		ns.CreatedAt,
+	)
+	return checkAffected(res, err, ErrNamespaceExists)
+}
+
+func (r *namespaceRepo) Update(ctx context.Context, ns *model.Namespace) error {
+	ctx, done := observe(ctx, "namespaces", "update")
+	defer done()
+
+	res, err := r.db.ExecContext(
+		ctx,
+		`UPDATE namespaces SET description = $1, max_schedules = $2, min_interval = $3, max_deliveries_per_minute = $4 WHERE name = $5`,
+		ns.Description,
+		ns.Quota.MaxSchedules,
+		ns.Quota.MinInterval,
+		ns.Quota.MaxDeliveriesPerMinute,
+		ns.Name,
+	)
+	return checkAffected(res, err, ErrNamespaceNotExist)
+}
+
+func (r *namespaceRepo) Delete(ctx context.Context, name string) error {
+	ctx, done := observe(ctx, "namespaces", "delete")
+	defer done()
+
+	res, err := r.db.ExecContext(ctx, "DELETE FROM namespaces WHERE name = $1", name)
+	return checkAffected(res, err, ErrNamespaceNotExist)
+}
+
+func (r *namespaceRepo) List(ctx context.Context) ([]*model.Namespace, error) {
+	ctx, done := observe(ctx, "namespaces", "list")
+	defer done()
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM namespaces ORDER BY name", strings.Join(namespacesCols, ",")),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	namespaces := make([]*model.Namespace, 0)
+	for rows.Next() {
+		ns, err := scanNamespace(rows)
+		if err != nil {
+			return nil, err
+		}
+		namespaces = append(namespaces, ns)
+	}
+	return namespaces, rows.Err()
+}
+
+func scanNamespace[T interface{ Scan(...any) error }](row T) (*model.Namespace, error) {
+	var ns model.Namespace
+
+	err := row.Scan(
+		&ns.Name,
+		&ns.Description,
+		&ns.Quota.MaxSchedules,
+		&ns.Quota.MinInterval,
+		&ns.Quota.MaxDeliveriesPerMinute,
+		&ns.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &ns, nil
+}
+
+// checkAffected returns errNoRows if the statement didn't affect any row.
+func checkAffected(res sql.Result, err error, errNoRows error) error {
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err == nil && n == 0 {
+		return errNoRows
+	}
+	return err
+}
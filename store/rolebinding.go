@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ostafen/kronos/model"
+)
+
+var ErrRoleBindingNotExist = errors.New("role binding does not exist")
+
+type RoleBindingRepository interface {
+	Save(ctx context.Context, binding *model.RoleBinding) (int64, error)
+	// List returns the bindings of a namespace. Bindings spanning all namespaces are only returned for AllNamespaces.
+	List(ctx context.Context, namespace string) ([]*model.RoleBinding, error)
+	ListBySubject(ctx context.Context, subject string) ([]*model.RoleBinding, error)
+	Delete(ctx context.Context, namespace string, id int64) error
+}
+
+var roleBindingsCols = []string{
+	"id",
+	"subject",
+	"role",
+	"namespace",
+	"selector",
+	"created_at",
+}
+
+func (s *sqlStore) migrateRoleBindings() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS role_bindings (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			subject VARCHAR NOT NULL,
+			role VARCHAR NOT NULL,
+			namespace VARCHAR NOT NULL,
+			selector VARCHAR NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`CREATE INDEX IF NOT EXISTS role_bindings_subject ON role_bindings(subject)`)
+	return err
+}
+
+func (s *sqlStore) RoleBindingRepository() RoleBindingRepository {
+	return &roleBindingRepo{db: s.db}
+}
+
+type roleBindingRepo struct {
+	db *sql.DB
+}
+
+func (r *roleBindingRepo) Save(ctx context.Context, binding *model.RoleBinding) (int64, error) {
+	ctx, done := observe(ctx, "role_bindings", "save")
+	defer done()
+
+	selector, err := json.Marshal(binding.Selector)
+	if err != nil {
+		return -1, err
+	}
+
+	row := r.db.QueryRowContext(ctx,
+		fmt.Sprintf(
+			`INSERT INTO role_bindings(%s) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+			strings.Join(roleBindingsCols[1:], ","),
+		),
+		binding.Subject,
+		binding.Role,
+		binding.Namespace,
+		selector,
+		binding.CreatedAt,
+	)
+
+	var id int64
+	err = row.Scan(&id)
+	return id, err
+}
+
+func (r *roleBindingRepo) List(ctx context.Context, namespace string) ([]*model.RoleBinding, error) {
+	ctx, done := observe(ctx, "role_bindings", "list")
+	defer done()
+
+	return r.query(ctx, "WHERE $1 = '' OR namespace = $1", namespace)
+}
+
+func (r *roleBindingRepo) ListBySubject(ctx context.Context, subject string) ([]*model.RoleBinding, error) {
+	ctx, done := observe(ctx, "role_bindings", "list_by_subject")
+	defer done()
+
+	return r.query(ctx, "WHERE subject = $1", subject)
+}
+
+func (r *roleBindingRepo) query(ctx context.Context, where string, args ...any) ([]*model.RoleBinding, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM role_bindings %s ORDER BY id", strings.Join(roleBindingsCols, ","), where),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bindings := make([]*model.RoleBinding, 0)
+	for rows.Next() {
+		binding, err := scanRoleBinding(rows)
+		if err != nil {
+			return nil, err
+		}
+		bindings = append(bindings, binding)
+	}
+	return bindings, rows.Err()
+}
+
+func (r *roleBindingRepo) Delete(ctx context.Context, namespace string, id int64) error {
+	ctx, done := observe(ctx, "role_bindings", "delete")
+	defer done()
+
+	res, err := r.db.ExecContext(ctx, "DELETE FROM role_bindings WHERE id = $1 AND ($2 = '' OR namespace = $2)", id, namespace)
+	return checkAffected(res, err, ErrRoleBindingNotExist)
+}
+
+func scanRoleBinding[T interface{ Scan(...any) error }](row T) (*model.RoleBinding, error) {
+	var binding model.RoleBinding
+	var selector string
+
+	err := row.Scan(
+		&binding.ID,
+		&binding.Subject,
+		&binding.Role,
+		&binding.Namespace,
+		&selector,
+		&binding.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal([]byte(selector), &binding.Selector)
+	return &binding, err
+}
@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ostafen/kronos/model"
+)
+
+var ErrSecretNotExist = errors.New("secret does not exist")
+
+type SecretRepository interface {
+	// Save creates the secret, or replaces the value of an existing one.
+	Save(ctx context.Context, secret *model.EncryptedSecret) error
+	Get(ctx context.Context, namespace, name string) (*model.EncryptedSecret, error)
+	List(ctx context.Context, namespace string) ([]*model.EncryptedSecret, error)
+	Delete(ctx context.Context, namespace, name string) error
+}
+
+var secretsCols = []string{
+	"namespace",
+	"name",
+	"key_id",
+	"ciphertext",
+	"created_at",
+	"updated_at",
+}
+
+func (s *sqlStore) migrateSecrets() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS secrets (
+			namespace VARCHAR NOT NULL,
+			name VARCHAR NOT NULL,
+			key_id VARCHAR NOT NULL,
+			ciphertext BLOB NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (namespace, name)
+		)
+	`)
+	return err
+}
+
+func (s *sqlStore) SecretRepository() SecretRepository {
+	return &secretRepo{db: s.db}
+}
+
+type secretRepo struct {
+	db *sql.DB
+}
+
+func (r *secretRepo) Save(ctx context.Context, secret *model.EncryptedSecret) error {
+	ctx, done := observe(ctx, "secrets", "save")
+	defer done()
+
+	_, err := r.db.ExecContext(ctx,
+		fmt.Sprintf(
+			`INSERT INTO secrets(%s) VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (namespace, name) DO UPDATE
+			SET key_id = excluded.key_id, ciphertext = excluded.ciphertext, updated_at = excluded.updated_at`,
+			strings.Join(secretsCols, ","),
+		),
+		secret.Namespace,
+		secret.Name,
+		secret.KeyID,
+		secret.Ciphertext,
+		secret.CreatedAt,
+		secret.UpdatedAt,
+	)
+	return err
+}
+
+func (r *secretRepo) Get(ctx context.Context, namespace, name string) (*model.EncryptedSecret, error) {
+	ctx, done := observe(ctx, "secrets", "get")
+	defer done()
+
+	row := r.db.QueryRowContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM secrets WHERE namespace = $1 AND name = $2", strings.Join(secretsCols, ",")),
+		namespace,
+		name,
+	)
+
+	secret, err := scanSecret(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSecretNotExist
+	}
+	return secret, err
+}
+
+func (r *secretRepo) List(ctx context.Context, namespace string) ([]*model.EncryptedSecret, error) {
+	ctx, done := observe(ctx, "secrets", "list")
+	defer done()
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM secrets WHERE $1 = '' OR namespace = $1 ORDER BY namespace, name", strings.Join(secretsCols, ",")),
+		namespace,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	secrets := make([]*model.EncryptedSecret, 0)
+	for rows.Next() {
+		secret, err := scanSecret(rows)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, rows.Err()
+}
+
+func (r *secretRepo) Delete(ctx context.Context, namespace, name string) error {
+	ctx, done := observe(ctx, "secrets", "delete")
+	defer done()
+
+	res, err := r.db.ExecContext(ctx, "DELETE FROM secrets WHERE namespace = $1 AND name = $2", namespace, name)
+	return checkAffected(res, err, ErrSecretNotExist)
+}
+
+func scanSecret[T interface{ Scan(...any) error }](row T) (*model.EncryptedSecret, error) {
+	var secret model.EncryptedSecret
+
+	err := row.Scan(
+		&secret.Namespace,
+		&secret.Name,
+		&secret.KeyID,
+		&secret.Ciphertext,
+		&secret.CreatedAt,
+		&secret.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
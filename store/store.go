@@ -0,0 +1,694 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/ostafen/kronos/internal/metrics"
+	"github.com/ostafen/kronos/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrScheduleNotExist = errors.New("schedule does not exist")
+	ErrScheduleExists   = errors.New("a schedule with the same title already exists")
+)
+
+// AllNamespaces can be passed to repository methods to disable namespace scoping,
+// and should only be used by internal components, such as the scheduler.
+const AllNamespaces = ""
+
+type Store interface {
+	CronScheduleRepository() CronScheduleRepository
+	HistoryRepository() CronHistoryRepository
+	APIKeyRepository() APIKeyRepository
+	NamespaceRepository() NamespaceRepository
+	RoleBindingRepository() RoleBindingRepository
+	AuditRepository() AuditRepository
+	SecretRepository() SecretRepository
+	CalendarRepository() CalendarRepository
+	IdempotencyRepository() IdempotencyRepository
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+type CronScheduleRepository interface {
+	Get(ctx context.Context, namespace string, id int64) (*model.CronSchedule, error)
+	// Save inserts or updates a schedule. Updates never move a schedule to a different namespace.
+	Save(ctx context.Context, sched *model.CronSchedule) (int64, error)
+	Delete(ctx context.Context, namespace string, id int64) error
+	Iter(ctx context.Context, namespace string, iterFunc func(cron *model.CronSchedule) error) error
+	Count(ctx context.Context, namespace string) (int, error)
+	// IncrementRuns atomically adds to the run counters of a schedule, which Save never overwrites,
+	// and returns their updated values.
+	IncrementRuns(ctx context.Context, id int64, runs, successfulRuns int) (int, int, error)
+}
+
+type CronHistoryRepository interface {
+	Insert(ctx context.Context, status *model.CronStatus, maxSamplesPerCron int) error
+	GetHistory(ctx context.Context, namespace string, n int) ([]*model.CronStatus, error)
+	GetCronHistory(ctx context.Context, namespace string, cronID int64, n int) ([]*model.CronStatus, error)
+	GetHistorySince(ctx context.Context, namespace string, since time.Time) ([]*model.CronStatus, error)
+	GetCronHistorySince(ctx context.Context, namespace string, cronID int64, since time.Time) ([]*model.CronStatus, error)
+}
+
+var (
+	cronSchedulesCols = []string{
+		"id",
+		"namespace",
+		"title",
+		"status",
+		"description",
+		"cron_expr",
+		"url",
+		"metadata",
+		"created_at",
+		"is_recurring",
+		"run_at",
+		"start_at",
+		"end_at",
+		"tls_profile",
+		"credentials",
+		"secret_headers",
+		"action",
+		"rrule",
+		"calendars",
+		"blackout_policy",
+		"jitter",
+		"interval",
+		"interval_mode",
+		"anchor",
+		"max_runs",
+		"max_runs_mode",
+		"runs",
+		"successful_runs",
+	}
+
+	cronStatusCols = []string{
+		"cron_id",
+		"namespace",
+		"at",
+		"status_code",
+		"duration",
+		"lag",
+		"skipped",
+	}
+)
+
+var (
+	cronSchedulesValues []string
+	cronStatusValues    []string
+)
+
+func init() {
+	cronSchedulesValues = make([]string, len(cronSchedulesCols))
+	for i := range cronSchedulesCols {
+		cronSchedulesValues[i] = fmt.Sprintf("$%d", i)
+	}
+
+	cronStatusValues = make([]string, len(cronStatusCols))
+	for i := range cronStatusCols {
+		cronStatusValues[i] = fmt.Sprintf("$%d", i)
+	}
+}
+
+var tracer = otel.Tracer("github.com/ostafen/kronos/store")
+
+// observe traces a store operation and records its duration once the returned function is called.
+func observe(ctx context.Context, repository, operation string) (context.Context, func()) {
+	start := time.Now()
+
+	ctx, span := tracer.Start(ctx, repository+"."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "sqlite"),
+			attribute.String("db.operation", operation),
+		),
+	)
+	return ctx, func() {
+		span.End()
+		metrics.ObserveStoreOperation(repository, operation, start)
+	}
+}
+
+type sqlStore struct {
+	db *sql.DB
+}
+
+func (s *sqlStore) migrate() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS cron_schedules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			title VARCHAR NOT NULL,
+			status VARCHAR NOT NULL,
+			description VARCHAR NOT NULL,
+			cron_expr VARCHAR NOT NULL,
+			url VARCHAR NOT NULL,
+			metadata VARCHAR,
+			created_at TIMESTAMP NOT NULL,
+			is_recurring BOOLEAN NOT NULL,
+			run_at TIMESTAMP,
+			start_at TIMESTAMP NOT NULL,
+			end_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS cron_status (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			cron_id INTEGER,
+			at TIMESTAMP,
+			status_code INTEGER,
+			duration INTEGER
+		);
+
+		CREATE INDEX IF NOT EXISTS at_index ON cron_status(at);
+	`)
+	if err != nil {
+		return err
+	}
+	if err := s.addColumn("cron_status", "lag", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	if err := s.addColumn("cron_schedules", "namespace", "VARCHAR NOT NULL DEFAULT 'default'"); err != nil {
+		return err
+	}
+
+	if err := s.addColumn("cron_status", "namespace", "VARCHAR NOT NULL DEFAULT 'default'"); err != nil {
+		return err
+	}
+
+	if err := s.addColumn("cron_schedules", "tls_profile", "VARCHAR NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	if err := s.addColumn("cron_schedules", "credentials", "VARCHAR NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	if err := s.addColumn("cron_schedules", "secret_headers", "VARCHAR NOT NULL DEFAULT 'null'"); err != nil {
+		return err
+	}
+
+	if err := s.addColumn("cron_schedules", "action", "VARCHAR NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	if err := s.addColumn("cron_schedules", "rrule", "VARCHAR NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	if err := s.addColumn("cron_schedules", "calendars", "VARCHAR NOT NULL DEFAULT 'null'"); err != nil {
+		return err
+	}
+
+	if err := s.addColumn("cron_schedules", "blackout_policy", "VARCHAR NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	if err := s.addColumn("cron_schedules", "jitter", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	if err := s.addColumn("cron_schedules", "interval", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	if err := s.addColumn("cron_schedules", "interval_mode", "VARCHAR NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	if err := s.addColumn("cron_schedules", "anchor", "TIMESTAMP NOT NULL DEFAULT '0001-01-01 00:00:00+00:00'"); err != nil {
+		return err
+	}
+
+	if err := s.addColumn("cron_schedules", "max_runs", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	if err := s.addColumn("cron_schedules", "max_runs_mode", "VARCHAR NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	if err := s.addColumn("cron_schedules", "runs", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	if err := s.addColumn("cron_schedules", "successful_runs", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	if err := s.addColumn("cron_status", "skipped", "VARCHAR NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	if err := s.migrateNamespaces(); err != nil {
+		return err
+	}
+	if err := s.migrateAPIKeys(); err != nil {
+		return err
+	}
+	if err := s.migrateRoleBindings(); err != nil {
+		return err
+	}
+	if err := s.migrateSecrets(); err != nil {
+		return err
+	}
+	if err := s.migrateCalendars(); err != nil {
+		return err
+	}
+	if err := s.migrateUniqueTitles(); err != nil {
+		return err
+	}
+	if err := s.migrateIdempotencyKeys(); err != nil {
+		return err
+	}
+	return s.migrateAuditLog()
+}
+
+// migrateUniqueTitles enforces the uniqueness of titles within each namespace.
+// Titles duplicated before the constraint existed are disambiguated by appending the id of the schedule.
+func (s *sqlStore) migrateUniqueTitles() error {
+	_, err := s.db.Exec(`
+		UPDATE cron_schedules SET title = title || ' (' || id || ')'
+		WHERE id NOT IN (SELECT MIN(id) FROM cron_schedules GROUP BY namespace, title);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS cron_schedules_title ON cron_schedules(namespace, title);
+	`)
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
+
+func (s *sqlStore) addColumn(table, column, definition string) error {
+	var n int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info($1) WHERE name = $2`, table, column).Scan(&n)
+	if err != nil || n > 0 {
+		return err
+	}
+
+	_, err = s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+func New(path string) (Store, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	s := &sqlStore{db: db}
+	return s, s.migrate()
+}
+
+func (s *sqlStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *sqlStore) CronScheduleRepository() CronScheduleRepository {
+	return &cronScheduleRepo{s.db}
+}
+
+func (s *sqlStore) HistoryRepository() CronHistoryRepository {
+	return &statusRepo{db: s.db}
+}
+
+type cronScheduleRepo struct {
+	db *sql.DB
+}
+
+func (s *cronScheduleRepo) Get(ctx context.Context, namespace string, id int64) (*model.CronSchedule, error) {
+	ctx, done := observe(ctx, "schedules", "get")
+	defer done()
+
+	row := s.db.QueryRowContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM cron_schedules WHERE id = $1 AND ($2 = '' OR namespace = $2)", strings.Join(cronSchedulesCols, ",")),
+		id,
+		namespace,
+	)
+
+	cron, err := scanCron(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrScheduleNotExist
+	}
+	return cron, err
+}
+
+func (s *cronScheduleRepo) Save(ctx context.Context, cron *model.CronSchedule) (int64, error) {
+	ctx, done := observe(ctx, "schedules", "save")
+	defer done()
+
+	metadata, err := json.Marshal(cron.Metadata)
+	if err != nil {
+		return -1, err
+	}
+
+	secretHeaders, err := json.Marshal(cron.SecretHeaders)
+	if err != nil {
+		return -1, err
+	}
+
+	calendars, err := json.Marshal(cron.Calendars)
+	if err != nil {
+		return -1, err
+	}
+
+	values := []any{
+		cron.ID,
+		cron.Namespace,
+		cron.Title,
+		cron.Status,
+		cron.Description,
+		cron.CronExpr,
+		cron.URL,
+		metadata,
+		cron.CreatedAt,
+		cron.IsRecurring,
+		cron.RunAt,
+		cron.StartAt,
+		cron.EndAt,
+		cron.TLSProfile,
+		cron.Credentials,
+		secretHeaders,
+		cron.Action,
+		cron.RRule,
+		calendars,
+		cron.BlackoutPolicy,
+		cron.Jitter,
+		cron.Interval,
+		cron.IntervalMode,
+		cron.Anchor,
+		cron.MaxRuns,
+		cron.MaxRunsMode,
+		cron.Runs,
+		cron.SuccessfulRuns,
+	}
+
+	cols := cronSchedulesCols
+	placeHolders := cronSchedulesValues
+	if cron.ID <= 0 {
+		cols = cols[1:]
+		values = values[1:]
+		placeHolders = placeHolders[:len(placeHolders)-1]
+	}
+
+	row := s.db.QueryRowContext(
+		ctx,
+		fmt.Sprintf(
+			`INSERT INTO cron_schedules(%s) VALUES (%s)
+			ON CONFLICT (id) DO UPDATE
+			SET title = excluded.title, status = excluded.status, description = excluded.description,
+				cron_expr = excluded.cron_expr, url = excluded.url, metadata = excluded.metadata,
+				is_recurring = excluded.is_recurring, run_at = excluded.run_at, start_at = excluded.start_at,
+				end_at = excluded.end_at, tls_profile = excluded.tls_profile, credentials = excluded.credentials,
+				secret_headers = excluded.secret_headers, action = excluded.action, rrule = excluded.rrule,
+				calendars = excluded.calendars, blackout_policy = excluded.blackout_policy,
+				jitter = excluded.jitter, interval = excluded.interval, interval_mode = excluded.interval_mode,
+				anchor = excluded.anchor, max_runs = excluded.max_runs, max_runs_mode = excluded.max_runs_mode
+			WHERE cron_schedules.namespace = excluded.namespace
+			RETURNING id;
+			`,
+			strings.Join(cols, ","),
+			strings.Join(placeHolders, ","),
+		),
+		values...,
+	)
+
+	var id int64
+	err = row.Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return -1, ErrScheduleNotExist
+	}
+
+	if isUniqueViolation(err) {
+		return -1, fmt.Errorf("%w: %q", ErrScheduleExists, cron.Title)
+	}
+	return id, err
+}
+
+func (s *cronScheduleRepo) Delete(ctx context.Context, namespace string, id int64) error {
+	ctx, done := observe(ctx, "schedules", "delete")
+	defer done()
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM cron_schedules WHERE id = $1 AND ($2 = '' OR namespace = $2)", id, namespace)
+	return checkAffected(res, err, ErrScheduleNotExist)
+}
+
+func (s *cronScheduleRepo) Count(ctx context.Context, namespace string) (int, error) {
+	ctx, done := observe(ctx, "schedules", "count")
+	defer done()
+
+	var n int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM cron_schedules WHERE $1 = '' OR namespace = $1", namespace).Scan(&n)
+	return n, err
+}
+
+func (s *cronScheduleRepo) IncrementRuns(ctx context.Context, id int64, runs, successfulRuns int) (int, int, error) {
+	ctx, done := observe(ctx, "schedules", "increment_runs")
+	defer done()
+
+	row := s.db.QueryRowContext(
+		ctx,
+		`UPDATE cron_schedules SET runs = runs + $1, successful_runs = successful_runs + $2
+		WHERE id = $3
+		RETURNING runs, successful_runs`,
+		runs,
+		successfulRuns,
+		id,
+	)
+
+	err := row.Scan(&runs, &successfulRuns)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, ErrScheduleNotExist
+	}
+	return runs, successfulRuns, err
+}
+
+func (s *cronScheduleRepo) Iter(ctx context.Context, namespace string, onCron func(cron *model.CronSchedule) error) error {
+	ctx, done := observe(ctx, "schedules", "iter")
+	defer done()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM cron_schedules WHERE $1 = '' OR namespace = $1", strings.Join(cronSchedulesCols, ",")),
+		namespace,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		cron, err := scanCron(rows)
+		if err != nil {
+			return err
+		}
+
+		if err := onCron(cron); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanCron[T interface{ Scan(...any) error }](row T) (*model.CronSchedule, error) {
+	var cron model.CronSchedule
+	var metadata, secretHeaders, calendars string
+
+	err := row.Scan(
+		&cron.ID,
+		&cron.Namespace,
+		&cron.Title,
+		&cron.Status,
+		&cron.Description,
+		&cron.CronExpr,
+		&cron.URL,
+		&metadata,
+		&cron.CreatedAt,
+		&cron.IsRecurring,
+		&cron.RunAt,
+		&cron.StartAt,
+		&cron.EndAt,
+		&cron.TLSProfile,
+		&cron.Credentials,
+		&secretHeaders,
+		&cron.Action,
+		&cron.RRule,
+		&calendars,
+		&cron.BlackoutPolicy,
+		&cron.Jitter,
+		&cron.Interval,
+		&cron.IntervalMode,
+		&cron.Anchor,
+		&cron.MaxRuns,
+		&cron.MaxRunsMode,
+		&cron.Runs,
+		&cron.SuccessfulRuns,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(metadata), &cron.Metadata); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(secretHeaders), &cron.SecretHeaders); err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal([]byte(calendars), &cron.Calendars)
+	return &cron, err
+}
+
+type statusRepo struct {
+	db *sql.DB
+}
+
+func (r *statusRepo) Insert(ctx context.Context, cs *model.CronStatus, maxSamplesPerCron int) error {
+	ctx, done := observe(ctx, "history", "insert")
+	defer done()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		fmt.Sprintf(
+			`INSERT INTO cron_status(%s) VALUES(%s)`,
+			strings.Join(cronStatusCols, ","),
+			strings.Join(cronStatusValues, ","),
+		),
+		cs.CronID,
+		cs.Namespace,
+		cs.At,
+		cs.StatusCode,
+		cs.Duration,
+		cs.Lag,
+		cs.Skipped,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM cron_status WHERE cron_id = $1 AND id NOT IN (SELECT id FROM cron_status WHERE cron_id = $1 ORDER BY at DESC LIMIT $2)`, cs.CronID, maxSamplesPerCron)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *statusRepo) GetCronHistory(ctx context.Context, namespace string, cronID int64, n int) ([]*model.CronStatus, error) {
+	ctx, done := observe(ctx, "history", "get_cron_history")
+	defer done()
+
+	statuses := make([]*model.CronStatus, 0, n)
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM cron_status WHERE cron_id = $1 AND ($2 = '' OR namespace = $2) ORDER BY at DESC LIMIT $3", strings.Join(cronStatusCols, ",")),
+		cronID,
+		namespace,
+		n,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanStatuses(rows, statuses)
+}
+
+func (r *statusRepo) GetHistory(ctx context.Context, namespace string, n int) ([]*model.CronStatus, error) {
+	ctx, done := observe(ctx, "history", "get_history")
+	defer done()
+
+	statuses := make([]*model.CronStatus, 0, n)
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM cron_status WHERE $1 = '' OR namespace = $1 ORDER BY at DESC LIMIT $2", strings.Join(cronStatusCols, ",")),
+		namespace,
+		n,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanStatuses(rows, statuses)
+}
+
+func (r *statusRepo) GetCronHistorySince(ctx context.Context, namespace string, cronID int64, since time.Time) ([]*model.CronStatus, error) {
+	ctx, done := observe(ctx, "history", "get_cron_history_since")
+	defer done()
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM cron_status WHERE cron_id = $1 AND at >= $2 AND ($3 = '' OR namespace = $3) ORDER BY at DESC", strings.Join(cronStatusCols, ",")),
+		cronID,
+		since,
+		namespace,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanStatuses(rows, make([]*model.CronStatus, 0))
+}
+
+func (r *statusRepo) GetHistorySince(ctx context.Context, namespace string, since time.Time) ([]*model.CronStatus, error) {
+	ctx, done := observe(ctx, "history", "get_history_since")
+	defer done()
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM cron_status WHERE at >= $1 AND ($2 = '' OR namespace = $2) ORDER BY at DESC", strings.Join(cronStatusCols, ",")),
+		since,
+		namespace,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanStatuses(rows, make([]*model.CronStatus, 0))
+}
+
+func scanStatuses(rows *sql.Rows, statuses []*model.CronStatus) ([]*model.CronStatus, error) {
+	defer rows.Close()
+
+	for rows.Next() {
+		var s model.CronStatus
+		err := rows.Scan(
+			&s.CronID,
+			&s.Namespace,
+			&s.At,
+			&s.StatusCode,
+			&s.Duration,
+			&s.Lag,
+			&s.Skipped,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		statuses = append(statuses, &s)
+	}
+	return statuses, rows.Err()
+}
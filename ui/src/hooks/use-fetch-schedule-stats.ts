@@ -0,0 +1,24 @@
+import { useQuery } from '@tanstack/react-query';
+import ScheduleStats from '@/model/schedule-stats.ts';
+
+export default function useFetchScheduleStats(
+  scheduleId?: string,
+  window: string = '24h'
+) {
+  return useQuery({
+    queryKey: ['schedule-stats', scheduleId, window],
+    queryFn: () => fetchScheduleStats(window, scheduleId),
+    retry: false,
+  });
+}
+
+const fetchScheduleStats = async (
+  window: string,
+  scheduleId?: string
+): Promise<ScheduleStats> => {
+  const path = scheduleId ? `/schedules/${scheduleId}/stats` : '/stats';
+  const response = await fetch(
+    `${import.meta.env.VITE_API_URL}${path}?window=${encodeURIComponent(window)}`
+  );
+  return response.json();
+};
@@ -0,0 +1,22 @@
+export default interface ScheduleStats {
+  cronId?: number;
+  window: string;
+  from: string;
+  to: string;
+  runs: number;
+  successes: number;
+  failures: number;
+  successRate: number;
+  latency: Percentiles;
+  lag: Percentiles;
+  lastSuccessAt: string | null;
+  lastFailureAt: string | null;
+}
+
+// durations are Go duration strings, such as "1.5s"
+export interface Percentiles {
+  p50: string;
+  p95: string;
+  p99: string;
+  max: string;
+}